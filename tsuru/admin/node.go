@@ -17,7 +17,7 @@ import (
 
 	"github.com/ajg/form"
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/healer"
 	"github.com/tsuru/tsuru/net"
 	"github.com/tsuru/tsuru/provision"
@@ -95,7 +95,7 @@ func (a *AddNodeCmd) Run(ctx *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	ctx.Stdout.Write([]byte("Node successfully registered.\n"))
+	ctx.Successf("Node successfully registered.\n")
 	return nil
 }
 
@@ -165,7 +165,7 @@ func (a *UpdateNodeCmd) Run(ctx *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	ctx.Stdout.Write([]byte("Node successfully updated.\n"))
+	ctx.Successf("Node successfully updated.\n")
 	return nil
 }
 
@@ -218,7 +218,7 @@ func (c *RemoveNodeCmd) Run(ctx *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	ctx.Stdout.Write([]byte("Node successfully removed.\n"))
+	ctx.Successf("Node successfully removed.\n")
 	return nil
 }
 
@@ -484,7 +484,7 @@ func (c *SetNodeHealingConfigCmd) Run(ctx *cmd.Context, client *cmd.Client) erro
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	_, err = client.Do(req)
 	if err == nil {
-		fmt.Fprintln(ctx.Stdout, "Node healing configuration successfully updated.")
+		ctx.Successf("Node healing configuration successfully updated.\n")
 	}
 	return err
 }
@@ -556,7 +556,7 @@ func (c *DeleteNodeHealingConfigCmd) Run(ctx *cmd.Context, client *cmd.Client) e
 	}
 	_, err = client.Do(req)
 	if err == nil {
-		fmt.Fprintln(ctx.Stdout, "Node healing configuration successfully removed.")
+		ctx.Successf("Node healing configuration successfully removed.\n")
 	}
 	return err
 }
@@ -11,7 +11,7 @@ import (
 	"strings"
 
 	"github.com/ajg/form"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"github.com/tsuru/tsuru/provision"
 	"gopkg.in/check.v1"
@@ -17,7 +17,7 @@ import (
 	"sort"
 
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 )
 
 type platform struct {
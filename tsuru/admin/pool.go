@@ -12,7 +12,7 @@ import (
 	"strings"
 
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/errors"
 )
 
@@ -76,7 +76,7 @@ func (c *AddPoolToSchedulerCmd) Run(ctx *cmd.Context, client *cmd.Client) error
 		}
 		return err
 	}
-	ctx.Stdout.Write([]byte("Pool successfully registered.\n"))
+	ctx.Successf("Pool successfully registered.\n")
 	return nil
 }
 
@@ -99,7 +99,7 @@ func confirmAction(ctx *cmd.Context, client *cmd.Client, url, method, body strin
 		if err != nil {
 			return err
 		}
-		ctx.Stdout.Write([]byte(successMessage))
+		ctx.Successf("%s", successMessage)
 		return nil
 
 	}
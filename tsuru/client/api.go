@@ -0,0 +1,223 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	tsuruerr "github.com/tsuru/tsuru/errors"
+)
+
+var apiVersionPrefix = regexp.MustCompile(`^/\d+\.\d+/`)
+
+// APIRequest sends a raw request to the tsuru API, for the endpoints not
+// wrapped by other commands yet.
+type APIRequest struct {
+	fs      *gnuflag.FlagSet
+	data    string
+	headers cmd.StringSliceFlag
+	pretty  bool
+}
+
+func (c *APIRequest) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "api",
+		Usage: "api <method> <path> [-d/--data body] [-H/--header 'Name: value']... [--pretty]",
+		Desc: `Sends a request to the tsuru API of the current target, authenticated with
+the current session, and displays the body of the response. The status of the
+response is displayed in the standard error, so the body can be piped to other
+tools. It's an escape hatch for endpoints not supported by other commands yet.
+
+WARNING: this command is unstable, its usage and output may change in future
+versions. Don't rely on it in scripts.
+
+The path is relative to the target, like [[/apps/myapp]], and may start with
+the version of the API, like [[/1.5/apps]]. Paths without a version use the
+version 1.0, like the other commands.
+
+The [[--data]] flag is the body of the request. Values starting with @ are
+read from the file in the rest of the value, or from the standard input with
+@-. Bodies read from files with the .json extension and bodies holding a JSON
+object or array are sent with the application/json content type, other bodies
+with application/x-www-form-urlencoded. The [[--header]] flag adds a header to
+the request, like a custom Content-Type, and may be used multiple times. The
+Authorization header is always set with the token of the current session.
+
+The [[--pretty]] flag indents the body of JSON responses.
+
+Examples:
+
+==========
+tsuru api GET /apps/myapp --pretty
+tsuru api POST /apps/myapp/env --data 'Envs.0.Name=KEY&Envs.0.Value=value'
+tsuru api POST /1.5/pools --data @pool.json
+==========`,
+		MinArgs: 2,
+		MaxArgs: 2,
+	}
+}
+
+func (c *APIRequest) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("api", gnuflag.ExitOnError)
+		data := "Body of the request, read from a file when starting with @"
+		c.fs.StringVar(&c.data, "data", "", data)
+		c.fs.StringVar(&c.data, "d", "", data)
+		header := "Header added to the request, in the format 'Name: value'"
+		c.fs.Var(&c.headers, "header", header)
+		c.fs.Var(&c.headers, "H", header)
+		c.fs.BoolVar(&c.pretty, "pretty", false, "Indent the body of JSON responses")
+	}
+	return c.fs
+}
+
+func (c *APIRequest) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	method := strings.ToUpper(context.Args[0])
+	path := context.Args[1]
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("Invalid path %q: it must start with /.", path)
+	}
+	headers := make(http.Header)
+	for _, h := range c.headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return fmt.Errorf("Invalid header %q: it must be in the format 'Name: value'.", h)
+		}
+		headers.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+	body, contentType, err := c.body(context)
+	if err != nil {
+		return err
+	}
+	u, err := apiURL(path)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	for name, values := range headers {
+		request.Header[name] = values
+	}
+	if body != nil && request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	fmt.Fprintln(context.Stderr, "WARNING: the api command is unstable and may change in future versions.")
+	response, err := client.Do(request)
+	if httpErr, ok := err.(*tsuruerr.HTTP); ok && httpErr.Code != http.StatusUnauthorized && response != nil {
+		fmt.Fprintln(context.Stderr, response.Status)
+		if httpErr.Message != response.Status {
+			c.writeBody(context.Stdout, response, []byte(strings.TrimRight(httpErr.Message, "\n")+"\n"))
+		}
+		return fmt.Errorf("The API answered with status %d.", httpErr.Code)
+	}
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	fmt.Fprintln(context.Stderr, response.Status)
+	if !c.pretty {
+		_, err = io.Copy(context.Stdout, response.Body)
+		return err
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	return c.writeBody(context.Stdout, response, data)
+}
+
+// writeBody writes the body of the response, indented when it's JSON and
+// the --pretty flag is set.
+func (c *APIRequest) writeBody(w io.Writer, response *http.Response, data []byte) error {
+	if c.pretty && isJSONContentType(response.Header.Get("Content-Type")) {
+		var buf bytes.Buffer
+		if json.Indent(&buf, data, "", "  ") == nil {
+			buf.WriteString("\n")
+			data = buf.Bytes()
+		}
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// body returns the body of the request given in the --data flag, or nil
+// when the flag is not set, and its default content type.
+func (c *APIRequest) body(context *cmd.Context) (io.Reader, string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case c.data == "":
+		return nil, "", nil
+	case c.data == "@-":
+		data, err = ioutil.ReadAll(context.Stdin)
+		if err != nil {
+			return nil, "", err
+		}
+	case strings.HasPrefix(c.data, "@"):
+		file, err := filesystem().Open(c.data[1:])
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed to read the body of the request from %q: %s", c.data[1:], err)
+		}
+		defer file.Close()
+		data, err = ioutil.ReadAll(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed to read the body of the request from %q: %s", c.data[1:], err)
+		}
+	default:
+		data = []byte(c.data)
+	}
+	contentType := "application/x-www-form-urlencoded"
+	if strings.ToLower(filepath.Ext(c.data)) == ".json" || isJSONDocument(data) {
+		contentType = "application/json"
+	}
+	return bytes.NewReader(data), contentType, nil
+}
+
+// isJSONDocument reports whether data holds a JSON object or array.
+func isJSONDocument(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+	var doc json.RawMessage
+	return json.Unmarshal(trimmed, &doc) == nil
+}
+
+// apiURL returns the URL of the given path in the current target, using the
+// version 1.0 of the API when the path doesn't start with a version.
+func apiURL(path string) (string, error) {
+	if !apiVersionPrefix.MatchString(path) {
+		return cmd.GetURL(path)
+	}
+	target, err := cmd.GetTarget()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(target, "/") + path, nil
+}
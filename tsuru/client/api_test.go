@@ -0,0 +1,219 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	"github.com/tsuru/tsuru/cmd/cmdtest"
+	"github.com/tsuru/tsuru/fs/fstest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAPIRequestInfo(c *check.C) {
+	c.Assert((&APIRequest{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAPIRequestRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"get", "/1.5/apps/myapp"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `{"name":"myapp"}`, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Method == "GET" && req.URL.Path == "/1.5/apps/myapp"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := APIRequest{}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, `{"name":"myapp"}`)
+	c.Assert(stderr.String(), check.Equals, "WARNING: the api command is unstable and may change in future versions.\n200 OK\n")
+}
+
+func (s *S) TestAPIRequestRunWithData(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"POST", "/apps/myapp/env"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "ok", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			body, _ := ioutil.ReadAll(req.Body)
+			return req.Method == "POST" && req.URL.Path == "/1.0/apps/myapp/env" &&
+				string(body) == "key=value" &&
+				req.Header.Get("Content-Type") == "application/x-www-form-urlencoded" &&
+				req.Header.Get("X-Custom") == "custom value"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := APIRequest{}
+	command.Flags().Parse(true, []string{"-d", "key=value", "-H", "X-Custom: custom value"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "ok")
+}
+
+func (s *S) TestIsJSONDocument(c *check.C) {
+	tests := []struct {
+		data     string
+		expected bool
+	}{
+		{`{"name":"myapp"}`, true},
+		{` [1, 2]` + "\n", true},
+		{`{"name":`, false},
+		{`"myapp"`, false},
+		{`key=value`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		c.Check(isJSONDocument([]byte(tt.data)), check.Equals, tt.expected, check.Commentf("%q", tt.data))
+	}
+}
+
+func (s *S) TestAPIRequestRunWithJSONFile(c *check.C) {
+	rfs := &fstest.RecordingFs{}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	f, err := rfs.Create("/tmp/pool.json")
+	c.Assert(err, check.IsNil)
+	f.Write([]byte(`{"name": "mypool"}`))
+	f.Close()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"POST", "/1.5/pools"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusCreated},
+		CondFunc: func(req *http.Request) bool {
+			body, _ := ioutil.ReadAll(req.Body)
+			return req.Method == "POST" && req.URL.Path == "/1.5/pools" &&
+				string(body) == `{"name": "mypool"}` &&
+				req.Header.Get("Content-Type") == "application/json"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := APIRequest{}
+	command.Flags().Parse(true, []string{"--data", "@/tmp/pool.json"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestAPIRequestRunWithDataCustomContentType(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"POST", "/apps/myapp/deploy"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "ok", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Header.Get("Content-Type") == "text/plain"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := APIRequest{}
+	command.Flags().Parse(true, []string{"-d", `{"a": 1}`, "-H", "Content-Type: text/plain"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestAPIRequestRunPretty(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"GET", "/apps/myapp"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.Transport{
+		Message: `{"name":"myapp","units":[1,2]}`,
+		Status:  http.StatusOK,
+		Headers: map[string][]string{"Content-Type": {"application/json; charset=utf-8"}},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := APIRequest{}
+	command.Flags().Parse(true, []string{"--pretty"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `{
+  "name": "myapp",
+  "units": [
+    1,
+    2
+  ]
+}
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAPIRequestRunPrettyNotJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"GET", "/apps/myapp/log"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.Transport{
+		Message: `{"name":"myapp"}`,
+		Status:  http.StatusOK,
+		Headers: map[string][]string{"Content-Type": {"text/plain"}},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := APIRequest{}
+	command.Flags().Parse(true, []string{"--pretty"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, `{"name":"myapp"}`)
+}
+
+func (s *S) TestAPIRequestRunErrorStatus(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"DELETE", "/apps/myapp"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.Transport{Message: "app not found\n", Status: http.StatusNotFound}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := APIRequest{}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "The API answered with status 404.")
+	c.Assert(stdout.String(), check.Equals, "app not found\n")
+	c.Assert(strings.HasSuffix(stderr.String(), "404 Not Found\n"), check.Equals, true)
+}
+
+func (s *S) TestAPIRequestRunInvalidArgs(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"GET", "apps"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	command := APIRequest{}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, `Invalid path "apps": it must start with /.`)
+	context.Args = []string{"GET", "/apps"}
+	command = APIRequest{}
+	command.Flags().Parse(true, []string{"-H", "invalid"})
+	err = command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, `Invalid header "invalid": it must be in the format 'Name: value'.`)
+}
@@ -9,20 +9,25 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/cezarsa/form"
 	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	tsuruapp "github.com/tsuru/tsuru/app"
-	"github.com/tsuru/tsuru/cmd"
 	tsuruerr "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/git"
 )
 
 type AppCreate struct {
@@ -31,13 +36,14 @@ type AppCreate struct {
 	pool        string
 	description string
 	routerOpts  cmd.MapFlag
+	addRemote   bool
 	fs          *gnuflag.FlagSet
 }
 
 func (c *AppCreate) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "app-create",
-		Usage: "app-create <appname> <platform> [--plan/-p plan_name] [--team/-t (team owner)] [--pool/-o pool_name] [--description/-d description] [--router-opts key=value]...",
+		Usage: "app-create <appname> <platform> [--plan/-p plan_name] [--team/-t (team owner)] [--pool/-o pool_name] [--description/-d description] [--router-opts key=value]... [--add-remote]",
 		Desc: `Creates a new app using the given name and platform. For tsuru,
 a platform is provisioner dependent. To check the available platforms, use the
 command [[tsuru platform-list]] and to add a platform use the command [[tsuru-admin platform-add]].
@@ -59,8 +65,11 @@ If this parameter is not informed, tsuru will choose the plan with the
 [[default]] flag set to true.
 
 The [[--team]] parameter describes which team is responsible for the created
-app, this is only needed if the current user belongs to more than one team, in
-which case this parameter will be mandatory.
+app, this is only needed if the current user belongs to more than one team.
+When it's not given, the team in the TSURU_TEAM environment variable is used,
+or else the team set in the configuration file. If none of them is set and the
+user may create apps in more than one team, the teams are listed and the app is
+not created.
 
 The [[--pool]] parameter defines which pool your app will be deployed.
 This is only needed if you have more than one pool associated with your teams.
@@ -71,7 +80,12 @@ description associated.
 
 The [[--router-opts]] parameter allow passing custom parameters to the router
 used by the application's plan. The key and values used depends on the router
-implementation.`,
+implementation.
+
+The [[--add-remote]] flag adds the git repository of the app as the "tsuru"
+remote of the git repository in the current directory, allowing other commands
+to guess the name of the app. It's skipped when the current directory is not in
+a git repository or when the remote already exists.`,
 		MinArgs: 2,
 	}
 }
@@ -92,6 +106,7 @@ func (c *AppCreate) Flags() *gnuflag.FlagSet {
 		c.fs.StringVar(&c.description, "description", "", descriptionMessage)
 		c.fs.StringVar(&c.description, "d", "", descriptionMessage)
 		c.fs.Var(&c.routerOpts, "router-opts", "Router options")
+		c.fs.BoolVar(&c.addRemote, "add-remote", false, "Add the git repository of the app as the tsuru remote of the current git repository")
 	}
 	return c.fs
 }
@@ -99,6 +114,10 @@ func (c *AppCreate) Flags() *gnuflag.FlagSet {
 func (c *AppCreate) Run(context *cmd.Context, client *cmd.Client) error {
 	appName := context.Args[0]
 	platform := context.Args[1]
+	teamOwner, err := resolveTeam(client, c.teamOwner, "app.create")
+	if err != nil {
+		return err
+	}
 	v, err := form.EncodeToValues(map[string]interface{}{"routeropts": c.routerOpts})
 	if err != nil {
 		return err
@@ -106,7 +125,7 @@ func (c *AppCreate) Run(context *cmd.Context, client *cmd.Client) error {
 	v.Set("name", appName)
 	v.Set("platform", platform)
 	v.Set("plan", c.plan)
-	v.Set("teamOwner", c.teamOwner)
+	v.Set("teamOwner", teamOwner)
 	v.Set("pool", c.pool)
 	v.Set("description", c.description)
 	b := strings.NewReader(v.Encode())
@@ -133,14 +152,62 @@ func (c *AppCreate) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "App %q has been created!\n", appName)
+	context.Successf("App %q has been created!\n", appName)
 	fmt.Fprintln(context.Stdout, "Use app-info to check the status of the app and its units.")
 	if out["repository_url"] != "" {
 		fmt.Fprintf(context.Stdout, "Your repository for %q project is %q\n", appName, out["repository_url"])
 	}
+	if c.addRemote {
+		c.addGitRemote(context, out["repository_url"])
+	}
 	return nil
 }
 
+// addGitRemote adds the repository of the app as the tsuru remote of the
+// git repository in the current directory. The app is already created, so
+// failures are only reported.
+func (c *AppCreate) addGitRemote(context *cmd.Context, repositoryURL string) {
+	if repositoryURL == "" {
+		fmt.Fprintln(context.Stderr, "The app has no git repository, the tsuru remote was not added.")
+		return
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(context.Stderr, "Failed to add the tsuru remote: %s\n", err)
+		return
+	}
+	repoPath, err := git.DiscoverRepositoryPath(dir)
+	if err != nil {
+		fmt.Fprintln(context.Stderr, "Not inside a git repository, the tsuru remote was not added.")
+		return
+	}
+	repo, err := git.OpenRepository(repoPath)
+	if err == nil {
+		if _, remoteErr := repo.RemoteURL("tsuru"); remoteErr == nil {
+			fmt.Fprintln(context.Stderr, "The tsuru remote already exists in the git repository, it was not changed.")
+			return
+		}
+		err = appendGitRemote(repoPath, "tsuru", repositoryURL)
+	}
+	if err != nil {
+		fmt.Fprintf(context.Stderr, "Failed to add the tsuru remote: %s\n", err)
+		return
+	}
+	fmt.Fprintf(context.Stdout, "Added the tsuru remote to the git repository, pointing to %q.\n", repositoryURL)
+}
+
+// appendGitRemote declares a new remote in the config file of the git
+// repository at repoPath.
+func appendGitRemote(repoPath, name, url string) error {
+	config, err := os.OpenFile(filepath.Join(repoPath, "config"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	defer config.Close()
+	_, err = fmt.Fprintf(config, "[remote %q]\n\turl = %s\n\tfetch = +refs/heads/*:refs/remotes/%s/*\n", name, url, name)
+	return err
+}
+
 type AppUpdate struct {
 	description string
 	plan        string
@@ -192,7 +259,10 @@ func (c *AppUpdate) Flags() *gnuflag.FlagSet {
 
 func (c *AppUpdate) Run(context *cmd.Context, client *cmd.Client) error {
 	context.RawOutput()
-	appName := c.Flags().Lookup("app").Value.String()
+	appName, err := c.AppFlag()
+	if err != nil {
+		return err
+	}
 	if appName == "" {
 		return errors.New("Please use the -a/--app flag to specify which app you want to update.")
 	}
@@ -222,7 +292,87 @@ func (c *AppUpdate) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "App %q has been updated!\n", appName)
+	context.Successf("App %q has been updated!\n", appName)
+	return nil
+}
+
+type AppMove struct {
+	cmd.GuessingCommand
+	pool string
+	fs   *gnuflag.FlagSet
+}
+
+func (c *AppMove) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "app-move",
+		Usage: "app-move <-o/--pool pool> [-a/--app appname]",
+		Desc: `Moves an app to another pool. The units of the app are recreated in the
+nodes of the target pool, and the output of this process is displayed while
+the app is moved.
+
+The [[--pool]] parameter is mandatory and must be the name of a pool that
+your teams have access to. The list of available pools can be found running
+[[tsuru pool-list]].`,
+		MinArgs: 0,
+	}
+}
+
+func (c *AppMove) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		poolMessage := "The pool to move the app to"
+		c.fs.StringVar(&c.pool, "pool", "", poolMessage)
+		c.fs.StringVar(&c.pool, "o", "", poolMessage)
+	}
+	return c.fs
+}
+
+func (c *AppMove) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	if c.pool == "" {
+		return errors.New("Please use the -o/--pool flag to specify the pool you want to move the app to.")
+	}
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	pools, err := getPools(client)
+	if err != nil {
+		return err
+	}
+	var found bool
+	for _, p := range pools {
+		if p.Name == c.pool {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Pool %q does not exist or you don't have access to it.", c.pool)
+	}
+	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s", appName))
+	if err != nil {
+		return err
+	}
+	v := url.Values{}
+	v.Set("pool", c.pool)
+	request, err := http.NewRequest("PUT", u, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := client.Do(request)
+	if err != nil {
+		if e, ok := err.(*tsuruerr.HTTP); ok && e.Code == http.StatusForbidden {
+			return fmt.Errorf("You don't have permission to move the app %q to the pool %q.", appName, c.pool)
+		}
+		return err
+	}
+	err = cmd.StreamJSONResponse(context.Stdout, response)
+	if err != nil {
+		return err
+	}
+	context.Successf("App %q has been moved to pool %q!\n", appName, c.pool)
 	return nil
 }
 
@@ -247,7 +397,10 @@ remove it (you are able to remove any app that you see in [[tsuru app-list]]).`,
 
 func (c *AppRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	context.RawOutput()
-	appName := c.Flags().Lookup("app").Value.String()
+	appName, err := c.AppFlag()
+	if err != nil {
+		return err
+	}
 	if appName == "" {
 		return errors.New("Please use the -a/--app flag to specify which app you want to remove.")
 	}
@@ -281,77 +434,372 @@ func (c *AppRemove) Flags() *gnuflag.FlagSet {
 
 type AppInfo struct {
 	cmd.GuessingCommand
+	appFormat
+	fs         *gnuflag.FlagSet
+	watch      bool
+	interval   time.Duration
+	unitsOnly  bool
+	withStatus bool
+	json       bool
+	compare    bool
+	env        bool
 }
 
 func (c *AppInfo) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "app-info",
-		Usage: "app-info [-a/--app appname]",
+		Usage: "app-info [-a/--app appname]... [-w/--watch [--interval duration]] [--format template] [--units-only [--with-status]] [--env] [--compare] [--json]",
 		Desc: `Shows information about a specific app. Its state, platform, git repository,
 etc. You need to be a member of a team that has access to the app to be able to
-see information about it.`,
+see information about it.
+
+This command honors the global --output flag, so [[tsuru -o yaml app-info]]
+displays the app information in YAML format.
+
+The [[--format]] flag formats the app using the given Go template instead of
+displaying the detailed information, like [[docker inspect --format]]. For
+example, [[tsuru app-info -a myapp --format '{{.Name}} {{.Pool}}']] displays
+the name and the pool of the app. The fields of the app are IP, CName, Name,
+Platform, Repository, Teams, Units, Owner, TeamOwner, Deploys, Pool,
+Description, Lock, Quota, Plan and Routers.
+
+The [[--units-only]] flag displays only the names of the units of the app, one
+per line, which is useful in scripts like [[for unit in $(tsuru app-info -a
+myapp --units-only)]]. Nothing is displayed for apps without units. Use the
+[[--with-status]] flag to display the status of each unit after its name,
+separated by a tab.
+
+The plan of the app, with its memory, swap and CPU share, and the units of
+the app quota in use are always displayed. The [[--json]] flag is a shortcut
+for [[tsuru -o json app-info]].
+
+The [[--env]] flag summarizes the environment variables of the app, without
+their values: how many are public, private and injected by service
+instances, and how many each bound service instance injected. It's included
+as "env" in the [[--output]] formats.
+
+The [[--watch]] flag refreshes the information every [[--interval]] (5s by
+default) until interrupted with Ctrl-C, which is useful to follow a deploy or
+a scale up. When the output is a terminal the screen is cleared between
+refreshes, otherwise each refresh is appended to the output.
+
+The [[--app]] flag may be repeated to display the information of many apps,
+one after the other with a separator, like [[tsuru app-info -a myapp-staging
+-a myapp-prod]]. The [[--compare]] flag displays them side by side in a table
+instead, marking the fields whose values differ. With [[--json]] or
+[[--output]], the information of the apps is displayed as a list.`,
 		MinArgs: 0,
 	}
 }
 
+func (c *AppInfo) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		watch := "Refresh the information until interrupted"
+		c.fs.BoolVar(&c.watch, "watch", false, watch)
+		c.fs.BoolVar(&c.watch, "w", false, watch)
+		c.fs.DurationVar(&c.interval, "interval", 5*time.Second, "Interval between refreshes when using --watch")
+		c.appFormat.addFlags(c.fs)
+		c.fs.BoolVar(&c.unitsOnly, "units-only", false, "Display only the names of the units of the app")
+		c.fs.BoolVar(&c.withStatus, "with-status", false, "Display the status of each unit, used with --units-only")
+		c.fs.BoolVar(&c.json, "json", false, "Display the information in JSON format")
+		c.fs.BoolVar(&c.compare, "compare", false, "Display the information of the apps given in --app side by side")
+		c.fs.BoolVar(&c.env, "env", false, "Display a summary of the environment variables of the app")
+	}
+	return c.fs
+}
+
 func (c *AppInfo) Run(context *cmd.Context, client *cmd.Client) error {
-	appName, err := c.Guess()
+	appNames, err := c.GuessApps()
 	if err != nil {
 		return err
 	}
-	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s", appName))
+	if c.json {
+		context.OutputFormat = cmd.JSONOutput
+	}
+	if c.withStatus && !c.unitsOnly {
+		return errors.New("The --with-status flag requires --units-only.")
+	}
+	if c.unitsOnly && (c.format != "" || context.StructuredOutput()) {
+		return errors.New("The --units-only flag can't be used with the --format and --output flags.")
+	}
+	if c.compare && (len(appNames) < 2 || c.unitsOnly || c.format != "" || context.StructuredOutput()) {
+		return errors.New("The --compare flag requires at least two apps and can't be used with the --units-only, --format and --output flags.")
+	}
+	if c.watch && len(appNames) > 1 {
+		return errors.New("The --watch flag can't be used with more than one app.")
+	}
+	err = c.appFormat.prepare(context)
 	if err != nil {
 		return err
 	}
+	if len(appNames) > 1 {
+		return c.showMany(context, client, appNames)
+	}
+	appName := appNames[0]
+	if !c.watch {
+		return c.show(context, client, appName)
+	}
+	if c.interval <= 0 {
+		return errors.New("The interval must be positive.")
+	}
+	context.RawOutput()
+	tty := context.IsTerminal()
+	interrupt := make(chan os.Signal, 1)
+	notifyInterrupt(interrupt)
+	defer stopInterrupt(interrupt)
+	for {
+		if tty {
+			fmt.Fprint(context.Stdout, "\033[H\033[2J")
+		}
+		fmt.Fprintf(context.Stdout, "Every %s: app-info -a %s    %s\n\n", c.interval, appName, time.Now().Format(time.RFC1123))
+		err = c.show(context, client, appName)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-interrupt:
+			return nil
+		case <-time.After(c.interval):
+		}
+	}
+}
+
+func (c *AppInfo) show(context *cmd.Context, client *cmd.Client, appName string) error {
+	info, err := c.fetch(context, client, appName)
+	if err != nil || info == nil {
+		return err
+	}
+	return c.render(info, context)
+}
+
+// showMany displays the information of many apps, one after the other, side
+// by side with --compare or as a list in the structured output formats.
+func (c *AppInfo) showMany(context *cmd.Context, client *cmd.Client, appNames []string) error {
+	infos := make([]*appInfoResult, 0, len(appNames))
+	for _, appName := range appNames {
+		info, err := c.fetch(context, client, appName)
+		if err != nil {
+			return fmt.Errorf("Failed to get the information of app %q: %s", appName, err)
+		}
+		if info != nil {
+			infos = append(infos, info)
+		}
+	}
+	if context.StructuredOutput() {
+		data := make([]map[string]interface{}, len(infos))
+		for i, info := range infos {
+			d, err := info.data()
+			if err != nil {
+				return err
+			}
+			data[i] = d
+		}
+		return context.WriteStructured(data)
+	}
+	if c.compare {
+		apps := make([]*app, len(infos))
+		for i, info := range infos {
+			a, err := info.parse()
+			if err != nil {
+				return err
+			}
+			apps[i] = a
+		}
+		writeAppComparison(context.Stdout, apps)
+		return nil
+	}
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Fprintln(context.Stdout)
+		}
+		if c.tmpl == nil {
+			fmt.Fprintf(context.Stdout, "==> %s <==\n", info.name)
+		}
+		err := c.render(info, context)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appInfoResult holds the raw responses of the API describing an app.
+type appInfoResult struct {
+	name     string
+	app      []byte
+	services []byte
+	quota    []byte
+	env      *envSummary
+}
+
+// data returns the information of the app as displayed in the structured
+// output formats.
+func (r *appInfoResult) data() (map[string]interface{}, error) {
+	var data map[string]interface{}
+	err := json.Unmarshal(r.app, &data)
+	if err != nil {
+		return nil, err
+	}
+	var services, quotaData interface{}
+	json.Unmarshal(r.services, &services)
+	json.Unmarshal(r.quota, &quotaData)
+	data["services"] = services
+	data["quota"] = quotaData
+	if r.env != nil {
+		data["env"] = r.env
+	}
+	return data, nil
+}
+
+func (r *appInfoResult) parse() (*app, error) {
+	var a app
+	err := json.Unmarshal(r.app, &a)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal(r.services, &a.services)
+	json.Unmarshal(r.quota, &a.Quota)
+	a.env = r.env
+	return &a, nil
+}
+
+// fetch gets the information of the app from the API. It returns nil when
+// the app is not found.
+func (c *AppInfo) fetch(context *cmd.Context, client *cmd.Client, appName string) (*appInfoResult, error) {
+	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s", appName))
+	if err != nil {
+		return nil, err
+	}
 	request, err := http.NewRequest("GET", u, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	response, err := client.Do(request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if response.StatusCode == http.StatusNoContent {
-		return nil
+		return nil, nil
 	}
 	defer response.Body.Close()
-	result, err := ioutil.ReadAll(response.Body)
+	info := appInfoResult{name: appName}
+	info.app, err = ioutil.ReadAll(response.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	u, err = cmd.GetURL(fmt.Sprintf("/services/instances?app=%s", appName))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	request, err = http.NewRequest("GET", u, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	response, err = client.Do(request)
-	var servicesResult []byte
 	if err == nil {
 		defer response.Body.Close()
-		servicesResult, err = ioutil.ReadAll(response.Body)
+		info.services, err = ioutil.ReadAll(response.Body)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	var quota []byte
 	u, err = cmd.GetURL("/apps/" + appName + "/quota")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	request, _ = http.NewRequest("GET", u, nil)
 	response, err = client.Do(request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer response.Body.Close()
-	quota, err = ioutil.ReadAll(response.Body)
+	info.quota, err = ioutil.ReadAll(response.Body)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if c.env && !c.unitsOnly {
+		envs, err := getAppEnvs(client, appName)
+		if err != nil {
+			return nil, err
+		}
+		info.env = summarizeEnvs(envs)
+	}
+	return &info, nil
+}
+
+// writeAppComparison writes a table with the information of the apps side by
+// side. Fields whose values differ between the apps are marked with an
+// asterisk.
+func writeAppComparison(w io.Writer, apps []*app) {
+	fields := []struct {
+		name  string
+		value func(a *app) string
+	}{
+		{"Platform", func(a *app) string { return a.Platform }},
+		{"Pool", func(a *app) string { return a.Pool }},
+		{"Plan", func(a *app) string { return a.Plan.Name }},
+		{"Team owner", func(a *app) string { return a.TeamOwner }},
+		{"Teams", func(a *app) string { return strings.Join(a.Teams, ", ") }},
+		{"Units", func(a *app) string {
+			var started int
+			for _, u := range a.Units {
+				if u.Available() {
+					started++
+				}
+			}
+			return fmt.Sprintf("%d (%d started)", len(a.Units), started)
+		}},
+		{"Quota", func(a *app) string {
+			if a.Quota.Limit == 0 {
+				return fmt.Sprintf("%d/unlimited", a.Quota.InUse)
+			}
+			return fmt.Sprintf("%d/%d", a.Quota.InUse, a.Quota.Limit)
+		}},
+		{"Deploys", func(a *app) string { return strconv.FormatUint(uint64(a.Deploys), 10) }},
+		{"CNames", func(a *app) string { return strings.Join(a.CName, ", ") }},
+		{"Routers", func(a *app) string {
+			names := make([]string, len(a.Routers))
+			for i, r := range a.Routers {
+				names[i] = r.Name
+			}
+			return strings.Join(names, ", ")
+		}},
+		{"Services", func(a *app) string {
+			var instances []string
+			for _, s := range a.services {
+				for _, instance := range s.Instances {
+					instances = append(instances, s.Service+"/"+instance)
+				}
+			}
+			return strings.Join(instances, ", ")
+		}},
+	}
+	table := cmd.NewTable()
+	headers := cmd.Row{""}
+	for _, a := range apps {
+		headers = append(headers, a.Name)
+	}
+	table.Headers = headers
+	var differ bool
+	for _, f := range fields {
+		row := cmd.Row{f.name}
+		for _, a := range apps {
+			row = append(row, f.value(a))
+		}
+		for _, value := range row[2:] {
+			if value != row[1] {
+				row[0] = "* " + f.name
+				differ = true
+				break
+			}
+		}
+		table.AddRow(row)
+	}
+	w.Write(table.Bytes())
+	if differ {
+		fmt.Fprintln(w, "* The values differ between the apps.")
 	}
-	return c.Show(result, servicesResult, quota, context)
 }
 
 type unit struct {
@@ -412,8 +860,16 @@ type app struct {
 	Description string
 	Lock        lock
 	services    []serviceData
+	env         *envSummary
 	Quota       quota
 	Plan        tsuruapp.Plan
+	Routers     []appRouter
+}
+
+type appRouter struct {
+	Name    string            `json:"name"`
+	Opts    map[string]string `json:"opts,omitempty"`
+	Address string            `json:"address"`
 }
 
 type serviceData struct {
@@ -427,6 +883,28 @@ type quota struct {
 	InUse int
 }
 
+func getApp(client *cmd.Client, appName string) (*app, error) {
+	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s", appName))
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var a app
+	err = json.NewDecoder(response.Body).Decode(&a)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
 func (a *app) Addr() string {
 	cnames := strings.Join(a.CName, ", ")
 	if cnames != "" {
@@ -435,6 +913,31 @@ func (a *app) Addr() string {
 	return a.IP
 }
 
+// RouterAddr returns the address of the first router of the app, indicating
+// how many other routers there are.
+func (a *app) RouterAddr() string {
+	if len(a.Routers) == 0 {
+		return a.IP
+	}
+	addr := a.Routers[0].Address
+	if len(a.Routers) > 1 {
+		addr = fmt.Sprintf("%s (+%d more)", addr, len(a.Routers)-1)
+	}
+	return addr
+}
+
+// unitsCount returns the number of units of the app, ignoring units without
+// an ID.
+func (a *app) unitsCount() int {
+	var count int
+	for _, unit := range a.Units {
+		if unit.ID != "" {
+			count++
+		}
+	}
+	return count
+}
+
 func (a *app) GetTeams() string {
 	return strings.Join(a.Teams, ", ")
 }
@@ -519,20 +1022,53 @@ Quota: {{.Quota.InUse}}/{{if .Quota.Limit}}{{.Quota.Limit}} units{{else}}unlimit
 		buf.WriteString("App Plan:\n")
 		buf.WriteString(renderPlans([]tsuruapp.Plan{a.Plan}, true))
 	}
+	if a.env != nil {
+		buf.WriteString("\n")
+		buf.WriteString(fmt.Sprintf("Environment variables: %d (%d public, %d private, %d from service instances)\n", a.env.Total, a.env.Public, a.env.Private, a.env.Bound))
+		if len(a.env.Services) > 0 {
+			envTable := cmd.NewTable()
+			envTable.Headers = cmd.Row([]string{"Service", "Instance", "Variables"})
+			for _, s := range a.env.Services {
+				envTable.AddRow(cmd.Row([]string{s.Service, s.Instance, strconv.Itoa(s.Variables)}))
+			}
+			buf.WriteString(envTable.String())
+		}
+	}
 	var tplBuffer bytes.Buffer
 	tmpl.Execute(&tplBuffer, a)
 	return tplBuffer.String() + buf.String()
 }
 
 func (c *AppInfo) Show(result []byte, servicesResult []byte, quota []byte, context *cmd.Context) error {
-	var a app
-	err := json.Unmarshal(result, &a)
+	return c.render(&appInfoResult{app: result, services: servicesResult, quota: quota}, context)
+}
+
+func (c *AppInfo) render(info *appInfoResult, context *cmd.Context) error {
+	if context.StructuredOutput() {
+		data, err := info.data()
+		if err != nil {
+			return err
+		}
+		return context.WriteStructured(data)
+	}
+	a, err := info.parse()
 	if err != nil {
 		return err
 	}
-	json.Unmarshal(servicesResult, &a.services)
-	json.Unmarshal(quota, &a.Quota)
-	fmt.Fprintln(context.Stdout, &a)
+	if c.unitsOnly {
+		for _, u := range a.Units {
+			if c.withStatus {
+				fmt.Fprintf(context.Stdout, "%s\t%s\n", u.ID, u.Status)
+			} else {
+				fmt.Fprintln(context.Stdout, u.ID)
+			}
+		}
+		return nil
+	}
+	if c.tmpl != nil {
+		return c.appFormat.write(context.Stdout, a)
+	}
+	fmt.Fprintln(context.Stdout, a)
 	return nil
 }
 
@@ -543,10 +1079,12 @@ type AppGrant struct {
 func (c *AppGrant) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "app-grant",
-		Usage: "app-grant <teamname> [-a/--app appname]",
+		Usage: "app-grant <teamname> [teamname...] [-a/--app appname]",
 		Desc: `Allows a team to access an application. You need to be a member of a team that
 has access to the app to allow another team to access it. grants access to an
-app to a team.`,
+app to a team.
+
+Multiple teams may be given at once, the result is reported for each team.`,
 		MinArgs: 1,
 	}
 }
@@ -556,20 +1094,19 @@ func (c *AppGrant) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	teamName := context.Args[0]
-	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s/teams/%s", appName, teamName))
-	if err != nil {
-		return err
+	var failed int
+	for _, teamName := range context.Args {
+		err = changeAppTeam("PUT", appName, teamName, client)
+		if err != nil {
+			failed++
+			fmt.Fprintf(context.Stderr, "Failed to add team %q to the %q app: %s\n", teamName, appName, err)
+			continue
+		}
+		context.Successf(`Team "%s" was added to the "%s" app`+"\n", teamName, appName)
 	}
-	request, err := http.NewRequest("PUT", u, nil)
-	if err != nil {
-		return err
+	if failed > 0 {
+		return fmt.Errorf("Failed to grant access to %d of %d team(s).", failed, len(context.Args))
 	}
-	_, err = client.Do(request)
-	if err != nil {
-		return err
-	}
-	fmt.Fprintf(context.Stdout, `Team "%s" was added to the "%s" app`+"\n", teamName, appName)
 	return nil
 }
 
@@ -580,12 +1117,15 @@ type AppRevoke struct {
 func (c *AppRevoke) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "app-revoke",
-		Usage: "app-revoke <teamname> [-a/--app appname]",
+		Usage: "app-revoke <teamname> [teamname...] [-a/--app appname]",
 		Desc: `Revokes the permission to access an application from a team. You need to have
 access to the application to revoke access from a team.
 
+Multiple teams may be given at once, the result is reported for each team.
+
 An application cannot be orphaned, so it will always have at least one
-authorized team.`,
+authorized team. Revoking access from all the teams of the application is
+refused before any change is made.`,
 		MinArgs: 1,
 	}
 }
@@ -595,21 +1135,46 @@ func (c *AppRevoke) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	teamName := context.Args[0]
-	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s/teams/%s", appName, teamName))
+	a, err := getApp(client, appName)
 	if err != nil {
 		return err
 	}
-	request, err := http.NewRequest("DELETE", u, nil)
+	var remaining int
+	for _, team := range a.Teams {
+		if !in(team, context.Args) {
+			remaining++
+		}
+	}
+	if remaining == 0 {
+		return fmt.Errorf("Cannot revoke access from all the teams of the %q app, it must keep at least one authorized team.", appName)
+	}
+	var failed int
+	for _, teamName := range context.Args {
+		err = changeAppTeam("DELETE", appName, teamName, client)
+		if err != nil {
+			failed++
+			fmt.Fprintf(context.Stderr, "Failed to remove team %q from the %q app: %s\n", teamName, appName, err)
+			continue
+		}
+		context.Successf(`Team "%s" was removed from the "%s" app`+"\n", teamName, appName)
+	}
+	if failed > 0 {
+		return fmt.Errorf("Failed to revoke access from %d of %d team(s).", failed, len(context.Args))
+	}
+	return nil
+}
+
+func changeAppTeam(method, appName, teamName string, client *cmd.Client) error {
+	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s/teams/%s", appName, teamName))
 	if err != nil {
 		return err
 	}
-	_, err = client.Do(request)
+	request, err := http.NewRequest(method, u, nil)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, `Team "%s" was removed from the "%s" app`+"\n", teamName, appName)
-	return nil
+	_, err = client.Do(request)
+	return err
 }
 
 type appFilter struct {
@@ -656,17 +1221,53 @@ func (f *appFilter) queryString(client *cmd.Client) (url.Values, error) {
 	return result, nil
 }
 
+// appListTotalHeader is the header used by servers supporting pagination to
+// report the number of apps matching the filters.
+const appListTotalHeader = "X-Total-Count"
+
 type AppList struct {
+	appFormat
 	fs         *gnuflag.FlagSet
 	filter     appFilter
 	simplified bool
+	addresses  bool
+	limit      int
+	page       int
+	sort       string
+	order      appSort
 }
 
 func (c *AppList) Run(context *cmd.Context, client *cmd.Client) error {
+	var err error
+	c.order, err = parseAppSort(c.sort)
+	if err != nil {
+		return err
+	}
+	if c.limit < 0 {
+		return errors.New("The limit must be a positive number.")
+	}
+	if c.page < 1 {
+		c.page = 1
+	}
+	if c.page > 1 && c.limit == 0 {
+		return errors.New("The --page flag requires --limit.")
+	}
+	if c.format != "" && (c.simplified || c.addresses) {
+		return errors.New("The --format flag can't be used with the -q and --addresses flags.")
+	}
+	err = c.appFormat.prepare(context)
+	if err != nil {
+		return err
+	}
 	qs, err := c.filter.queryString(client)
 	if err != nil {
 		return err
 	}
+	offset := (c.page - 1) * c.limit
+	if c.limit > 0 {
+		qs.Set("limit", strconv.Itoa(c.limit))
+		qs.Set("offset", strconv.Itoa(offset))
+	}
 	u, err := cmd.GetURL(fmt.Sprintf("/apps?%s", qs.Encode()))
 	if err != nil {
 		return err
@@ -687,7 +1288,127 @@ func (c *AppList) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	return c.Show(result, context)
+	if c.limit == 0 {
+		return c.Show(result, context)
+	}
+	var total int
+	if header := response.Header.Get(appListTotalHeader); header != "" {
+		total, err = strconv.Atoi(header)
+		if err != nil {
+			return fmt.Errorf("invalid %s header: %s", appListTotalHeader, header)
+		}
+	} else {
+		result, total, err = paginateApps(result, offset, c.limit, c.order)
+		if err != nil {
+			return err
+		}
+	}
+	err = c.Show(result, context)
+	if err != nil {
+		return err
+	}
+	if !context.StructuredOutput() && !c.simplified && c.tmpl == nil && offset+c.limit < total {
+		fmt.Fprintf(context.Stdout, "Showing apps %d-%d of %d. Use --page %d to see more.\n", offset+1, offset+c.limit, total, c.page+1)
+	}
+	return nil
+}
+
+// paginateApps slices the list of apps returned by servers that don't
+// support pagination, sorting them first so pages are stable. It returns the
+// apps in the page and the total number of apps.
+func paginateApps(result []byte, offset, limit int, order appSort) ([]byte, int, error) {
+	var raws []json.RawMessage
+	err := json.Unmarshal(result, &raws)
+	if err != nil {
+		return nil, 0, err
+	}
+	apps := make([]app, len(raws))
+	for i, raw := range raws {
+		err = json.Unmarshal(raw, &apps[i])
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	indexes := sortedIndexes(apps, order)
+	total := len(indexes)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	page := make([]json.RawMessage, 0, end-offset)
+	for _, i := range indexes[offset:end] {
+		page = append(page, raws[i])
+	}
+	data, err := json.Marshal(page)
+	return data, total, err
+}
+
+var appSortKeys = []string{"name", "platform", "pool", "units"}
+
+// appSort is the order of apps in app-list, given by a sort key optionally
+// prefixed with "-" for descending order.
+type appSort struct {
+	key  string
+	desc bool
+}
+
+func parseAppSort(value string) (appSort, error) {
+	order := appSort{key: strings.TrimPrefix(value, "-"), desc: strings.HasPrefix(value, "-")}
+	if value == "" {
+		order.key = "name"
+	}
+	for _, key := range appSortKeys {
+		if key == order.key {
+			return order, nil
+		}
+	}
+	return appSort{}, fmt.Errorf("Invalid sort key %q. Valid keys are: %s. Prefix the key with - for descending order.", value, strings.Join(appSortKeys, ", "))
+}
+
+// less reports whether a comes before b. Apps with the same value for the
+// sort key are ordered by name.
+func (o appSort) less(a, b *app) bool {
+	var cmp int
+	switch o.key {
+	case "platform":
+		cmp = strings.Compare(a.Platform, b.Platform)
+	case "pool":
+		cmp = strings.Compare(a.Pool, b.Pool)
+	case "units":
+		cmp = a.unitsCount() - b.unitsCount()
+	}
+	if cmp == 0 {
+		cmp = strings.Compare(a.Name, b.Name)
+	}
+	if o.desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+type sortedApps struct {
+	indexes []int
+	apps    []app
+	order   appSort
+}
+
+func (l sortedApps) Len() int { return len(l.indexes) }
+func (l sortedApps) Less(i, j int) bool {
+	return l.order.less(&l.apps[l.indexes[i]], &l.apps[l.indexes[j]])
+}
+func (l sortedApps) Swap(i, j int) { l.indexes[i], l.indexes[j] = l.indexes[j], l.indexes[i] }
+
+// sortedIndexes returns the indexes of the apps in the given order.
+func sortedIndexes(apps []app, order appSort) []int {
+	indexes := make([]int, len(apps))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.Stable(sortedApps{indexes: indexes, apps: apps, order: order})
+	return indexes
 }
 
 func (c *AppList) Show(result []byte, context *cmd.Context) error {
@@ -696,6 +1417,37 @@ func (c *AppList) Show(result []byte, context *cmd.Context) error {
 	if err != nil {
 		return err
 	}
+	if context.StructuredOutput() {
+		var raws []interface{}
+		err = json.Unmarshal(result, &raws)
+		if err != nil {
+			return err
+		}
+		if c.sort != "" {
+			sorted := make([]interface{}, len(raws))
+			for i, index := range sortedIndexes(apps, c.order) {
+				sorted[i] = raws[index]
+			}
+			raws = sorted
+		}
+		return context.WriteStructured(raws)
+	}
+	if c.sort != "" || c.tmpl != nil {
+		sorted := make([]app, len(apps))
+		for i, index := range sortedIndexes(apps, c.order) {
+			sorted[i] = apps[index]
+		}
+		apps = sorted
+	}
+	if c.tmpl != nil {
+		for i := range apps {
+			err = c.appFormat.write(context.Stdout, &apps[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	table := cmd.NewTable()
 	if c.simplified {
 		for _, app := range apps {
@@ -704,23 +1456,28 @@ func (c *AppList) Show(result []byte, context *cmd.Context) error {
 		return nil
 	}
 	table.Headers = cmd.Row([]string{"Application", "Units State Summary", "Address"})
+	if c.addresses {
+		table.Headers = append(table.Headers, "Router Address")
+	}
 	for _, app := range apps {
 		var available int
-		var total int
 		for _, unit := range app.Units {
-			if unit.ID != "" {
-				total++
-				if unit.Available() {
-					available++
-				}
+			if unit.ID != "" && unit.Available() {
+				available++
 			}
 		}
-		summary := fmt.Sprintf("%d of %d units in-service", available, total)
+		summary := fmt.Sprintf("%d of %d units in-service", available, app.unitsCount())
 		addrs := strings.Replace(app.Addr(), ", ", "\n", -1)
-		table.AddRow(cmd.Row([]string{app.Name, summary, addrs}))
+		row := cmd.Row([]string{app.Name, summary, addrs})
+		if c.addresses {
+			row = append(row, app.RouterAddr())
+		}
+		table.AddRow(row)
 	}
 	table.LineSeparator = true
-	table.Sort()
+	if c.sort == "" {
+		table.Sort()
+	}
 	context.Stdout.Write(table.Bytes())
 	return nil
 }
@@ -743,6 +1500,11 @@ func (c *AppList) Flags() *gnuflag.FlagSet {
 		c.fs.BoolVar(&c.filter.locked, "locked", false, "Filter applications by lock status")
 		c.fs.BoolVar(&c.filter.locked, "l", false, "Filter applications by lock status")
 		c.fs.BoolVar(&c.simplified, "q", false, "Display only applications name")
+		c.fs.BoolVar(&c.addresses, "addresses", false, "Display the router address of each application")
+		c.fs.IntVar(&c.limit, "limit", 0, "Maximum number of applications to display")
+		c.fs.IntVar(&c.page, "page", 1, "Page of applications to display, used with --limit")
+		c.fs.StringVar(&c.sort, "sort", "", "Sort applications by name, platform, pool or units. Prefix with - for descending order")
+		c.appFormat.addFlags(c.fs)
 	}
 	return c.fs
 }
@@ -750,11 +1512,32 @@ func (c *AppList) Flags() *gnuflag.FlagSet {
 func (c *AppList) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "app-list",
-		Usage: "app-list",
+		Usage: "app-list [--limit number [--page number]] [--sort [-]name|platform|pool|units] [--format template]",
 		Desc: `Lists all apps that you have access to. App access is controlled by teams. If
 your team has access to an app, then you have access to it.
 
-Flags can be used to filter the list of applications.`,
+Flags can be used to filter the list of applications.
+
+The [[--addresses]] flag adds a column with the address of the router of each
+application. When the application has multiple routers, the address of the
+first one is displayed along with the number of other routers.
+
+The [[--limit]] flag limits the number of applications displayed, and the
+[[--page]] flag chooses which page of applications is displayed, starting at
+1. When there are more applications, a footer shows how to get the next page.
+
+The [[--sort]] flag sorts the applications by name (the default), platform,
+pool or number of units. Prefix the key with - to sort in descending order,
+for example [[--sort -units]].
+
+The [[--format]] flag formats each application using the given Go template,
+one per line, instead of displaying the table. For example, [[tsuru app-list
+--format '{{.Name}} {{.Pool}}']] displays the name and the pool of each
+application. The available fields are the same of the [[--format]] flag of
+app-info.
+
+This command honors the global --output flag, so [[tsuru -o json app-list]]
+lists the apps in JSON format.`,
 	}
 }
 
@@ -775,7 +1558,7 @@ func (c *AppStop) Info() *cmd.Info {
 
 func (c *AppStop) Run(context *cmd.Context, client *cmd.Client) error {
 	context.RawOutput()
-	appName, err := c.Guess()
+	appName, err := c.GuessDestructive(context)
 	if err != nil {
 		return err
 	}
@@ -854,13 +1637,14 @@ func (c *AppStart) Flags() *gnuflag.FlagSet {
 
 type AppRestart struct {
 	cmd.GuessingCommand
+	appWait
 	process string
 	fs      *gnuflag.FlagSet
 }
 
 func (c *AppRestart) Run(context *cmd.Context, client *cmd.Client) error {
 	context.RawOutput()
-	appName, err := c.Guess()
+	appName, err := c.GuessDestructive(context)
 	if err != nil {
 		return err
 	}
@@ -878,14 +1662,23 @@ func (c *AppRestart) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	return cmd.StreamJSONResponse(context.Stdout, response)
+	err = cmd.StreamJSONResponse(context.Stdout, response)
+	if err != nil {
+		return err
+	}
+	return c.waitUnits(context, client, appName)
 }
 
 func (c *AppRestart) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "app-restart",
-		Usage:   "app-restart [-a/--app appname] [-p/--process processname]",
-		Desc:    `Restarts an application, or one of the processes of the application.`,
+		Name:  "app-restart",
+		Usage: "app-restart [-a/--app appname] [-p/--process processname] [--wait [--timeout duration]]",
+		Desc: `Restarts an application, or one of the processes of the application.
+
+The [[--wait]] flag makes the command wait until all units of the app are
+started, polling until the app has at least one unit. The command fails if
+any unit enters the error state or is stopped or asleep, or if the units are
+not started after [[--timeout]] (10m by default).`,
 		MinArgs: 0,
 	}
 }
@@ -895,6 +1688,7 @@ func (c *AppRestart) Flags() *gnuflag.FlagSet {
 		c.fs = c.GuessingCommand.Flags()
 		c.fs.StringVar(&c.process, "process", "", "Process name")
 		c.fs.StringVar(&c.process, "p", "", "Process name")
+		c.addFlags(c.fs)
 	}
 	return c.fs
 }
@@ -904,11 +1698,33 @@ type CnameAdd struct {
 }
 
 func (c *CnameAdd) Run(context *cmd.Context, client *cmd.Client) error {
-	err := addCName(context.Args, c.GuessingCommand, client)
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	a, err := getApp(client, appName)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(context.Stdout, "cname successfully defined.")
+	var added, present []string
+	for _, cname := range context.Args {
+		if in(cname, a.CName) || in(cname, added) {
+			present = append(present, cname)
+		} else {
+			added = append(added, cname)
+		}
+	}
+	if len(added) > 0 {
+		err = addCName(appName, added, client)
+		if err != nil {
+			return err
+		}
+		context.Successf("cname successfully defined.\n")
+		fmt.Fprintf(context.Stdout, "Added: %s\n", strings.Join(added, ", "))
+	}
+	if len(present) > 0 {
+		fmt.Fprintf(context.Stdout, "Already present: %s\n", strings.Join(present, ", "))
+	}
 	return nil
 }
 
@@ -916,7 +1732,9 @@ func (c *CnameAdd) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "cname-add",
 		Usage: "cname-add <cname> [<cname> ...] [-a/--app appname]",
-		Desc: `Adds a new CNAME to the application.
+		Desc: `Adds new CNAMEs to the application. Multiple CNAMEs may be given at once,
+the command reports which of them were added and which were already defined
+in the app.
 
 It will not manage any DNS register, it's up to the user to create the DNS
 register. Once the app contains a custom CNAME, it will be displayed by "app-
@@ -930,32 +1748,43 @@ type CnameRemove struct {
 }
 
 func (c *CnameRemove) Run(context *cmd.Context, client *cmd.Client) error {
-	err := unsetCName(context.Args, c.GuessingCommand, client)
+	appName, err := c.GuessDestructive(context)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(context.Stdout, "cname successfully undefined.")
+	cnames := context.Args
+	if len(cnames) == 0 {
+		a, err := getApp(client, appName)
+		if err != nil {
+			return err
+		}
+		if len(a.CName) == 0 {
+			return fmt.Errorf("app %q has no cnames.", appName)
+		}
+		cnames = a.CName
+	}
+	err = unsetCName(appName, cnames, client)
+	if err != nil {
+		return err
+	}
+	context.Successf("cname successfully undefined.\n")
 	return nil
 }
 
 func (c *CnameRemove) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "cname-remove",
-		Usage: "cname-remove <cname> [<cname> ...] [-a/--app appname]",
-		Desc: `Removes a CNAME from the application. This undoes the change that cname-add
-does.
+		Usage: "cname-remove [<cname> ...] [-a/--app appname]",
+		Desc: `Removes CNAMEs from the application. This undoes the change that cname-add
+does. If no CNAME is given, all CNAMEs of the app are removed.
 
 After unsetting the CNAME from the app, [[tsuru app-list]] and [[tsuru app-
 info]] will display the internal, unfriendly address that tsuru uses.`,
-		MinArgs: 1,
+		MinArgs: 0,
 	}
 }
 
-func unsetCName(cnames []string, g cmd.GuessingCommand, client *cmd.Client) error {
-	appName, err := g.Guess()
-	if err != nil {
-		return err
-	}
+func unsetCName(appName string, cnames []string, client *cmd.Client) error {
 	v := url.Values{}
 	for _, cname := range cnames {
 		v.Add("cname", cname)
@@ -972,11 +1801,7 @@ func unsetCName(cnames []string, g cmd.GuessingCommand, client *cmd.Client) erro
 	return err
 }
 
-func addCName(cnames []string, g cmd.GuessingCommand, client *cmd.Client) error {
-	appName, err := g.Guess()
-	if err != nil {
-		return err
-	}
+func addCName(appName string, cnames []string, client *cmd.Client) error {
 	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s/cname", appName))
 	if err != nil {
 		return err
@@ -997,16 +1822,28 @@ func addCName(cnames []string, g cmd.GuessingCommand, client *cmd.Client) error
 
 type UnitAdd struct {
 	cmd.GuessingCommand
+	appWait
 	fs      *gnuflag.FlagSet
 	process string
+	pool    string
 }
 
 func (c *UnitAdd) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "unit-add",
-		Usage: "unit-add <# of units> [-a/--app appname] [-p/--process processname]",
+		Usage: "unit-add <# of units> [-a/--app appname] [-p/--process processname] [--pool poolname] [--wait [--timeout duration]]",
 		Desc: `Adds new units to a process of an application. You need to have access to the
-app to be able to add new units to it.`,
+app to be able to add new units to it.
+
+The [[--process]] flag chooses the process of the app, like a worker
+declared in the Procfile, which is required when the app has more than one
+process. The [[--pool]] flag asks for the new units to be placed in the given
+pool. Invalid processes and pools are reported by the tsuru server.
+
+The [[--wait]] flag makes the command wait until all units of the app are
+started, polling until the app has at least one unit. The command fails if
+any unit enters the error state or is stopped or asleep, or if the units are
+not started after [[--timeout]] (10m by default).`,
 		MinArgs: 1,
 	}
 }
@@ -1016,6 +1853,8 @@ func (c *UnitAdd) Flags() *gnuflag.FlagSet {
 		c.fs = c.GuessingCommand.Flags()
 		c.fs.StringVar(&c.process, "process", "", "Process name")
 		c.fs.StringVar(&c.process, "p", "", "Process name")
+		c.fs.StringVar(&c.pool, "pool", "", "Pool where the units are placed")
+		c.addFlags(c.fs)
 	}
 	return c.fs
 }
@@ -1033,6 +1872,9 @@ func (c *UnitAdd) Run(context *cmd.Context, client *cmd.Client) error {
 	val := url.Values{}
 	val.Add("units", context.Args[0])
 	val.Add("process", c.process)
+	if c.pool != "" {
+		val.Add("pool", c.pool)
+	}
 	request, err := http.NewRequest("PUT", u, bytes.NewBufferString(val.Encode()))
 	if err != nil {
 		return err
@@ -1043,21 +1885,31 @@ func (c *UnitAdd) Run(context *cmd.Context, client *cmd.Client) error {
 		return err
 	}
 	defer response.Body.Close()
-	return cmd.StreamJSONResponse(context.Stdout, response)
+	err = cmd.StreamJSONResponse(context.Stdout, response)
+	if err != nil {
+		return err
+	}
+	return c.waitUnits(context, client, appName)
 }
 
 type UnitRemove struct {
 	cmd.GuessingCommand
 	fs      *gnuflag.FlagSet
 	process string
+	pool    string
 }
 
 func (c *UnitRemove) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "unit-remove",
-		Usage: "unit-remove <# of units> [-a/--app appname] [-p/-process processname]",
+		Usage: "unit-remove <# of units> [-a/--app appname] [-p/--process processname] [--pool poolname]",
 		Desc: `Removes units from a process of an application. You need to have access to the
-app to be able to remove units from it.`,
+app to be able to remove units from it.
+
+The [[--process]] flag chooses the process of the app, which is required when
+the app has more than one process. The [[--pool]] flag removes only units
+placed in the given pool. Invalid processes and pools are reported by the
+tsuru server.`,
 		MinArgs: 1,
 	}
 }
@@ -1067,19 +1919,23 @@ func (c *UnitRemove) Flags() *gnuflag.FlagSet {
 		c.fs = c.GuessingCommand.Flags()
 		c.fs.StringVar(&c.process, "process", "", "Process name")
 		c.fs.StringVar(&c.process, "p", "", "Process name")
+		c.fs.StringVar(&c.pool, "pool", "", "Pool of the units to remove")
 	}
 	return c.fs
 }
 
 func (c *UnitRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	context.RawOutput()
-	appName, err := c.Guess()
+	appName, err := c.GuessDestructive(context)
 	if err != nil {
 		return err
 	}
 	val := url.Values{}
 	val.Add("units", context.Args[0])
 	val.Add("process", c.process)
+	if c.pool != "" {
+		val.Add("pool", c.pool)
+	}
 	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/units?%s", appName, val.Encode()))
 	if err != nil {
 		return err
@@ -1094,3 +1950,97 @@ func (c *UnitRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	}
 	return cmd.StreamJSONResponse(context.Stdout, response)
 }
+
+type UnitKill struct {
+	cmd.GuessingCommand
+	appWait
+	fs   *gnuflag.FlagSet
+	unit string
+}
+
+func (c *UnitKill) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "unit-kill",
+		Usage: "unit-kill -u/--unit unitid [-a/--app appname] [--wait [--timeout duration]]",
+		Desc: `Kills a single unit of an application, which is replaced by a new unit of the
+same process. Use it to get rid of a misbehaving unit without changing the
+number of units of the app. The unit must belong to the app.
+
+The [[--wait]] flag makes the command wait until all units of the app are
+started, polling until the app has at least one unit. The command fails if
+any unit enters the error state or is stopped or asleep, or if the units are
+not started after [[--timeout]] (10m by default).`,
+		MinArgs: 0,
+		MaxArgs: 0,
+	}
+}
+
+func (c *UnitKill) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		unit := "The ID of the unit to kill"
+		c.fs.StringVar(&c.unit, "unit", "", unit)
+		c.fs.StringVar(&c.unit, "u", "", unit)
+		c.addFlags(c.fs)
+	}
+	return c.fs
+}
+
+func (c *UnitKill) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	if c.unit == "" {
+		return errors.New("You must provide the unit to kill with --unit.")
+	}
+	appName, err := c.GuessDestructive(context)
+	if err != nil {
+		return err
+	}
+	units, err := appUnits(client, appName)
+	if err != nil {
+		return err
+	}
+	before := make(map[string]bool, len(units))
+	for _, u := range units {
+		before[u.ID] = true
+	}
+	if !before[c.unit] {
+		return fmt.Errorf("Unit %q doesn't belong to app %q.", c.unit, appName)
+	}
+	u, err := cmd.GetURLVersion("1.5", fmt.Sprintf("/apps/%s/units/%s", appName, c.unit))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	err = cmd.StreamJSONResponse(context.Stdout, response)
+	if err != nil {
+		return err
+	}
+	err = c.waitUnits(context, client, appName)
+	if err != nil {
+		return err
+	}
+	units, err = appUnits(client, appName)
+	if err != nil {
+		return err
+	}
+	var replacements []string
+	for _, u := range units {
+		if !before[u.ID] {
+			replacements = append(replacements, u.ID)
+		}
+	}
+	if len(replacements) == 0 {
+		fmt.Fprintf(context.Stdout, "Unit %q killed, its replacement is not listed in the app yet.\n", c.unit)
+		return nil
+	}
+	context.Successf("Unit %q killed and replaced by %s.\n", c.unit, strings.Join(replacements, ", "))
+	return nil
+}
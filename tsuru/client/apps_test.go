@@ -11,11 +11,14 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
+	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"github.com/tsuru/tsuru/io"
 	"gopkg.in/check.v1"
@@ -58,13 +61,68 @@ Your repository for "ble" project is "git@tsuru.plataformas.glb.com:ble.git"` +
 			return method && url && name && platform && teamOwner && plan && pool && description && contentType
 		},
 	}
-	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	client := cmd.NewClient(&http.Client{Transport: noTeamsTransport{&trans}}, nil, manager)
 	command := AppCreate{}
 	err := command.Run(&context, client)
 	c.Assert(err, check.IsNil)
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+// chdir changes the working directory to dir until the returned function is
+// called.
+func chdir(c *check.C, dir string) func() {
+	old, err := os.Getwd()
+	c.Assert(err, check.IsNil)
+	c.Assert(os.Chdir(dir), check.IsNil)
+	return func() { os.Chdir(old) }
+}
+
+func (s *S) TestAppCreateAddRemote(c *check.C) {
+	dir := c.MkDir()
+	defer chdir(c, dir)()
+	err := os.Mkdir(filepath.Join(dir, ".git"), 0755)
+	c.Assert(err, check.IsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, ".git", "config"), []byte("[core]\n\tbare = false\n"), 0644)
+	c.Assert(err, check.IsNil)
+	var stdout, stderr bytes.Buffer
+	result := `{"status":"success", "repository_url":"git@tsuru.plataformas.glb.com:ble.git"}`
+	context := cmd.Context{
+		Args:   []string{"ble", "django"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: noTeamsTransport{&cmdtest.Transport{Message: result, Status: http.StatusOK}}}, nil, manager)
+	command := AppCreate{}
+	command.Flags().Parse(true, []string{"--add-remote"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Matches, `(?s).*Added the tsuru remote to the git repository, pointing to "git@tsuru.plataformas.glb.com:ble.git".\n`)
+	name, err := cmd.GitGuesser{}.GuessName(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "ble")
+	stdout.Reset()
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stderr.String(), check.Equals, "The tsuru remote already exists in the git repository, it was not changed.\n")
+}
+
+func (s *S) TestAppCreateAddRemoteNotInRepository(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `{"status":"success", "repository_url":"git@tsuru.plataformas.glb.com:ble.git"}`
+	context := cmd.Context{
+		Args:   []string{"ble", "django"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: noTeamsTransport{&cmdtest.Transport{Message: result, Status: http.StatusOK}}}, nil, manager)
+	defer chdir(c, c.MkDir())()
+	command := AppCreate{}
+	command.Flags().Parse(true, []string{"--add-remote"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stderr.String(), check.Equals, "Not inside a git repository, the tsuru remote was not added.\n")
+}
+
 func (s *S) TestAppCreateTeamOwner(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	result := `{"status":"success", "repository_url":"git@tsuru.plataformas.glb.com:ble.git"}`
@@ -99,6 +157,80 @@ Your repository for "ble" project is "git@tsuru.plataformas.glb.com:ble.git"` +
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+func (s *S) TestAppCreateTeamFromEnv(c *check.C) {
+	os.Setenv("TSURU_TEAM", "myteam")
+	defer os.Unsetenv("TSURU_TEAM")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"ble", "django"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `{"status":"success"}`, Status: http.StatusOK},
+		CondFunc: func(r *http.Request) bool {
+			return r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/apps") && r.FormValue("teamOwner") == "myteam"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := AppCreate{}
+	command.Flags().Parse(true, []string{})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestAppCreateSingleTeam(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"ble", "django"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"myteam","permissions":["app.create"]},{"name":"viewers","permissions":["app.read"]}]`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/teams")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `{"status":"success"}`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/apps") && r.FormValue("teamOwner") == "myteam"
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppCreate{}
+	command.Flags().Parse(true, []string{})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestAppCreateAmbiguousTeam(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"ble", "django"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `[{"name":"web","permissions":["app"]},{"name":"admin","permissions":[""]},{"name":"viewers","permissions":["app.read"]}]`, Status: http.StatusOK},
+		CondFunc: func(r *http.Request) bool {
+			return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/teams")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := AppCreate{}
+	command.Flags().Parse(true, []string{})
+	err := command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `You're allowed to use more than one team, use the --team flag to choose one of them: admin, web.
+A default team may be set in the TSURU_TEAM environment variable or in ~/.tsuru/config.yaml, like "team: admin".`)
+}
+
 func (s *S) TestAppCreatePlan(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	result := `{"status":"success", "repository_url":"git@tsuru.plataformas.glb.com:ble.git"}`
@@ -125,7 +257,7 @@ Your repository for "ble" project is "git@tsuru.plataformas.glb.com:ble.git"` +
 			return method && url && name && platform && teamOwner && plan && pool && description && contentType
 		},
 	}
-	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	client := cmd.NewClient(&http.Client{Transport: noTeamsTransport{&trans}}, nil, manager)
 	command := AppCreate{}
 	command.Flags().Parse(true, []string{"-p", "myplan"})
 	err := command.Run(&context, client)
@@ -159,7 +291,7 @@ Your repository for "ble" project is "git@tsuru.plataformas.glb.com:ble.git"` +
 			return method && url && name && platform && teamowner && plan && pool && description && contentType
 		},
 	}
-	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	client := cmd.NewClient(&http.Client{Transport: noTeamsTransport{&trans}}, nil, manager)
 	command := AppCreate{}
 	command.Flags().Parse(true, []string{"-o", "mypool"})
 	err := command.Run(&context, client)
@@ -187,15 +319,14 @@ Your repository for "ble" project is "git@tsuru.plataformas.glb.com:ble.git"` +
 			plan := r.FormValue("plan") == ""
 			pool := r.FormValue("pool") == ""
 			description := r.FormValue("description") == ""
-			c.Assert(r.FormValue("routeropts.a"), check.Equals, "1")
-			c.Assert(r.FormValue("routeropts.b"), check.Equals, "2")
+			routerOpts := r.FormValue("routeropts.a") == "1" && r.FormValue("routeropts.b") == "2"
 			method := r.Method == "POST"
 			url := strings.HasSuffix(r.URL.Path, "/apps")
 			contentType := r.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
-			return method && url && name && platform && teamowner && plan && pool && description && contentType
+			return method && url && name && platform && teamowner && plan && pool && description && routerOpts && contentType
 		},
 	}
-	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	client := cmd.NewClient(&http.Client{Transport: noTeamsTransport{&trans}}, nil, manager)
 	command := AppCreate{}
 	command.Flags().Parse(true, []string{"--router-opts", "a=1", "--router-opts", "b=2"})
 	err := command.Run(&context, client)
@@ -228,7 +359,7 @@ Use app-info to check the status of the app and its units.` + "\n"
 			return method && url && name && platform && teamowner && plan && pool && description && contentType
 		},
 	}
-	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	client := cmd.NewClient(&http.Client{Transport: noTeamsTransport{&trans}}, nil, manager)
 	command := AppCreate{}
 	err := command.Run(&context, client)
 	c.Assert(err, check.IsNil)
@@ -335,6 +466,98 @@ func (s *S) TestAppUpdateWithoutArgs(c *check.C) {
 	c.Assert(err.Error(), check.Equals, expected)
 }
 
+func (s *S) TestAppMoveInfo(c *check.C) {
+	c.Assert((&AppMove{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAppMove(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	var called bool
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Message: "moving units\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"pool1"},{"name":"pool2"}]`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/pools")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					called = true
+					method := req.Method == "PUT"
+					url := strings.HasSuffix(req.URL.Path, "/apps/ble")
+					pool := req.FormValue("pool") == "pool2"
+					return method && url && pool
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppMove{}
+	command.Flags().Parse(true, []string{"-a", "ble", "-o", "pool2"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, true)
+	c.Assert(stdout.String(), check.Equals, "moving units\nApp \"ble\" has been moved to pool \"pool2\"!\n")
+}
+
+func (s *S) TestAppMoveWithoutPool(c *check.C) {
+	context := cmd.Context{Stdout: &bytes.Buffer{}}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{}}, nil, manager)
+	command := AppMove{}
+	command.Flags().Parse(true, []string{"-a", "ble"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "Please use the -o/--pool flag .*")
+}
+
+func (s *S) TestAppMovePoolNotFound(c *check.C) {
+	context := cmd.Context{Stdout: &bytes.Buffer{}}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `[{"name":"pool1"}]`, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/pools")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppMove{}
+	command.Flags().Parse(true, []string{"-a", "ble", "--pool", "pool2"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Pool "pool2" does not exist or you don't have access to it.`)
+}
+
+func (s *S) TestAppMoveForbidden(c *check.C) {
+	context := cmd.Context{Stdout: &bytes.Buffer{}}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"pool2"}]`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "forbidden", Status: http.StatusForbidden},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "PUT"
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppMove{}
+	command.Flags().Parse(true, []string{"-a", "ble", "--pool", "pool2"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `You don't have permission to move the app "ble" to the pool "pool2".`)
+}
+
 func (s *S) TestAppUpdateFlags(c *check.C) {
 	command := AppUpdate{}
 	flagset := command.Flags()
@@ -543,102 +766,507 @@ Units: 3
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
-func (s *S) TestAppInfoWithDescription(c *check.C) {
+func (s *S) TestAppInfoJSONOutput(c *check.C) {
 	var stdout, stderr bytes.Buffer
-	result := `{"name":"app1","teamowner":"myteam","cname":[""],"ip":"myapp.tsuru.io","platform":"php","repository":"git@git.com:php.git","state":"dead", "units":[{"Ip":"10.10.10.10","ID":"app1/0","Status":"started"}, {"Ip":"9.9.9.9","ID":"app1/1","Status":"started"}, {"Ip":"","ID":"app1/2","Status":"pending"}],"teams":["tsuruteam","crane"], "owner": "myapp_owner", "deploys": 7, "description": "My app"}`
-	expected := `Application: app1
-Description: My app
-Repository: git@git.com:php.git
-Platform: php
-Teams: tsuruteam, crane
-Address: myapp.tsuru.io
-Owner: myapp_owner
-Team owner: myteam
-Deploys: 7
-Pool:
-Quota: 0/unlimited
+	result := `{"name":"app1","platform":"php"}`
+	services := `[{"service":"redisapi","instances":["myredisapi"]}]`
+	quota := `{"limit":10,"inuse":2}`
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: result, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return strings.HasSuffix(req.URL.Path, "/apps/app1")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: services, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return strings.HasSuffix(req.URL.Path, "/services/instances")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: quota, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return strings.HasSuffix(req.URL.Path, "/apps/app1/quota")
+				},
+			},
+		},
+	}
+	context := cmd.Context{
+		Stdout:       &stdout,
+		Stderr:       &stderr,
+		OutputFormat: cmd.JSONOutput,
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var data map[string]interface{}
+	err = json.Unmarshal(stdout.Bytes(), &data)
+	c.Assert(err, check.IsNil)
+	c.Assert(data, check.DeepEquals, map[string]interface{}{
+		"name":     "app1",
+		"platform": "php",
+		"services": []interface{}{
+			map[string]interface{}{"service": "redisapi", "instances": []interface{}{"myredisapi"}},
+		},
+		"quota": map[string]interface{}{"limit": 10.0, "inuse": 2.0},
+	})
+}
 
-Units: 3
-+--------+---------+------+------+
-| Unit   | State   | Host | Port |
-+--------+---------+------+------+
-| app1/0 | started |      |      |
-| app1/1 | started |      |      |
-| app1/2 | pending |      |      |
-+--------+---------+------+------+
+func appInfoMultiTransport() http.RoundTripper {
+	apps := map[string]string{
+		"staging": `{"name":"staging","platform":"python","pool":"dev","teamowner":"myteam","units":[{"ID":"staging/0","Status":"started"}],"deploys":12,"plan":{"name":"small"}}`,
+		"prod":    `{"name":"prod","platform":"python","pool":"prod","teamowner":"myteam","units":[{"ID":"prod/0","Status":"started"},{"ID":"prod/1","Status":"error"}],"deploys":7,"plan":{"name":"small"}}`,
+	}
+	return transportFunc(func(req *http.Request) (*http.Response, error) {
+		message := "[]"
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/quota"):
+			message = `{"limit":0,"inuse":1}`
+		case strings.Contains(req.URL.Path, "/apps/"):
+			var ok bool
+			message, ok = apps[path.Base(req.URL.Path)]
+			if !ok {
+				return &http.Response{Body: ioutil.NopCloser(strings.NewReader("")), StatusCode: http.StatusNoContent}, nil
+			}
+		}
+		return &http.Response{
+			Body:       ioutil.NopCloser(strings.NewReader(message)),
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+}
 
-`
+func (s *S) TestAppInfoMultipleApps(c *check.C) {
+	var stdout, stderr bytes.Buffer
 	context := cmd.Context{
 		Stdout: &stdout,
 		Stderr: &stderr,
 	}
-	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	client := cmd.NewClient(&http.Client{Transport: appInfoMultiTransport()}, nil, manager)
 	command := AppInfo{}
-	command.Flags().Parse(true, []string{"-a/--app", "app1"})
+	command.Flags().Parse(true, []string{"-a", "staging", "--app", "prod", "--units-only"})
 	err := command.Run(&context, client)
 	c.Assert(err, check.IsNil)
-	c.Assert(stdout.String(), check.Equals, expected)
+	c.Assert(stdout.String(), check.Equals, "==> staging <==\nstaging/0\n\n==> prod <==\nprod/0\nprod/1\n")
 }
 
-type transportFunc func(req *http.Request) (resp *http.Response, err error)
+func (s *S) TestAppInfoMultipleAppsSkipsMissingApps(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: appInfoMultiTransport()}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "missing", "--app", "prod", "--units-only"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "==> prod <==\nprod/0\nprod/1\n")
+}
 
-func (fn transportFunc) RoundTrip(req *http.Request) (resp *http.Response, err error) {
-	return fn(req)
+func (s *S) TestSingleAppCommandsRejectManyApps(c *check.C) {
+	trans := transportFunc(func(req *http.Request) (*http.Response, error) {
+		c.Fatalf("unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	commands := []cmd.FlaggedCommand{&AppRemove{}, &AppUpdate{}, &AppStop{}, &AppLog{}, &UnitRemove{}}
+	for _, command := range commands {
+		var stdout, stderr bytes.Buffer
+		context := cmd.Context{Args: []string{"1"}, Stdout: &stdout, Stderr: &stderr}
+		command.Flags().Parse(true, []string{"-a", "staging", "-a", "prod"})
+		err := command.Run(&context, client)
+		c.Check(err, check.ErrorMatches, "This command accepts a single app, but the --app flag was given more than once.", check.Commentf("%T", command))
+	}
 }
 
-func (s *S) TestAppInfoWithQuota(c *check.C) {
+func (s *S) TestAppInfoMultipleAppsCompare(c *check.C) {
 	var stdout, stderr bytes.Buffer
-	expected := `Application: app1
-Description:
-Repository: git@git.com:php.git
-Platform: php
-Teams: tsuruteam, crane
-Address: myapp.tsuru.io
-Owner: myapp_owner
-Team owner: myteam
-Deploys: 7
-Pool:
-Quota: 3/40 units
-
-Units: 3
-+--------+---------+------+------+
-| Unit   | State   | Host | Port |
-+--------+---------+------+------+
-| app1/0 | started |      |      |
-| app1/1 | started |      |      |
-| app1/2 | pending |      |      |
-+--------+---------+------+------+
-
-`
 	context := cmd.Context{
 		Stdout: &stdout,
 		Stderr: &stderr,
 	}
-	transport := transportFunc(func(req *http.Request) (resp *http.Response, err error) {
-		var body string
-		if strings.HasSuffix(req.URL.Path, "/apps/app1/quota") {
-			body = `{"Limit":40,"InUse":3}`
-		} else if strings.HasSuffix(req.URL.Path, "/apps/app1") {
-			body = `{"name":"app1","teamowner":"myteam","cname":[""],"ip":"myapp.tsuru.io","platform":"php","repository":"git@git.com:php.git","state":"dead", "units":[{"Ip":"10.10.10.10","ID":"app1/0","Status":"started"}, {"Ip":"9.9.9.9","ID":"app1/1","Status":"started"}, {"Ip":"","ID":"app1/2","Status":"pending"}],"teams":["tsuruteam","crane"], "owner": "myapp_owner", "deploys": 7}`
-		}
-		return &http.Response{
-			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
-			StatusCode: http.StatusOK,
-		}, nil
-	})
-	client := cmd.NewClient(&http.Client{Transport: transport}, nil, manager)
+	client := cmd.NewClient(&http.Client{Transport: appInfoMultiTransport()}, nil, manager)
 	command := AppInfo{}
-	command.Flags().Parse(true, []string{"--app", "app1"})
+	command.Flags().Parse(true, []string{"-a", "staging", "-a", "prod", "--compare"})
 	err := command.Run(&context, client)
 	c.Assert(err, check.IsNil)
+	expected := `+------------+---------------+---------------+
+|            | staging       | prod          |
++------------+---------------+---------------+
+| Platform   | python        | python        |
+| * Pool     | dev           | prod          |
+| Plan       | small         | small         |
+| Team owner | myteam        | myteam        |
+| Teams      |               |               |
+| * Units    | 1 (1 started) | 2 (1 started) |
+| Quota      | 1/unlimited   | 1/unlimited   |
+| * Deploys  | 12            | 7             |
+| CNames     |               |               |
+| Routers    |               |               |
+| Services   |               |               |
++------------+---------------+---------------+
+* The values differ between the apps.
+`
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
-func (s *S) TestAppInfoLock(c *check.C) {
+func (s *S) TestAppInfoMultipleAppsJSON(c *check.C) {
 	var stdout, stderr bytes.Buffer
-	result := `{"name":"app1","teamowner":"myteam","cname":[""],"ip":"myapp.tsuru.io","platform":"php","repository":"git@git.com:php.git","state":"dead", "units":[{"Ip":"10.10.10.10","ID":"app1/0","Status":"started"}, {"Ip":"9.9.9.9","ID":"app1/1","Status":"started"}, {"Ip":"","ID":"app1/2","Status":"pending"}],"teams":["tsuruteam","crane"], "owner": "myapp_owner", "deploys": 7, "lock": {"locked": true, "owner": "admin@example.com", "reason": "DELETE /apps/rbsample/units", "acquiredate": "2012-04-01T10:32:00Z"}}`
-	expected := `Application: app1
-Description:
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: appInfoMultiTransport()}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "staging", "-a", "prod", "--json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var data []map[string]interface{}
+	err = json.Unmarshal(stdout.Bytes(), &data)
+	c.Assert(err, check.IsNil)
+	c.Assert(data, check.HasLen, 2)
+	c.Assert(data[0]["name"], check.Equals, "staging")
+	c.Assert(data[1]["name"], check.Equals, "prod")
+	c.Assert(data[1]["quota"], check.DeepEquals, map[string]interface{}{"limit": 0.0, "inuse": 1.0})
+}
+
+func (s *S) TestAppInfoMultipleAppsInvalidFlags(c *check.C) {
+	context := cmd.Context{
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	client := cmd.NewClient(&http.Client{Transport: appInfoMultiTransport()}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "staging", "--compare"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "The --compare flag requires at least two apps.*")
+	command = AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "staging", "-a", "prod", "--watch"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "The --watch flag can't be used with more than one app.")
+}
+
+func appInfoEnvTransport() http.RoundTripper {
+	services := `{"mysql":[{"instance_name":"db","envs":{"DB_HOST":"host","DB_PASSWORD":"secret"}}],"redis":[{"instance_name":"cache","envs":{"REDIS_URL":"redis://"}}]}`
+	envs, _ := json.Marshal([]envVar{
+		{Name: "DEBUG", Value: "1", Public: true},
+		{Name: "API_KEY", Public: false},
+		{Name: "DB_HOST", Public: false},
+		{Name: "DB_PASSWORD", Public: false},
+		{Name: "REDIS_URL", Public: false},
+		{Name: "TSURU_SERVICES", Value: services, Public: true},
+	})
+	return transportFunc(func(req *http.Request) (*http.Response, error) {
+		message := "[]"
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/apps/app1/env"):
+			message = string(envs)
+		case strings.HasSuffix(req.URL.Path, "/apps/app1/quota"):
+			message = `{"limit":0,"inuse":0}`
+		case strings.HasSuffix(req.URL.Path, "/apps/app1"):
+			message = `{"name":"app1","platform":"php"}`
+		}
+		return &http.Response{
+			Body:       ioutil.NopCloser(strings.NewReader(message)),
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+}
+
+func (s *S) TestAppInfoEnv(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: appInfoEnvTransport()}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--env"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `
+Environment variables: 6 (2 public, 1 private, 3 from service instances)
++---------+----------+-----------+
+| Service | Instance | Variables |
++---------+----------+-----------+
+| mysql   | db       | 2         |
+| redis   | cache    | 1         |
++---------+----------+-----------+
+
+`
+	c.Assert(strings.HasSuffix(stdout.String(), expected), check.Equals, true, check.Commentf("%s", stdout.String()))
+}
+
+func (s *S) TestAppInfoEnvJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: appInfoEnvTransport()}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--env", "--json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var data map[string]interface{}
+	err = json.Unmarshal(stdout.Bytes(), &data)
+	c.Assert(err, check.IsNil)
+	c.Assert(data["env"], check.DeepEquals, map[string]interface{}{
+		"total":   6.0,
+		"public":  2.0,
+		"private": 1.0,
+		"bound":   3.0,
+		"services": []interface{}{
+			map[string]interface{}{"service": "mysql", "instance": "db", "variables": 2.0},
+			map[string]interface{}{"service": "redis", "instance": "cache", "variables": 1.0},
+		},
+	})
+}
+
+func (s *S) TestAppInfoWithDescription(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `{"name":"app1","teamowner":"myteam","cname":[""],"ip":"myapp.tsuru.io","platform":"php","repository":"git@git.com:php.git","state":"dead", "units":[{"Ip":"10.10.10.10","ID":"app1/0","Status":"started"}, {"Ip":"9.9.9.9","ID":"app1/1","Status":"started"}, {"Ip":"","ID":"app1/2","Status":"pending"}],"teams":["tsuruteam","crane"], "owner": "myapp_owner", "deploys": 7, "description": "My app"}`
+	expected := `Application: app1
+Description: My app
+Repository: git@git.com:php.git
+Platform: php
+Teams: tsuruteam, crane
+Address: myapp.tsuru.io
+Owner: myapp_owner
+Team owner: myteam
+Deploys: 7
+Pool:
+Quota: 0/unlimited
+
+Units: 3
++--------+---------+------+------+
+| Unit   | State   | Host | Port |
++--------+---------+------+------+
+| app1/0 | started |      |      |
+| app1/1 | started |      |      |
+| app1/2 | pending |      |      |
++--------+---------+------+------+
+
+`
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a/--app", "app1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+type transportFunc func(req *http.Request) (resp *http.Response, err error)
+
+func (fn transportFunc) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	return fn(req)
+}
+
+// noTeamsTransport answers the request listing the teams, sent by the
+// commands choosing the team owner, with no teams, leaving the choice to the
+// server, and sends the other requests to the wrapped transport.
+type noTeamsTransport struct {
+	http.RoundTripper
+}
+
+func (t noTeamsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/teams") {
+		return &http.Response{StatusCode: http.StatusNoContent, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
+
+func (s *S) TestAppInfoWatch(c *check.C) {
+	var interrupt chan<- os.Signal
+	defer func(notify, stop func(chan<- os.Signal)) {
+		notifyInterrupt, stopInterrupt = notify, stop
+	}(notifyInterrupt, stopInterrupt)
+	notifyInterrupt = func(ch chan<- os.Signal) { interrupt = ch }
+	stopInterrupt = func(chan<- os.Signal) {}
+	var calls int
+	trans := transportFunc(func(req *http.Request) (*http.Response, error) {
+		message := `{"name":"app1","platform":"php"}`
+		if strings.HasSuffix(req.URL.Path, "/apps/app1") {
+			calls++
+			if calls == 2 {
+				interrupt <- os.Interrupt
+			}
+		} else {
+			message = "{}"
+		}
+		return &http.Response{
+			Body:       ioutil.NopCloser(strings.NewReader(message)),
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+		}, nil
+	})
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--watch", "--interval", "10ms"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(calls, check.Equals, 2)
+	c.Assert(strings.Count(stdout.String(), "Every 10ms: app-info -a app1"), check.Equals, 2)
+	c.Assert(strings.Count(stdout.String(), "Application: app1\n"), check.Equals, 2)
+	c.Assert(strings.Contains(stdout.String(), "\033[2J"), check.Equals, false)
+}
+
+func (s *S) TestAppInfoWatchInvalidInterval(c *check.C) {
+	context := cmd.Context{}
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--watch", "--interval", "0s"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "The interval must be positive.")
+}
+
+func (s *S) TestAppInfoFormat(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `{"name":"app1","platform":"php","pool":"dev","units":[{"ID":"app1/0","Status":"started"}]}`
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--format", "{{.Name}} {{.Pool}} {{len .Units}}"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "app1 dev 1\n")
+}
+
+func (s *S) TestAppInfoFormatInvalidTemplate(c *check.C) {
+	context := cmd.Context{}
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--format", "{{.Name"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "Invalid format template: .*")
+}
+
+func (s *S) TestAppInfoFormatUnknownField(c *check.C) {
+	var stdout bytes.Buffer
+	result := `{"name":"app1"}`
+	context := cmd.Context{Stdout: &stdout}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--format", "{{.Name}} {{.Color}}"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Failed to format app "app1": .*Color.*`)
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
+func (s *S) TestAppInfoFormatWithOutput(c *check.C) {
+	context := cmd.Context{OutputFormat: cmd.JSONOutput}
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--format", "{{.Name}}"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "The --format flag can't be used with the --output flag.")
+}
+
+func (s *S) TestAppInfoUnitsOnly(c *check.C) {
+	var stdout bytes.Buffer
+	result := `{"name":"app1","units":[{"ID":"app1/0","Status":"started"},{"ID":"app1/1","Status":"error"}]}`
+	context := cmd.Context{Stdout: &stdout}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--units-only"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "app1/0\napp1/1\n")
+	stdout.Reset()
+	command = AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--units-only", "--with-status"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "app1/0\tstarted\napp1/1\terror\n")
+}
+
+func (s *S) TestAppInfoUnitsOnlyWithoutUnits(c *check.C) {
+	var stdout bytes.Buffer
+	result := `{"name":"app1","units":[]}`
+	context := cmd.Context{Stdout: &stdout}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--units-only"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
+func (s *S) TestAppInfoWithStatusRequiresUnitsOnly(c *check.C) {
+	context := cmd.Context{}
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--with-status"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "The --with-status flag requires --units-only.")
+}
+
+func (s *S) TestAppInfoWithQuota(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	expected := `Application: app1
+Description:
+Repository: git@git.com:php.git
+Platform: php
+Teams: tsuruteam, crane
+Address: myapp.tsuru.io
+Owner: myapp_owner
+Team owner: myteam
+Deploys: 7
+Pool:
+Quota: 3/40 units
+
+Units: 3
++--------+---------+------+------+
+| Unit   | State   | Host | Port |
++--------+---------+------+------+
+| app1/0 | started |      |      |
+| app1/1 | started |      |      |
+| app1/2 | pending |      |      |
++--------+---------+------+------+
+
+`
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	transport := transportFunc(func(req *http.Request) (resp *http.Response, err error) {
+		var body string
+		if strings.HasSuffix(req.URL.Path, "/apps/app1/quota") {
+			body = `{"Limit":40,"InUse":3}`
+		} else if strings.HasSuffix(req.URL.Path, "/apps/app1") {
+			body = `{"name":"app1","teamowner":"myteam","cname":[""],"ip":"myapp.tsuru.io","platform":"php","repository":"git@git.com:php.git","state":"dead", "units":[{"Ip":"10.10.10.10","ID":"app1/0","Status":"started"}, {"Ip":"9.9.9.9","ID":"app1/1","Status":"started"}, {"Ip":"","ID":"app1/2","Status":"pending"}],"teams":["tsuruteam","crane"], "owner": "myapp_owner", "deploys": 7}`
+		}
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+	client := cmd.NewClient(&http.Client{Transport: transport}, nil, manager)
+	command := AppInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppInfoLock(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `{"name":"app1","teamowner":"myteam","cname":[""],"ip":"myapp.tsuru.io","platform":"php","repository":"git@git.com:php.git","state":"dead", "units":[{"Ip":"10.10.10.10","ID":"app1/0","Status":"started"}, {"Ip":"9.9.9.9","ID":"app1/1","Status":"started"}, {"Ip":"","ID":"app1/2","Status":"pending"}],"teams":["tsuruteam","crane"], "owner": "myapp_owner", "deploys": 7, "lock": {"locked": true, "owner": "admin@example.com", "reason": "DELETE /apps/rbsample/units", "acquiredate": "2012-04-01T10:32:00Z"}}`
+	expected := `Application: app1
+Description:
 Repository: git@git.com:php.git
 Platform: php
 Teams: tsuruteam, crane
@@ -1221,6 +1849,39 @@ func (s *S) TestAppGrantWithoutFlag(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+func (s *S) TestAppGrantMultipleTeams(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"cobrateam", "pythonistas", "gophers"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	command := AppGrant{}
+	command.Flags().Parse(true, []string{"--app", "games"})
+	ok := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+		CondFunc: func(r *http.Request) bool {
+			return r.Method == "PUT"
+		},
+	}
+	notFound := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "Team not found", Status: http.StatusNotFound},
+		CondFunc: func(r *http.Request) bool {
+			return r.Method == "PUT" && strings.HasSuffix(r.URL.Path, "/apps/games/teams/pythonistas")
+		},
+	}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{ok, notFound, ok},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Failed to grant access to 1 of 3 team\(s\).`)
+	c.Assert(stdout.String(), check.Equals, `Team "cobrateam" was added to the "games" app
+Team "gophers" was added to the "games" app
+`)
+	c.Assert(stderr.String(), check.Equals, `Failed to add team "pythonistas" to the "games" app: Team not found`+"\n")
+}
+
 func (s *S) TestAppGrantInfo(c *check.C) {
 	c.Assert((&AppGrant{}).Info(), check.NotNil)
 }
@@ -1235,7 +1896,23 @@ func (s *S) TestAppRevoke(c *check.C) {
 	}
 	command := AppRevoke{}
 	command.Flags().Parse(true, []string{"--app", "games"})
-	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: "", Status: http.StatusOK}}, nil, manager)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `{"name":"games","teams":["cobrateam","admin"]}`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/apps/games")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "DELETE" && strings.HasSuffix(r.URL.Path, "/apps/games/teams/cobrateam")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
 	err := command.Run(&context, client)
 	c.Assert(err, check.IsNil)
 	c.Assert(stdout.String(), check.Equals, expected)
@@ -1252,17 +1929,97 @@ func (s *S) TestAppRevokeWithoutFlag(c *check.C) {
 	fake := &cmdtest.FakeGuesser{Name: "fights"}
 	command := AppRevoke{GuessingCommand: cmd.GuessingCommand{G: fake}}
 	command.Flags().Parse(true, nil)
-	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: "", Status: http.StatusOK}}, nil, manager)
-	err := command.Run(&context, client)
-	c.Assert(err, check.IsNil)
-	c.Assert(stdout.String(), check.Equals, expected)
-}
-
-func (s *S) TestAppRevokeInfo(c *check.C) {
-	c.Assert((&AppRevoke{}).Info(), check.NotNil)
-}
-
-func (s *S) TestAppList(c *check.C) {
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `{"name":"fights","teams":["cobrateam","admin"]}`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/apps/fights")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "DELETE" && strings.HasSuffix(r.URL.Path, "/apps/fights/teams/cobrateam")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppRevokeMultipleTeams(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"cobrateam", "gophers"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	command := AppRevoke{}
+	command.Flags().Parse(true, []string{"--app", "games"})
+	var deleted []string
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `{"name":"games","teams":["cobrateam","gophers","admin"]}`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					deleted = append(deleted, r.URL.Path)
+					return r.Method == "DELETE"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					deleted = append(deleted, r.URL.Path)
+					return r.Method == "DELETE"
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(deleted, check.DeepEquals, []string{"/1.0/apps/games/teams/cobrateam", "/1.0/apps/games/teams/gophers"})
+	c.Assert(stdout.String(), check.Equals, `Team "cobrateam" was removed from the "games" app
+Team "gophers" was removed from the "games" app
+`)
+}
+
+func (s *S) TestAppRevokeWouldOrphanApp(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"cobrateam", "gophers"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	command := AppRevoke{}
+	command.Flags().Parse(true, []string{"--app", "games"})
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `{"name":"games","teams":["cobrateam","gophers"]}`, Status: http.StatusOK},
+		CondFunc: func(r *http.Request) bool {
+			return r.Method == "GET"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Cannot revoke access from all the teams of the "games" app, it must keep at least one authorized team.`)
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
+func (s *S) TestAppRevokeInfo(c *check.C) {
+	c.Assert((&AppRevoke{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAppList(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	result := `[{"ip":"10.10.10.10","name":"app1","units":[{"ID":"app1/0","Status":"started"}]}]`
 	expected := `+-------------+-------------------------+-------------+
@@ -1283,6 +2040,84 @@ func (s *S) TestAppList(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+func (s *S) TestAppListAddresses(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `[
+{"ip":"10.10.10.10","name":"app1","units":[{"ID":"app1/0","Status":"started"}]},
+{"ip":"app2.example.com","name":"app2","units":[],"routers":[{"name":"r1","address":"app2.r1.com"},{"name":"r2","address":"app2.r2.com"},{"name":"r3","address":"app2.r3.com"}]},
+{"ip":"app3.example.com","name":"app3","units":[],"routers":[{"name":"r1","address":"app3.r1.com"}]}
+]`
+	expected := `+-------------+-------------------------+------------------+-----------------------+
+| Application | Units State Summary     | Address          | Router Address        |
++-------------+-------------------------+------------------+-----------------------+
+| app1        | 1 of 1 units in-service | 10.10.10.10      | 10.10.10.10           |
++-------------+-------------------------+------------------+-----------------------+
+| app2        | 0 of 0 units in-service | app2.example.com | app2.r1.com (+2 more) |
++-------------+-------------------------+------------------+-----------------------+
+| app3        | 0 of 0 units in-service | app3.example.com | app3.r1.com           |
++-------------+-------------------------+------------------+-----------------------+
+`
+	context := cmd.Context{
+		Args:   []string{},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	command := AppList{}
+	command.Flags().Parse(true, []string{"--addresses"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppListJSONOutput(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `[{"ip":"10.10.10.10","name":"app1","units":[{"ID":"app1/0","Status":"started"}]}]`
+	expected := `[
+  {
+    "ip": "10.10.10.10",
+    "name": "app1",
+    "units": [
+      {
+        "ID": "app1/0",
+        "Status": "started"
+      }
+    ]
+  }
+]
+`
+	context := cmd.Context{
+		Args:         []string{},
+		Stdout:       &stdout,
+		Stderr:       &stderr,
+		OutputFormat: cmd.JSONOutput,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	command := AppList{}
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppListYAMLOutput(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `[{"ip":"10.10.10.10","name":"app1"}]`
+	expected := `- ip: 10.10.10.10
+  name: app1
+`
+	context := cmd.Context{
+		Args:         []string{},
+		Stdout:       &stdout,
+		Stderr:       &stderr,
+		OutputFormat: cmd.YAMLOutput,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	command := AppList{}
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
 func (s *S) TestAppListDisplayAppsInAlphabeticalOrder(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	result := `[{"ip":"10.10.10.11","name":"sapp","units":[{"ID":"sapp1/0","Status":"started"}]},{"ip":"10.10.10.10","name":"app1","units":[{"ID":"app1/0","Status":"started"}]}]`
@@ -1480,6 +2315,189 @@ app3
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+func (s *S) TestAppListWithLimitClientSide(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `[{"ip":"10.10.10.12","name":"app3","units":[]},{"ip":"10.10.10.10","name":"app1","units":[]},{"ip":"10.10.10.11","name":"app2","units":[]}]`
+	expected := `+-------------+-------------------------+-------------+
+| Application | Units State Summary     | Address     |
++-------------+-------------------------+-------------+
+| app1        | 0 of 0 units in-service | 10.10.10.10 |
++-------------+-------------------------+-------------+
+| app2        | 0 of 0 units in-service | 10.10.10.11 |
++-------------+-------------------------+-------------+
+Showing apps 1-2 of 3. Use --page 2 to see more.
+`
+	context := cmd.Context{
+		Args:   []string{},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: result, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.URL.Query().Get("limit") == "2" && req.URL.Query().Get("offset") == "0"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppList{}
+	command.Flags().Parse(true, []string{"--limit", "2"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppListWithLimitClientSideLastPage(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `[{"ip":"10.10.10.12","name":"app3","units":[]},{"ip":"10.10.10.10","name":"app1","units":[]},{"ip":"10.10.10.11","name":"app2","units":[]}]`
+	context := cmd.Context{
+		Args:   []string{},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: result, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.URL.Query().Get("limit") == "2" && req.URL.Query().Get("offset") == "2"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppList{}
+	command.Flags().Parse(true, []string{"--limit", "2", "--page", "2", "-q"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "app3\n")
+}
+
+func (s *S) TestAppListWithLimitServerSide(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `[{"ip":"10.10.10.13","name":"app4","units":[]}]`
+	expected := `+-------------+-------------------------+-------------+
+| Application | Units State Summary     | Address     |
++-------------+-------------------------+-------------+
+| app4        | 0 of 0 units in-service | 10.10.10.13 |
++-------------+-------------------------+-------------+
+Showing apps 4-4 of 5. Use --page 5 to see more.
+`
+	context := cmd.Context{
+		Args:   []string{},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{
+			Message: result,
+			Status:  http.StatusOK,
+			Headers: map[string][]string{"X-Total-Count": {"5"}},
+		},
+		CondFunc: func(req *http.Request) bool {
+			return req.URL.Query().Get("limit") == "1" && req.URL.Query().Get("offset") == "3"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppList{}
+	command.Flags().Parse(true, []string{"--limit", "1", "--page", "4"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppListPageWithoutLimit(c *check.C) {
+	context := cmd.Context{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	command := AppList{}
+	command.Flags().Parse(true, []string{"--page", "2"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "The --page flag requires --limit.")
+}
+
+func (s *S) TestAppListSortByUnitsDescending(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `[
+{"name":"app1","platform":"python","units":[{"ID":"app1/0","Status":"started"}]},
+{"name":"app2","platform":"go","units":[{"ID":"app2/0","Status":"started"},{"ID":"app2/1","Status":"started"}]},
+{"name":"app3","platform":"go","units":[{"ID":"app3/0","Status":"started"}]}
+]`
+	context := cmd.Context{
+		Args:   []string{},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	command := AppList{}
+	command.Flags().Parse(true, []string{"--sort", "-units", "-q"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "app2\napp3\napp1\n")
+	stdout.Reset()
+	command = AppList{}
+	command.Flags().Parse(true, []string{"--sort", "platform", "-q"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "app2\napp3\napp1\n")
+}
+
+func (s *S) TestAppListSortJSONOutput(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `[{"name":"app1","pool":"prod"},{"name":"app2","pool":"dev"}]`
+	expected := `[
+  {
+    "name": "app2",
+    "pool": "dev"
+  },
+  {
+    "name": "app1",
+    "pool": "prod"
+  }
+]
+`
+	context := cmd.Context{
+		Args:         []string{},
+		Stdout:       &stdout,
+		Stderr:       &stderr,
+		OutputFormat: cmd.JSONOutput,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	command := AppList{}
+	command.Flags().Parse(true, []string{"--sort", "pool"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppListSortInvalidKey(c *check.C) {
+	context := cmd.Context{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	command := AppList{}
+	command.Flags().Parse(true, []string{"--sort", "owner"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, `Invalid sort key "owner". Valid keys are: name, platform, pool, units. Prefix the key with - for descending order.`)
+}
+
+func (s *S) TestAppListFormat(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `[
+{"name":"app2","pool":"prod"},
+{"name":"app1","pool":"dev"}
+]`
+	context := cmd.Context{
+		Args:   []string{},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: result, Status: http.StatusOK}}, nil, manager)
+	command := AppList{}
+	command.Flags().Parse(true, []string{"--format", "{{.Name}} {{.Pool}}"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "app1 dev\napp2 prod\n")
+}
+
+func (s *S) TestAppListFormatWithSimplified(c *check.C) {
+	context := cmd.Context{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	command := AppList{}
+	command.Flags().Parse(true, []string{"--format", "{{.Name}}", "-q"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "The --format flag can't be used with the -q and --addresses flags.")
+}
+
 func (s *S) TestAppListInfo(c *check.C) {
 	c.Assert((&AppList{}).Info(), check.NotNil)
 }
@@ -1566,15 +2584,25 @@ func (s *S) TestAddCName(c *check.C) {
 		Stderr: &stderr,
 		Args:   []string{"death.evergrey.mycompany.com"},
 	}
-	trans := &cmdtest.ConditionalTransport{
-		Transport: cmdtest.Transport{Message: "Restarted", Status: http.StatusOK},
-		CondFunc: func(req *http.Request) bool {
-			called = true
-			cname := req.FormValue("cname") == "death.evergrey.mycompany.com"
-			method := req.Method == "POST"
-			url := strings.HasSuffix(req.URL.Path, "/apps/death/cname")
-			contentType := req.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
-			return method && url && cname && contentType
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `{"name":"death"}`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/death")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "Restarted", Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					called = true
+					cname := req.FormValue("cname") == "death.evergrey.mycompany.com"
+					method := req.Method == "POST"
+					url := strings.HasSuffix(req.URL.Path, "/apps/death/cname")
+					contentType := req.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
+					return method && url && cname && contentType
+				},
+			},
 		},
 	}
 	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
@@ -1583,7 +2611,7 @@ func (s *S) TestAddCName(c *check.C) {
 	err := command.Run(&context, client)
 	c.Assert(err, check.IsNil)
 	c.Assert(called, check.Equals, true)
-	c.Assert(stdout.String(), check.Equals, "cname successfully defined.\n")
+	c.Assert(stdout.String(), check.Equals, "cname successfully defined.\nAdded: death.evergrey.mycompany.com\n")
 }
 
 func (s *S) TestAddCNameWithoutTheFlag(c *check.C) {
@@ -1597,22 +2625,94 @@ func (s *S) TestAddCNameWithoutTheFlag(c *check.C) {
 		Args:   []string{"corey.evergrey.mycompany.com"},
 	}
 	fake := &cmdtest.FakeGuesser{Name: "corey"}
-	trans := &cmdtest.ConditionalTransport{
-		Transport: cmdtest.Transport{Message: "Restarted", Status: http.StatusOK},
-		CondFunc: func(req *http.Request) bool {
-			called = true
-			cname := req.FormValue("cname") == "corey.evergrey.mycompany.com"
-			method := req.Method == "POST"
-			url := strings.HasSuffix(req.URL.Path, "/apps/corey/cname")
-			contentType := req.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
-			return method && url && cname && contentType
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `{"name":"corey"}`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/corey")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "Restarted", Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					called = true
+					cname := req.FormValue("cname") == "corey.evergrey.mycompany.com"
+					method := req.Method == "POST"
+					url := strings.HasSuffix(req.URL.Path, "/apps/corey/cname")
+					contentType := req.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
+					return method && url && cname && contentType
+				},
+			},
 		},
 	}
 	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
 	err := (&CnameAdd{cmd.GuessingCommand{G: fake}}).Run(&context, client)
 	c.Assert(err, check.IsNil)
 	c.Assert(called, check.Equals, true)
-	c.Assert(stdout.String(), check.Equals, "cname successfully defined.\n")
+	c.Assert(stdout.String(), check.Equals, "cname successfully defined.\nAdded: corey.evergrey.mycompany.com\n")
+}
+
+func (s *S) TestAddCNameMultipleWithAlreadyPresent(c *check.C) {
+	var (
+		called         bool
+		stdout, stderr bytes.Buffer
+	)
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"a.example.com", "b.example.com", "c.example.com"},
+	}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `{"name":"death","cname":["b.example.com"]}`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/death")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					called = true
+					req.ParseForm()
+					cnames := req.Form["cname"]
+					method := req.Method == "POST"
+					url := strings.HasSuffix(req.URL.Path, "/apps/death/cname")
+					return method && url && len(cnames) == 2 && cnames[0] == "a.example.com" && cnames[1] == "c.example.com"
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := CnameAdd{}
+	command.Flags().Parse(true, []string{"-a", "death"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, true)
+	expected := "cname successfully defined.\nAdded: a.example.com, c.example.com\nAlready present: b.example.com\n"
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAddCNameAllAlreadyPresent(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"b.example.com"},
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `{"name":"death","cname":["b.example.com"]}`, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/death")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := CnameAdd{}
+	command.Flags().Parse(true, []string{"-a", "death"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Already present: b.example.com\n")
 }
 
 func (s *S) TestAddCNameFailure(c *check.C) {
@@ -1647,6 +2747,7 @@ func (s *S) TestRemoveCName(c *check.C) {
 	context := cmd.Context{
 		Stdout: &stdout,
 		Stderr: &stderr,
+		Args:   []string{"death.evergrey.mycompany.com"},
 	}
 	trans := &cmdtest.ConditionalTransport{
 		Transport: cmdtest.Transport{Message: "Restarted", Status: http.StatusOK},
@@ -1666,6 +2767,93 @@ func (s *S) TestRemoveCName(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, "cname successfully undefined.\n")
 }
 
+func (s *S) TestRemoveMultipleCNames(c *check.C) {
+	var (
+		called         bool
+		stdout, stderr bytes.Buffer
+	)
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"a.example.com", "b.example.com"},
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			called = true
+			cnames := req.URL.Query()["cname"]
+			method := req.Method == "DELETE"
+			url := strings.HasSuffix(req.URL.Path, "/apps/death/cname")
+			return method && url && len(cnames) == 2 && cnames[0] == "a.example.com" && cnames[1] == "b.example.com"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := CnameRemove{}
+	command.Flags().Parse(true, []string{"--app", "death"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, true)
+	c.Assert(stdout.String(), check.Equals, "cname successfully undefined.\n")
+}
+
+func (s *S) TestRemoveCNameWithoutCNames(c *check.C) {
+	var (
+		called         bool
+		stdout, stderr bytes.Buffer
+	)
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `{"name":"death","cname":["a.example.com","b.example.com"]}`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/death")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					called = true
+					cnames := req.URL.Query()["cname"]
+					method := req.Method == "DELETE"
+					url := strings.HasSuffix(req.URL.Path, "/apps/death/cname")
+					return method && url && len(cnames) == 2 && cnames[0] == "a.example.com" && cnames[1] == "b.example.com"
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := CnameRemove{}
+	command.Flags().Parse(true, []string{"--app", "death"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, true)
+	c.Assert(stdout.String(), check.Equals, "cname successfully undefined.\n")
+}
+
+func (s *S) TestRemoveCNameWithoutCNamesAppHasNone(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `{"name":"death","cname":[]}`, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/death")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := CnameRemove{}
+	command.Flags().Parse(true, []string{"--app", "death"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `app "death" has no cnames.`)
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
 func (s *S) TestRemoveCNameWithoutTheFlag(c *check.C) {
 	var (
 		called         bool
@@ -1674,6 +2862,7 @@ func (s *S) TestRemoveCNameWithoutTheFlag(c *check.C) {
 	context := cmd.Context{
 		Stdout: &stdout,
 		Stderr: &stderr,
+		Args:   []string{"corey.evergrey.mycompany.com"},
 	}
 	fake := &cmdtest.FakeGuesser{Name: "corey"}
 	trans := &cmdtest.ConditionalTransport{
@@ -1900,6 +3089,41 @@ func (s *S) TestUnitAdd(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expectedOut)
 }
 
+func (s *S) TestUnitAddPool(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"2"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return strings.HasSuffix(req.URL.Path, "/apps/radio/units") && req.Method == "PUT" &&
+				req.FormValue("process") == "worker" && req.FormValue("pool") == "highmem" && req.FormValue("units") == "2"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := UnitAdd{}
+	command.Flags().Parse(true, []string{"-a", "radio", "--process", "worker", "--pool", "highmem"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestUnitAddInvalidPool(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"2"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: "pool not found", Status: http.StatusNotFound}}, nil, manager)
+	command := UnitAdd{}
+	command.Flags().Parse(true, []string{"-a", "radio", "--pool", "nopool"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "pool not found")
+}
+
 func (s *S) TestUnitAddFailure(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	context := cmd.Context{
@@ -1956,6 +3180,27 @@ func (s *S) TestUnitRemove(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, "-- removed unit --")
 }
 
+func (s *S) TestUnitRemovePool(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"1"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return strings.HasSuffix(req.URL.Path, "/apps/vapor/units") && req.Method == "DELETE" &&
+				req.FormValue("process") == "worker" && req.FormValue("pool") == "highmem"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := UnitRemove{}
+	command.Flags().Parse(true, []string{"-a", "vapor", "-p", "worker", "--pool", "highmem"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+}
+
 func (s *S) TestUnitRemoveFailure(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	context := cmd.Context{
@@ -1973,6 +3218,142 @@ func (s *S) TestUnitRemoveFailure(c *check.C) {
 	c.Assert(err.Error(), check.Equals, "Failed to remove.")
 }
 
+func (s *S) TestUnitRemoveConfirmGuessedApp(c *check.C) {
+	os.Setenv("TSURU_CONFIRM_APP", "1")
+	defer os.Unsetenv("TSURU_CONFIRM_APP")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"2"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("y\n"),
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return strings.HasSuffix(req.URL.Path, "/apps/vapor/units") && req.Method == "DELETE"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := UnitRemove{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "vapor"}}}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stderr.String(), check.Equals, "using guessed app vapor\n")
+	c.Assert(stdout.String(), check.Equals, `Are you sure you want to run this command on app "vapor"? (y/n) `)
+}
+
+func (s *S) TestUnitRemoveConfirmGuessedAppRefused(c *check.C) {
+	os.Setenv("TSURU_CONFIRM_APP", "1")
+	defer os.Unsetenv("TSURU_CONFIRM_APP")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"2"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+	}
+	trans := transportFunc(func(req *http.Request) (*http.Response, error) {
+		c.Fatalf("unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := UnitRemove{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "vapor"}}}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&context, client)
+	c.Assert(err, check.Equals, cmd.ErrGuessNotConfirmed)
+}
+
+func (s *S) TestAppStopConfirmGuessedAppRefused(c *check.C) {
+	os.Setenv("TSURU_CONFIRM_APP", "1")
+	defer os.Unsetenv("TSURU_CONFIRM_APP")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   nil,
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+	}
+	trans := transportFunc(func(req *http.Request) (*http.Response, error) {
+		c.Fatalf("unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppStop{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "vapor"}}}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&context, client)
+	c.Assert(err, check.Equals, cmd.ErrGuessNotConfirmed)
+	c.Assert(stderr.String(), check.Equals, "using guessed app vapor\n")
+}
+
+func (s *S) TestAppRestartConfirmGuessedAppRefused(c *check.C) {
+	os.Setenv("TSURU_CONFIRM_APP", "1")
+	defer os.Unsetenv("TSURU_CONFIRM_APP")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   nil,
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+	}
+	trans := transportFunc(func(req *http.Request) (*http.Response, error) {
+		c.Fatalf("unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRestart{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "vapor"}}}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&context, client)
+	c.Assert(err, check.Equals, cmd.ErrGuessNotConfirmed)
+	c.Assert(stderr.String(), check.Equals, "using guessed app vapor\n")
+}
+
+func (s *S) TestUnitKillConfirmGuessedAppRefused(c *check.C) {
+	os.Setenv("TSURU_CONFIRM_APP", "1")
+	defer os.Unsetenv("TSURU_CONFIRM_APP")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   nil,
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+	}
+	trans := transportFunc(func(req *http.Request) (*http.Response, error) {
+		c.Fatalf("unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := UnitKill{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "vapor"}}}
+	command.Flags().Parse(true, []string{"--unit", "vapor-1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.Equals, cmd.ErrGuessNotConfirmed)
+	c.Assert(stderr.String(), check.Equals, "using guessed app vapor\n")
+}
+
+func (s *S) TestUnitRemoveConfirmAppNotGuessed(c *check.C) {
+	os.Setenv("TSURU_CONFIRM_APP", "1")
+	defer os.Unsetenv("TSURU_CONFIRM_APP")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"2"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return strings.HasSuffix(req.URL.Path, "/apps/vapor/units") && req.Method == "DELETE"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := UnitRemove{}
+	command.Flags().Parse(true, []string{"-a", "vapor"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stderr.String(), check.Equals, "")
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
 func (s *S) TestUnitRemoveInfo(c *check.C) {
 	c.Assert((&UnitRemove{}).Info(), check.NotNil)
 }
@@ -1980,3 +3361,67 @@ func (s *S) TestUnitRemoveInfo(c *check.C) {
 func (s *S) TestUnitRemoveIsACommand(c *check.C) {
 	var _ cmd.Command = &UnitRemove{}
 }
+
+func (s *S) TestUnitKill(c *check.C) {
+	var killed bool
+	trans := transportFunc(func(req *http.Request) (*http.Response, error) {
+		var message string
+		switch {
+		case req.Method == "DELETE" && req.URL.Path == "/1.5/apps/app1/units/app1-1":
+			killed = true
+			message = `{"Message":"killing unit app1-1\n"}`
+		case req.Method == "GET" && req.URL.Path == "/1.0/apps/app1":
+			message = `{"name":"app1","units":[{"ID":"app1-0","Status":"started"},{"ID":"app1-1","Status":"error"}]}`
+			if killed {
+				message = `{"name":"app1","units":[{"ID":"app1-0","Status":"started"},{"ID":"app1-2","Status":"started"}]}`
+			}
+		default:
+			c.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		return &http.Response{
+			Body:       ioutil.NopCloser(strings.NewReader(message)),
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+		}, nil
+	})
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := UnitKill{}
+	command.Flags().Parse(true, []string{"-a", "app1", "-u", "app1-1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(killed, check.Equals, true)
+	c.Assert(stdout.String(), check.Equals, "killing unit app1-1\nUnit \"app1-1\" killed and replaced by app1-2.\n")
+}
+
+func (s *S) TestUnitKillUnitNotInApp(c *check.C) {
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `{"name":"app1","units":[{"ID":"app1-0","Status":"started"}]}`, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Method == "GET" && req.URL.Path == "/1.0/apps/app1"
+		},
+	}
+	context := cmd.Context{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := UnitKill{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--unit", "other-0"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Unit "other-0" doesn't belong to app "app1".`)
+}
+
+func (s *S) TestUnitKillWithoutUnit(c *check.C) {
+	context := cmd.Context{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	command := UnitKill{}
+	command.Flags().Parse(true, []string{"-a", "app1"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "You must provide the unit to kill with --unit.")
+}
+
+func (s *S) TestUnitKillInfo(c *check.C) {
+	c.Assert((&UnitKill{}).Info(), check.NotNil)
+}
+
+func (s *S) TestUnitKillIsFlaggedACommand(c *check.C) {
+	var _ cmd.FlaggedCommand = &UnitKill{}
+}
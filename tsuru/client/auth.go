@@ -5,29 +5,51 @@
 package client
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	tsuruerr "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/validation"
 )
 
-type UserCreate struct{}
+type UserCreate struct {
+	passwordFromStdin bool
+	fs                *gnuflag.FlagSet
+}
 
 func (c *UserCreate) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "user-create",
-		Usage:   "user-create <email>",
-		Desc:    "Creates a user within tsuru remote server. It will ask for the password before issue the request.",
+		Name:  "user-create",
+		Usage: "user-create <email> [--password-stdin]",
+		Desc: `Creates a user within tsuru remote server. It will ask for the password before issue the request.
+
+The [[--password-stdin]] flag makes the command read the password from the
+first line of stdin instead of asking for it, which is useful for scripted
+user provisioning.`,
 		MinArgs: 1,
 	}
 }
 
+func (c *UserCreate) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("user-create", gnuflag.ExitOnError)
+		c.fs.BoolVar(&c.passwordFromStdin, "password-stdin", false, "Read the password from stdin")
+	}
+	return c.fs
+}
+
 func (c *UserCreate) Run(context *cmd.Context, client *cmd.Client) error {
 	context.RawOutput()
 	u, err := cmd.GetURL("/users")
@@ -35,19 +57,34 @@ func (c *UserCreate) Run(context *cmd.Context, client *cmd.Client) error {
 		return err
 	}
 	email := context.Args[0]
-	fmt.Fprint(context.Stdout, "Password: ")
-	password, err := cmd.PasswordFromReader(context.Stdin)
-	if err != nil {
-		return err
-	}
-	fmt.Fprint(context.Stdout, "\nConfirm: ")
-	confirm, err := cmd.PasswordFromReader(context.Stdin)
-	if err != nil {
-		return err
+	if !validation.ValidateEmail(email) {
+		return fmt.Errorf("Invalid email: %q.", email)
 	}
-	fmt.Fprintln(context.Stdout)
-	if password != confirm {
-		return errors.New("Passwords didn't match.")
+	var password string
+	if c.passwordFromStdin {
+		line, err := bufio.NewReader(context.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		password = strings.TrimRight(line, "\r\n")
+		if password == "" {
+			return errors.New("You must provide the password in stdin.")
+		}
+	} else {
+		fmt.Fprint(context.Stdout, "Password: ")
+		password, err = cmd.PasswordFromReader(context.Stdin)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(context.Stdout, "\nConfirm: ")
+		confirm, err := cmd.PasswordFromReader(context.Stdin)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(context.Stdout)
+		if password != confirm {
+			return errors.New("Passwords didn't match.")
+		}
 	}
 	v := url.Values{}
 	v.Set("email", email)
@@ -68,7 +105,7 @@ func (c *UserCreate) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, `User "%s" successfully created!`+"\n", email)
+	context.Successf(`User "%s" successfully created!`+"\n", email)
 	return nil
 }
 
@@ -129,7 +166,7 @@ func (c *UserRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "User %q successfully removed.\n", email)
+	context.Successf("User %q successfully removed.\n", email)
 	return nil
 }
 
@@ -146,22 +183,47 @@ you remove the team using ` + "`team-remove`" + ` before removing the user.`,
 	}
 }
 
-type TeamCreate struct{}
+type TeamCreate struct {
+	fs    *gnuflag.FlagSet
+	users cmd.StringSliceFlag
+	role  string
+}
 
 func (c *TeamCreate) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "team-create",
-		Usage: "team-create <teamname>",
+		Usage: "team-create <teamname> [-u/--user <email>]... [-r/--role <role>]",
 		Desc: `Create a team for the user. tsuru requires a user to be a member of at least
 one team in order to create an app or a service instance.
 
-When you create a team, you're automatically member of this team.`,
+When you create a team, you're automatically member of this team.
+
+The [[--user]] flag, which may be used multiple times, adds other users to the
+team right after creating it, assigning them the role given in [[--role]] with
+the team as context, just like [[tsuru role-assign]]. The team is created even
+if adding some of the users fails, and the result for each user is shown.`,
 		MinArgs: 1,
 	}
 }
 
+func (c *TeamCreate) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("team-create", gnuflag.ExitOnError)
+		user := "Email of a user to add to the team, may be used multiple times"
+		c.fs.Var(&c.users, "user", user)
+		c.fs.Var(&c.users, "u", user)
+		role := "Role assigned to the users given in --user"
+		c.fs.StringVar(&c.role, "role", "", role)
+		c.fs.StringVar(&c.role, "r", "", role)
+	}
+	return c.fs
+}
+
 func (c *TeamCreate) Run(context *cmd.Context, client *cmd.Client) error {
 	team := context.Args[0]
+	if len(c.users) > 0 && c.role == "" {
+		return errors.New("You must provide the role assigned to the users with --role.")
+	}
 	v := url.Values{}
 	v.Set("name", team)
 	b := strings.NewReader(v.Encode())
@@ -178,7 +240,20 @@ func (c *TeamCreate) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, `Team "%s" successfully created!`+"\n", team)
+	context.Successf(`Team "%s" successfully created!`+"\n", team)
+	var failed int
+	for _, email := range c.users {
+		err = assignRole(client, c.role, email, team)
+		if err != nil {
+			failed++
+			fmt.Fprintf(context.Stderr, "Failed to add user %q to the team: %s\n", email, err)
+			continue
+		}
+		context.Successf("User %q added to the team with role %q.\n", email, c.role)
+	}
+	if failed > 0 {
+		return fmt.Errorf("Failed to add %d of %d users to team %q.", failed, len(c.users), team)
+	}
 	return nil
 }
 
@@ -204,7 +279,7 @@ func (c *TeamRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, `Team "%s" successfully removed!`+"\n", team)
+	context.Successf(`Team "%s" successfully removed!`+"\n", team)
 	return nil
 }
 
@@ -220,20 +295,115 @@ and "app-revoke" commands for details).`,
 	}
 }
 
-type TeamList struct{}
+type TeamList struct {
+	detailed bool
+	json     bool
+	fs       *gnuflag.FlagSet
+}
 
 func (c *TeamList) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "team-list",
-		Usage:   "team-list",
-		Desc:    "List all teams that you are member.",
+		Name:  "team-list",
+		Usage: "team-list [--detailed/-d] [--json]",
+		Desc: `List all teams that you are member.
+
+The [[--detailed]] flag also displays the number of members of each team and
+the number of apps each team has access to. Counting members requires
+permission to list users, teams whose members can't be listed are displayed
+with an empty member count.
+
+The [[--json]] flag displays the raw data in JSON format.`,
 		MinArgs: 0,
 	}
 }
 
+func (c *TeamList) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("", gnuflag.ExitOnError)
+		detailedMessage := "Display the number of members and apps of each team"
+		c.fs.BoolVar(&c.detailed, "detailed", false, detailedMessage)
+		c.fs.BoolVar(&c.detailed, "d", false, detailedMessage)
+		c.fs.BoolVar(&c.json, "json", false, "Display teams in JSON format")
+	}
+	return c.fs
+}
+
 type teamItem struct {
-	Name        string
-	Permissions []string
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+	Members     *int     `json:"members,omitempty"`
+	Apps        *int     `json:"apps,omitempty"`
+}
+
+// defaultTeamEnvVar is the environment variable holding the team used by the
+// commands creating resources owned by a team when --team is not given.
+const defaultTeamEnvVar = "TSURU_TEAM"
+
+// resolveTeam returns the given team, set with --team, or the team in the
+// TSURU_TEAM environment variable, or the team in the configuration file.
+// Without any of them, the teams of the user allowed to create the resource,
+// having the given permission, are listed: the only one is returned, and an
+// error listing them is returned when there's more than one, as tsuru can't
+// choose the owner. When there's none, the empty team is returned and the
+// server reports the error.
+func resolveTeam(client *cmd.Client, team, permission string) (string, error) {
+	if team != "" {
+		return team, nil
+	}
+	if team = os.Getenv(defaultTeamEnvVar); team != "" {
+		return team, nil
+	}
+	if team = cmd.DefaultTeam(); team != "" {
+		return team, nil
+	}
+	u, err := cmd.GetURL("/teams")
+	if err != nil {
+		return "", err
+	}
+	request, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("Unable to list your teams to choose the owner, use the --team flag: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return "", nil
+	}
+	var teams []teamItem
+	if err = json.NewDecoder(resp.Body).Decode(&teams); err != nil {
+		return "", fmt.Errorf("Unable to list your teams to choose the owner, use the --team flag: %s", err)
+	}
+	var names []string
+	for _, t := range teams {
+		if hasPermission(t.Permissions, permission) {
+			names = append(names, t.Name)
+		}
+	}
+	switch len(names) {
+	case 0:
+		return "", nil
+	case 1:
+		return names[0], nil
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf(`You're allowed to use more than one team, use the --team flag to choose one of them: %s.
+A default team may be set in the %s environment variable or in ~/.tsuru/config.yaml, like "team: %s".`, strings.Join(names, ", "), defaultTeamEnvVar, names[0])
+}
+
+// hasPermission reports whether the permissions of a team listed by /teams
+// include the given permission. The list only has the topmost permissions
+// granted, so a parent permission (like "app" for "app.create") or the root
+// permission (the empty name) also grants it.
+func hasPermission(permissions []string, name string) bool {
+	for _, p := range permissions {
+		if p == "" || p == name || strings.HasPrefix(name, p+".") {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *TeamList) Run(context *cmd.Context, client *cmd.Client) error {
@@ -249,51 +419,160 @@ func (c *TeamList) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	if resp.StatusCode == http.StatusOK {
-		defer resp.Body.Close()
-		b, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-		var teams []teamItem
-		err = json.Unmarshal(b, &teams)
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var teams []teamItem
+	err = json.Unmarshal(b, &teams)
+	if err != nil {
+		return err
+	}
+	if c.detailed {
+		err = c.countMembersAndApps(teams, client)
 		if err != nil {
 			return err
 		}
-		table := cmd.NewTable()
-		table.Headers = cmd.Row{"Team", "Permissions"}
-		table.LineSeparator = true
-		for _, team := range teams {
-			table.AddRow(cmd.Row{team.Name, strings.Join(team.Permissions, "\n")})
+	}
+	if c.json {
+		context.OutputFormat = cmd.JSONOutput
+	}
+	if context.StructuredOutput() {
+		return context.WriteStructured(teams)
+	}
+	table := cmd.NewTable()
+	table.Headers = cmd.Row{"Team", "Permissions"}
+	if c.detailed {
+		table.Headers = append(table.Headers, "Members", "Apps")
+	}
+	table.LineSeparator = true
+	for _, team := range teams {
+		row := cmd.Row{team.Name, strings.Join(team.Permissions, "\n")}
+		if c.detailed {
+			var members string
+			if team.Members != nil {
+				members = strconv.Itoa(*team.Members)
+			}
+			row = append(row, members, strconv.Itoa(*team.Apps))
 		}
-		fmt.Fprint(context.Stdout, table.String())
+		table.AddRow(row)
 	}
+	fmt.Fprint(context.Stdout, table.String())
 	return nil
 }
 
-type ChangePassword struct{}
-
-func (c *ChangePassword) Run(context *cmd.Context, client *cmd.Client) error {
-	u, err := cmd.GetURL("/users/password")
+func (c *TeamList) countMembersAndApps(teams []teamItem, client *cmd.Client) error {
+	u, err := cmd.GetURL("/apps")
 	if err != nil {
 		return err
 	}
-	fmt.Fprint(context.Stdout, "Current password: ")
-	old, err := cmd.PasswordFromReader(context.Stdin)
+	request, err := http.NewRequest("GET", u, nil)
 	if err != nil {
 		return err
 	}
-	fmt.Fprint(context.Stdout, "\nNew password: ")
-	new, err := cmd.PasswordFromReader(context.Stdin)
+	resp, err := client.Do(request)
 	if err != nil {
 		return err
 	}
-	fmt.Fprint(context.Stdout, "\nConfirm: ")
-	confirm, err := cmd.PasswordFromReader(context.Stdin)
+	defer resp.Body.Close()
+	var apps []app
+	if resp.StatusCode == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(&apps)
+		if err != nil {
+			return err
+		}
+	}
+	var users []cmd.APIUser
+	usersAvailable := true
+	u, err = cmd.GetURL("/users")
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(context.Stdout)
+	request, err = http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err = client.Do(request)
+	if err != nil {
+		if e, ok := err.(*tsuruerr.HTTP); !ok || e.Code != http.StatusForbidden {
+			return err
+		}
+		usersAvailable = false
+	} else {
+		defer resp.Body.Close()
+		err = json.NewDecoder(resp.Body).Decode(&users)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range teams {
+		var appCount int
+		for _, a := range apps {
+			if in(teams[i].Name, a.Teams) {
+				appCount++
+			}
+		}
+		teams[i].Apps = &appCount
+		if !usersAvailable {
+			continue
+		}
+		var memberCount int
+		for _, user := range users {
+			for _, role := range user.Roles {
+				if role.ContextType == "team" && role.ContextValue == teams[i].Name {
+					memberCount++
+					break
+				}
+			}
+		}
+		teams[i].Members = &memberCount
+	}
+	return nil
+}
+
+type ChangePassword struct {
+	oldFromStdin bool
+	newFromStdin bool
+	fs           *gnuflag.FlagSet
+}
+
+func (c *ChangePassword) Run(context *cmd.Context, client *cmd.Client) error {
+	u, err := cmd.GetURL("/users/password")
+	if err != nil {
+		return err
+	}
+	var old, new, confirm string
+	if c.oldFromStdin || c.newFromStdin {
+		old, new, err = c.readPasswords(context)
+		if err != nil {
+			return err
+		}
+		confirm = new
+	} else {
+		fmt.Fprint(context.Stdout, "Current password: ")
+		old, err = cmd.PasswordFromReader(context.Stdin)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(context.Stdout, "\nNew password: ")
+		new, err = cmd.PasswordFromReader(context.Stdin)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(context.Stdout, "\nConfirm: ")
+		confirm, err = cmd.PasswordFromReader(context.Stdin)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(context.Stdout)
+	}
+	if new != confirm {
+		return errors.New("New password and password confirmation didn't match.")
+	}
 	v := url.Values{}
 	v.Set("old", old)
 	v.Set("new", new)
@@ -307,27 +586,63 @@ func (c *ChangePassword) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(context.Stdout, "Password successfully updated!")
+	context.Successf("Password successfully updated!\n")
 	return nil
 }
 
+// readPasswords reads the current and the new password from stdin, one per
+// line, in this order.
+func (c *ChangePassword) readPasswords(context *cmd.Context) (string, string, error) {
+	if !c.oldFromStdin || !c.newFromStdin {
+		return "", "", errors.New("The --old-password-stdin and --new-password-stdin flags must be used together.")
+	}
+	reader := bufio.NewReader(context.Stdin)
+	var passwords [2]string
+	for i := range passwords {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", "", err
+		}
+		passwords[i] = strings.TrimRight(line, "\r\n")
+		if passwords[i] == "" {
+			return "", "", errors.New("Expected the current and the new password in stdin, one per line.")
+		}
+	}
+	return passwords[0], passwords[1], nil
+}
+
 func (c *ChangePassword) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "change-password",
-		Usage: "change-password",
+		Usage: "change-password [--old-password-stdin --new-password-stdin]",
 		Desc: `Changes the password of the logged in user. It will ask for the current
-password, the new and the confirmation.`,
+password, the new and the confirmation.
+
+For automation, the [[--old-password-stdin]] and [[--new-password-stdin]]
+flags make the command read the current password and the new password from
+stdin, one per line and in this order, instead of asking for them. Both flags
+must be used together.`,
 	}
 }
 
+func (c *ChangePassword) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("change-password", gnuflag.ExitOnError)
+		c.fs.BoolVar(&c.oldFromStdin, "old-password-stdin", false, "Read the current password from the first line of stdin")
+		c.fs.BoolVar(&c.newFromStdin, "new-password-stdin", false, "Read the new password from the second line of stdin")
+	}
+	return c.fs
+}
+
 type ResetPassword struct {
-	token string
+	token       string
+	setPassword bool
 }
 
 func (c *ResetPassword) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "reset-password",
-		Usage: "reset-password <email> [--token|-t <token>]",
+		Usage: "reset-password <email> [--token|-t <token>] [--set-password]",
 		Desc: `Resets the user password.
 
 This process is composed of two steps:
@@ -339,7 +654,12 @@ In order to generate the token, users should run this command without the
 --token flag. The token will be mailed to the user.
 
 With the token in hand, the user can finally reset the password using the
---token flag. The new password will also be mailed to the user.`,
+--token flag. The new password will also be mailed to the user.
+
+If the [[--set-password]] flag is used along with --token, the command asks
+for a new password, chosen by the user, instead of generating one. If the
+tsuru server doesn't support choosing the password, the command falls back to
+the regular flow and the generated password is mailed to the user.`,
 		MinArgs: 1,
 	}
 }
@@ -356,6 +676,12 @@ Please check your email.`
 }
 
 func (c *ResetPassword) Run(context *cmd.Context, client *cmd.Client) error {
+	if c.setPassword {
+		if c.token == "" {
+			return errors.New("The --set-password flag requires the token, use the --token flag to provide it.")
+		}
+		return c.resetWithPassword(context, client)
+	}
 	url := fmt.Sprintf("/users/%s/password", context.Args[0])
 	if c.token != "" {
 		url += "?token=" + c.token
@@ -373,10 +699,53 @@ func (c *ResetPassword) Run(context *cmd.Context, client *cmd.Client) error {
 	return nil
 }
 
+func (c *ResetPassword) resetWithPassword(context *cmd.Context, client *cmd.Client) error {
+	fmt.Fprint(context.Stdout, "New password: ")
+	password, err := cmd.PasswordFromReader(context.Stdin)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(context.Stdout, "\nConfirm: ")
+	confirm, err := cmd.PasswordFromReader(context.Stdin)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(context.Stdout)
+	if password != confirm {
+		return errors.New("Passwords didn't match.")
+	}
+	u, err := cmd.GetURL(fmt.Sprintf("/users/%s/password", context.Args[0]))
+	if err != nil {
+		return err
+	}
+	v := url.Values{}
+	v.Set("token", c.token)
+	v.Set("new", password)
+	v.Set("confirm", confirm)
+	request, err := http.NewRequest("PUT", u, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = client.Do(request)
+	if err != nil {
+		e, ok := err.(*tsuruerr.HTTP)
+		if !ok || (e.Code != http.StatusNotFound && e.Code != http.StatusMethodNotAllowed) {
+			return err
+		}
+		fmt.Fprintln(context.Stdout, "The tsuru server doesn't support choosing the password, a new password will be generated.")
+		c.setPassword = false
+		return c.Run(context, client)
+	}
+	context.Successf("Your password has been reset.\n")
+	return nil
+}
+
 func (c *ResetPassword) Flags() *gnuflag.FlagSet {
 	fs := gnuflag.NewFlagSet("reset-password", gnuflag.ExitOnError)
 	fs.StringVar(&c.token, "token", "", "Token to reset the password")
 	fs.StringVar(&c.token, "t", "", "Token to reset the password")
+	fs.BoolVar(&c.setPassword, "set-password", false, "Choose the new password instead of receiving a generated one")
 	return fs
 }
 
@@ -499,9 +868,29 @@ type ListUsers struct {
 	userEmail string
 	role      string
 	context   string
+	json      bool
 	fs        *gnuflag.FlagSet
 }
 
+type userItem struct {
+	Email string   `json:"email"`
+	Roles []string `json:"roles"`
+	Teams []string `json:"teams"`
+}
+
+// userTeams returns the teams the user has some role in, based on the
+// context value of team scoped roles.
+func userTeams(u *cmd.APIUser) []string {
+	teams := []string{}
+	for _, r := range u.Roles {
+		if r.ContextType == "team" && r.ContextValue != "" && !in(r.ContextValue, teams) {
+			teams = append(teams, r.ContextValue)
+		}
+	}
+	sort.Strings(teams)
+	return teams
+}
+
 func (c *ListUsers) Run(ctx *cmd.Context, client *cmd.Client) error {
 	if c.userEmail != "" && c.role != "" {
 		return errors.New("You cannot filter by user email and role at same time. Enter <tsuru user-list --help> for more information.")
@@ -525,12 +914,27 @@ func (c *ListUsers) Run(ctx *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	items := make([]userItem, len(users))
+	for i := range users {
+		items[i] = userItem{
+			Email: users[i].Email,
+			Roles: users[i].RoleInstances(),
+			Teams: userTeams(&users[i]),
+		}
+	}
+	if c.json {
+		ctx.OutputFormat = cmd.JSONOutput
+	}
+	if ctx.StructuredOutput() {
+		return ctx.WriteStructured(items)
+	}
 	table := cmd.NewTable()
-	table.Headers = cmd.Row([]string{"User", "Roles"})
-	for _, u := range users {
+	table.Headers = cmd.Row([]string{"User", "Roles", "Teams"})
+	for _, u := range items {
 		table.AddRow(cmd.Row([]string{
 			u.Email,
-			strings.Join(u.RoleInstances(), "\n"),
+			strings.Join(u.Roles, "\n"),
+			strings.Join(u.Teams, "\n"),
 		}))
 	}
 	table.LineSeparator = true
@@ -543,8 +947,11 @@ func (c *ListUsers) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:    "user-list",
 		MinArgs: 0,
-		Usage:   "user-list [--user/-u useremail] [--role/-r role [-c/--context-value value]]",
-		Desc:    "List all users in tsuru. It may also filter users by user email or role name with context value.",
+		Usage:   "user-list [--user/-u useremail] [--role/-r role [-c/--context-value value]] [--json]",
+		Desc: `List all users in tsuru. It may also filter users by user email or role name with context value.
+
+The teams column lists the teams in which the user has some role. The [[--json]]
+flag prints the list as JSON, including the roles and teams of each user.`,
 	}
 }
 
@@ -557,6 +964,7 @@ func (c *ListUsers) Flags() *gnuflag.FlagSet {
 		c.fs.StringVar(&c.role, "role", "", "Filter user by role")
 		c.fs.StringVar(&c.context, "c", "", "Filter user by role context value")
 		c.fs.StringVar(&c.context, "context-value", "", "Filter user by role context value")
+		c.fs.BoolVar(&c.json, "json", false, "Display the list of users in JSON format")
 	}
 	return c.fs
 }
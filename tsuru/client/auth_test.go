@@ -6,6 +6,7 @@ package client
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -14,7 +15,7 @@ import (
 
 	"gopkg.in/check.v1"
 
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"github.com/tsuru/tsuru/fs/fstest"
 )
@@ -47,6 +48,56 @@ func (s *S) TestTeamCreate(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+func (s *S) TestTeamCreateWithUsers(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"core"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Status: http.StatusCreated},
+				CondFunc: func(r *http.Request) bool {
+					return r.URL.Path == "/1.0/teams" && r.FormValue("name") == "core"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.URL.Path == "/1.0/roles/team-member/user" && r.FormValue("email") == "alice@example.com" &&
+						r.FormValue("context") == "core"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "user not found", Status: http.StatusNotFound},
+				CondFunc: func(r *http.Request) bool {
+					return r.URL.Path == "/1.0/roles/team-member/user" && r.FormValue("email") == "bob@example.com" &&
+						r.FormValue("context") == "core"
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := TeamCreate{}
+	command.Flags().Parse(true, []string{"-u", "alice@example.com", "--user", "bob@example.com", "-r", "team-member"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Failed to add 1 of 2 users to team "core".`)
+	c.Assert(stdout.String(), check.Equals, `Team "core" successfully created!
+User "alice@example.com" added to the team with role "team-member".
+`)
+	c.Assert(stderr.String(), check.Equals, `Failed to add user "bob@example.com" to the team: user not found`+"\n")
+}
+
+func (s *S) TestTeamCreateWithUsersWithoutRole(c *check.C) {
+	context := cmd.Context{Args: []string{"core"}}
+	command := TeamCreate{}
+	command.Flags().Parse(true, []string{"-u", "alice@example.com"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "You must provide the role assigned to the users with --role.")
+}
+
 func (s *S) TestTeamCreateInfo(c *check.C) {
 	c.Assert((&TeamCreate{}).Info(), check.NotNil)
 }
@@ -175,6 +226,119 @@ func (s *S) TestTeamListRunNoPermissions(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+func (s *S) TestTeamListRunDetailed(c *check.C) {
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"timeredbull","permissions":["app"]},{"name":"cobrateam"}]`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/teams")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"app1","teams":["timeredbull","cobrateam"]},{"name":"app2","teams":["timeredbull"]}]`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `[
+{"email":"a@tsuru.io","roles":[{"name":"team-member","contexttype":"team","contextvalue":"timeredbull"},{"name":"team-admin","contexttype":"team","contextvalue":"timeredbull"}]},
+{"email":"b@tsuru.io","roles":[{"name":"team-member","contexttype":"team","contextvalue":"timeredbull"}]},
+{"email":"c@tsuru.io","roles":[{"name":"admin","contexttype":"global"}]}
+]`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/users")
+				},
+			},
+		},
+	}
+	expected := `+-------------+-------------+---------+------+
+| Team        | Permissions | Members | Apps |
++-------------+-------------+---------+------+
+| timeredbull | app         | 2       | 2    |
++-------------+-------------+---------+------+
+| cobrateam   |             | 0       | 1    |
++-------------+-------------+---------+------+
+`
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	var stdout, stderr bytes.Buffer
+	command := TeamList{}
+	command.Flags().Parse(true, []string{"--detailed"})
+	err := command.Run(&cmd.Context{
+		Args:   []string{},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestTeamListRunDetailedWithoutUsersPermission(c *check.C) {
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"cobrateam"}]`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return strings.HasSuffix(req.URL.Path, "/teams")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"app1","teams":["cobrateam"]}]`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return strings.HasSuffix(req.URL.Path, "/apps")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "forbidden", Status: http.StatusForbidden},
+				CondFunc: func(req *http.Request) bool {
+					return strings.HasSuffix(req.URL.Path, "/users")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	var stdout, stderr bytes.Buffer
+	command := TeamList{}
+	command.Flags().Parse(true, []string{"-d", "--json"})
+	err := command.Run(&cmd.Context{
+		Args:   []string{},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}, client)
+	c.Assert(err, check.IsNil)
+	var teams []map[string]interface{}
+	err = json.Unmarshal(stdout.Bytes(), &teams)
+	c.Assert(err, check.IsNil)
+	c.Assert(teams, check.DeepEquals, []map[string]interface{}{
+		{"name": "cobrateam", "permissions": nil, "apps": 1.0},
+	})
+}
+
+func (s *S) TestTeamListRunJSON(c *check.C) {
+	trans := &cmdtest.Transport{Message: `[{"name":"timeredbull","permissions":["app.deploy"]}]`, Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	var stdout, stderr bytes.Buffer
+	command := TeamList{}
+	command.Flags().Parse(true, []string{"--json"})
+	err := command.Run(&cmd.Context{
+		Args:   []string{},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}, client)
+	c.Assert(err, check.IsNil)
+	expected := `[
+  {
+    "name": "timeredbull",
+    "permissions": [
+      "app.deploy"
+    ]
+  }
+]
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
 func (s *S) TestTeamListRunWithNoContent(c *check.C) {
 	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: "", Status: http.StatusNoContent}}, nil, manager)
 	var stdout, stderr bytes.Buffer
@@ -262,6 +426,58 @@ func (s *S) TestUserCreate(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+func (s *S) TestUserCreatePasswordFromStdin(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	var called bool
+	context := cmd.Context{
+		Args:   []string{"foo@foo.com"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("foo123\n"),
+	}
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusCreated},
+		CondFunc: func(r *http.Request) bool {
+			called = true
+			return r.FormValue("email") == "foo@foo.com" && r.FormValue("password") == "foo123"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := UserCreate{}
+	command.Flags().Parse(true, []string{"--password-stdin"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, true)
+	c.Assert(stdout.String(), check.Equals, `User "foo@foo.com" successfully created!`+"\n")
+}
+
+func (s *S) TestUserCreatePasswordFromEmptyStdin(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"foo@foo.com"},
+		Stdout: &bytes.Buffer{},
+		Stdin:  strings.NewReader(""),
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{}}, nil, manager)
+	command := UserCreate{}
+	command.Flags().Parse(true, []string{"--password-stdin"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "You must provide the password in stdin.")
+}
+
+func (s *S) TestUserCreateInvalidEmail(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"foo.com"},
+		Stdout: &stdout,
+		Stdin:  strings.NewReader("foo123\nfoo123\n"),
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{}}, nil, manager)
+	command := UserCreate{}
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Invalid email: "foo.com".`)
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
 func (s *S) TestUserCreateShouldReturnErrorIfThePasswordIsNotGiven(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	context := cmd.Context{
@@ -474,6 +690,58 @@ func (s *S) TestChangePasswordWrongConfirmation(c *check.C) {
 	c.Assert(err.Error(), check.Equals, "New password and password confirmation didn't match.")
 }
 
+func (s *S) TestChangePasswordFromStdin(c *check.C) {
+	var (
+		buf    bytes.Buffer
+		called bool
+	)
+	context := cmd.Context{
+		Stdout: &buf,
+		Stdin:  strings.NewReader("gopher\nbbrothers\n"),
+	}
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+		CondFunc: func(r *http.Request) bool {
+			old := r.FormValue("old") == "gopher"
+			new := r.FormValue("new") == "bbrothers"
+			confirm := r.FormValue("confirm") == "bbrothers"
+			called = true
+			return r.Method == "PUT" && old && new && confirm
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := ChangePassword{}
+	command.Flags().Parse(true, []string{"--old-password-stdin", "--new-password-stdin"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, true)
+	c.Assert(buf.String(), check.Equals, "Password successfully updated!\n")
+}
+
+func (s *S) TestChangePasswordFromStdinMissingNewPassword(c *check.C) {
+	context := cmd.Context{
+		Stdout: &bytes.Buffer{},
+		Stdin:  strings.NewReader("gopher\n"),
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{}}, nil, manager)
+	command := ChangePassword{}
+	command.Flags().Parse(true, []string{"--old-password-stdin", "--new-password-stdin"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "Expected the current and the new password in stdin, one per line.")
+}
+
+func (s *S) TestChangePasswordFromStdinRequiresBothFlags(c *check.C) {
+	context := cmd.Context{
+		Stdout: &bytes.Buffer{},
+		Stdin:  strings.NewReader("gopher\nbbrothers\n"),
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{}}, nil, manager)
+	command := ChangePassword{}
+	command.Flags().Parse(true, []string{"--new-password-stdin"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "The --old-password-stdin and --new-password-stdin flags must be used together.")
+}
+
 func (s *S) TestChangePasswordInfo(c *check.C) {
 	command := ChangePassword{}
 	c.Assert(command.Info(), check.NotNil)
@@ -540,6 +808,101 @@ Please check your email.` + "\n"
 	c.Assert(called, check.Equals, true)
 }
 
+func (s *S) TestResetPasswordSetPassword(c *check.C) {
+	var (
+		buf    bytes.Buffer
+		called bool
+	)
+	context := cmd.Context{
+		Args:   []string{"user@tsuru.io"},
+		Stdout: &buf,
+		Stdin:  strings.NewReader("bbrothers\nbbrothers\n"),
+	}
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Status: http.StatusOK, Message: ""},
+		CondFunc: func(r *http.Request) bool {
+			called = true
+			token := r.FormValue("token") == "secret"
+			new := r.FormValue("new") == "bbrothers"
+			confirm := r.FormValue("confirm") == "bbrothers"
+			url := strings.HasSuffix(r.URL.Path, "/users/user@tsuru.io/password")
+			return r.Method == "PUT" && url && token && new && confirm
+		},
+	}
+	command := ResetPassword{}
+	command.Flags().Parse(true, []string{"-t", "secret", "--set-password"})
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, true)
+	c.Assert(buf.String(), check.Equals, "New password: \nConfirm: \nYour password has been reset.\n")
+}
+
+func (s *S) TestResetPasswordSetPasswordFallback(c *check.C) {
+	var buf bytes.Buffer
+	var calls []string
+	context := cmd.Context{
+		Args:   []string{"user@tsuru.io"},
+		Stdout: &buf,
+		Stdin:  strings.NewReader("bbrothers\nbbrothers\n"),
+	}
+	trans := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Status: http.StatusMethodNotAllowed, Message: "not allowed"},
+				CondFunc: func(r *http.Request) bool {
+					calls = append(calls, r.Method)
+					return r.Method == "PUT"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Status: http.StatusOK, Message: ""},
+				CondFunc: func(r *http.Request) bool {
+					calls = append(calls, r.Method)
+					return r.Method == "POST" && r.URL.Query().Get("token") == "secret"
+				},
+			},
+		},
+	}
+	command := ResetPassword{}
+	command.Flags().Parse(true, []string{"-t", "secret", "--set-password"})
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(calls, check.DeepEquals, []string{"PUT", "POST"})
+	expected := `New password: 
+Confirm: 
+The tsuru server doesn't support choosing the password, a new password will be generated.
+Your password has been reset and mailed to you.
+
+Please check your email.
+`
+	c.Assert(buf.String(), check.Equals, expected)
+}
+
+func (s *S) TestResetPasswordSetPasswordWrongConfirmation(c *check.C) {
+	var buf bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"user@tsuru.io"},
+		Stdout: &buf,
+		Stdin:  strings.NewReader("bbrothers\nbrothers\n"),
+	}
+	command := ResetPassword{}
+	command.Flags().Parse(true, []string{"-t", "secret", "--set-password"})
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{}}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "Passwords didn't match.")
+}
+
+func (s *S) TestResetPasswordSetPasswordWithoutToken(c *check.C) {
+	context := cmd.Context{Args: []string{"user@tsuru.io"}, Stdout: &bytes.Buffer{}}
+	command := ResetPassword{}
+	command.Flags().Parse(true, []string{"--set-password"})
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{}}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "The --set-password flag requires the token.*")
+}
+
 func (s *S) TestResetPasswordInfo(c *check.C) {
 	c.Assert((&ResetPassword{}).Info(), check.NotNil)
 }
@@ -721,12 +1084,52 @@ func (s *S) TestListUsersInfo(c *check.C) {
 	expected := &cmd.Info{
 		Name:    "user-list",
 		MinArgs: 0,
-		Usage:   "user-list [--user/-u useremail] [--role/-r role [-c/--context-value value]]",
-		Desc:    "List all users in tsuru. It may also filter users by user email or role name with context value.",
+		Usage:   "user-list [--user/-u useremail] [--role/-r role [-c/--context-value value]] [--json]",
+		Desc: `List all users in tsuru. It may also filter users by user email or role name with context value.
+
+The teams column lists the teams in which the user has some role. The [[--json]]
+flag prints the list as JSON, including the roles and teams of each user.`,
 	}
 	c.Assert((&ListUsers{}).Info(), check.DeepEquals, expected)
 }
 
+func (s *S) TestListUsersRunJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	result := `[{"email": "test@test.com",
+"roles":[
+	{"name": "role1", "contexttype": "team", "contextvalue": "b"},
+	{"name": "role3", "contexttype": "team", "contextvalue": "a"},
+	{"name": "role2", "contexttype": "app", "contextvalue": "x"}
+]
+}]`
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: result, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/users") &&
+				req.URL.RawQuery == "userEmail=&role=role1&context="
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := ListUsers{}
+	command.Flags().Parse(true, []string{"--json", "--role", "role1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var users []map[string]interface{}
+	err = json.Unmarshal(stdout.Bytes(), &users)
+	c.Assert(err, check.IsNil)
+	c.Assert(users, check.DeepEquals, []map[string]interface{}{
+		{
+			"email": "test@test.com",
+			"roles": []interface{}{"role1(team b)", "role2(app x)", "role3(team a)"},
+			"teams": []interface{}{"a", "b"},
+		},
+	})
+}
+
 func (s *S) TestListUsersRunWithoutFlags(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	context := cmd.Context{
@@ -746,12 +1149,12 @@ func (s *S) TestListUsersRunWithoutFlags(c *check.C) {
 			return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/users")
 		},
 	}
-	expected := `+---------------+---------------+
-| User          | Roles         |
-+---------------+---------------+
-| test@test.com | role1(team a) |
-|               | role2(app x)  |
-+---------------+---------------+
+	expected := `+---------------+---------------+-------+
+| User          | Roles         | Teams |
++---------------+---------------+-------+
+| test@test.com | role1(team a) | a     |
+|               | role2(app x)  |       |
++---------------+---------------+-------+
 `
 	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
 	command := ListUsers{}
@@ -784,12 +1187,12 @@ func (s *S) TestListUsersRunFilterByUserEmail(c *check.C) {
 				req.URL.RawQuery == "userEmail=test2@test.com&role=&context="
 		},
 	}
-	expected := `+---------------+---------------+
-| User          | Roles         |
-+---------------+---------------+
-| test@test.com | role1(team a) |
-|               | role2(app x)  |
-+---------------+---------------+
+	expected := `+---------------+---------------+-------+
+| User          | Roles         | Teams |
++---------------+---------------+-------+
+| test@test.com | role1(team a) | a     |
+|               | role2(app x)  |       |
++---------------+---------------+-------+
 `
 	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
 	command := ListUsers{}
@@ -823,12 +1226,12 @@ func (s *S) TestListUsersRunFilterByRole(c *check.C) {
 				req.URL.RawQuery == "userEmail=&role=role2&context="
 		},
 	}
-	expected := `+---------------+---------------+
-| User          | Roles         |
-+---------------+---------------+
-| test@test.com | role1(team a) |
-|               | role2(app x)  |
-+---------------+---------------+
+	expected := `+---------------+---------------+-------+
+| User          | Roles         | Teams |
++---------------+---------------+-------+
+| test@test.com | role1(team a) | a     |
+|               | role2(app x)  |       |
++---------------+---------------+-------+
 `
 	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
 	command := ListUsers{}
@@ -862,12 +1265,12 @@ func (s *S) TestListUsersRunFilterByRoleWithContext(c *check.C) {
 				req.URL.RawQuery == "userEmail=&role=role2&context=x"
 		},
 	}
-	expected := `+---------------+---------------+
-| User          | Roles         |
-+---------------+---------------+
-| test@test.com | role1(team a) |
-|               | role2(app x)  |
-+---------------+---------------+
+	expected := `+---------------+---------------+-------+
+| User          | Roles         | Teams |
++---------------+---------------+-------+
+| test@test.com | role1(team a) | a     |
+|               | role2(app x)  |       |
++---------------+---------------+-------+
 `
 	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
 	command := ListUsers{}
@@ -964,3 +1367,53 @@ func (s *S) TestListUsersFlags(c *check.C) {
 	c.Check(srole.Value.String(), check.Equals, "role1")
 	c.Check(srole.DefValue, check.Equals, "")
 }
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func (s *S) TestResolveTeamClosesTheBodyOfUnexpectedResponses(c *check.C) {
+	body := &closeRecorder{Reader: strings.NewReader("")}
+	trans := transportFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNoContent, Body: body}, nil
+	})
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	team, err := resolveTeam(client, "", "app.create")
+	c.Assert(err, check.IsNil)
+	c.Assert(team, check.Equals, "")
+	c.Assert(body.closed, check.Equals, true)
+}
+
+func (s *S) TestResolveTeamFailureListingTheTeams(c *check.C) {
+	trans := &cmdtest.Transport{Message: "internal error", Status: http.StatusInternalServerError}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	_, err := resolveTeam(client, "", "app.create")
+	c.Assert(err, check.ErrorMatches, "Unable to list your teams to choose the owner, use the --team flag: internal error")
+}
+
+func (s *S) TestResolveTeamInvalidTeamList(c *check.C) {
+	trans := &cmdtest.Transport{Message: "not json", Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	_, err := resolveTeam(client, "", "app.create")
+	c.Assert(err, check.ErrorMatches, "Unable to list your teams to choose the owner, use the --team flag: .*")
+}
+
+func (s *S) TestResolveTeamOnlyConsidersTeamsWithThePermission(c *check.C) {
+	trans := &cmdtest.Transport{
+		Message: `[{"name":"web","permissions":["app.create"]},{"name":"dbas","permissions":["service-instance"]},{"name":"viewers","permissions":["app.read","service-instance.read"]}]`,
+		Status:  http.StatusOK,
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	team, err := resolveTeam(client, "", "app.create")
+	c.Assert(err, check.IsNil)
+	c.Assert(team, check.Equals, "web")
+	team, err = resolveTeam(client, "", "service-instance.create")
+	c.Assert(err, check.IsNil)
+	c.Assert(team, check.Equals, "dbas")
+}
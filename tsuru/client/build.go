@@ -0,0 +1,120 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+)
+
+type AppBuild struct {
+	cmd.GuessingCommand
+	tags cmd.StringSliceFlag
+	fs   *gnuflag.FlagSet
+}
+
+func (c *AppBuild) Info() *cmd.Info {
+	desc := `Builds an image of the app from the given files and/or directories,
+without deploying it. The units of the app are not changed. Some examples of
+calls are:
+
+::
+
+    $ tsuru app-build .
+    $ tsuru app-build -t v1.2.3 -t latest myfile.jar Procfile
+
+The [[--tag]] flag tags the resulting image, and may be used multiple times.
+
+The output of the build is streamed while it runs and the reference of the
+resulting image is shown at the end, so it can be deployed later with
+[[tsuru app-deploy --image]]. When the build fails, tsuru exits with the exit
+status of the build.`
+	return &cmd.Info{
+		Name:    "app-build",
+		Usage:   "app-build [-a/--app <appname>] [-t/--tag <tag>]... <file-or-dir-1> [file-or-dir-2] ... [file-or-dir-n]",
+		Desc:    desc,
+		MinArgs: 1,
+	}
+}
+
+func (c *AppBuild) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		tag := "A tag for the resulting image"
+		c.fs.Var(&c.tags, "tag", tag)
+		c.fs.Var(&c.tags, "t", tag)
+	}
+	return c.fs
+}
+
+func (c *AppBuild) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, tag := range c.tags {
+		writer.WriteField("tag", tag)
+	}
+	file, err := writer.CreateFormFile("file", "archive.tar.gz")
+	if err != nil {
+		return err
+	}
+	err = targz(context, file, context.Args...)
+	if err != nil {
+		return err
+	}
+	writer.Close()
+	u, err := cmd.GetURLVersion("1.5", fmt.Sprintf("/apps/%s/build", appName))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("POST", u, &body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "multipart/form-data; boundary="+writer.Boundary())
+	fmt.Fprintf(context.Stdout, "Uploading files (%0.2fMB)...\n", float64(body.Len())/(1024*1024))
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	var output bytes.Buffer
+	_, err = io.Copy(io.MultiWriter(context.Stdout, &output), response.Body)
+	if err != nil {
+		return err
+	}
+	if image, ok := builtImage(output.String()); ok {
+		if image != "" {
+			fmt.Fprintf(context.Stdout, "Image: %s\n", image)
+		}
+		return nil
+	}
+	return &cmd.StatusError{Status: exitStatus(output.String()), Message: "The build failed."}
+}
+
+var builtImageRegexp = regexp.MustCompile(`^OK(?: (\S+))?$`)
+
+// builtImage checks whether the build succeeded, in which case the output
+// ends with "OK <image>", returning the reference of the image.
+func builtImage(output string) (string, bool) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	m := builtImageRegexp.FindStringSubmatch(strings.TrimSpace(lines[len(lines)-1]))
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
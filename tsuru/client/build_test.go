@@ -0,0 +1,68 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	"github.com/tsuru/tsuru/cmd/cmdtest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAppBuildInfo(c *check.C) {
+	c.Assert((&AppBuild{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAppBuild(c *check.C) {
+	var buf bytes.Buffer
+	ctx := cmd.Context{Stderr: bytes.NewBufferString("")}
+	err := targz(&ctx, &buf, "testdata")
+	c.Assert(err, check.IsNil)
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "building\nOK registry.example.com/tsuru/app-secret:v1\n", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			file, _, err := req.FormFile("file")
+			c.Assert(err, check.IsNil)
+			content, err := ioutil.ReadAll(file)
+			c.Assert(err, check.IsNil)
+			c.Assert(content, check.DeepEquals, buf.Bytes())
+			c.Assert(req.MultipartForm.Value["tag"], check.DeepEquals, []string{"v1", "latest"})
+			return req.Method == "POST" && req.URL.Path == "/1.5/apps/secret/build"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr, Args: []string{"testdata"}}
+	command := AppBuild{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "secret"}}}
+	command.Flags().Parse(true, []string{"-t", "v1", "--tag", "latest"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.HasSuffix(stdout.String(), "building\nOK registry.example.com/tsuru/app-secret:v1\nImage: registry.example.com/tsuru/app-secret:v1\n"), check.Equals, true)
+}
+
+func (s *S) TestAppBuildFailure(c *check.C) {
+	trans := cmdtest.Transport{Message: "building\nERROR: exit status 3\n", Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr, Args: []string{"testdata"}}
+	command := AppBuild{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "secret"}}}
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "The build failed.")
+	c.Assert(cmd.ExitCode(err), check.Equals, 3)
+}
+
+func (s *S) TestAppBuildFailureWithoutStatus(c *check.C) {
+	trans := cmdtest.Transport{Message: "building\nERROR: no space left\n", Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr, Args: []string{"testdata"}}
+	command := AppBuild{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "secret"}}}
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "The build failed.")
+}
@@ -17,6 +17,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path"
 	"sort"
 	"strings"
@@ -24,8 +25,9 @@ import (
 	"time"
 
 	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	tsuruapp "github.com/tsuru/tsuru/app"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru/event"
 	tsuruIo "github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/safe"
 )
@@ -120,8 +122,11 @@ func (c *AppDeployList) Run(context *cmd.Context, client *cmd.Client) error {
 
 type AppDeploy struct {
 	cmd.GuessingCommand
+	appWait
 	image   string
 	message string
+	dir     string
+	file    string
 	fs      *gnuflag.FlagSet
 }
 
@@ -134,6 +139,13 @@ func (c *AppDeploy) Flags() *gnuflag.FlagSet {
 		message := "A message describing this deploy"
 		c.fs.StringVar(&c.message, "message", "", message)
 		c.fs.StringVar(&c.message, "m", "", message)
+		dir := "The directory to deploy, its contents are sent as the root of the app"
+		c.fs.StringVar(&c.dir, "dir", "", dir)
+		c.fs.StringVar(&c.dir, "d", "", dir)
+		file := "A gzipped tarball with the files to deploy, uploaded as is"
+		c.fs.StringVar(&c.file, "file", "", file)
+		c.fs.StringVar(&c.file, "f", "", file)
+		c.addFlags(c.fs)
 	}
 	return c.fs
 }
@@ -147,11 +159,29 @@ calls are:
     $ tsuru app-deploy .
     $ tsuru app-deploy myfile.jar Procfile
     $ tsuru app-deploy mysite
+    $ tsuru app-deploy --dir ~/projects/mysite
+    $ tsuru app-deploy --file mysite.tar.gz
     $ tsuru app-deploy -i http://registry.mysite.com:5000/image-name
+
+The [[--dir]] flag deploys the contents of the given directory, as if the
+command was run inside it. It can't be combined with files or with [[--image]].
+
+The [[--file]] flag deploys a prebuilt gzipped tarball, like one built in a CI
+pipeline, uploading it as is. It can't be combined with files, [[--dir]] or
+[[--image]].
+
+The output of the build and of the deploy is streamed while the deploy runs.
+When the output is a terminal, the progress of the upload is shown. Hitting
+Ctrl-C cancels the deploy in the server and shows its final status.
+
+The [[--wait]] flag makes the command wait until all units of the app are
+started after the deploy, polling until the app has at least one unit. The
+command fails if any unit enters the error state or is stopped or asleep, or if
+the units are not started after [[--timeout]] (10m by default).
 `
 	return &cmd.Info{
 		Name:    "app-deploy",
-		Usage:   "app-deploy [-a/--app <appname>] [-i/--image <image_url>] <file-or-dir-1> [file-or-dir-2] ... [file-or-dir-n]",
+		Usage:   "app-deploy [-a/--app <appname>] [-i/--image <image_url>] [-d/--dir <dir>] [-f/--file <tarball>] [--wait [--timeout duration]] <file-or-dir-1> [file-or-dir-2] ... [file-or-dir-n]",
 		Desc:    desc,
 		MinArgs: 0,
 	}
@@ -170,7 +200,22 @@ func (w *safeWriter) Write(p []byte) (int, error) {
 
 func (c *AppDeploy) Run(context *cmd.Context, client *cmd.Client) error {
 	context.RawOutput()
-	if c.image == "" && len(context.Args) == 0 {
+	if c.dir != "" {
+		if c.image != "" || len(context.Args) > 0 {
+			return errors.New("You can't use --dir with files or a docker image.\n")
+		}
+		context.Args = []string{c.dir}
+	}
+	if c.file != "" {
+		if c.image != "" || c.dir != "" || len(context.Args) > 0 {
+			return errors.New("You can't use --file with files, --dir or a docker image.\n")
+		}
+		err := checkTarball(c.file)
+		if err != nil {
+			return fmt.Errorf("Invalid file %q: %s.\n", c.file, err)
+		}
+	}
+	if c.image == "" && c.file == "" && len(context.Args) == 0 {
 		return errors.New("You should provide at least one file or a docker image to deploy.\n")
 	}
 	if c.image != "" && len(context.Args) > 0 {
@@ -231,7 +276,11 @@ func (c *AppDeploy) Run(context *cmd.Context, client *cmd.Client) error {
 		if err != nil {
 			return err
 		}
-		err = targz(context, file, context.Args...)
+		if c.file != "" {
+			err = copyFile(file, c.file)
+		} else {
+			err = targz(context, file, context.Args...)
+		}
 		if err != nil {
 			return err
 		}
@@ -240,42 +289,188 @@ func (c *AppDeploy) Run(context *cmd.Context, client *cmd.Client) error {
 		fullSize := float64(body.Len())
 		megabyte := 1024.0 * 1024.0
 		fmt.Fprintf(context.Stdout, "Uploading files (%0.2fMB)... ", fullSize/megabyte)
-		count := 0
-		go func() {
-			t0 := time.Now()
-			lastTransferred := 0.0
-			for buf.Len() == 0 {
-				remaining := body.Len()
-				transferred := fullSize - float64(remaining)
-				speed := ((transferred - lastTransferred) / megabyte) / (float64(time.Since(t0)) / float64(time.Second))
-				t0 = time.Now()
-				lastTransferred = transferred
-				percent := (transferred / fullSize) * 100.0
-				fmt.Fprintf(safeStdout, "\rUploading files (%0.2fMB)... %0.2f%%", fullSize/megabyte, percent)
-				if remaining > 0 {
-					fmt.Fprintf(safeStdout, " (%0.2fMB/s)", speed)
-				}
-				if remaining == 0 && buf.Len() == 0 {
-					fmt.Fprintf(safeStdout, " Processing%s", strings.Repeat(".", count))
-					count++
+		if context.IsTerminal() {
+			go func() {
+				count := 0
+				t0 := time.Now()
+				lastTransferred := 0.0
+				for buf.Len() == 0 {
+					remaining := body.Len()
+					transferred := fullSize - float64(remaining)
+					speed := ((transferred - lastTransferred) / megabyte) / (float64(time.Since(t0)) / float64(time.Second))
+					t0 = time.Now()
+					lastTransferred = transferred
+					percent := (transferred / fullSize) * 100.0
+					fmt.Fprintf(safeStdout, "\r\033[KUploading files... %0.2fMB of %0.2fMB (%0.2f%%)", transferred/megabyte, fullSize/megabyte, percent)
+					if remaining > 0 {
+						fmt.Fprintf(safeStdout, " (%0.2fMB/s)", speed)
+					}
+					if remaining == 0 && buf.Len() == 0 {
+						fmt.Fprintf(safeStdout, " Processing%s", strings.Repeat(".", count))
+						count++
+					}
+					time.Sleep(2e9)
 				}
-				time.Sleep(2e9)
+			}()
+		}
+	}
+	interrupt := make(chan os.Signal, 1)
+	notifyInterrupt(interrupt)
+	defer stopInterrupt(interrupt)
+	// The deploy request is still running while it's canceled, so the
+	// cancellation uses its own copy of the client.
+	cancelClient := *client
+	done := make(chan error, 1)
+	go func() {
+		resp, err := client.Do(request)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer resp.Body.Close()
+		_, err = io.Copy(&respBody, resp.Body)
+		done <- err
+	}()
+	select {
+	case err = <-done:
+	case <-interrupt:
+		fmt.Fprintln(context.Stderr, "\nInterrupted, canceling the deploy...")
+		err = cancelDeploy(context, &cancelClient, appName)
+		if err != nil {
+			fmt.Fprintf(context.Stderr, "Failed to cancel the deploy: %s\n", err)
+		}
+		return cmd.ErrAbortCommand
+	}
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(buf.String(), "\nOK\n") {
+		return c.waitUnits(context, client, appName)
+	}
+	return cmd.ErrAbortCommand
+}
+
+var (
+	notifyInterrupt = func(c chan<- os.Signal) { signal.Notify(c, os.Interrupt) }
+	stopInterrupt   = func(c chan<- os.Signal) { signal.Stop(c) }
+
+	deployCancelPollInterval = time.Second
+	deployCancelTimeout      = time.Minute
+)
+
+// cancelDeploy cancels the running deploy of the app and waits for it to
+// finish, printing its final status.
+func cancelDeploy(context *cmd.Context, client *cmd.Client, appName string) error {
+	qs := url.Values{}
+	qs.Set("kindname", "app.deploy")
+	qs.Set("target.type", "app")
+	qs.Set("target.value", appName)
+	qs.Set("running", "true")
+	u, err := cmd.GetURLVersion("1.1", "/events?"+qs.Encode())
+	if err != nil {
+		return err
+	}
+	var evts []event.Event
+	err = getJSON(client, u, &evts)
+	if err != nil {
+		return err
+	}
+	if len(evts) == 0 {
+		return errors.New("no running deploy found")
+	}
+	id := evts[0].UniqueID.Hex()
+	u, err = cmd.GetURLVersion("1.1", fmt.Sprintf("/events/%s/cancel", id))
+	if err != nil {
+		return err
+	}
+	v := url.Values{}
+	v.Set("reason", "deploy interrupted by the user")
+	request, err := http.NewRequest("POST", u, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	u, err = cmd.GetURLVersion("1.1", "/events/"+id)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(deployCancelTimeout)
+	for {
+		var evt event.Event
+		err = getJSON(client, u, &evt)
+		if err != nil {
+			return err
+		}
+		if !evt.Running {
+			switch {
+			case evt.CancelInfo.Canceled:
+				fmt.Fprintln(context.Stderr, "Deploy canceled.")
+			case evt.Error != "":
+				fmt.Fprintf(context.Stderr, "Deploy failed: %s\n", evt.Error)
+			default:
+				fmt.Fprintln(context.Stderr, "The deploy finished successfully before being canceled.")
 			}
-		}()
+			return nil
+		}
+		if !time.Now().Add(deployCancelPollInterval).Before(deadline) {
+			return fmt.Errorf("the deploy is still running after %s, check it with event-info %s", deployCancelTimeout, id)
+		}
+		time.Sleep(deployCancelPollInterval)
 	}
-	resp, err := client.Do(request)
+}
+
+func getJSON(client *cmd.Client, u string, data interface{}) error {
+	request, err := http.NewRequest("GET", u, nil)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	_, err = io.Copy(&respBody, resp.Body)
+	response, err := client.Do(request)
 	if err != nil {
 		return err
 	}
-	if strings.HasSuffix(buf.String(), "\nOK\n") {
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNoContent {
 		return nil
 	}
-	return cmd.ErrAbortCommand
+	return json.NewDecoder(response.Body).Decode(data)
+}
+
+// checkTarball ensures the file is a readable gzipped tarball with at least
+// one file.
+func checkTarball(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gzipReader, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.New("not a gzip file")
+	}
+	defer gzipReader.Close()
+	_, err = tar.NewReader(gzipReader).Next()
+	if err == io.EOF {
+		return errors.New("the archive is empty")
+	}
+	if err != nil {
+		return errors.New("not a tar archive")
+	}
+	return nil
+}
+
+func copyFile(destination io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(destination, f)
+	return err
 }
 
 func targz(ctx *cmd.Context, destination io.Writer, filepaths ...string) error {
@@ -453,7 +648,7 @@ func (c *AppDeployRollback) Info() *cmd.Info {
 
 func (c *AppDeployRollback) Run(context *cmd.Context, client *cmd.Client) error {
 	context.RawOutput()
-	appName, err := c.Guess()
+	appName, err := c.GuessDestructive(context)
 	if err != nil {
 		return err
 	}
@@ -9,14 +9,18 @@ import (
 	"bytes"
 	"compress/gzip"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	tsuruIo "github.com/tsuru/tsuru/io"
 	"gopkg.in/check.v1"
@@ -236,6 +240,167 @@ func (s *S) TestDeployRunWithArgsAndImage(c *check.C) {
 	c.Assert(err.Error(), check.Equals, "You can't deploy files and docker image at the same time.\n")
 }
 
+func (s *S) TestDeployRunWithDir(c *check.C) {
+	var buf bytes.Buffer
+	ctx := cmd.Context{Stderr: bytes.NewBufferString("")}
+	err := targz(&ctx, &buf, "testdata")
+	c.Assert(err, check.IsNil)
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "deploy worked\nOK\n", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			if req.Method == "GET" {
+				return strings.HasSuffix(req.URL.Path, "/apps/secret")
+			}
+			file, _, transErr := req.FormFile("file")
+			c.Assert(transErr, check.IsNil)
+			content, transErr := ioutil.ReadAll(file)
+			c.Assert(transErr, check.IsNil)
+			c.Assert(content, check.DeepEquals, buf.Bytes())
+			return req.Method == "POST" && strings.HasSuffix(req.URL.Path, "/apps/secret/deploy")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	fake := cmdtest.FakeGuesser{Name: "secret"}
+	command := AppDeploy{GuessingCommand: cmd.GuessingCommand{G: &fake}}
+	command.Flags().Parse(true, []string{"--dir", "testdata"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Matches, `(?s).*deploy worked\nOK\n`)
+}
+
+func (s *S) TestDeployRunWithDirAndArgs(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	ctx := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"testdata"},
+	}
+	trans := cmdtest.Transport{Message: "OK\n", Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	fake := cmdtest.FakeGuesser{Name: "secret"}
+	command := AppDeploy{GuessingCommand: cmd.GuessingCommand{G: &fake}}
+	command.Flags().Parse(true, []string{"--dir", "testdata"})
+	err := command.Run(&ctx, client)
+	c.Assert(err, check.ErrorMatches, "You can't use --dir with files or a docker image.\n")
+}
+
+func (s *S) TestDeployRunWithFile(c *check.C) {
+	var buf bytes.Buffer
+	ctx := cmd.Context{Stderr: bytes.NewBufferString("")}
+	err := targz(&ctx, &buf, "testdata")
+	c.Assert(err, check.IsNil)
+	archive := filepath.Join(c.MkDir(), "app.tar.gz")
+	err = ioutil.WriteFile(archive, buf.Bytes(), 0644)
+	c.Assert(err, check.IsNil)
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "deploy worked\nOK\n", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			if req.Method == "GET" {
+				return strings.HasSuffix(req.URL.Path, "/apps/secret")
+			}
+			file, _, transErr := req.FormFile("file")
+			c.Assert(transErr, check.IsNil)
+			content, transErr := ioutil.ReadAll(file)
+			c.Assert(transErr, check.IsNil)
+			c.Assert(content, check.DeepEquals, buf.Bytes())
+			return req.Method == "POST" && strings.HasSuffix(req.URL.Path, "/apps/secret/deploy")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	fake := cmdtest.FakeGuesser{Name: "secret"}
+	command := AppDeploy{GuessingCommand: cmd.GuessingCommand{G: &fake}}
+	command.Flags().Parse(true, []string{"--file", archive})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Matches, `(?s).*deploy worked\nOK\n`)
+}
+
+func (s *S) TestDeployRunWithInvalidFile(c *check.C) {
+	archive := filepath.Join(c.MkDir(), "app.tar.gz")
+	err := ioutil.WriteFile(archive, []byte("not an archive"), 0644)
+	c.Assert(err, check.IsNil)
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	trans := cmdtest.Transport{Message: "OK\n", Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	fake := cmdtest.FakeGuesser{Name: "secret"}
+	command := AppDeploy{GuessingCommand: cmd.GuessingCommand{G: &fake}}
+	command.Flags().Parse(true, []string{"--file", archive})
+	err = command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, fmt.Sprintf("Invalid file %q: not a gzip file.\n", archive))
+}
+
+func (s *S) TestDeployRunWithFileAndImage(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	trans := cmdtest.Transport{Message: "OK\n", Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	fake := cmdtest.FakeGuesser{Name: "secret"}
+	command := AppDeploy{GuessingCommand: cmd.GuessingCommand{G: &fake}}
+	command.Flags().Parse(true, []string{"--file", "app.tar.gz", "-i", "registry.com/image"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "You can't use --file with files, --dir or a docker image.\n")
+}
+
+type blockingDeployTransport struct {
+	release  chan struct{}
+	mu       sync.Mutex
+	requests []string
+}
+
+func (t *blockingDeployTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.requests = append(t.requests, req.Method+" "+req.URL.RequestURI())
+	t.mu.Unlock()
+	message := ""
+	switch {
+	case req.Method == "POST" && strings.HasSuffix(req.URL.Path, "/deploy"):
+		<-t.release
+		message = "canceled\n"
+	case strings.HasSuffix(req.URL.Path, "/events"):
+		message = `[{"UniqueID":"998e3908413daf5fd9891aac","Running":true}]`
+	case strings.HasSuffix(req.URL.Path, "/events/998e3908413daf5fd9891aac"):
+		message = `{"UniqueID":"998e3908413daf5fd9891aac","Running":false,"CancelInfo":{"Canceled":true}}`
+	}
+	return &http.Response{
+		Body:       ioutil.NopCloser(strings.NewReader(message)),
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+	}, nil
+}
+
+func (s *S) TestDeployRunInterrupted(c *check.C) {
+	defer func(notify, stop func(chan<- os.Signal)) {
+		notifyInterrupt, stopInterrupt = notify, stop
+	}(notifyInterrupt, stopInterrupt)
+	notifyInterrupt = func(ch chan<- os.Signal) { ch <- os.Interrupt }
+	stopInterrupt = func(chan<- os.Signal) {}
+	trans := &blockingDeployTransport{release: make(chan struct{})}
+	defer close(trans.release)
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	fake := cmdtest.FakeGuesser{Name: "secret"}
+	command := AppDeploy{GuessingCommand: cmd.GuessingCommand{G: &fake}}
+	command.Flags().Parse(true, []string{"-i", "registry.com/image"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.Equals, cmd.ErrAbortCommand)
+	c.Assert(stderr.String(), check.Equals, "\nInterrupted, canceling the deploy...\nDeploy canceled.\n")
+	trans.mu.Lock()
+	defer trans.mu.Unlock()
+	c.Assert(trans.requests[len(trans.requests)-3:], check.DeepEquals, []string{
+		"GET /1.1/events?kindname=app.deploy&running=true&target.type=app&target.value=secret",
+		"POST /1.1/events/998e3908413daf5fd9891aac/cancel",
+		"GET /1.1/events/998e3908413daf5fd9891aac",
+	})
+}
+
 func (s *S) TestDeployRunRequestFailure(c *check.C) {
 	trans := cmdtest.Transport{Message: "app not found\n", Status: http.StatusNotFound}
 	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
@@ -19,8 +19,8 @@ import (
 
 	"github.com/cezarsa/form"
 	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/api"
-	"github.com/tsuru/tsuru/cmd"
 	tsuruIo "github.com/tsuru/tsuru/io"
 )
 
@@ -38,19 +38,57 @@ Example:
 
 type EnvGet struct {
 	cmd.GuessingCommand
+	fs    *gnuflag.FlagSet
+	match string
+	shell bool
 }
 
 func (c *EnvGet) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "env-get",
-		Usage:   "env-get [-a/--app appname] [ENVIRONMENT_VARIABLE1] [ENVIRONMENT_VARIABLE2] ...",
-		Desc:    `Retrieves environment variables for an application.`,
+		Name:  "env-get",
+		Usage: "env-get [-a/--app appname] [--match regexp] [--shell] [ENVIRONMENT_VARIABLE1] [ENVIRONMENT_VARIABLE2] ...",
+		Desc: `Retrieves environment variables for an application.
+
+The [[--match]] flag retrieves the variables whose names match the given
+regular expression, along with the variables given by name, if any. For
+example, [[tsuru env-get -a myapp --match '^DB_']] retrieves all variables
+starting with DB_. The values of private variables are still hidden.
+
+The [[--shell]] flag displays the variables as shell export commands, with
+their values quoted, to load them in the current shell:
+
+::
+
+    $ eval "$(tsuru env-get -a myapp --shell)"
+
+Private variables are skipped, as their values can't be retrieved, and so
+are variables whose names are not valid in the shell. Their names are
+written to stderr.`,
 		MinArgs: 0,
 	}
 }
 
+func (c *EnvGet) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		c.fs.StringVar(&c.match, "match", "", "Retrieve the variables whose names match the given regular expression")
+		c.fs.BoolVar(&c.shell, "shell", false, "Display the public variables as shell export commands")
+	}
+	return c.fs
+}
+
 func (c *EnvGet) Run(context *cmd.Context, client *cmd.Client) error {
-	b, err := requestEnvGetURL(c.GuessingCommand, context.Args, client)
+	var match *regexp.Regexp
+	names := context.Args
+	if c.match != "" {
+		var err error
+		match, err = regexp.Compile(c.match)
+		if err != nil {
+			return fmt.Errorf("Invalid --match regular expression: %s", err)
+		}
+		names = nil
+	}
+	b, err := requestEnvGetURL(c.GuessingCommand, names, client)
 	if err != nil {
 		return err
 	}
@@ -60,7 +98,20 @@ func (c *EnvGet) Run(context *cmd.Context, client *cmd.Client) error {
 		return err
 	}
 	formatted := make([]string, 0, len(variables))
+	var skipped []string
 	for _, v := range variables {
+		name := v["name"].(string)
+		if match != nil && !envSelected(name, match, context.Args) {
+			continue
+		}
+		if c.shell {
+			if !v["public"].(bool) || !shellNameRegexp.MatchString(name) {
+				skipped = append(skipped, name)
+				continue
+			}
+			formatted = append(formatted, fmt.Sprintf("export %s=%s", name, shellQuote(v["value"].(string))))
+			continue
+		}
 		value := "*** (private variable)"
 		if v["public"].(bool) {
 			value = v["value"].(string)
@@ -68,10 +119,43 @@ func (c *EnvGet) Run(context *cmd.Context, client *cmd.Client) error {
 		formatted = append(formatted, fmt.Sprintf("%s=%s", v["name"], value))
 	}
 	sort.Strings(formatted)
+	if c.shell {
+		if len(skipped) > 0 {
+			sort.Strings(skipped)
+			fmt.Fprintf(context.Stderr, "Skipped private variables and variables with invalid names: %s\n", strings.Join(skipped, ", "))
+		}
+		if len(formatted) == 0 {
+			return nil
+		}
+	}
 	fmt.Fprintln(context.Stdout, strings.Join(formatted, "\n"))
 	return nil
 }
 
+// shellNameRegexp matches the names of variables accepted by the shell.
+var shellNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// shellQuote quotes the value with single quotes, so the shell doesn't
+// interpret any of its characters, newlines included. Single quotes in the
+// value are closed, escaped and reopened.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// envSelected reports whether the variable matches the --match regular
+// expression or is one of the variables given by name.
+func envSelected(name string, match *regexp.Regexp, names []string) bool {
+	if match.MatchString(name) {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
 type EnvSet struct {
 	cmd.GuessingCommand
 	fs        *gnuflag.FlagSet
@@ -81,16 +165,26 @@ type EnvSet struct {
 
 func (c *EnvSet) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "env-set",
-		Usage:   "env-set <NAME=value> [NAME=value] ... [-a/--app appname] [-p/--private] [--no-restart]",
-		Desc:    `Sets environment variables for an application.`,
+		Name:  "env-set",
+		Usage: "env-set <NAME=value> [NAME=value] ... [-a/--app appname] [-p/--private] [--no-restart]",
+		Desc: `Sets environment variables for an application.
+
+Values starting with @ are read from the file in the rest of the value, which
+is useful for large or multi-line values, like certificates:
+
+::
+
+    $ tsuru env-set MY_CERT=@/path/to/cert.pem -a myapp
+
+To set a value that starts with @, escape it with a backslash, as in
+NAME=\@value.`,
 		MinArgs: 1,
 	}
 }
 
 func (c *EnvSet) Run(context *cmd.Context, client *cmd.Client) error {
 	context.RawOutput()
-	appName, err := c.Guess()
+	appName, err := c.GuessDestructive(context)
 	if err != nil {
 		return err
 	}
@@ -103,13 +197,44 @@ func (c *EnvSet) Run(context *cmd.Context, client *cmd.Client) error {
 	envs := make([]struct{ Name, Value string }, len(decls))
 	for i := range decls {
 		parts := strings.SplitN(decls[i][1], "=", 2)
-		envs[i] = struct{ Name, Value string }{Name: parts[0], Value: parts[1]}
+		value, err := envValue(parts[0], parts[1])
+		if err != nil {
+			return err
+		}
+		envs[i] = struct{ Name, Value string }{Name: parts[0], Value: value}
 	}
 	e := api.Envs{
 		Envs:      envs,
 		NoRestart: c.noRestart,
 		Private:   c.private,
 	}
+	return setEnvs(context, client, appName, e)
+}
+
+// envValue resolves values starting with @, reading them from the file in
+// the rest of the value. A value starting with \@ is taken literally, without
+// the backslash.
+func envValue(name, value string) (string, error) {
+	if strings.HasPrefix(value, `\@`) {
+		return value[1:], nil
+	}
+	if !strings.HasPrefix(value, "@") {
+		return value, nil
+	}
+	path := value[1:]
+	file, err := filesystem().Open(path)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read the value of %s from %q: %s", name, path, err)
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read the value of %s from %q: %s", name, path, err)
+	}
+	return string(data), nil
+}
+
+func setEnvs(context *cmd.Context, client *cmd.Client, appName string, e api.Envs) error {
 	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/env", appName))
 	if err != nil {
 		return err
@@ -152,35 +277,66 @@ func (c *EnvSet) Flags() *gnuflag.FlagSet {
 
 type EnvUnset struct {
 	cmd.GuessingCommand
+	cmd.ConfirmationCommand
 	fs        *gnuflag.FlagSet
 	noRestart bool
+	all       bool
 }
 
 func (c *EnvUnset) Flags() *gnuflag.FlagSet {
 	if c.fs == nil {
-		c.fs = c.GuessingCommand.Flags()
+		c.fs = cmd.MergeFlagSet(
+			c.GuessingCommand.Flags(),
+			c.ConfirmationCommand.Flags(),
+		)
 		c.fs.BoolVar(&c.noRestart, "no-restart", false, "Unset environment variables without restart the application")
+		c.fs.BoolVar(&c.all, "all", false, "Unset all the public environment variables of the application")
 	}
 	return c.fs
 }
 
 func (c *EnvUnset) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "env-unset",
-		Usage:   "env-unset <ENVIRONMENT_VARIABLE1> [ENVIRONMENT_VARIABLE2] ... [ENVIRONMENT_VARIABLEN] [-a/--app appname] [--no-restart]",
-		Desc:    `Unset environment variables for an application.`,
-		MinArgs: 1,
+		Name:  "env-unset",
+		Usage: "env-unset <ENVIRONMENT_VARIABLE1> [ENVIRONMENT_VARIABLE2] ... [ENVIRONMENT_VARIABLEN] [-a/--app appname] [--no-restart] [--all [-y/--assume-yes]]",
+		Desc: `Unset environment variables for an application.
+
+The [[--all]] flag unsets all the public environment variables of the
+application, instead of the given ones. Private variables, including the ones
+exported by service instances, and variables managed by tsuru are never
+unset. As it can't be undone, it asks for confirmation, unless the
+[[--assume-yes]] flag is used.`,
 	}
 }
 
 func (c *EnvUnset) Run(context *cmd.Context, client *cmd.Client) error {
 	context.RawOutput()
-	appName, err := c.Guess()
+	appName, err := c.GuessDestructive(context)
 	if err != nil {
 		return err
 	}
+	names := context.Args
+	if c.all {
+		if len(names) > 0 {
+			return errors.New("You must provide either the variable names or the --all flag, not both.")
+		}
+		names, err = publicEnvNames(client, appName)
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			fmt.Fprintf(context.Stdout, "App %q has no public environment variables to unset.\n", appName)
+			return nil
+		}
+		question := fmt.Sprintf("Are you sure you want to unset the following variables of app %q: %s?", appName, strings.Join(names, ", "))
+		if !c.Confirm(context, question) {
+			return nil
+		}
+	} else if len(names) == 0 {
+		return errors.New("You must provide the names of the variables to unset, or the --all flag.")
+	}
 	v := url.Values{}
-	for _, e := range context.Args {
+	for _, e := range names {
 		v.Add("env", e)
 	}
 	v.Set("noRestart", strconv.FormatBool(c.noRestart))
@@ -237,3 +393,330 @@ func requestEnvGetURL(g cmd.GuessingCommand, args []string, client *cmd.Client)
 	}
 	return b, nil
 }
+
+type EnvDiff struct {
+	fs   *gnuflag.FlagSet
+	json bool
+}
+
+func (c *EnvDiff) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "env-diff",
+		Usage: "env-diff <app1> <app2> [--json]",
+		Desc: `Compares the environment variables of two applications, printing the
+differences in the unified diff format: lines starting with "-" are only set, or
+set with a different value, in the first app, and lines starting with "+" in
+the second one.
+
+Values of private variables are never shown, only whether they are set in each
+app.`,
+		MinArgs: 2,
+		MaxArgs: 2,
+	}
+}
+
+func (c *EnvDiff) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("env-diff", gnuflag.ExitOnError)
+		c.fs.BoolVar(&c.json, "json", false, "Display the differences in JSON format")
+	}
+	return c.fs
+}
+
+type envVar struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Public bool   `json:"public"`
+}
+
+func (v envVar) String() string {
+	if !v.Public {
+		return fmt.Sprintf("%s=*** (private variable)", v.Name)
+	}
+	return fmt.Sprintf("%s=%s", v.Name, v.Value)
+}
+
+type envDiffValue struct {
+	Value   string `json:"value,omitempty"`
+	Private bool   `json:"private"`
+}
+
+type envDiffItem struct {
+	Name   string        `json:"name"`
+	Change string        `json:"change"`
+	Old    *envDiffValue `json:"old,omitempty"`
+	New    *envDiffValue `json:"new,omitempty"`
+}
+
+func newEnvDiffValue(v *envVar) *envDiffValue {
+	if v == nil {
+		return nil
+	}
+	if !v.Public {
+		return &envDiffValue{Private: true}
+	}
+	return &envDiffValue{Value: v.Value}
+}
+
+func (c *EnvDiff) Run(context *cmd.Context, client *cmd.Client) error {
+	app1, app2 := context.Args[0], context.Args[1]
+	envs1, err := getAppEnvs(client, app1)
+	if err != nil {
+		return err
+	}
+	envs2, err := getAppEnvs(client, app2)
+	if err != nil {
+		return err
+	}
+	diff := diffEnvs(envs1, envs2)
+	if c.json {
+		context.OutputFormat = cmd.JSONOutput
+	}
+	if context.StructuredOutput() {
+		return context.WriteStructured(map[string]interface{}{
+			"apps":    []string{app1, app2},
+			"changes": diff,
+		})
+	}
+	if len(diff) == 0 {
+		fmt.Fprintf(context.Stdout, "Apps %q and %q have the same environment variables.\n", app1, app2)
+		return nil
+	}
+	fmt.Fprintf(context.Stdout, "--- %s\n+++ %s\n", app1, app2)
+	for _, item := range diff {
+		if v, ok := envs1[item.Name]; ok {
+			fmt.Fprintf(context.Stdout, "-%s\n", v)
+		}
+		if v, ok := envs2[item.Name]; ok {
+			fmt.Fprintf(context.Stdout, "+%s\n", v)
+		}
+	}
+	return nil
+}
+
+// diffEnvs compares two sets of environment variables, sorted by name.
+// Private variables set in both sets are considered equal, as their values
+// are not available.
+func diffEnvs(envs1, envs2 map[string]envVar) []envDiffItem {
+	names := make([]string, 0, len(envs1)+len(envs2))
+	for name := range envs1 {
+		names = append(names, name)
+	}
+	for name := range envs2 {
+		if _, ok := envs1[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	diff := []envDiffItem{}
+	for _, name := range names {
+		v1, in1 := envs1[name]
+		v2, in2 := envs2[name]
+		item := envDiffItem{Name: name}
+		switch {
+		case !in2:
+			item.Change = "removed"
+			item.Old = newEnvDiffValue(&v1)
+		case !in1:
+			item.Change = "added"
+			item.New = newEnvDiffValue(&v2)
+		case v1.Public != v2.Public || (v1.Public && v1.Value != v2.Value):
+			item.Change = "changed"
+			item.Old = newEnvDiffValue(&v1)
+			item.New = newEnvDiffValue(&v2)
+		default:
+			continue
+		}
+		diff = append(diff, item)
+	}
+	return diff
+}
+
+func getAppEnvs(client *cmd.Client, appName string) (map[string]envVar, error) {
+	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s/env", appName))
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var variables []envVar
+	err = json.NewDecoder(response.Body).Decode(&variables)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	envs := make(map[string]envVar, len(variables))
+	for _, v := range variables {
+		envs[v.Name] = v
+	}
+	return envs, nil
+}
+
+// envSummary counts the environment variables of an app by origin, without
+// their values.
+type envSummary struct {
+	Total    int               `json:"total"`
+	Public   int               `json:"public"`
+	Private  int               `json:"private"`
+	Bound    int               `json:"bound"`
+	Services []envServiceCount `json:"services"`
+}
+
+// envServiceCount is the number of variables injected in the app by a bound
+// service instance.
+type envServiceCount struct {
+	Service   string `json:"service"`
+	Instance  string `json:"instance"`
+	Variables int    `json:"variables"`
+}
+
+// summarizeEnvs counts the variables of the app. Variables injected by
+// service instances are counted as bound, and the other ones as public or
+// private.
+func summarizeEnvs(envs map[string]envVar) *envSummary {
+	summary := envSummary{Total: len(envs), Services: []envServiceCount{}}
+	bound := make(map[string]bool)
+	services := boundServiceInstances(envs)
+	serviceNames := make([]string, 0, len(services))
+	for service := range services {
+		serviceNames = append(serviceNames, service)
+	}
+	sort.Strings(serviceNames)
+	for _, service := range serviceNames {
+		for _, instance := range services[service] {
+			count := envServiceCount{Service: service, Instance: instance.Name}
+			for name := range instance.Envs {
+				if _, ok := envs[name]; ok {
+					count.Variables++
+					bound[name] = true
+				}
+			}
+			summary.Services = append(summary.Services, count)
+		}
+	}
+	for name, v := range envs {
+		switch {
+		case bound[name]:
+			summary.Bound++
+		case v.Public:
+			summary.Public++
+		default:
+			summary.Private++
+		}
+	}
+	return &summary
+}
+
+type EnvCopy struct {
+	fs        *gnuflag.FlagSet
+	from      string
+	to        string
+	dryRun    bool
+	noRestart bool
+}
+
+func (c *EnvCopy) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "env-copy",
+		Usage: "env-copy --from <app> --to <app> [ENVIRONMENT_VARIABLE1] [ENVIRONMENT_VARIABLE2] ... [--dry-run] [--no-restart]",
+		Desc: `Copies public environment variables from one application to another,
+setting them all at once in the destination app.
+
+If no variable is given, all public variables of the source app are copied.
+Private variables, including the ones set by service bindings, and variables
+managed by tsuru (prefixed by TSURU_) are never copied.
+
+Use the [[--dry-run]] flag to preview the variables that would be set.`,
+		MinArgs: 0,
+	}
+}
+
+func (c *EnvCopy) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("env-copy", gnuflag.ExitOnError)
+		c.fs.StringVar(&c.from, "from", "", "The app to copy the environment variables from")
+		c.fs.StringVar(&c.to, "to", "", "The app to copy the environment variables to")
+		c.fs.BoolVar(&c.dryRun, "dry-run", false, "Show the variables that would be set, without setting them")
+		c.fs.BoolVar(&c.noRestart, "no-restart", false, "Sets environment varibles without restart the application")
+	}
+	return c.fs
+}
+
+func (c *EnvCopy) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	if c.from == "" || c.to == "" {
+		return errors.New("You must provide both the --from and --to apps.")
+	}
+	if c.from == c.to {
+		return errors.New("The source and destination apps must be different.")
+	}
+	source, err := getAppEnvs(client, c.from)
+	if err != nil {
+		return err
+	}
+	names := context.Args
+	explicit := len(names) > 0
+	if !explicit {
+		for name := range source {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	var envs []struct{ Name, Value string }
+	var skipped []string
+	for _, name := range names {
+		v, ok := source[name]
+		if !ok {
+			return fmt.Errorf("Variable %q is not set in app %q.", name, c.from)
+		}
+		if !v.Public || isTsuruEnv(name) {
+			if explicit {
+				return fmt.Errorf("Variable %q is private or managed by tsuru and can't be copied.", name)
+			}
+			skipped = append(skipped, name)
+			continue
+		}
+		envs = append(envs, struct{ Name, Value string }{Name: v.Name, Value: v.Value})
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(context.Stderr, "Skipping private or tsuru managed variables: %s.\n", strings.Join(skipped, ", "))
+	}
+	if len(envs) == 0 {
+		return fmt.Errorf("No public variables to copy from app %q.", c.from)
+	}
+	if c.dryRun {
+		fmt.Fprintf(context.Stdout, "The following variables would be set in app %q:\n", c.to)
+		for _, e := range envs {
+			fmt.Fprintf(context.Stdout, "  %s=%s\n", e.Name, e.Value)
+		}
+		return nil
+	}
+	return setEnvs(context, client, c.to, api.Envs{Envs: envs, NoRestart: c.noRestart})
+}
+
+// publicEnvNames returns the sorted names of the public variables of the
+// app, ignoring the ones managed by tsuru.
+func publicEnvNames(client *cmd.Client, appName string) ([]string, error) {
+	envs, err := getAppEnvs(client, appName)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name, v := range envs {
+		if v.Public && !isTsuruEnv(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func isTsuruEnv(name string) bool {
+	return strings.HasPrefix(name, "TSURU_")
+}
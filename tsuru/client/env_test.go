@@ -8,12 +8,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"os"
+	"reflect"
 	"strings"
 
 	"github.com/cezarsa/form"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/api"
-	"github.com/tsuru/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
+	"github.com/tsuru/tsuru/fs/fstest"
 	"github.com/tsuru/tsuru/io"
 	"gopkg.in/check.v1"
 )
@@ -39,6 +42,37 @@ func (s *S) TestEnvGetRun(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, result)
 }
 
+func (s *S) TestEnvGetRunShell(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	jsonResult := `[{"name": "GREETING", "value": "it's\nmultiline", "public": true}, {"name": "DATABASE_PASSWORD", "value": "", "public": false}, {"name": "DATABASE_HOST", "value": "somehost", "public": true}, {"name": "INVALID-NAME", "value": "x", "public": true}]`
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: jsonResult, Status: http.StatusOK}}, nil, manager)
+	command := EnvGet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--shell"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "export DATABASE_HOST='somehost'\nexport GREETING='it'\\''s\nmultiline'\n")
+	c.Assert(stderr.String(), check.Equals, "Skipped private variables and variables with invalid names: DATABASE_PASSWORD, INVALID-NAME\n")
+}
+
+func (s *S) TestEnvGetRunShellOnlyPrivate(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	jsonResult := `[{"name": "DATABASE_PASSWORD", "value": "", "public": false}]`
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: jsonResult, Status: http.StatusOK}}, nil, manager)
+	command := EnvGet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--shell"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
 func (s *S) TestEnvGetRunWithMultipleParams(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	jsonResult := `[{"name": "DATABASE_HOST", "value": "somehost", "public": true}, {"name": "DATABASE_USER", "value": "someuser", "public": true}]`
@@ -103,6 +137,37 @@ func (s *S) TestEnvGetPrivateVariables(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, result)
 }
 
+func (s *S) TestEnvGetMatch(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	jsonResult := `[{"name": "DB_HOST", "value": "somehost", "public": true}, {"name": "DB_PASSWORD", "value": "secret", "public": false}, {"name": "PORT", "value": "8888", "public": true}, {"name": "MY_DB_NAME", "value": "mydb", "public": true}, {"name": "LANG", "value": "en", "public": true}]`
+	result := "DB_HOST=somehost\nDB_PASSWORD=*** (private variable)\nLANG=en\n"
+	context := cmd.Context{
+		Args:   []string{"LANG"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: jsonResult, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return strings.HasSuffix(req.URL.Path, "/apps/someapp/env") && len(req.URL.Query()["env"]) == 0
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := EnvGet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--match", "^DB_"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, result)
+}
+
+func (s *S) TestEnvGetInvalidMatch(c *check.C) {
+	context := cmd.Context{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	command := EnvGet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--match", "DB_("})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "Invalid --match regular expression: .*")
+}
+
 func (s *S) TestEnvGetWithoutTheFlag(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	jsonResult := `[{"name": "DATABASE_HOST", "value": "somehost", "public": true}, {"name": "DATABASE_USER", "value": "someuser", "public": true}]`
@@ -121,7 +186,7 @@ func (s *S) TestEnvGetWithoutTheFlag(c *check.C) {
 	}
 	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
 	fake := &cmdtest.FakeGuesser{Name: "seek"}
-	err := (&EnvGet{cmd.GuessingCommand{G: fake}}).Run(&context, client)
+	err := (&EnvGet{GuessingCommand: cmd.GuessingCommand{G: fake}}).Run(&context, client)
 	c.Assert(err, check.IsNil)
 	c.Assert(stdout.String(), check.Equals, result)
 }
@@ -167,6 +232,64 @@ func (s *S) TestEnvSetRun(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expectedOut)
 }
 
+func (s *S) TestEnvSetRunValueFromFile(c *check.C) {
+	rfs := &fstest.RecordingFs{}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	cert := "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+	f, err := rfs.Create("/certs/cert.pem")
+	c.Assert(err, check.IsNil)
+	f.Write([]byte(cert))
+	f.Close()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"MY_CERT=@/certs/cert.pem", `HANDLE=\@tsuru`},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Message: "variable(s) successfully exported\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			err = req.ParseForm()
+			c.Assert(err, check.IsNil)
+			var e api.Envs
+			dec := form.NewDecoder(nil)
+			dec.IgnoreUnknownKeys(true)
+			err = dec.DecodeValues(&e, req.Form)
+			c.Assert(err, check.IsNil)
+			c.Assert(e.Envs, check.HasLen, 2)
+			return e.Envs[0].Name == "MY_CERT" && e.Envs[0].Value == cert &&
+				e.Envs[1].Name == "HANDLE" && e.Envs[1].Value == "@tsuru"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := EnvSet{}
+	command.Flags().Parse(true, []string{"-a", "someapp"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestEnvSetRunValueFromMissingFile(c *check.C) {
+	fsystem = &fstest.RecordingFs{}
+	defer func() {
+		fsystem = nil
+	}()
+	context := cmd.Context{
+		Args:   []string{"MY_CERT=@/certs/cert.pem"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	command := EnvSet{}
+	command.Flags().Parse(true, []string{"-a", "someapp"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, `Failed to read the value of MY_CERT from "/certs/cert.pem": no such file or directory`)
+}
+
 func (s *S) TestEnvSetRunWithMultipleParams(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	context := cmd.Context{
@@ -378,6 +501,77 @@ func (s *S) TestEnvUnsetRun(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expectedOut)
 }
 
+func (s *S) TestEnvUnsetAll(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("y\n"),
+	}
+	envs := `[{"name":"PORT","value":"8888","public":true},{"name":"DEBUG","value":"1","public":true},{"name":"DATABASE_PASSWORD","value":"","public":false},{"name":"TSURU_APPDIR","value":"/home/application/current","public":true}]`
+	msg := io.SimpleJsonMessage{Message: "variable(s) successfully unset\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: envs, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/someapp/env")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "DELETE" && strings.HasSuffix(req.URL.Path, "/apps/someapp/env") &&
+						reflect.DeepEqual(req.URL.Query()["env"], []string{"DEBUG", "PORT"})
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := EnvUnset{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--all"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `Are you sure you want to unset the following variables of app "someapp": DEBUG, PORT? (y/n) variable(s) successfully unset
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestEnvUnsetAllAbort(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `[{"name":"PORT","value":"8888","public":true}]`, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/someapp/env")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := EnvUnset{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--all"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, `Are you sure you want to unset the following variables of app "someapp": PORT? (y/n) Abort.`+"\n")
+}
+
+func (s *S) TestEnvUnsetAllWithNames(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"PORT"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	command := EnvUnset{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--all", "-y"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "You must provide either the variable names or the --all flag, not both.")
+}
+
 func (s *S) TestEnvUnsetWithNoRestartFlag(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	context := cmd.Context{
@@ -444,3 +638,240 @@ func (s *S) TestRequestEnvURL(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(b, check.DeepEquals, []byte(result))
 }
+
+func envDiffTransport() http.RoundTripper {
+	return &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{
+					Message: `[{"name": "DATABASE_HOST", "value": "staging-db", "public": true},
+{"name": "DEBUG", "value": "1", "public": true},
+{"name": "LANG", "value": "C", "public": true},
+{"name": "PASSWORD", "value": "", "public": false},
+{"name": "TOKEN", "value": "abc", "public": true}]`,
+					Status: http.StatusOK,
+				},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/apps/staging/env")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{
+					Message: `[{"name": "DATABASE_HOST", "value": "prod-db", "public": true},
+{"name": "LANG", "value": "C", "public": true},
+{"name": "PASSWORD", "value": "", "public": false},
+{"name": "SECRET", "value": "", "public": false},
+{"name": "TOKEN", "value": "", "public": false}]`,
+					Status: http.StatusOK,
+				},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/apps/prod/env")
+				},
+			},
+		},
+	}
+}
+
+func (s *S) TestEnvDiffInfo(c *check.C) {
+	c.Assert((&EnvDiff{}).Info(), check.NotNil)
+}
+
+func (s *S) TestEnvDiffRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"staging", "prod"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: envDiffTransport()}, nil, manager)
+	command := EnvDiff{}
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `--- staging
++++ prod
+-DATABASE_HOST=staging-db
++DATABASE_HOST=prod-db
+-DEBUG=1
++SECRET=*** (private variable)
+-TOKEN=abc
++TOKEN=*** (private variable)
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestEnvDiffRunJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"staging", "prod"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: envDiffTransport()}, nil, manager)
+	command := EnvDiff{}
+	command.Flags().Parse(true, []string{"--json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var result struct {
+		Apps    []string
+		Changes []envDiffItem
+	}
+	err = json.Unmarshal(stdout.Bytes(), &result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result.Apps, check.DeepEquals, []string{"staging", "prod"})
+	c.Assert(result.Changes, check.DeepEquals, []envDiffItem{
+		{Name: "DATABASE_HOST", Change: "changed", Old: &envDiffValue{Value: "staging-db"}, New: &envDiffValue{Value: "prod-db"}},
+		{Name: "DEBUG", Change: "removed", Old: &envDiffValue{Value: "1"}},
+		{Name: "SECRET", Change: "added", New: &envDiffValue{Private: true}},
+		{Name: "TOKEN", Change: "changed", Old: &envDiffValue{Value: "abc"}, New: &envDiffValue{Private: true}},
+	})
+}
+
+func (s *S) TestEnvDiffRunNoDifferences(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"staging", "staging"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{
+			Message: `[{"name": "LANG", "value": "C", "public": true}, {"name": "PASSWORD", "value": "", "public": false}]`,
+			Status:  http.StatusOK,
+		},
+		CondFunc: func(r *http.Request) bool {
+			return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/apps/staging/env")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := EnvDiff{}
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Apps \"staging\" and \"staging\" have the same environment variables.\n")
+}
+
+const envCopySource = `[{"name": "DATABASE_HOST", "value": "staging-db", "public": true},
+{"name": "DEBUG", "value": "1", "public": true},
+{"name": "PASSWORD", "value": "", "public": false},
+{"name": "TSURU_APPNAME", "value": "staging", "public": true}]`
+
+func (s *S) TestEnvCopyInfo(c *check.C) {
+	c.Assert((&EnvCopy{}).Info(), check.NotNil)
+}
+
+func (s *S) TestEnvCopyRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	expectedOut := "variable(s) successfully exported\n"
+	msg := io.SimpleJsonMessage{Message: expectedOut}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: envCopySource, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/staging/env")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					err = req.ParseForm()
+					c.Assert(err, check.IsNil)
+					var e api.Envs
+					dec := form.NewDecoder(nil)
+					dec.IgnoreUnknownKeys(true)
+					err = dec.DecodeValues(&e, req.Form)
+					c.Assert(err, check.IsNil)
+					c.Assert(e.Envs, check.DeepEquals, []struct{ Name, Value string }{
+						{Name: "DATABASE_HOST", Value: "staging-db"},
+						{Name: "DEBUG", Value: "1"},
+					})
+					c.Assert(e.Private, check.Equals, false)
+					c.Assert(e.NoRestart, check.Equals, true)
+					return req.Method == "POST" && strings.HasSuffix(req.URL.Path, "/apps/prod/env")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := EnvCopy{}
+	command.Flags().Parse(true, []string{"--from", "staging", "--to", "prod", "--no-restart"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expectedOut)
+	c.Assert(stderr.String(), check.Equals, "Skipping private or tsuru managed variables: PASSWORD, TSURU_APPNAME.\n")
+}
+
+func (s *S) TestEnvCopyRunDryRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"DEBUG"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: envCopySource, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/staging/env")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := EnvCopy{}
+	command.Flags().Parse(true, []string{"--from", "staging", "--to", "prod", "--dry-run"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "The following variables would be set in app \"prod\":\n  DEBUG=1\n")
+}
+
+func (s *S) TestEnvCopyRunRefusesPrivateVariables(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"DEBUG", "PASSWORD"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	trans := &cmdtest.Transport{Message: envCopySource, Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := EnvCopy{}
+	command.Flags().Parse(true, []string{"--from", "staging", "--to", "prod"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Variable "PASSWORD" is private or managed by tsuru and can't be copied.`)
+	context.Args = []string{"TSURU_APPNAME"}
+	err = command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Variable "TSURU_APPNAME" is private or managed by tsuru and can't be copied.`)
+	context.Args = []string{"NOT_SET"}
+	err = command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Variable "NOT_SET" is not set in app "staging".`)
+}
+
+func (s *S) TestEnvCopyRunMissingApps(c *check.C) {
+	command := EnvCopy{}
+	command.Flags().Parse(true, []string{"--from", "staging"})
+	err := command.Run(&cmd.Context{}, nil)
+	c.Assert(err, check.ErrorMatches, "You must provide both the --from and --to apps.")
+}
+
+func (s *S) TestEnvSetConfirmGuessedAppRefused(c *check.C) {
+	os.Setenv("TSURU_CONFIRM_APP", "1")
+	defer os.Unsetenv("TSURU_CONFIRM_APP")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"FOO=bar"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+	}
+	trans := transportFunc(func(req *http.Request) (*http.Response, error) {
+		c.Fatalf("unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := EnvSet{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "vapor"}}}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&context, client)
+	c.Assert(err, check.Equals, cmd.ErrGuessNotConfirmed)
+	c.Assert(stderr.String(), check.Equals, "using guessed app vapor\n")
+}
@@ -14,7 +14,7 @@ import (
 	"github.com/cezarsa/form"
 	"github.com/ghodss/yaml"
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/event"
 )
 
@@ -316,6 +316,6 @@ func (c *EventCancel) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(context.Stdout, "Event successfully canceled.")
+	context.Successf("Event successfully canceled.\n")
 	return nil
 }
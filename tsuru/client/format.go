@@ -0,0 +1,58 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+)
+
+// appFormat holds the --format flag of the commands that display apps. The
+// flag is a Go template evaluated against each app, replacing the table
+// output.
+type appFormat struct {
+	format string
+	tmpl   *template.Template
+}
+
+func (f *appFormat) addFlags(fs *gnuflag.FlagSet) {
+	fs.StringVar(&f.format, "format", "", "Format each app using the given Go template, like '{{.Name}} {{.Pool}}'")
+}
+
+// prepare parses the template given in the --format flag, if any. It must be
+// called before write.
+func (f *appFormat) prepare(context *cmd.Context) error {
+	if f.format == "" {
+		return nil
+	}
+	if context.StructuredOutput() {
+		return errors.New("The --format flag can't be used with the --output flag.")
+	}
+	tmpl, err := template.New("format").Parse(f.format)
+	if err != nil {
+		return fmt.Errorf("Invalid format template: %s", err)
+	}
+	f.tmpl = tmpl
+	return nil
+}
+
+// write writes the app formatted with the template of the --format flag,
+// followed by a new line. Nothing is written when the template fails.
+func (f *appFormat) write(w io.Writer, a *app) error {
+	var buf bytes.Buffer
+	err := f.tmpl.Execute(&buf, a)
+	if err != nil {
+		return fmt.Errorf("Failed to format app %q: %s", a.Name, err)
+	}
+	buf.WriteString("\n")
+	_, err = buf.WriteTo(w)
+	return err
+}
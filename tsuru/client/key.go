@@ -16,7 +16,7 @@ import (
 	"strings"
 
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/fs"
 )
@@ -84,14 +84,14 @@ func (c *KeyAdd) Run(context *cmd.Context, client *cmd.Client) error {
 			fmt.Fscan(context.Stdin, &answer)
 			if answer == "y" || answer == "yes" {
 				if err = c.sendRequest(client, keyName, body, true); err == nil {
-					fmt.Fprintf(context.Stdout, "Key %q successfully replaced!\n", keyName)
+					context.Successf("Key %q successfully replaced!\n", keyName)
 					return nil
 				}
 			}
 		}
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "Key %q successfully added!\n", keyName)
+	context.Successf("Key %q successfully added!\n", keyName)
 	return nil
 }
 
@@ -153,7 +153,7 @@ func (c *KeyRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "Key %q successfully removed!\n", context.Args[0])
+	context.Successf("Key %q successfully removed!\n", context.Args[0])
 	return nil
 }
 
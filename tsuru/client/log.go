@@ -6,14 +6,18 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	tsuruerr "github.com/tsuru/tsuru/errors"
 	tsuruIo "github.com/tsuru/tsuru/io"
 )
 
@@ -26,12 +30,17 @@ type AppLog struct {
 	follow   bool
 	noDate   bool
 	noSource bool
+	grep     string
+	invert   bool
+	json     bool
+
+	maxReconnects int
 }
 
 func (c *AppLog) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "app-log",
-		Usage: "app-log [-a/--app appname] [-l/--lines numberOfLines] [-s/--source source] [-u/--unit unit] [-f/--follow]",
+		Usage: "app-log [-a/--app appname] [-l/--lines numberOfLines] [-s/--source source] [-u/--unit unit] [-f/--follow [--max-reconnects n]] [--no-date] [--no-source] [--grep pattern [-v/--invert]] [--json]",
 		Desc: `Shows log entries for an application. These logs include everything the
 application send to stdout and stderr, alongside with logs from tsuru server
 (deployments, restarts, etc.)
@@ -42,15 +51,32 @@ The [[--source]] flag is optional and allows filtering logs by log source
 (e.g. application, tsuru api).
 
 The [[--unit]] flag is optional and allows filtering by unit. It's useful if
-your application has multiple units and you want logs from a single one.
+your application has multiple units and you want logs from a single one. It
+may be combined with [[--source]] and [[--follow]]. An error is returned when
+there are no logs and the unit doesn't exist in the application.
 
 The [[--follow]] flag is optional and makes the command wait for additional
-log output
+log output. When the connection is lost, it's reestablished after a delay that
+doubles at each attempt, up to 30 seconds, resuming after the last entry
+displayed. The [[--max-reconnects]] flag limits the number of reconnections,
+which are unlimited by default. Use [[--max-reconnects 0]] to stop when the
+connection is lost.
 
-The [[--no-date]] flag is optional and makes the log output without date.
+The [[--no-date]] flag is optional and makes the log output without date,
+keeping only the source and the message of each entry. Combined with
+[[--source]], it's useful to pipe the log to other tools. It doesn't change the
+entries fetched from the server.
 
 The [[--no-source]] flag is optional and makes the log output without source
 information, useful to very dense logs.
+
+The [[--grep]] flag is optional and only displays the log entries whose message
+matches the given regular expression. It also works with [[--follow]]. The
+[[--invert]] flag displays the entries that don't match the expression instead.
+
+The [[--json]] flag is optional and displays each log entry as a JSON object
+in its own line, with the timestamp, source, unit and message fields. It's
+useful to send the logs to tools expecting newline delimited JSON.
 `,
 		MinArgs: 0,
 	}
@@ -59,6 +85,21 @@ information, useful to very dense logs.
 type logFormatter struct {
 	noDate   bool
 	noSource bool
+	filter   *regexp.Regexp
+	invert   bool
+	json     bool
+	// app and appColor are set when the logs of many apps are displayed
+	// together, so each entry is prefixed with the name of its app.
+	app      string
+	appColor string
+}
+
+type jsonLog struct {
+	App       string    `json:"app,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+	Unit      string    `json:"unit"`
+	Message   string    `json:"message"`
 }
 
 func (f logFormatter) Format(out io.Writer, data []byte) error {
@@ -67,18 +108,44 @@ func (f logFormatter) Format(out io.Writer, data []byte) error {
 	if err != nil {
 		return tsuruIo.ErrInvalidStreamChunk
 	}
+	return f.write(out, logs)
+}
+
+func (f logFormatter) write(out io.Writer, logs []log) error {
 	for _, l := range logs {
+		if f.filter != nil && f.filter.MatchString(l.Message) == f.invert {
+			continue
+		}
+		if f.json {
+			data, err := json.Marshal(jsonLog{App: f.app, Timestamp: l.Date, Source: l.Source, Unit: l.Unit, Message: l.Message})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%s\n", data)
+			continue
+		}
 		prefix := f.prefix(l)
 
 		if prefix == "" {
-			fmt.Fprintf(out, "%s\n", l.Message)
+			fmt.Fprintf(out, "%s%s\n", f.appPrefix(), l.Message)
 		} else {
-			fmt.Fprintf(out, "%s %s\n", cmd.Colorfy(prefix, "blue", "", ""), l.Message)
+			fmt.Fprintf(out, "%s%s %s\n", f.appPrefix(), cmd.Colorfy(prefix, "blue", "", ""), l.Message)
 		}
 	}
 	return nil
 }
 
+func (f logFormatter) appPrefix() string {
+	if f.app == "" {
+		return ""
+	}
+	name := "[" + f.app + "]"
+	if f.appColor != "" {
+		name = cmd.Colorfy(name, f.appColor, "", "bold")
+	}
+	return name + " "
+}
+
 func (f logFormatter) prefix(l log) string {
 	parts := make([]string, 0, 2)
 	if !f.noDate {
@@ -111,6 +178,15 @@ func (c *AppLog) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	var filter *regexp.Regexp
+	if c.grep != "" {
+		filter, err = regexp.Compile(c.grep)
+		if err != nil {
+			return fmt.Errorf("Invalid --grep pattern: %s", err)
+		}
+	} else if c.invert {
+		return errors.New("The --invert flag must be used with --grep.")
+	}
 	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/log?lines=%d", appName, c.lines))
 	if err != nil {
 		return err
@@ -121,8 +197,27 @@ func (c *AppLog) Run(context *cmd.Context, client *cmd.Client) error {
 	if c.unit != "" {
 		url = fmt.Sprintf("%s&unit=%s", url, c.unit)
 	}
+	formatter := logFormatter{
+		noDate:   c.noDate,
+		noSource: c.noSource,
+		filter:   filter,
+		invert:   c.invert,
+		json:     c.json || context.OutputFormat == cmd.JSONOutput,
+	}
 	if c.follow {
-		url += "&follow=1"
+		if c.unit != "" {
+			// following logs from a unit that doesn't exist would wait
+			// forever without output.
+			if err = checkUnit(appName, c.unit, client); err != nil {
+				return err
+			}
+		}
+		follower := newLogFollower(client, appName, formatter, context.Stdout, context.Stderr)
+		follower.lines = c.lines
+		follower.source = c.source
+		follower.unit = c.unit
+		follower.maxReconnects = c.maxReconnects
+		return follower.run(nil)
 	}
 	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -133,13 +228,13 @@ func (c *AppLog) Run(context *cmd.Context, client *cmd.Client) error {
 		return err
 	}
 	if response.StatusCode == http.StatusNoContent {
+		if c.unit != "" {
+			return checkUnit(appName, c.unit, client)
+		}
 		return nil
 	}
 	defer response.Body.Close()
-	w := tsuruIo.NewStreamWriter(context.Stdout, logFormatter{
-		noDate:   c.noDate,
-		noSource: c.noSource,
-	})
+	w := tsuruIo.NewStreamWriter(context.Stdout, formatter)
 	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(w, response.Body) {
 	}
 	unparsed := w.Remaining()
@@ -149,6 +244,20 @@ func (c *AppLog) Run(context *cmd.Context, client *cmd.Client) error {
 	return nil
 }
 
+// checkUnit returns an error if the app doesn't have the given unit.
+func checkUnit(appName, unitID string, client *cmd.Client) error {
+	a, err := getApp(client, appName)
+	if err != nil {
+		return err
+	}
+	for _, u := range a.Units {
+		if u.ID == unitID {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unit %q not found in app %q.", unitID, appName)
+}
+
 func (c *AppLog) Flags() *gnuflag.FlagSet {
 	if c.fs == nil {
 		c.fs = c.GuessingCommand.Flags()
@@ -160,8 +269,334 @@ func (c *AppLog) Flags() *gnuflag.FlagSet {
 		c.fs.StringVar(&c.unit, "u", "", "The log from the given unit")
 		c.fs.BoolVar(&c.follow, "follow", false, "Follow logs")
 		c.fs.BoolVar(&c.follow, "f", false, "Follow logs")
+		c.fs.IntVar(&c.maxReconnects, "max-reconnects", -1, "Maximum number of reconnections when following logs, negative for unlimited")
+		c.fs.BoolVar(&c.noDate, "no-date", false, "No date information")
+		c.fs.BoolVar(&c.noSource, "no-source", false, "No source information")
+		c.fs.StringVar(&c.grep, "grep", "", "Only display log entries matching the given regular expression")
+		c.fs.BoolVar(&c.invert, "invert", false, "Display log entries not matching the --grep expression")
+		c.fs.BoolVar(&c.invert, "v", false, "Display log entries not matching the --grep expression")
+		c.fs.BoolVar(&c.json, "json", false, "Display each log entry as a JSON object in its own line")
+	}
+	return c.fs
+}
+
+var (
+	logReconnectBackoff    = time.Second
+	logMaxReconnectBackoff = 30 * time.Second
+	// logResumeLines is the number of log lines requested when reconnecting,
+	// as the API can't send the entries after a given date. The entries
+	// already displayed are skipped.
+	logResumeLines = 100
+
+	logMultiColors = []string{"cyan", "green", "yellow", "magenta", "red", "blue"}
+)
+
+type AppLogMulti struct {
+	fs       *gnuflag.FlagSet
+	apps     cmd.StringSliceFlag
+	source   string
+	lines    int
+	noDate   bool
+	noSource bool
+	grep     string
+	invert   bool
+	json     bool
+	color    bool
+}
+
+func (c *AppLogMulti) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "app-log-multi",
+		Usage: "app-log-multi -a/--app appname [-a/--app appname]... [-l/--lines numberOfLines] [-s/--source source] [--no-date] [--no-source] [--grep pattern [-v/--invert]] [--json] [--color]",
+		Desc: `Follows the log entries of many applications at once, in a single stream.
+Each entry is prefixed with the name of its application. The command runs until
+interrupted with Ctrl-C.
+
+The [[--app]] flag is required and may be used multiple times, once for each
+application. The [[--lines]] flag is the number of log lines of each
+application displayed before following them, by default 10.
+
+The [[--source]], [[--no-date]], [[--no-source]], [[--grep]], [[--invert]] and
+[[--json]] flags work like in [[tsuru app-log]]. In JSON, the application is in
+the app field of each entry.
+
+When the connection of an application is lost, it's reestablished after a
+delay that doubles at each failed attempt, up to 30 seconds. The logs of an
+application are not followed when the first connection fails or when they
+can't be read, like when the application doesn't exist, and the command fails
+when no application is left.
+
+The [[--color]] flag displays the name of each application in a different
+color.`,
+		MinArgs: 0,
+		MaxArgs: 0,
+	}
+}
+
+func (c *AppLogMulti) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("app-log-multi", gnuflag.ExitOnError)
+		app := "The name of an app whose logs are followed"
+		c.fs.Var(&c.apps, "app", app)
+		c.fs.Var(&c.apps, "a", app)
+		c.fs.IntVar(&c.lines, "lines", 10, "The number of log lines of each app to display")
+		c.fs.IntVar(&c.lines, "l", 10, "The number of log lines of each app to display")
+		c.fs.StringVar(&c.source, "source", "", "The log from the given source")
+		c.fs.StringVar(&c.source, "s", "", "The log from the given source")
 		c.fs.BoolVar(&c.noDate, "no-date", false, "No date information")
 		c.fs.BoolVar(&c.noSource, "no-source", false, "No source information")
+		c.fs.StringVar(&c.grep, "grep", "", "Only display log entries matching the given regular expression")
+		c.fs.BoolVar(&c.invert, "invert", false, "Display log entries not matching the --grep expression")
+		c.fs.BoolVar(&c.invert, "v", false, "Display log entries not matching the --grep expression")
+		c.fs.BoolVar(&c.json, "json", false, "Display each log entry as a JSON object in its own line")
+		c.fs.BoolVar(&c.color, "color", false, "Display the name of each app in a different color")
 	}
 	return c.fs
 }
+
+func (c *AppLogMulti) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	if len(c.apps) == 0 {
+		return errors.New("Please provide at least one app with the --app flag.")
+	}
+	var filter *regexp.Regexp
+	if c.grep != "" {
+		var err error
+		filter, err = regexp.Compile(c.grep)
+		if err != nil {
+			return fmt.Errorf("Invalid --grep pattern: %s", err)
+		}
+	} else if c.invert {
+		return errors.New("The --invert flag must be used with --grep.")
+	}
+	stdout := &safeWriter{w: context.Stdout}
+	stderr := &safeWriter{w: context.Stderr}
+	interrupt := make(chan os.Signal, 1)
+	notifyInterrupt(interrupt)
+	defer stopInterrupt(interrupt)
+	stop := make(chan struct{})
+	defer close(stop)
+	errs := make(chan error, len(c.apps))
+	for i, appName := range c.apps {
+		formatter := logFormatter{
+			noDate:   c.noDate,
+			noSource: c.noSource,
+			filter:   filter,
+			invert:   c.invert,
+			json:     c.json || context.OutputFormat == cmd.JSONOutput,
+			app:      appName,
+		}
+		if c.color {
+			formatter.appColor = logMultiColors[i%len(logMultiColors)]
+		}
+		// the logs are followed concurrently, so each app uses its own
+		// copy of the client.
+		appClient := *client
+		follower := newLogFollower(&appClient, appName, formatter, stdout, stderr)
+		follower.lines = c.lines
+		follower.source = c.source
+		go func(appName string) {
+			if err := follower.run(stop); err != nil {
+				errs <- fmt.Errorf("Stopped following the logs of app %q: %s", appName, strings.TrimSpace(err.Error()))
+			}
+		}(appName)
+	}
+	var failed int
+	for range c.apps {
+		select {
+		case <-interrupt:
+			return nil
+		case err := <-errs:
+			failed++
+			fmt.Fprintln(stderr, err)
+		}
+	}
+	return fmt.Errorf("Failed to follow the logs of %d app(s).", failed)
+}
+
+// logFollower follows the logs of an app, reconnecting with an increasing
+// delay when the connection is lost.
+type logFollower struct {
+	client    *cmd.Client
+	appName   string
+	lines     int
+	source    string
+	unit      string
+	formatter logFormatter
+	stdout    io.Writer
+	stderr    io.Writer
+	// maxReconnects is the maximum number of reconnections, unlimited
+	// when negative.
+	maxReconnects int
+	// backoff is the delay before the first reconnection, doubled at each
+	// attempt up to maxBackoff.
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+func newLogFollower(client *cmd.Client, appName string, formatter logFormatter, stdout, stderr io.Writer) *logFollower {
+	return &logFollower{
+		client:        client,
+		appName:       appName,
+		formatter:     formatter,
+		stdout:        stdout,
+		stderr:        stderr,
+		maxReconnects: -1,
+		backoff:       logReconnectBackoff,
+		maxBackoff:    logMaxReconnectBackoff,
+	}
+}
+
+// run streams the logs of the app until stop is closed or the number of
+// reconnections reaches maxReconnects. Failures of the first connection and
+// errors of the client, like an app that doesn't exist, are returned
+// without reconnecting.
+func (f *logFollower) run(stop <-chan struct{}) error {
+	resume := &logResume{}
+	formatter := appLogFormatter{logFormatter: f.formatter, resume: resume}
+	backoff := f.backoff
+	var connectedOnce bool
+	for reconnects := 0; ; reconnects++ {
+		lines := f.lines
+		if reconnects > 0 && resume.restart() && lines < logResumeLines {
+			lines = logResumeLines
+		}
+		connected, err := f.stream(lines, formatter)
+		if err != nil && !connected && !connectedOnce {
+			return err
+		}
+		if httpErr, ok := err.(*tsuruerr.HTTP); ok && httpErr.Code < http.StatusInternalServerError {
+			return err
+		}
+		if connected {
+			connectedOnce = true
+			backoff = f.backoff
+		}
+		if f.maxReconnects >= 0 && reconnects >= f.maxReconnects {
+			if reconnects == 0 {
+				return nil
+			}
+			return fmt.Errorf("Lost the connection to the logs of app %q after %d reconnection(s).", f.appName, reconnects)
+		}
+		if err != nil {
+			fmt.Fprintf(f.stderr, "Lost the connection to the logs of app %q: %s. Reconnecting in %s.\n", f.appName, err, backoff)
+		} else {
+			fmt.Fprintf(f.stderr, "The logs of app %q were disconnected. Reconnecting in %s.\n", f.appName, backoff)
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > f.maxBackoff {
+			backoff = f.maxBackoff
+		}
+	}
+}
+
+// stream copies the logs of the app to stdout until the connection ends,
+// returning whether the server accepted the request.
+func (f *logFollower) stream(lines int, formatter tsuruIo.Formatter) (bool, error) {
+	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s/log?lines=%d", f.appName, lines))
+	if err != nil {
+		return false, err
+	}
+	if f.source != "" {
+		u = fmt.Sprintf("%s&source=%s", u, f.source)
+	}
+	if f.unit != "" {
+		u = fmt.Sprintf("%s&unit=%s", u, f.unit)
+	}
+	u += "&follow=1"
+	request, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return false, err
+	}
+	response, err := f.client.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	w := tsuruIo.NewStreamWriter(f.stdout, formatter)
+	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(w, response.Body) {
+	}
+	if unparsed := w.Remaining(); len(unparsed) > 0 {
+		fmt.Fprintf(f.stdout, "Error: %s", string(unparsed))
+	}
+	return true, err
+}
+
+// appLogFormatter formats the logs of an app followed by logFollower,
+// skipping the entries already displayed before a reconnection.
+type appLogFormatter struct {
+	logFormatter
+	resume *logResume
+}
+
+func (f appLogFormatter) Format(out io.Writer, data []byte) error {
+	var logs []log
+	err := json.Unmarshal(data, &logs)
+	if err != nil {
+		return tsuruIo.ErrInvalidStreamChunk
+	}
+	newLogs := logs[:0]
+	for _, l := range logs {
+		if !f.resume.skip(l) {
+			newLogs = append(newLogs, l)
+		}
+	}
+	return f.write(out, newLogs)
+}
+
+type logKey struct {
+	source, unit, message string
+}
+
+// logResume tracks the entries received by a logFollower, so the ones sent
+// again by the server after a reconnection are not displayed twice. Many
+// entries may share the same date, so the entries received with the date of
+// the last one are counted by their contents.
+type logResume struct {
+	last time.Time
+	seen map[logKey]int
+	// resuming is set after a reconnection, until an entry newer than the
+	// last one received before it arrives.
+	resuming bool
+	replayed map[logKey]int
+}
+
+// restart prepares the tracking for a new connection, returning whether any
+// entry was received before it.
+func (r *logResume) restart() bool {
+	r.resuming = r.seen != nil
+	r.replayed = map[logKey]int{}
+	return r.resuming
+}
+
+// skip records the entry, returning whether it was already received before
+// the last reconnection.
+func (r *logResume) skip(l log) bool {
+	key := logKey{source: l.Source, unit: l.Unit, message: l.Message}
+	if r.resuming {
+		switch {
+		case l.Date.Before(r.last):
+			return true
+		case l.Date.Equal(r.last):
+			r.replayed[key]++
+			if r.replayed[key] <= r.seen[key] {
+				return true
+			}
+		default:
+			r.resuming = false
+		}
+	}
+	if r.seen == nil || l.Date.After(r.last) {
+		r.last = l.Date
+		r.seen = map[logKey]int{}
+	}
+	if l.Date.Equal(r.last) {
+		r.seen[key]++
+	}
+	return false
+}
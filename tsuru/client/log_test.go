@@ -7,11 +7,15 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"gopkg.in/check.v1"
 )
@@ -181,6 +185,139 @@ func (s *S) TestAppLogBySource(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+func (s *S) TestAppLogGrep(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	t := time.Now()
+	logs := []log{
+		{Date: t, Message: "GET /health 200", Source: "app", Unit: "abc"},
+		{Date: t, Message: "GET /users 500", Source: "app", Unit: "abc"},
+		{Date: t, Message: "POST /users 500", Source: "app", Unit: "abc"},
+	}
+	result, err := json.Marshal(logs)
+	c.Assert(err, check.IsNil)
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	fake := &cmdtest.FakeGuesser{Name: "hitthelights"}
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: fake}}
+	command.Flags().Parse(true, []string{"--grep", " 5\\d\\d$", "--no-date", "--no-source"})
+	trans := &cmdtest.Transport{Message: string(result), Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "GET /users 500\nPOST /users 500\n")
+	stdout.Reset()
+	command = AppLog{GuessingCommand: cmd.GuessingCommand{G: fake}}
+	command.Flags().Parse(true, []string{"--grep", "users", "-v", "--no-date", "--no-source"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "GET /health 200\n")
+}
+
+func (s *S) TestAppLogJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	t := time.Date(2016, 5, 10, 13, 20, 5, 0, time.UTC)
+	logs := []log{
+		{Date: t, Message: "starting", Source: "tsuru", Unit: ""},
+		{Date: t.Add(time.Second), Message: "GET / 200", Source: "app", Unit: "abc"},
+	}
+	result, err := json.Marshal(logs)
+	c.Assert(err, check.IsNil)
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	fake := &cmdtest.FakeGuesser{Name: "hitthelights"}
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: fake}}
+	command.Flags().Parse(true, []string{"--json"})
+	trans := &cmdtest.Transport{Message: string(result), Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `{"timestamp":"2016-05-10T13:20:05Z","source":"tsuru","unit":"","message":"starting"}
+{"timestamp":"2016-05-10T13:20:06Z","source":"app","unit":"abc","message":"GET / 200"}
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppLogGrepInvalidPattern(c *check.C) {
+	context := cmd.Context{
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	fake := &cmdtest.FakeGuesser{Name: "hitthelights"}
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: fake}}
+	command.Flags().Parse(true, []string{"--grep", "a("})
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{}}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "Invalid --grep pattern: .*")
+}
+
+func (s *S) TestAppLogInvertWithoutGrep(c *check.C) {
+	context := cmd.Context{
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	fake := &cmdtest.FakeGuesser{Name: "hitthelights"}
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: fake}}
+	command.Flags().Parse(true, []string{"--invert"})
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{}}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "The --invert flag must be used with --grep.")
+}
+
+func (s *S) TestAppLogByUnitNotFound(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	fake := &cmdtest.FakeGuesser{Name: "hitthelights"}
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: fake}}
+	command.Flags().Parse(true, []string{"--unit", "abc"})
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Status: http.StatusNoContent},
+				CondFunc: func(req *http.Request) bool {
+					return req.URL.Query().Get("unit") == "abc"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `{"name":"hitthelights","units":[{"ID":"def"}]}`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return strings.HasSuffix(req.URL.Path, "/apps/hitthelights")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Unit "abc" not found in app "hitthelights".`)
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
+func (s *S) TestAppLogFollowByUnitNotFound(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	fake := &cmdtest.FakeGuesser{Name: "hitthelights"}
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: fake}}
+	command.Flags().Parse(true, []string{"--unit", "abc", "-f", "-s", "app"})
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `{"name":"hitthelights","units":[{"ID":"def"}]}`, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return strings.HasSuffix(req.URL.Path, "/apps/hitthelights")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Unit "abc" not found in app "hitthelights".`)
+}
+
 func (s *S) TestAppLogByUnit(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	t := time.Now()
@@ -264,7 +401,7 @@ func (s *S) TestAppLogWithFollow(c *check.C) {
 	}
 	fake := &cmdtest.FakeGuesser{Name: "hitthelights"}
 	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: fake}}
-	command.Flags().Parse(true, []string{"--lines", "12", "-f"})
+	command.Flags().Parse(true, []string{"--lines", "12", "-f", "--max-reconnects", "0"})
 	trans := &cmdtest.ConditionalTransport{
 		Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
 		CondFunc: func(req *http.Request) bool {
@@ -295,7 +432,7 @@ func (s *S) TestAppLogWithNoDateAndNoSource(c *check.C) {
 	}
 	fake := &cmdtest.FakeGuesser{Name: "hitthelights"}
 	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: fake}}
-	command.Flags().Parse(true, []string{"--lines", "12", "-f", "--no-date", "--no-source"})
+	command.Flags().Parse(true, []string{"--lines", "12", "-f", "--no-date", "--no-source", "--max-reconnects", "0"})
 	trans := &cmdtest.ConditionalTransport{
 		Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
 		CondFunc: func(req *http.Request) bool {
@@ -327,7 +464,7 @@ func (s *S) TestAppLogWithNoSource(c *check.C) {
 	}
 	fake := &cmdtest.FakeGuesser{Name: "hitthelights"}
 	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: fake}}
-	command.Flags().Parse(true, []string{"--lines", "12", "-f", "--no-source"})
+	command.Flags().Parse(true, []string{"--lines", "12", "-f", "--no-source", "--max-reconnects", "0"})
 	trans := &cmdtest.ConditionalTransport{
 		Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
 		CondFunc: func(req *http.Request) bool {
@@ -340,6 +477,36 @@ func (s *S) TestAppLogWithNoSource(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+func (s *S) TestAppLogWithNoDate(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	t := time.Now()
+	logs := []log{
+		{Date: t, Message: "GET /", Source: "web", Unit: "abcdef"},
+		{Date: t.Add(2 * time.Hour), Message: "POST /", Source: "web"},
+	}
+	result, err := json.Marshal(logs)
+	c.Assert(err, check.IsNil)
+	expected := cmd.Colorfy("[web][abcdef]:", "blue", "", "") + " GET /\n"
+	expected = expected + cmd.Colorfy("[web]:", "blue", "", "") + " POST /\n"
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	fake := &cmdtest.FakeGuesser{Name: "hitthelights"}
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: fake}}
+	command.Flags().Parse(true, []string{"--source", "web", "--no-date"})
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.URL.Query().Get("source") == "web" && req.URL.Query().Get("lines") == "10"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
 func (s *S) TestAppLogFlagSet(c *check.C) {
 	command := AppLog{}
 	flagset := command.Flags()
@@ -417,3 +584,273 @@ func (s *S) TestAppLogFlagSet(c *check.C) {
 	c.Check(noSource.Value.String(), check.Equals, "true")
 	c.Check(noSource.DefValue, check.Equals, "false")
 }
+
+// logMultiTransport answers the log requests of each app with the bodies in
+// responses, in order, and with 404 when they run out.
+func logMultiTransport(c *check.C, responses map[string][][]log, requests map[string][]string) transportFunc {
+	var mu sync.Mutex
+	return transportFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		appName := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/1.0/apps/"), "/log")
+		requests[appName] = append(requests[appName], req.URL.RawQuery)
+		if len(responses[appName]) == 0 {
+			return &http.Response{
+				Body:       ioutil.NopCloser(strings.NewReader("App not found.\n")),
+				StatusCode: http.StatusNotFound,
+			}, nil
+		}
+		data, err := json.Marshal(responses[appName][0])
+		c.Assert(err, check.IsNil)
+		responses[appName] = responses[appName][1:]
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewReader(data)),
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+}
+
+func (s *S) TestAppLogFollowReconnects(c *check.C) {
+	defer func(d time.Duration) { logReconnectBackoff = d }(logReconnectBackoff)
+	logReconnectBackoff = time.Millisecond
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	t := time.Now()
+	responses := map[string][][]log{
+		"app1": {
+			{{Date: t, Message: "first", Source: "app"}, {Date: t.Add(time.Second), Message: "second", Source: "app"}},
+			{{Date: t.Add(time.Second), Message: "second", Source: "app"}, {Date: t.Add(2 * time.Second), Message: "third", Source: "app"}},
+			{},
+		},
+	}
+	requests := map[string][]string{}
+	client := cmd.NewClient(&http.Client{Transport: logMultiTransport(c, responses, requests)}, nil, manager)
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "app1"}}}
+	command.Flags().Parse(true, []string{"-f", "--no-date", "--no-source", "-s", "app", "--max-reconnects", "2"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Lost the connection to the logs of app "app1" after 2 reconnection\(s\).`)
+	c.Assert(stdout.String(), check.Equals, "first\nsecond\nthird\n")
+	c.Assert(requests["app1"], check.DeepEquals, []string{
+		"lines=10&source=app&follow=1",
+		"lines=100&source=app&follow=1",
+		"lines=100&source=app&follow=1",
+	})
+	c.Assert(stderr.String(), check.Equals, `The logs of app "app1" were disconnected. Reconnecting in 1ms.
+The logs of app "app1" were disconnected. Reconnecting in 1ms.
+`)
+}
+
+func (s *S) TestAppLogFollowShowsEntriesWithTheSameDate(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	t := time.Now()
+	responses := map[string][][]log{
+		"app1": {
+			{{Date: t, Message: "first", Source: "app"}, {Date: t, Message: "second", Source: "app"}, {Date: t, Message: "second", Source: "app"}},
+		},
+	}
+	requests := map[string][]string{}
+	client := cmd.NewClient(&http.Client{Transport: logMultiTransport(c, responses, requests)}, nil, manager)
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "app1"}}}
+	command.Flags().Parse(true, []string{"-f", "--no-date", "--no-source", "--max-reconnects", "0"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "first\nsecond\nsecond\n")
+}
+
+func (s *S) TestAppLogFollowReconnectSkipsOnlyDisplayedEntries(c *check.C) {
+	defer func(d time.Duration) { logReconnectBackoff = d }(logReconnectBackoff)
+	logReconnectBackoff = time.Millisecond
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	t := time.Now()
+	responses := map[string][][]log{
+		"app1": {
+			{
+				{Date: t, Message: "first", Source: "app"},
+				{Date: t.Add(time.Second), Message: "second", Source: "app", Unit: "u1"},
+			},
+			{
+				{Date: t, Message: "first", Source: "app"},
+				{Date: t.Add(time.Second), Message: "second", Source: "app", Unit: "u1"},
+				{Date: t.Add(time.Second), Message: "second", Source: "app", Unit: "u2"},
+				{Date: t.Add(time.Second), Message: "lost", Source: "app", Unit: "u1"},
+				{Date: t.Add(2 * time.Second), Message: "third", Source: "app", Unit: "u1"},
+			},
+		},
+	}
+	requests := map[string][]string{}
+	client := cmd.NewClient(&http.Client{Transport: logMultiTransport(c, responses, requests)}, nil, manager)
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "app1"}}}
+	command.Flags().Parse(true, []string{"-f", "--no-date", "-l", "2"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "App not found.\n")
+	c.Assert(stdout.String(), check.Equals, cmd.Colorfy("[app]:", "blue", "", "")+" first\n"+
+		cmd.Colorfy("[app][u1]:", "blue", "", "")+" second\n"+
+		cmd.Colorfy("[app][u2]:", "blue", "", "")+" second\n"+
+		cmd.Colorfy("[app][u1]:", "blue", "", "")+" lost\n"+
+		cmd.Colorfy("[app][u1]:", "blue", "", "")+" third\n")
+	c.Assert(requests["app1"], check.DeepEquals, []string{"lines=2&follow=1", "lines=100&follow=1", "lines=100&follow=1"})
+}
+
+func (s *S) TestAppLogFollowReconnectWithoutEntriesKeepsTheLines(c *check.C) {
+	defer func(d time.Duration) { logReconnectBackoff = d }(logReconnectBackoff)
+	logReconnectBackoff = time.Millisecond
+	context := cmd.Context{
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	responses := map[string][][]log{"app1": {{}}}
+	requests := map[string][]string{}
+	client := cmd.NewClient(&http.Client{Transport: logMultiTransport(c, responses, requests)}, nil, manager)
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "app1"}}}
+	command.Flags().Parse(true, []string{"-f", "-l", "5"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "App not found.\n")
+	c.Assert(requests["app1"], check.DeepEquals, []string{"lines=5&follow=1", "lines=5&follow=1"})
+}
+
+func (s *S) TestAppLogFollowReconnectsUntilClientError(c *check.C) {
+	defer func(d time.Duration) { logReconnectBackoff = d }(logReconnectBackoff)
+	logReconnectBackoff = time.Millisecond
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	responses := map[string][][]log{
+		"app1": {{{Date: time.Now(), Message: "first", Source: "app"}}},
+	}
+	requests := map[string][]string{}
+	client := cmd.NewClient(&http.Client{Transport: logMultiTransport(c, responses, requests)}, nil, manager)
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "app1"}}}
+	command.Flags().Parse(true, []string{"-f", "--no-date", "--no-source"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "App not found.\n")
+	c.Assert(stdout.String(), check.Equals, "first\n")
+	c.Assert(requests["app1"], check.HasLen, 2)
+}
+
+func (s *S) TestAppLogFollowFirstConnectionFails(c *check.C) {
+	context := cmd.Context{
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	trans := &cmdtest.Transport{Message: "internal error", Status: http.StatusInternalServerError}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppLog{GuessingCommand: cmd.GuessingCommand{G: &cmdtest.FakeGuesser{Name: "app1"}}}
+	command.Flags().Parse(true, []string{"-f"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "internal error")
+}
+
+func (s *S) TestAppLogMultiInfo(c *check.C) {
+	c.Assert((&AppLogMulti{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAppLogMultiRun(c *check.C) {
+	defer func(d time.Duration) { logReconnectBackoff = d }(logReconnectBackoff)
+	logReconnectBackoff = time.Millisecond
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	t := time.Now()
+	responses := map[string][][]log{
+		"app1": {{{Date: t, Message: "app1 started", Source: "app"}}},
+		"app2": {{{Date: t, Message: "app2 started", Source: "app"}}},
+	}
+	requests := map[string][]string{}
+	client := cmd.NewClient(&http.Client{Transport: logMultiTransport(c, responses, requests)}, nil, manager)
+	command := AppLogMulti{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--app", "app2", "--no-date", "-l", "5"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Failed to follow the logs of 2 app\(s\).`)
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	sort.Strings(lines)
+	c.Assert(lines, check.DeepEquals, []string{
+		"[app1] " + cmd.Colorfy("[app]:", "blue", "", "") + " app1 started",
+		"[app2] " + cmd.Colorfy("[app]:", "blue", "", "") + " app2 started",
+	})
+	c.Assert(requests["app1"], check.DeepEquals, []string{"lines=5&follow=1", "lines=100&follow=1"})
+	c.Assert(strings.Contains(stderr.String(), `The logs of app "app1" were disconnected. Reconnecting in 1ms.`), check.Equals, true)
+	c.Assert(strings.Contains(stderr.String(), `Stopped following the logs of app "app2": App not found.`), check.Equals, true)
+}
+
+func (s *S) TestAppLogMultiRunSkipsDisplayedEntriesAfterReconnecting(c *check.C) {
+	defer func(d time.Duration) { logReconnectBackoff = d }(logReconnectBackoff)
+	logReconnectBackoff = time.Millisecond
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	t := time.Now()
+	responses := map[string][][]log{
+		"app1": {
+			{{Date: t, Message: "first", Source: "app"}, {Date: t.Add(time.Second), Message: "second", Source: "app"}},
+			{{Date: t.Add(time.Second), Message: "second", Source: "app"}, {Date: t.Add(2 * time.Second), Message: "third", Source: "app"}},
+		},
+	}
+	requests := map[string][]string{}
+	client := cmd.NewClient(&http.Client{Transport: logMultiTransport(c, responses, requests)}, nil, manager)
+	command := AppLogMulti{}
+	command.Flags().Parse(true, []string{"-a", "app1", "--json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Failed to follow the logs of 1 app\(s\).`)
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		var entry map[string]interface{}
+		err = json.Unmarshal([]byte(line), &entry)
+		c.Assert(err, check.IsNil)
+		c.Assert(entry["app"], check.Equals, "app1")
+		messages = append(messages, entry["message"].(string))
+	}
+	c.Assert(messages, check.DeepEquals, []string{"first", "second", "third"})
+}
+
+func (s *S) TestAppLogMultiRunInterrupted(c *check.C) {
+	defer func(notify, stop func(chan<- os.Signal)) {
+		notifyInterrupt, stopInterrupt = notify, stop
+	}(notifyInterrupt, stopInterrupt)
+	notifyInterrupt = func(ch chan<- os.Signal) { ch <- os.Interrupt }
+	stopInterrupt = func(chan<- os.Signal) {}
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	defer func(d time.Duration) { logReconnectBackoff = d }(logReconnectBackoff)
+	logReconnectBackoff = time.Millisecond
+	trans := &cmdtest.Transport{Message: "[]", Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppLogMulti{}
+	command.Flags().Parse(true, []string{"-a", "app1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestAppLogMultiRunWithoutApps(c *check.C) {
+	command := AppLogMulti{}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&cmd.Context{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}, nil)
+	c.Assert(err, check.ErrorMatches, "Please provide at least one app with the --app flag.")
+}
+
+func (s *S) TestLogFormatterAppColor(c *check.C) {
+	var buf bytes.Buffer
+	f := logFormatter{noDate: true, noSource: true, app: "app1", appColor: "green"}
+	err := f.write(&buf, []log{{Message: "hello"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, cmd.Colorfy("[app1]", "green", "", "bold")+" hello\n")
+}
@@ -14,7 +14,7 @@ import (
 	"strings"
 
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/permission"
 )
 
@@ -246,7 +246,7 @@ func (c *RoleAdd) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "Role successfully created!\n")
+	context.Successf("Role successfully created!\n")
 	return nil
 }
 
@@ -323,7 +323,7 @@ func (c *RolePermissionAdd) Run(context *cmd.Context, client *cmd.Client) error
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "Permission successfully added!\n")
+	context.Successf("Permission successfully added!\n")
 	return nil
 }
 
@@ -353,7 +353,7 @@ func (c *RolePermissionRemove) Run(context *cmd.Context, client *cmd.Client) err
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "Permission successfully removed!\n")
+	context.Successf("Permission successfully removed!\n")
 	return nil
 }
 
@@ -375,6 +375,15 @@ func (c *RoleAssign) Run(context *cmd.Context, client *cmd.Client) error {
 	if len(context.Args) > 2 {
 		contextValue = context.Args[2]
 	}
+	err := assignRole(client, roleName, userEmail, contextValue)
+	if err != nil {
+		return err
+	}
+	context.Successf("Role successfully assigned!\n")
+	return nil
+}
+
+func assignRole(client *cmd.Client, roleName, userEmail, contextValue string) error {
 	params := url.Values{}
 	params.Set("email", userEmail)
 	params.Set("context", contextValue)
@@ -388,11 +397,7 @@ func (c *RoleAssign) Run(context *cmd.Context, client *cmd.Client) error {
 	}
 	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	_, err = client.Do(request)
-	if err != nil {
-		return err
-	}
-	fmt.Fprintf(context.Stdout, "Role successfully assigned!\n")
-	return nil
+	return err
 }
 
 type RoleDissociate struct{}
@@ -428,7 +433,7 @@ func (c *RoleDissociate) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "Role successfully dissociated!\n")
+	context.Successf("Role successfully dissociated!\n")
 	return nil
 }
 
@@ -462,7 +467,7 @@ func (c *RoleRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "Role successfully removed!\n")
+	context.Successf("Role successfully removed!\n")
 	return nil
 }
 
@@ -522,7 +527,7 @@ func (c *RoleDefaultAdd) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "Roles successfully added as default!\n")
+	context.Successf("Roles successfully added as default!\n")
 	return nil
 }
 
@@ -581,7 +586,7 @@ func (c *RoleDefaultRemove) Run(context *cmd.Context, client *cmd.Client) error
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, "Roles successfully removed as default!\n")
+	context.Successf("Roles successfully removed as default!\n")
 	return nil
 }
 
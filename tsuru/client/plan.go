@@ -11,8 +11,8 @@ import (
 	"strconv"
 
 	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	tsuruapp "github.com/tsuru/tsuru/app"
-	"github.com/tsuru/tsuru/cmd"
 )
 
 type PlanList struct {
@@ -11,7 +11,7 @@ import (
 	"net/http"
 	"os"
 
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/exec"
 )
 
@@ -55,7 +55,7 @@ func (c *PluginInstall) Run(context *cmd.Context, client *cmd.Client) error {
 	if n != len(data) {
 		return errors.New("Failed to install plugin.")
 	}
-	fmt.Fprintf(context.Stdout, `Plugin "%s" successfully installed!`+"\n", pluginName)
+	context.Successf(`Plugin "%s" successfully installed!`+"\n", pluginName)
 	return nil
 }
 
@@ -77,7 +77,7 @@ func (c *PluginRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(context.Stdout, `Plugin "%s" successfully removed!`+"\n", pluginName)
+	context.Successf(`Plugin "%s" successfully removed!`+"\n", pluginName)
 	return nil
 }
 
@@ -10,7 +10,7 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 )
 
 type PoolList struct{}
@@ -52,22 +52,30 @@ func (l poolEntriesList) Less(i, j int) bool {
 	return cmp < 0
 }
 
-func (PoolList) Run(context *cmd.Context, client *cmd.Client) error {
+func getPools(client *cmd.Client) ([]Pool, error) {
 	url, err := cmd.GetURL("/pools")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	resp, err := client.Do(request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	var pools []Pool
 	err = json.NewDecoder(resp.Body).Decode(&pools)
+	if err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+func (PoolList) Run(context *cmd.Context, client *cmd.Client) error {
+	pools, err := getPools(client)
 	if err != nil {
 		return err
 	}
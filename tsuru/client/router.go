@@ -0,0 +1,214 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+)
+
+type AppRoutersList struct {
+	cmd.GuessingCommand
+	json bool
+	fs   *gnuflag.FlagSet
+}
+
+func (c *AppRoutersList) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "app-router-list",
+		Usage: "app-router-list [-a/--app appname] [--json]",
+		Desc: `Lists the routers of an application, with their addresses and options.
+
+The [[--json]] flag displays the routers in JSON format.`,
+		MinArgs: 0,
+	}
+}
+
+func (c *AppRoutersList) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		c.fs.BoolVar(&c.json, "json", false, "Display the routers in JSON format")
+	}
+	return c.fs
+}
+
+func (c *AppRoutersList) Run(context *cmd.Context, client *cmd.Client) error {
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	return renderAppRouters(context, client, appName, c.json)
+}
+
+type AppRoutersAdd struct {
+	cmd.GuessingCommand
+	opts cmd.MapFlag
+	json bool
+	fs   *gnuflag.FlagSet
+}
+
+func (c *AppRoutersAdd) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "app-router-add",
+		Usage: "app-router-add <router> [-a/--app appname] [-o/--opts key=value]... [--json]",
+		Desc: `Adds a new router to an application. The [[--opts]] parameter sets an option
+of the router and may be used multiple times.
+
+After adding the router, the routers of the application are listed. The
+[[--json]] flag displays them in JSON format.`,
+		MinArgs: 1,
+		MaxArgs: 1,
+	}
+}
+
+func (c *AppRoutersAdd) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		optsMessage := "Router option, in the form key=value"
+		c.fs.Var(&c.opts, "opts", optsMessage)
+		c.fs.Var(&c.opts, "o", optsMessage)
+		c.fs.BoolVar(&c.json, "json", false, "Display the routers in JSON format")
+	}
+	return c.fs
+}
+
+func (c *AppRoutersAdd) Run(context *cmd.Context, client *cmd.Client) error {
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	routerName := context.Args[0]
+	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s/routers", appName))
+	if err != nil {
+		return err
+	}
+	v := url.Values{}
+	v.Set("name", routerName)
+	for key, value := range c.opts {
+		v.Set("opts."+key, value)
+	}
+	request, err := http.NewRequest("POST", u, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = client.Do(request)
+	if err != nil {
+		return err
+	}
+	if !c.json && !context.StructuredOutput() {
+		context.Successf("Router %q successfully added to app %q.\n", routerName, appName)
+	}
+	return renderAppRouters(context, client, appName, c.json)
+}
+
+type AppRoutersRemove struct {
+	cmd.GuessingCommand
+	json bool
+	fs   *gnuflag.FlagSet
+}
+
+func (c *AppRoutersRemove) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "app-router-remove",
+		Usage: "app-router-remove <router> [-a/--app appname] [--json]",
+		Desc: `Removes a router from an application.
+
+After removing the router, the remaining routers of the application are
+listed. The [[--json]] flag displays them in JSON format.`,
+		MinArgs: 1,
+		MaxArgs: 1,
+	}
+}
+
+func (c *AppRoutersRemove) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		c.fs.BoolVar(&c.json, "json", false, "Display the routers in JSON format")
+	}
+	return c.fs
+}
+
+func (c *AppRoutersRemove) Run(context *cmd.Context, client *cmd.Client) error {
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	routerName := context.Args[0]
+	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s/routers/%s", appName, routerName))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+	_, err = client.Do(request)
+	if err != nil {
+		return err
+	}
+	if !c.json && !context.StructuredOutput() {
+		context.Successf("Router %q successfully removed from app %q.\n", routerName, appName)
+	}
+	return renderAppRouters(context, client, appName, c.json)
+}
+
+func getAppRouters(client *cmd.Client, appName string) ([]appRouter, error) {
+	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s/routers", appName))
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	routers := []appRouter{}
+	if response.StatusCode == http.StatusNoContent {
+		return routers, nil
+	}
+	err = json.NewDecoder(response.Body).Decode(&routers)
+	if err != nil {
+		return nil, err
+	}
+	return routers, nil
+}
+
+func renderAppRouters(context *cmd.Context, client *cmd.Client, appName string, asJSON bool) error {
+	routers, err := getAppRouters(client, appName)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		context.OutputFormat = cmd.JSONOutput
+	}
+	if context.StructuredOutput() {
+		return context.WriteStructured(routers)
+	}
+	table := cmd.NewTable()
+	table.Headers = cmd.Row{"Name", "Address", "Opts"}
+	table.LineSeparator = true
+	for _, r := range routers {
+		opts := make([]string, 0, len(r.Opts))
+		for key, value := range r.Opts {
+			opts = append(opts, fmt.Sprintf("%s: %s", key, value))
+		}
+		sort.Strings(opts)
+		table.AddRow(cmd.Row{r.Name, r.Address, strings.Join(opts, "\n")})
+	}
+	context.Stdout.Write(table.Bytes())
+	return nil
+}
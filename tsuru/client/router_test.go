@@ -0,0 +1,151 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	"github.com/tsuru/tsuru/cmd/cmdtest"
+	"gopkg.in/check.v1"
+)
+
+var routersResult = `[{"name":"galeb","address":"myapp.galeb.com","opts":{"domain":"example.com","https":"true"}},{"name":"hipache","address":"myapp.hipache.com"}]`
+
+func (s *S) TestAppRoutersListInfo(c *check.C) {
+	c.Assert((&AppRoutersList{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAppRoutersListRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: routersResult, Status: http.StatusOK},
+		CondFunc: func(r *http.Request) bool {
+			return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/apps/myapp/routers")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRoutersList{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `+---------+-------------------+---------------------+
+| Name    | Address           | Opts                |
++---------+-------------------+---------------------+
+| galeb   | myapp.galeb.com   | domain: example.com |
+|         |                   | https: true         |
++---------+-------------------+---------------------+
+| hipache | myapp.hipache.com |                     |
++---------+-------------------+---------------------+
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppRoutersListRunJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.Transport{Message: routersResult, Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRoutersList{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `[
+  {
+    "name": "galeb",
+    "opts": {
+      "domain": "example.com",
+      "https": "true"
+    },
+    "address": "myapp.galeb.com"
+  },
+  {
+    "name": "hipache",
+    "address": "myapp.hipache.com"
+  }
+]
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppRoutersAddRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"galeb"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/apps/myapp/routers") &&
+						r.FormValue("name") == "galeb" && r.FormValue("opts.domain") == "example.com" &&
+						r.FormValue("opts.https") == "true"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: routersResult, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/apps/myapp/routers")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRoutersAdd{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "-o", "domain=example.com", "--opts", "https=true"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.HasPrefix(stdout.String(), `Router "galeb" successfully added to app "myapp".`+"\n+---------+"), check.Equals, true)
+}
+
+func (s *S) TestAppRoutersRemoveRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"hipache"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "DELETE" && strings.HasSuffix(r.URL.Path, "/apps/myapp/routers/hipache")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"galeb","address":"myapp.galeb.com"}]`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/apps/myapp/routers")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRoutersRemove{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `[
+  {
+    "name": "galeb",
+    "address": "myapp.galeb.com"
+  }
+]
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
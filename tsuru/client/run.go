@@ -5,22 +5,35 @@
 package client
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	tsuruIo "github.com/tsuru/tsuru/io"
 )
 
+// runExcludeVersion and runIsolatedVersion are the first versions of the
+// tsuru API honoring the exclude and isolated parameters of /apps/<app>/run.
+// Older servers ignore them and run the command in all the units.
+const (
+	runExcludeVersion  = "1.2.0"
+	runIsolatedVersion = "1.2.0"
+)
+
 type AppRun struct {
 	cmd.GuessingCommand
-	fs   *gnuflag.FlagSet
-	once bool
+	fs       *gnuflag.FlagSet
+	once     bool
+	isolated bool
+	exclude  cmd.StringSliceFlag
 }
 
 func (c *AppRun) Info() *cmd.Info {
@@ -28,10 +41,27 @@ func (c *AppRun) Info() *cmd.Info {
 all commands is the root of the application.
 
 If you use the [[--once]] flag tsuru will run the command only in one unit.
-Otherwise, it will run the command in all units.`
+Otherwise, it will run the command in all units.
+
+The [[--exclude]] flag skips the given unit, and may be used multiple times,
+for example to skip units already drained during a maintenance. The command
+fails if all the units of the app are excluded, or if the server is older than
+tsuru 1.2.0, which would ignore the flag and run the command in all the units.
+
+The [[--isolated]] flag runs the command in a new container, created from the
+current image of the application and removed once the command finishes,
+instead of running it in the units. This is the recommended way to run tasks
+like database migrations, as they won't compete for resources with the units
+serving requests. The command fails if the container exits with a non-zero
+status. It can't be combined with [[--once]] or [[--exclude]], and requires
+tsuru 1.2.0 or newer, as older servers would run the command in all the units.
+
+When the command fails, tsuru exits with the same exit status of the remote
+command. If the command fails in more than one unit, the failures are
+summarized and tsuru exits with the status of the first failure.`
 	return &cmd.Info{
 		Name:    "app-run",
-		Usage:   "app-run <command> [commandarg1] [commandarg2] ... [commandargn] [-a/--app appname] [-o/--once]",
+		Usage:   "app-run <command> [commandarg1] [commandarg2] ... [commandargn] [-a/--app appname] [-o/--once] [--exclude unit]... [--isolated]",
 		Desc:    desc,
 		MinArgs: 1,
 	}
@@ -47,9 +77,32 @@ func (c *AppRun) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	if c.isolated && (c.once || len(c.exclude) > 0) {
+		return errors.New("The --isolated flag can't be used with --once or --exclude.")
+	}
 	v := url.Values{}
 	v.Set("command", strings.Join(context.Args, " "))
 	v.Set("once", strconv.FormatBool(c.once))
+	if c.isolated {
+		err = client.RequireServerVersion(runIsolatedVersion, "The --isolated flag")
+		if err != nil {
+			return err
+		}
+		v.Set("isolated", strconv.FormatBool(c.isolated))
+	}
+	if len(c.exclude) > 0 {
+		err = checkExcludedUnits(appName, c.exclude, client)
+		if err != nil {
+			return err
+		}
+		err = client.RequireServerVersion(runExcludeVersion, "The --exclude flag")
+		if err != nil {
+			return err
+		}
+		for _, unit := range c.exclude {
+			v.Add("exclude", unit)
+		}
+	}
 	b := strings.NewReader(v.Encode())
 	request, err := http.NewRequest("POST", u, b)
 	if err != nil {
@@ -61,7 +114,8 @@ func (c *AppRun) Run(context *cmd.Context, client *cmd.Client) error {
 		return err
 	}
 	defer r.Body.Close()
-	w := tsuruIo.NewStreamWriter(context.Stdout, nil)
+	formatter := &runFormatter{}
+	w := tsuruIo.NewStreamWriter(context.Stdout, formatter)
 	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(w, r.Body) {
 	}
 	if err != nil {
@@ -71,7 +125,14 @@ func (c *AppRun) Run(context *cmd.Context, client *cmd.Client) error {
 	if len(unparsed) > 0 {
 		return fmt.Errorf("unparsed message error: %s", string(unparsed))
 	}
-	return nil
+	if len(formatter.failures) == 0 {
+		return nil
+	}
+	message := formatter.failures[0]
+	if len(formatter.failures) > 1 {
+		message = fmt.Sprintf("the command failed in %d units:\n  %s", len(formatter.failures), strings.Join(formatter.failures, "\n  "))
+	}
+	return &cmd.StatusError{Status: exitStatus(formatter.failures[0]), Message: message}
 }
 
 func (c *AppRun) Flags() *gnuflag.FlagSet {
@@ -79,6 +140,70 @@ func (c *AppRun) Flags() *gnuflag.FlagSet {
 		c.fs = c.GuessingCommand.Flags()
 		c.fs.BoolVar(&c.once, "once", false, "Running only one unit")
 		c.fs.BoolVar(&c.once, "o", false, "Running only one unit")
+		c.fs.Var(&c.exclude, "exclude", "Unit to skip when running the command")
+		c.fs.BoolVar(&c.isolated, "isolated", false, "Running in a new container instead of the units")
 	}
 	return c.fs
 }
+
+var exitStatusRegexp = regexp.MustCompile(`exit (?:status|code):? (\d+)`)
+
+// exitStatus extracts the exit status of the remote command from the error
+// message sent by the API, defaulting to 1.
+func exitStatus(failure string) int {
+	if m := exitStatusRegexp.FindStringSubmatch(failure); m != nil {
+		if status, err := strconv.Atoi(m[1]); err == nil && status > 0 {
+			return status
+		}
+	}
+	return 1
+}
+
+// runFormatter writes the output of the command and collects the errors
+// reported by the API, so the output of the remaining units isn't lost when
+// the command fails in one of them.
+type runFormatter struct {
+	failures []string
+}
+
+func (f *runFormatter) Format(out io.Writer, data []byte) error {
+	if len(data) == 1 && data[0] == '\n' {
+		return nil
+	}
+	var msg tsuruIo.SimpleJsonMessage
+	err := json.Unmarshal(data, &msg)
+	if err != nil {
+		return tsuruIo.ErrInvalidStreamChunk
+	}
+	if msg.Error != "" {
+		f.failures = append(f.failures, msg.Error)
+		return nil
+	}
+	out.Write([]byte(msg.Message))
+	return nil
+}
+
+// checkExcludedUnits ensures all the excluded units belong to the app and
+// that at least one unit is left to run the command.
+func checkExcludedUnits(appName string, excluded []string, client *cmd.Client) error {
+	a, err := getApp(client, appName)
+	if err != nil {
+		return err
+	}
+	targets := make(map[string]bool, len(a.Units))
+	for _, u := range a.Units {
+		targets[u.ID] = true
+	}
+	for _, id := range excluded {
+		if !targets[id] {
+			return fmt.Errorf("Unit %q not found in app %q.", id, appName)
+		}
+	}
+	for _, id := range excluded {
+		delete(targets, id)
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("All the units of app %q are excluded, there's no unit left to run the command.", appName)
+	}
+	return nil
+}
@@ -10,7 +10,7 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"github.com/tsuru/tsuru/io"
 	"gopkg.in/check.v1"
@@ -141,3 +141,259 @@ func (s *S) TestAppRunInfo(c *check.C) {
 	command := AppRun{}
 	c.Assert(command.Info(), check.NotNil)
 }
+
+const runAppResult = `{"name":"ble","units":[{"ID":"ble/0","Status":"started"},{"ID":"ble/1","Status":"started"}]}`
+
+func (s *S) TestAppRunWithExclude(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	expected := "http.go\n"
+	context := cmd.Context{
+		Args:   []string{"ls"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Message: expected}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: runAppResult, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/ble")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `{"version":"1.2.0"}`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/info")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					req.ParseForm()
+					c.Assert(req.Form["exclude"], check.DeepEquals, []string{"ble/0"})
+					return req.Method == "POST" && strings.HasSuffix(req.URL.Path, "/apps/ble/run")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRun{}
+	command.Flags().Parse(true, []string{"--app", "ble", "--exclude", "ble/0"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppRunExcludeWithAnOlderServer(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"ls"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: runAppResult, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/ble")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `{"version":"1.1.0"}`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/info")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRun{}
+	command.Flags().Parse(true, []string{"--app", "ble", "--exclude", "ble/0"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `The --exclude flag requires tsuru 1.2.0 or newer, and the server runs version 1.1.0.`)
+	c.Assert(trans.ConditionalTransports, check.HasLen, 0)
+}
+
+func (s *S) TestAppRunExcludeAllUnits(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"ls"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	trans := &cmdtest.Transport{Message: runAppResult, Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRun{}
+	command.Flags().Parse(true, []string{"--app", "ble", "--exclude", "ble/0", "--exclude", "ble/1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `All the units of app "ble" are excluded, there's no unit left to run the command.`)
+}
+
+func (s *S) TestAppRunExcludeUnknownUnit(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"ls"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	trans := &cmdtest.Transport{Message: runAppResult, Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRun{}
+	command.Flags().Parse(true, []string{"--app", "ble", "--exclude", "ble/9"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Unit "ble/9" not found in app "ble".`)
+}
+
+func serverInfoTransport(version string) cmdtest.ConditionalTransport {
+	return cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `{"version":"` + version + `"}`, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/info")
+		},
+	}
+}
+
+func (s *S) TestAppRunIsolated(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	expected := "migrated\n"
+	context := cmd.Context{
+		Args:   []string{"make", "migrate"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Message: expected}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			serverInfoTransport("1.2.0"),
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.FormValue("command") == "make migrate" &&
+						req.FormValue("isolated") == "true" &&
+						req.FormValue("once") == "false" &&
+						strings.HasSuffix(req.URL.Path, "/apps/ble/run")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRun{}
+	command.Flags().Parse(true, []string{"--app", "ble", "--isolated"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppRunIsolatedCommandFailure(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"make", "migrate"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Error: "exit status 2"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			serverInfoTransport("1.2.0"),
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc:  func(req *http.Request) bool { return true },
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRun{}
+	command.Flags().Parse(true, []string{"--app", "ble", "--isolated"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "exit status 2")
+	c.Assert(cmd.ExitCode(err), check.Equals, 2)
+}
+
+func (s *S) TestAppRunIsolatedWithAnOlderServer(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"make", "migrate"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{serverInfoTransport("1.1.0")},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRun{}
+	command.Flags().Parse(true, []string{"--app", "ble", "--isolated"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `The --isolated flag requires tsuru 1.2.0 or newer, and the server runs version 1.1.0.`)
+	c.Assert(trans.ConditionalTransports, check.HasLen, 0)
+}
+
+func (s *S) TestAppRunIsolatedWithOnce(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"ls"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	client := cmd.NewClient(&http.Client{}, nil, manager)
+	command := AppRun{}
+	command.Flags().Parse(true, []string{"--app", "ble", "--isolated", "--once"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "The --isolated flag can't be used with --once or --exclude.")
+}
+
+func (s *S) TestAppRunFailureInMultipleUnits(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"false"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	var result string
+	for _, msg := range []io.SimpleJsonMessage{
+		{Message: "running in ble/0\n"},
+		{Error: "unit ble/0: exit status 3"},
+		{Message: "running in ble/1\n"},
+		{Error: "unit ble/1: exit status 4"},
+	} {
+		data, err := json.Marshal(msg)
+		c.Assert(err, check.IsNil)
+		result += string(data) + "\n"
+	}
+	trans := &cmdtest.Transport{Message: result, Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRun{}
+	command.Flags().Parse(true, []string{"--app", "ble"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `the command failed in 2 units:
+  unit ble/0: exit status 3
+  unit ble/1: exit status 4`)
+	c.Assert(cmd.ExitCode(err), check.Equals, 3)
+	c.Assert(stdout.String(), check.Equals, "running in ble/0\nrunning in ble/1\n")
+}
+
+func (s *S) TestAppRunFailureWithoutExitStatus(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"false"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	result := `{"Message":"","Error":"unit ble/0: failed"}` + "\n" + `{"Message":"","Error":"unit ble/1: failed"}` + "\n"
+	trans := &cmdtest.Transport{Message: result, Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := AppRun{}
+	command.Flags().Parse(true, []string{"--app", "ble"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "(?s)the command failed in 2 units:\n  unit ble/0: failed\n  unit ble/1: failed")
+	c.Assert(cmd.ExitCode(err), check.Equals, cmd.ExitGeneric)
+}
+
+func (s *S) TestExitStatus(c *check.C) {
+	c.Assert(exitStatus("exit status 2"), check.Equals, 2)
+	c.Assert(exitStatus("unit abc: exit code: 127"), check.Equals, 127)
+	c.Assert(exitStatus("exit status 0"), check.Equals, 1)
+	c.Assert(exitStatus("command not found"), check.Equals, 1)
+}
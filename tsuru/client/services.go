@@ -12,12 +12,16 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	"github.com/tsuru/tsuru/app/bind"
+	tsuruerr "github.com/tsuru/tsuru/errors"
 	tsuruIo "github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/service"
 )
@@ -69,12 +73,15 @@ type ServiceInstanceAdd struct {
 	fs          *gnuflag.FlagSet
 	teamOwner   string
 	description string
+	plan        string
+	tags        cmd.StringSliceFlag
+	params      cmd.MapFlag
 }
 
 func (c *ServiceInstanceAdd) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "service-instance-add",
-		Usage: "service-instance-add <service-name> <service-instance-name> [plan] [-t/--team-owner <team>] [-d/--description description]",
+		Usage: "service-instance-add <service-name> <service-instance-name> [plan] [-p/--plan plan] [-t/--team <team>] [-d/--description description] [-g/--tag tag]... [--param key=value]...",
 		Desc: `Creates a service instance of a service. There can later be binded to
 applications with [[tsuru service-bind]].
 
@@ -83,7 +90,28 @@ This example shows how to add a new instance of **mongodb** service, named
 
 ::
 
-    $ tsuru service-instance-add mongodb tsuru_mongodb small -t myteam
+    $ tsuru service-instance-add mongodb tsuru_mongodb -p small -t myteam
+
+The plan may also be given as the third argument. When the service exposes
+its plans, the plan is checked against them before creating the instance.
+
+The [[--team]] parameter, also available as [[--team-owner]], sets the team
+that owns the service instance. When it's not given, the team in the
+TSURU_TEAM environment variable is used, or else the team set in the
+configuration file. If none of them is set and the user may create service
+instances in more than one team, the teams are listed and the instance is not
+created.
+
+The [[--tag]] parameter adds a tag to the service instance and may be used
+multiple times.
+
+The [[--param]] parameter sets a custom creation parameter understood by the
+service, like a region or a size. It may be used multiple times and keys may
+be namespaced with dots:
+
+::
+
+    $ tsuru service-instance-add mongodb tsuru_mongodb --param region=us-east --param storage.size=10G
 `,
 		MinArgs: 2,
 		MaxArgs: 3,
@@ -92,27 +120,54 @@ This example shows how to add a new instance of **mongodb** service, named
 
 func (c *ServiceInstanceAdd) Run(ctx *cmd.Context, client *cmd.Client) error {
 	serviceName, instanceName := ctx.Args[0], ctx.Args[1]
-	var plan string
+	plan := c.plan
 	if len(ctx.Args) > 2 {
+		if plan != "" && plan != ctx.Args[2] {
+			return errors.New("You must provide the plan either as an argument or with the --plan flag, not both.")
+		}
 		plan = ctx.Args[2]
 	}
+	if plan != "" {
+		err := validatePlan(serviceName, plan, client)
+		if err != nil {
+			return err
+		}
+	}
+	teamOwner, err := resolveTeam(client, c.teamOwner, "service-instance.create")
+	if err != nil {
+		return err
+	}
 	v := url.Values{}
 	v.Set("name", instanceName)
 	v.Set("plan", plan)
-	v.Set("owner", c.teamOwner)
+	v.Set("owner", teamOwner)
 	v.Set("description", c.description)
+	for _, tag := range c.tags {
+		v.Add("tag", tag)
+	}
+	for key, value := range c.params {
+		v.Set("parameters."+key, value)
+	}
+	err = addServiceInstance(client, serviceName, v)
+	if err != nil {
+		return err
+	}
+	ctx.Successf("Service successfully added.\n")
+	return nil
+}
+
+func addServiceInstance(client *cmd.Client, serviceName string, v url.Values) error {
 	u, err := cmd.GetURL(fmt.Sprintf("/services/%s/instances", serviceName))
 	if err != nil {
 		return err
 	}
 	request, err := http.NewRequest("POST", u, strings.NewReader(v.Encode()))
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	_, err = client.Do(request)
 	if err != nil {
 		return err
 	}
-	fmt.Fprint(ctx.Stdout, "Service successfully added.\n")
-	return nil
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = client.Do(request)
+	return err
 }
 
 func (c *ServiceInstanceAdd) Flags() *gnuflag.FlagSet {
@@ -120,14 +175,46 @@ func (c *ServiceInstanceAdd) Flags() *gnuflag.FlagSet {
 		flagDesc := "the team that owns the service (mandatory if the user is member of more than one team)"
 		c.fs = gnuflag.NewFlagSet("service-instance-add", gnuflag.ExitOnError)
 		c.fs.StringVar(&c.teamOwner, "team-owner", "", flagDesc)
+		c.fs.StringVar(&c.teamOwner, "team", "", flagDesc)
 		c.fs.StringVar(&c.teamOwner, "t", "", flagDesc)
 		descriptionMessage := "service instance description"
 		c.fs.StringVar(&c.description, "description", "", descriptionMessage)
 		c.fs.StringVar(&c.description, "d", "", descriptionMessage)
+		planMessage := "the plan of the service instance"
+		c.fs.StringVar(&c.plan, "plan", "", planMessage)
+		c.fs.StringVar(&c.plan, "p", "", planMessage)
+		tagMessage := "service instance tag"
+		c.fs.Var(&c.tags, "tag", tagMessage)
+		c.fs.Var(&c.tags, "g", tagMessage)
+		c.fs.Var(&c.params, "param", "service instance creation parameter, in the form key=value")
 	}
 	return c.fs
 }
 
+// validatePlan checks that the plan is one of the plans of the service. If
+// the service doesn't expose its plans, the server is left to reject invalid
+// values.
+func validatePlan(serviceName, plan string, client *cmd.Client) error {
+	plans, err := servicePlans(serviceName, client)
+	if err != nil {
+		if e, ok := err.(*tsuruerr.HTTP); ok && e.Code == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	if len(plans) == 0 {
+		return nil
+	}
+	names := make([]string, len(plans))
+	for i, p := range plans {
+		names[i] = p["Name"]
+	}
+	if !in(plan, names) {
+		return fmt.Errorf("Plan %q does not exist for service %q. Available plans: %s.", plan, serviceName, strings.Join(names, ", "))
+	}
+	return nil
+}
+
 type ServiceInstanceUpdate struct {
 	fs          *gnuflag.FlagSet
 	description string
@@ -161,7 +248,7 @@ func (c *ServiceInstanceUpdate) Run(ctx *cmd.Context, client *cmd.Client) error
 	if err != nil {
 		return err
 	}
-	fmt.Fprint(ctx.Stdout, "Service successfully updated.\n")
+	ctx.Successf("Service successfully updated.\n")
 	return nil
 }
 
@@ -179,6 +266,9 @@ type ServiceInstanceBind struct {
 	cmd.GuessingCommand
 	fs        *gnuflag.FlagSet
 	noRestart bool
+	create    bool
+	plan      string
+	teamOwner string
 }
 
 func (sb *ServiceInstanceBind) Run(ctx *cmd.Context, client *cmd.Client) error {
@@ -193,6 +283,13 @@ func (sb *ServiceInstanceBind) Run(ctx *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	var created bool
+	if sb.create {
+		created, err = sb.createInstance(ctx, client, serviceName, instanceName)
+		if err != nil {
+			return err
+		}
+	}
 	v := url.Values{}
 	v.Set("noRestart", strconv.FormatBool(sb.noRestart))
 	request, err := http.NewRequest("PUT", u, strings.NewReader(v.Encode()))
@@ -202,10 +299,14 @@ func (sb *ServiceInstanceBind) Run(ctx *cmd.Context, client *cmd.Client) error {
 	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	resp, err := client.Do(request)
 	if err != nil {
+		if created {
+			return fmt.Errorf("Service instance %q was created, but binding it to app %q failed: %s", instanceName, appName, err)
+		}
 		return err
 	}
 	defer resp.Body.Close()
-	w := tsuruIo.NewStreamWriter(ctx.Stdout, nil)
+	formatter := &bindFormatter{}
+	w := tsuruIo.NewStreamWriter(ctx.Stdout, formatter)
 	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(w, resp.Body) {
 	}
 	if err != nil {
@@ -215,19 +316,111 @@ func (sb *ServiceInstanceBind) Run(ctx *cmd.Context, client *cmd.Client) error {
 	if len(unparsed) > 0 {
 		return fmt.Errorf("unparsed message error: %s", string(unparsed))
 	}
+	if ctx.Quiet {
+		return nil
+	}
+	if len(formatter.names) == 0 {
+		fmt.Fprintf(ctx.Stdout, "No environment variables were set in app %q.\n", appName)
+		return nil
+	}
+	sort.Strings(formatter.names)
+	fmt.Fprintf(ctx.Stdout, "Environment variables set in app %q:\n", appName)
+	printEnvNames(ctx.Stdout, formatter.names)
 	return nil
 }
 
+// bindEnvsHeader introduces the list of environment variables set by the
+// bind, in the response of the API.
+const bindEnvsHeader = "The following environment variables are available for use in your app:"
+
+// bindFormatter writes the messages sent by the API during the bind, except
+// for the list of environment variables set in the app, whose names are
+// collected to be displayed with their values masked.
+type bindFormatter struct {
+	listing bool
+	names   []string
+}
+
+func (f *bindFormatter) Format(out io.Writer, data []byte) error {
+	if len(data) == 1 && data[0] == '\n' {
+		return nil
+	}
+	var msg tsuruIo.SimpleJsonMessage
+	err := json.Unmarshal(data, &msg)
+	if err != nil {
+		return tsuruIo.ErrInvalidStreamChunk
+	}
+	if msg.Error != "" {
+		return errors.New(msg.Error)
+	}
+	for _, line := range strings.SplitAfter(msg.Message, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == bindEnvsHeader:
+			f.listing = true
+			continue
+		case f.listing && trimmed == "":
+			continue
+		case f.listing && strings.HasPrefix(trimmed, "- "):
+			f.names = append(f.names, strings.TrimSpace(trimmed[2:]))
+			continue
+		}
+		f.listing = false
+		io.WriteString(out, line)
+	}
+	return nil
+}
+
+// createInstance creates the service instance when it doesn't exist yet,
+// returning whether it was created.
+func (sb *ServiceInstanceBind) createInstance(ctx *cmd.Context, client *cmd.Client, serviceName, instanceName string) (bool, error) {
+	_, err := getServiceInstance(client, serviceName, instanceName)
+	if err == nil {
+		return false, nil
+	}
+	if e, ok := err.(*tsuruerr.HTTP); !ok || e.Code != http.StatusNotFound {
+		return false, fmt.Errorf("Failed to check whether service instance %q exists, the app was not bound: %s", instanceName, err)
+	}
+	if sb.plan != "" {
+		err = validatePlan(serviceName, sb.plan, client)
+		if err != nil {
+			return false, fmt.Errorf("Failed to create service instance %q, the app was not bound: %s", instanceName, err)
+		}
+	}
+	teamOwner, err := resolveTeam(client, sb.teamOwner, "service-instance.create")
+	if err != nil {
+		return false, fmt.Errorf("Failed to create service instance %q, the app was not bound: %s", instanceName, err)
+	}
+	v := url.Values{}
+	v.Set("name", instanceName)
+	v.Set("plan", sb.plan)
+	v.Set("owner", teamOwner)
+	err = addServiceInstance(client, serviceName, v)
+	if err != nil {
+		return false, fmt.Errorf("Failed to create service instance %q, the app was not bound: %s", instanceName, err)
+	}
+	ctx.Successf("Service instance %q created.\n", instanceName)
+	return true, nil
+}
+
 func (sb *ServiceInstanceBind) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "service-instance-bind",
-		Usage: "service-instance-bind <service-name> <service-instance-name> [-a/--app appname] [--no-restart]",
+		Usage: "service-instance-bind <service-name> <service-instance-name> [-a/--app appname] [--no-restart] [--create [-p/--plan plan] [-t/--team team]]",
 		Desc: `Binds an application to a previously created service instance. See [[tsuru
 service-add]] for more details on how to create a service instance.
 
 When binding an application to a service instance, tsuru will add new
 environment variables to the application. All environment variables exported
-by bind will be private (not accessible via [[tsuru env-get]]).`,
+by bind will be private (not accessible via [[tsuru env-get]]).
+
+After binding, the names of the environment variables set in the application
+by the service, as reported by the tsuru server, are displayed with their
+values masked. Use the global [[--quiet]] flag to omit them.
+
+The [[--create]] flag creates the service instance before binding it, in case
+it doesn't exist yet, using the plan given by [[--plan]] and the team given by
+[[--team]], resolved like in [[tsuru service-instance-add]]. The application is not bound if the creation fails.`,
 		MinArgs: 2,
 	}
 }
@@ -236,6 +429,14 @@ func (sb *ServiceInstanceBind) Flags() *gnuflag.FlagSet {
 	if sb.fs == nil {
 		sb.fs = sb.GuessingCommand.Flags()
 		sb.fs.BoolVar(&sb.noRestart, "no-restart", false, "Binds an application to a service instance without restart the application")
+		sb.fs.BoolVar(&sb.create, "create", false, "Creates the service instance if it doesn't exist")
+		planMessage := "the plan of the service instance, when it's created by --create"
+		sb.fs.StringVar(&sb.plan, "plan", "", planMessage)
+		sb.fs.StringVar(&sb.plan, "p", "", planMessage)
+		teamOwnerMessage := "the team that owns the service instance, when it's created by --create"
+		sb.fs.StringVar(&sb.teamOwner, "team-owner", "", teamOwnerMessage)
+		sb.fs.StringVar(&sb.teamOwner, "team", "", teamOwnerMessage)
+		sb.fs.StringVar(&sb.teamOwner, "t", "", teamOwnerMessage)
 	}
 	return sb.fs
 }
@@ -248,7 +449,7 @@ type ServiceInstanceUnbind struct {
 
 func (su *ServiceInstanceUnbind) Run(ctx *cmd.Context, client *cmd.Client) error {
 	ctx.RawOutput()
-	appName, err := su.Guess()
+	appName, err := su.GuessDestructive(ctx)
 	if err != nil {
 		return err
 	}
@@ -259,6 +460,15 @@ func (su *ServiceInstanceUnbind) Run(ctx *cmd.Context, client *cmd.Client) error
 		return err
 	}
 	url += fmt.Sprintf("?noRestart=%t", su.noRestart)
+	var names []string
+	if !ctx.Quiet {
+		envs, envsErr := getAppEnvs(client, appName)
+		if envsErr != nil {
+			fmt.Fprintf(ctx.Stderr, "Unable to list the environment variables of app %q, their names won't be displayed: %s\n", appName, envsErr)
+		} else {
+			names = serviceInstanceEnvNames(envs, serviceName, instanceName)
+		}
+	}
 	request, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
 		return err
@@ -268,7 +478,8 @@ func (su *ServiceInstanceUnbind) Run(ctx *cmd.Context, client *cmd.Client) error
 		return err
 	}
 	defer resp.Body.Close()
-	w := tsuruIo.NewStreamWriter(ctx.Stdout, nil)
+	formatter := &unbindFormatter{}
+	w := tsuruIo.NewStreamWriter(ctx.Stdout, formatter)
 	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(w, resp.Body) {
 	}
 	if err != nil {
@@ -278,6 +489,50 @@ func (su *ServiceInstanceUnbind) Run(ctx *cmd.Context, client *cmd.Client) error
 	if len(unparsed) > 0 {
 		return fmt.Errorf("unparsed message error: %s", string(unparsed))
 	}
+	if ctx.Quiet {
+		return nil
+	}
+	if formatter.removed == 0 {
+		fmt.Fprintf(ctx.Stdout, "No environment variables were removed from app %q.\n", appName)
+		return nil
+	}
+	if len(names) != formatter.removed {
+		fmt.Fprintf(ctx.Stdout, "%d environment variables were removed from app %q.\n", formatter.removed, appName)
+		return nil
+	}
+	fmt.Fprintf(ctx.Stdout, "Environment variables removed from app %q:\n", appName)
+	printEnvNames(ctx.Stdout, names)
+	return nil
+}
+
+// unbindEnvsRegexp matches the message of the API reporting how many
+// environment variables the unbind removed from the app.
+var unbindEnvsRegexp = regexp.MustCompile(`^---- Unsetting (\d+) environment variables ----$`)
+
+// unbindFormatter writes the messages sent by the API during the unbind,
+// collecting the number of environment variables removed from the app.
+type unbindFormatter struct {
+	removed int
+}
+
+func (f *unbindFormatter) Format(out io.Writer, data []byte) error {
+	if len(data) == 1 && data[0] == '\n' {
+		return nil
+	}
+	var msg tsuruIo.SimpleJsonMessage
+	err := json.Unmarshal(data, &msg)
+	if err != nil {
+		return tsuruIo.ErrInvalidStreamChunk
+	}
+	if msg.Error != "" {
+		return errors.New(msg.Error)
+	}
+	for _, line := range strings.Split(msg.Message, "\n") {
+		if m := unbindEnvsRegexp.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			f.removed, _ = strconv.Atoi(m[1])
+		}
+	}
+	io.WriteString(out, msg.Message)
 	return nil
 }
 
@@ -287,7 +542,13 @@ func (su *ServiceInstanceUnbind) Info() *cmd.Info {
 		Usage: "service-instance-unbind <service-name> <service-instance-name> [-a/--app appname] [--no-restart]",
 		Desc: `Unbinds an application from a service instance. After unbinding, the instance
 will not be available anymore. For example, when unbinding an application from
-a MySQL service, the application would lose access to the database.`,
+a MySQL service, the application would lose access to the database.
+
+After unbinding, the environment variables removed from the application, as
+reported by the tsuru server, are displayed. The server only reports how many
+variables were removed, so their names are taken from the variables exported
+by the service instance, listed before unbinding. Only the number is displayed
+when they can't be listed. Use the global [[--quiet]] flag to omit them.`,
 		MinArgs: 2,
 	}
 }
@@ -300,47 +561,246 @@ func (su *ServiceInstanceUnbind) Flags() *gnuflag.FlagSet {
 	return su.fs
 }
 
-type ServiceInstanceStatus struct{}
+// printEnvNames writes the given variables with their values masked, as
+// variables exported by services are private.
+func printEnvNames(w io.Writer, names []string) {
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", envVar{Name: name})
+	}
+}
+
+type ServiceInstanceBindStatus struct {
+	cmd.GuessingCommand
+	fs   *gnuflag.FlagSet
+	json bool
+}
 
-func (c ServiceInstanceStatus) Info() *cmd.Info {
+func (c *ServiceInstanceBindStatus) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "service-instance-bind-status",
+		Usage: "service-instance-bind-status <service-name> <service-instance-name> [-a/--app appname] [--json]",
+		Desc: `Displays whether an application is bound to a service instance and the
+environment variables injected in the application by the bind, with their
+values masked. Variables exported by the service instance that are not set in
+the application anymore are flagged as missing, which helps diagnosing
+applications that can't connect to a bound service.
+
+The [[--json]] flag displays the status in JSON format, like [[tsuru -o json
+service-instance-bind-status]].`,
+		MinArgs: 2,
+		MaxArgs: 2,
+	}
+}
+
+func (c *ServiceInstanceBindStatus) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		c.fs.BoolVar(&c.json, "json", false, "Display the status in JSON format")
+	}
+	return c.fs
+}
+
+type bindStatusEnv struct {
+	Name string `json:"name"`
+	Set  bool   `json:"set"`
+}
+
+type bindStatus struct {
+	App      string          `json:"app"`
+	Service  string          `json:"service"`
+	Instance string          `json:"instance"`
+	Bound    bool            `json:"bound"`
+	Envs     []bindStatusEnv `json:"envs"`
+}
+
+func (c *ServiceInstanceBindStatus) Run(ctx *cmd.Context, client *cmd.Client) error {
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	serviceName := ctx.Args[0]
+	instanceName := ctx.Args[1]
+	si, err := getServiceInstance(client, serviceName, instanceName)
+	if err != nil {
+		return err
+	}
+	envs, err := getAppEnvs(client, appName)
+	if err != nil {
+		return err
+	}
+	status := bindStatus{
+		App:      appName,
+		Service:  serviceName,
+		Instance: instanceName,
+		Bound:    in(appName, si.Apps),
+		Envs:     []bindStatusEnv{},
+	}
+	for _, name := range serviceInstanceEnvNames(envs, serviceName, instanceName) {
+		_, ok := envs[name]
+		status.Envs = append(status.Envs, bindStatusEnv{Name: name, Set: ok})
+	}
+	if c.json {
+		ctx.OutputFormat = cmd.JSONOutput
+	}
+	if ctx.StructuredOutput() {
+		return ctx.WriteStructured(status)
+	}
+	if !status.Bound {
+		fmt.Fprintf(ctx.Stdout, "App %q is not bound to service instance %q of service %q.\n", appName, instanceName, serviceName)
+	} else {
+		fmt.Fprintf(ctx.Stdout, "App %q is bound to service instance %q of service %q.\n", appName, instanceName, serviceName)
+	}
+	if len(status.Envs) == 0 {
+		if status.Bound {
+			fmt.Fprintln(ctx.Stdout, "No environment variables were injected by the bind.")
+		}
+		return nil
+	}
+	fmt.Fprintln(ctx.Stdout, "Environment variables injected by the bind:")
+	for _, env := range status.Envs {
+		if env.Set {
+			fmt.Fprintf(ctx.Stdout, "  %s\n", envVar{Name: env.Name})
+		} else {
+			fmt.Fprintf(ctx.Stdout, "  %s (missing in the app)\n", env.Name)
+		}
+	}
+	return nil
+}
+
+// serviceInstanceEnvNames returns the sorted names of the variables exported
+// by the given service instance, as recorded in the TSURU_SERVICES variable
+// of the app.
+func serviceInstanceEnvNames(envs map[string]envVar, serviceName, instanceName string) []string {
+	var names []string
+	for _, instance := range boundServiceInstances(envs)[serviceName] {
+		if instance.Name != instanceName {
+			continue
+		}
+		for name := range instance.Envs {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// boundServiceInstances returns the service instances bound to the app by
+// service name, with the variables they injected, as described by the
+// TSURU_SERVICES variable.
+func boundServiceInstances(envs map[string]envVar) map[string][]bind.ServiceInstance {
+	var services map[string][]bind.ServiceInstance
+	if json.Unmarshal([]byte(envs["TSURU_SERVICES"].Value), &services) != nil {
+		return nil
+	}
+	return services
+}
+
+// statusPollInterval is the interval between status checks when waiting for
+// a service instance to be up.
+var statusPollInterval = 2 * time.Second
+
+type ServiceInstanceStatus struct {
+	wait    bool
+	timeout time.Duration
+	fs      *gnuflag.FlagSet
+}
+
+func (c *ServiceInstanceStatus) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "service-instance-status",
-		Usage: "service-instance-status <service-name> <service-instance-name>",
+		Usage: "service-instance-status <service-name> <service-instance-name> [--wait [--timeout duration]]",
 		Desc: `Displays the status of the given service instance. For now, it checks only if
-the instance is "up" (receiving connections) or "down" (refusing connections).`,
+the instance is "up" (receiving connections) or "down" (refusing connections).
+
+The [[--wait]] flag makes the command poll the status until the instance is
+up, which is useful right after creating a service instance. Server failures
+and connection errors are retried, other errors (like an unknown instance) stop
+the command. The command fails if the instance is not up after [[--timeout]]
+(5m by default).`,
 		MinArgs: 2,
 	}
 }
 
-func (c ServiceInstanceStatus) Run(ctx *cmd.Context, client *cmd.Client) error {
+func (c *ServiceInstanceStatus) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("service-instance-status", gnuflag.ExitOnError)
+		c.fs.BoolVar(&c.wait, "wait", false, "Wait until the service instance is up")
+		c.fs.DurationVar(&c.timeout, "timeout", 5*time.Minute, "Maximum time to wait for the service instance to be up")
+	}
+	return c.fs
+}
+
+func (c *ServiceInstanceStatus) Run(ctx *cmd.Context, client *cmd.Client) error {
 	servName := ctx.Args[0]
 	instName := ctx.Args[1]
+	if !c.wait {
+		msg, err := c.status(servName, instName, client)
+		if err != nil {
+			return err
+		}
+		msg += "\n"
+		n, err := fmt.Fprint(ctx.Stdout, msg)
+		if err != nil {
+			return err
+		}
+		if n != len(msg) {
+			return errors.New("Failed to write to standard output.\n")
+		}
+		return nil
+	}
+	tty := ctx.IsTerminal()
+	var last string
+	deadline := time.Now().Add(c.timeout)
+	for {
+		msg, err := c.status(servName, instName, client)
+		if err != nil {
+			if cmd.ExitCode(err) != cmd.ExitServer {
+				return err
+			}
+			msg = err.Error()
+		}
+		if tty {
+			fmt.Fprintf(ctx.Stdout, "\r\033[K%s", msg)
+		} else if msg != last {
+			fmt.Fprintln(ctx.Stdout, msg)
+		}
+		last = msg
+		if err == nil && strings.Contains(msg, " is up") {
+			break
+		}
+		if !time.Now().Add(statusPollInterval).Before(deadline) {
+			if tty {
+				fmt.Fprintln(ctx.Stdout)
+			}
+			return fmt.Errorf("Timed out after %s waiting for service instance %q to be up.", c.timeout, instName)
+		}
+		time.Sleep(statusPollInterval)
+	}
+	if tty {
+		fmt.Fprintln(ctx.Stdout)
+	}
+	return nil
+}
+
+func (c *ServiceInstanceStatus) status(servName, instName string, client *cmd.Client) (string, error) {
 	url, err := cmd.GetURL("/services/" + servName + "/instances/" + instName + "/status")
 	if err != nil {
-		return err
+		return "", err
 	}
 	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return err
+		return "", err
 	}
 	resp, err := client.Do(request)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 	bMsg, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return "", err
 	}
-	msg := string(bMsg) + "\n"
-	n, err := fmt.Fprint(ctx.Stdout, msg)
-	if err != nil {
-		return err
-	}
-	if n != len(msg) {
-		return errors.New("Failed to write to standard output.\n")
-	}
-	return nil
+	return string(bMsg), nil
 }
 
 type ServiceInstanceInfo struct{}
@@ -366,28 +826,36 @@ type ServiceInstanceInfoModel struct {
 	CustomInfo      map[string]string
 }
 
-func (c ServiceInstanceInfo) Run(ctx *cmd.Context, client *cmd.Client) error {
-	serviceName := ctx.Args[0]
-	instanceName := ctx.Args[1]
+func getServiceInstance(client *cmd.Client, serviceName, instanceName string) (*ServiceInstanceInfoModel, error) {
 	url, err := cmd.GetURL("/services/" + serviceName + "/instances/" + instanceName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	resp, err := client.Do(request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	result, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var si ServiceInstanceInfoModel
 	err = json.Unmarshal(result, &si)
+	if err != nil {
+		return nil, err
+	}
+	return &si, nil
+}
+
+func (c ServiceInstanceInfo) Run(ctx *cmd.Context, client *cmd.Client) error {
+	serviceName := ctx.Args[0]
+	instanceName := ctx.Args[1]
+	si, err := getServiceInstance(client, serviceName, instanceName)
 	if err != nil {
 		return err
 	}
@@ -424,7 +892,11 @@ func (c ServiceInfo) Info() *cmd.Info {
 		Name:  "service-info",
 		Usage: "service-info <service-name>",
 		Desc: `Displays a list of all instances of a given service (that the user has access
-to), and apps bound to these instances.`,
+to), and apps bound to these instances.
+
+This command honors the global --output flag, so [[tsuru -o json service-info
+<service-name>]] displays the instances, plans and documentation of the
+service in JSON format.`,
 		MinArgs: 1,
 	}
 }
@@ -520,39 +992,47 @@ func (c ServiceInfo) BuildInstancesTable(serviceName string, ctx *cmd.Context, c
 }
 
 func (c ServiceInfo) BuildPlansTable(serviceName string, ctx *cmd.Context, client *cmd.Client) error {
-	url, err := cmd.GetURL(fmt.Sprintf("/services/%s/plans", serviceName))
+	plans, err := servicePlans(serviceName, client)
 	if err != nil {
 		return err
 	}
+	if len(plans) > 0 {
+		fmt.Fprint(ctx.Stdout, "\nPlans\n")
+		table := cmd.NewTable()
+		for _, plan := range plans {
+			data := []string{plan["Name"], plan["Description"]}
+			table.AddRow(cmd.Row(data))
+		}
+		table.Headers = cmd.Row([]string{"Name", "Description"})
+		ctx.Stdout.Write(table.Bytes())
+	}
+	return nil
+}
+
+func servicePlans(serviceName string, client *cmd.Client) ([]map[string]string, error) {
+	url, err := cmd.GetURL(fmt.Sprintf("/services/%s/plans", serviceName))
+	if err != nil {
+		return nil, err
+	}
 	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	resp, err := client.Do(request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	result, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var plans []map[string]string
 	err = json.Unmarshal(result, &plans)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if len(plans) > 0 {
-		fmt.Fprint(ctx.Stdout, "\nPlans\n")
-		table := cmd.NewTable()
-		for _, plan := range plans {
-			data := []string{plan["Name"], plan["Description"]}
-			table.AddRow(cmd.Row(data))
-		}
-		table.Headers = cmd.Row([]string{"Name", "Description"})
-		ctx.Stdout.Write(table.Bytes())
-	}
-	return nil
+	return plans, nil
 }
 
 func (c ServiceInfo) WriteDoc(ctx *cmd.Context, client *cmd.Client) error {
@@ -582,8 +1062,59 @@ func (c ServiceInfo) WriteDoc(ctx *cmd.Context, client *cmd.Client) error {
 	return nil
 }
 
+func (c ServiceInfo) fetch(path string, client *cmd.Client) ([]byte, error) {
+	url, err := cmd.GetURL(path)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (c ServiceInfo) WriteStructuredInfo(serviceName string, ctx *cmd.Context, client *cmd.Client) error {
+	result, err := c.fetch("/services/"+serviceName, client)
+	if err != nil {
+		return err
+	}
+	var instances []interface{}
+	err = json.Unmarshal(result, &instances)
+	if err != nil {
+		return err
+	}
+	result, err = c.fetch(fmt.Sprintf("/services/%s/plans", serviceName), client)
+	if err != nil {
+		return err
+	}
+	var plans []interface{}
+	err = json.Unmarshal(result, &plans)
+	if err != nil {
+		return err
+	}
+	doc, err := c.fetch(fmt.Sprintf("/services/%s/doc", serviceName), client)
+	if err != nil {
+		return err
+	}
+	return ctx.WriteStructured(map[string]interface{}{
+		"service":       serviceName,
+		"instances":     instances,
+		"plans":         plans,
+		"documentation": string(doc),
+	})
+}
+
 func (c ServiceInfo) Run(ctx *cmd.Context, client *cmd.Client) error {
 	serviceName := ctx.Args[0]
+	if ctx.StructuredOutput() {
+		return c.WriteStructuredInfo(serviceName, ctx, client)
+	}
 	err := c.BuildInstancesTable(serviceName, ctx, client)
 	if err != nil {
 		return err
@@ -633,7 +1164,7 @@ func removeServiceInstanceWithUnbind(ctx *cmd.Context, client *cmd.Client) error
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(ctx.Stdout, `Service "%s" successfully removed!`+"\n", instanceName)
+	ctx.Successf(`Service "%s" successfully removed!`+"\n", instanceName)
 
 	return nil
 }
@@ -688,7 +1219,7 @@ func (c *ServiceInstanceRemove) Run(ctx *cmd.Context, client *cmd.Client) error
 		}
 		return msgError
 	}
-	fmt.Fprintf(ctx.Stdout, `Service "%s" successfully removed!`+"\n", instanceName)
+	ctx.Successf(`Service "%s" successfully removed!`+"\n", instanceName)
 	return nil
 }
 
@@ -707,9 +1238,10 @@ type ServiceInstanceGrant struct{}
 
 func (c *ServiceInstanceGrant) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "service-instance-grant",
-		Usage:   "service-instance-grant <service-name> <service-instance-name> <team-name>",
-		Desc:    `Grant access to team in a service instance.`,
+		Name:  "service-instance-grant",
+		Usage: "service-instance-grant <service-name> <service-instance-name> <team-name>",
+		Desc: `Grant access to team in a service instance. After granting, the teams with
+access to the service instance are displayed.`,
 		MinArgs: 3,
 	}
 }
@@ -732,16 +1264,17 @@ func (c *ServiceInstanceGrant) Run(ctx *cmd.Context, client *cmd.Client) error {
 		return err
 	}
 	fmt.Fprintf(ctx.Stdout, `Granted access to team %s in %s service instance.`+"\n", teamName, siName)
-	return nil
+	return renderServiceInstanceTeams(ctx.Stdout, client, sName, siName)
 }
 
 type ServiceInstanceRevoke struct{}
 
 func (c *ServiceInstanceRevoke) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "service-instance-revoke",
-		Usage:   "service-instance-revoke <service-name> <service-instance-name> <team-name>",
-		Desc:    `Revoke access to team in a service instance.`,
+		Name:  "service-instance-revoke",
+		Usage: "service-instance-revoke <service-name> <service-instance-name> <team-name>",
+		Desc: `Revoke access to team in a service instance. After revoking, the teams with
+access to the service instance are displayed.`,
 		MinArgs: 3,
 	}
 }
@@ -764,5 +1297,14 @@ func (c *ServiceInstanceRevoke) Run(ctx *cmd.Context, client *cmd.Client) error
 		return err
 	}
 	fmt.Fprintf(ctx.Stdout, `Revoked access to team %s in %s service instance.`+"\n", teamName, siName)
+	return renderServiceInstanceTeams(ctx.Stdout, client, sName, siName)
+}
+
+func renderServiceInstanceTeams(w io.Writer, client *cmd.Client, serviceName, instanceName string) error {
+	si, err := getServiceInstance(client, serviceName, instanceName)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Teams with access to %s: %s\n", instanceName, strings.Join(si.Teams, ", "))
 	return nil
 }
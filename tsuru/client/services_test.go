@@ -9,9 +9,12 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"reflect"
 	"strings"
+	"time"
 
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"github.com/tsuru/tsuru/io"
 	"gopkg.in/check.v1"
@@ -143,6 +146,7 @@ func (s *S) TestServiceBind(c *check.C) {
 		stdout, stderr bytes.Buffer
 	)
 	ctx := cmd.Context{
+		Quiet:  true,
 		Args:   []string{"mysql", "my-mysql"},
 		Stdout: &stdout,
 		Stderr: &stderr,
@@ -176,6 +180,7 @@ func (s *S) TestServiceBindWithoutFlag(c *check.C) {
 		stdout, stderr bytes.Buffer
 	)
 	ctx := cmd.Context{
+		Quiet:  true,
 		Args:   []string{"mysql", "my-mysql"},
 		Stdout: &stdout,
 		Stderr: &stderr,
@@ -208,6 +213,7 @@ func (s *S) TestServiceBindWithoutFlag(c *check.C) {
 func (s *S) TestServiceBindWithoutEnvironmentVariables(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	ctx := cmd.Context{
+		Quiet:  true,
 		Args:   []string{"mysql", "my-mysql"},
 		Stdout: &stdout,
 		Stderr: &stderr,
@@ -233,6 +239,188 @@ func (s *S) TestServiceBindWithoutEnvironmentVariables(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expectedOut)
 }
 
+func (s *S) TestServiceBindShowsAddedEnvironmentVariables(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	ctx := cmd.Context{
+		Args:   []string{"mysql", "my-mysql"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Message: "binding\n\nInstance \"my-mysql\" is now bound to the app \"g1\".\nThe following environment variables are available for use in your app:\n\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	var lines []string
+	for _, name := range []string{"DATABASE_USER", "DATABASE_HOST", "TSURU_SERVICES"} {
+		msg = io.SimpleJsonMessage{Message: "- " + name + "\n"}
+		line, err := json.Marshal(msg)
+		c.Assert(err, check.IsNil)
+		lines = append(lines, string(line))
+	}
+	msg = io.SimpleJsonMessage{Message: "restarting\n"}
+	restart, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: string(result) + "\n" + strings.Join(lines, "\n") + "\n" + string(restart), Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Method == "PUT" && strings.HasSuffix(req.URL.Path, "/services/mysql/instances/my-mysql/g1")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceBind{}
+	command.Flags().Parse(true, []string{"-a", "g1"})
+	err = command.Run(&ctx, client)
+	c.Assert(err, check.IsNil)
+	expected := `binding
+
+Instance "my-mysql" is now bound to the app "g1".
+restarting
+Environment variables set in app "g1":
+  DATABASE_HOST=*** (private variable)
+  DATABASE_USER=*** (private variable)
+  TSURU_SERVICES=*** (private variable)
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestServiceBindReportsNoEnvironmentVariables(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	ctx := cmd.Context{
+		Args:   []string{"mysql", "my-mysql"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Message: "binding\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.Transport{Message: string(result), Status: http.StatusOK}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceBind{}
+	command.Flags().Parse(true, []string{"-a", "g1"})
+	err = command.Run(&ctx, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "binding\nNo environment variables were set in app \"g1\".\n")
+}
+
+func (s *S) TestServiceBindWithCreate(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	ctx := cmd.Context{
+		Args:   []string{"mysql", "my-mysql"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Message: "binding\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: "service instance not found", Status: http.StatusNotFound},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/services/mysql/instances/my-mysql")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `[{"Name":"small"}]`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/services/mysql/plans")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusCreated},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "POST" && strings.HasSuffix(req.URL.Path, "/services/mysql/instances") &&
+						req.FormValue("name") == "my-mysql" && req.FormValue("plan") == "small" &&
+						req.FormValue("owner") == "myteam"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "PUT" && strings.HasSuffix(req.URL.Path, "/services/mysql/instances/my-mysql/g1")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceBind{}
+	command.Flags().Parse(true, []string{"-a", "g1", "--create", "-p", "small", "-t", "myteam"})
+	err = command.Run(&ctx, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Service instance \"my-mysql\" created.\nbinding\nNo environment variables were set in app \"g1\".\n")
+}
+
+func (s *S) TestServiceBindWithCreateExistingInstance(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	ctx := cmd.Context{
+		Quiet:  true,
+		Args:   []string{"mysql", "my-mysql"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Message: "binding\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `{"InstanceName":"my-mysql"}`, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/services/mysql/instances/my-mysql")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "PUT" && strings.HasSuffix(req.URL.Path, "/services/mysql/instances/my-mysql/g1")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceBind{}
+	command.Flags().Parse(true, []string{"-a", "g1", "--create"})
+	err = command.Run(&ctx, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "binding\n")
+}
+
+func (s *S) TestServiceBindWithCreateFailure(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	ctx := cmd.Context{
+		Quiet:  true,
+		Args:   []string{"mysql", "my-mysql"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: "service instance not found", Status: http.StatusNotFound},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/services/mysql/instances/my-mysql")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"admin","permissions":["service-instance"]}]`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/teams")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "quota exceeded", Status: http.StatusForbidden},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "POST" && strings.HasSuffix(req.URL.Path, "/services/mysql/instances")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceBind{}
+	command.Flags().Parse(true, []string{"-a", "g1", "--create"})
+	err := command.Run(&ctx, client)
+	c.Assert(err, check.ErrorMatches, `Failed to create service instance "my-mysql", the app was not bound: quota exceeded`)
+}
+
 func (s *S) TestServiceBindWithRequestFailure(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	ctx := cmd.Context{
@@ -262,6 +450,7 @@ func (s *S) TestServiceUnbind(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	var called bool
 	ctx := cmd.Context{
+		Quiet:  true,
 		Args:   []string{"service", "hand"},
 		Stdout: &stdout,
 		Stderr: &stderr,
@@ -291,6 +480,7 @@ func (s *S) TestServiceUnbindWithoutFlag(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	var called bool
 	ctx := cmd.Context{
+		Quiet:  true,
 		Args:   []string{"service", "hand"},
 		Stdout: &stdout,
 		Stderr: &stderr,
@@ -315,6 +505,125 @@ func (s *S) TestServiceUnbindWithoutFlag(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expectedOut)
 }
 
+func (s *S) TestServiceUnbindShowsRemovedEnvironmentVariables(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	ctx := cmd.Context{
+		Args:   []string{"service", "hand"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Message: "---- Unsetting 1 environment variables ----\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	envs := `[{"name":"PORT","value":"8888","public":true},{"name":"SERVICE_URL","value":"","public":false},` +
+		`{"name":"TSURU_SERVICES","value":"{\"service\":[{\"instance_name\":\"hand\",\"envs\":{\"SERVICE_URL\":\"http://hand\"}}]}","public":false}]`
+	var gets int
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: envs, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					if req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/pocket/env") {
+						gets++
+						return true
+					}
+					return false
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "DELETE" && strings.HasSuffix(req.URL.Path, "/services/service/instances/hand/pocket")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceUnbind{}
+	command.Flags().Parse(true, []string{"-a", "pocket"})
+	err = command.Run(&ctx, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(gets, check.Equals, 1)
+	expected := `---- Unsetting 1 environment variables ----
+Environment variables removed from app "pocket":
+  SERVICE_URL=*** (private variable)
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestServiceUnbindWithoutRemovedEnvironmentVariables(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	ctx := cmd.Context{
+		Args:   []string{"service", "hand"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Message: "unbinding\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	envs := `[{"name":"TSURU_SERVICES","value":"{\"service\":[{\"instance_name\":\"hand\",\"envs\":{\"SERVICE_URL\":\"http://hand\"}}]}","public":false}]`
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: envs, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/pocket/env")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "DELETE" && strings.HasSuffix(req.URL.Path, "/services/service/instances/hand/pocket")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceUnbind{}
+	command.Flags().Parse(true, []string{"-a", "pocket"})
+	err = command.Run(&ctx, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "unbinding\nNo environment variables were removed from app \"pocket\".\n")
+}
+
+func (s *S) TestServiceUnbindWhenTheEnvironmentVariablesCantBeListed(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	ctx := cmd.Context{
+		Args:   []string{"service", "hand"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	msg := io.SimpleJsonMessage{Message: "---- Unsetting 2 environment variables ----\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	var deleted bool
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: "internal error", Status: http.StatusInternalServerError},
+				CondFunc: func(req *http.Request) bool {
+					return req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/apps/pocket/env")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					deleted = req.Method == "DELETE" && strings.HasSuffix(req.URL.Path, "/services/service/instances/hand/pocket")
+					return deleted
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceUnbind{}
+	command.Flags().Parse(true, []string{"-a", "pocket"})
+	err = command.Run(&ctx, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(deleted, check.Equals, true)
+	c.Assert(stderr.String(), check.Equals, "Unable to list the environment variables of app \"pocket\", their names won't be displayed: internal error\n")
+	c.Assert(stdout.String(), check.Equals, "---- Unsetting 2 environment variables ----\n2 environment variables were removed from app \"pocket\".\n")
+}
+
 func (s *S) TestServiceUnbindWithRequestFailure(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	ctx := cmd.Context{
@@ -358,17 +667,33 @@ func (s *S) TestServiceAddRun(c *check.C) {
 		Stdout: &stdout,
 		Stderr: &stderr,
 	}
-	trans := cmdtest.ConditionalTransport{
-		Transport: cmdtest.Transport{Message: result, Status: http.StatusOK},
-		CondFunc: func(r *http.Request) bool {
-			name := r.FormValue("name") == "my_app_db"
-			plan := r.FormValue("plan") == "small"
-			owner := r.FormValue("owner ") == ""
-			description := r.FormValue("description") == ""
-			method := r.Method == "POST"
-			contentType := r.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
-			url := strings.HasSuffix(r.URL.Path, "/services/mysql/instances")
-			return method && url && name && owner && plan && description && contentType
+	trans := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `[{"Name":"small"},{"Name":"big"}]`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/services/mysql/plans")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"admin","permissions":["service-instance"]}]`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/teams")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: result, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					name := r.FormValue("name") == "my_app_db"
+					plan := r.FormValue("plan") == "small"
+					owner := r.FormValue("owner") == "admin"
+					description := r.FormValue("description") == ""
+					method := r.Method == "POST"
+					contentType := r.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
+					url := strings.HasSuffix(r.URL.Path, "/services/mysql/instances")
+					return method && url && name && owner && plan && description && contentType
+				},
+			},
 		},
 	}
 	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
@@ -378,6 +703,151 @@ func (s *S) TestServiceAddRun(c *check.C) {
 	c.Assert(obtained, check.Equals, result)
 }
 
+func (s *S) TestServiceAddRunQuiet(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"mysql", "my_app_db", "small"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Quiet:  true,
+	}
+	trans := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `[{"Name":"small"},{"Name":"big"}]`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/services/mysql/plans")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"admin","permissions":["service-instance"]}]`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/teams")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusCreated},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/services/mysql/instances")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	err := (&ServiceInstanceAdd{}).Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
+func (s *S) TestServiceAddRunWithPlanAndTags(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"mysql", "my_app_db"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `[{"Name":"small"},{"Name":"big"}]`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/services/mysql/plans")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusCreated},
+				CondFunc: func(r *http.Request) bool {
+					r.ParseForm()
+					return r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/services/mysql/instances") &&
+						r.FormValue("plan") == "big" && r.FormValue("owner") == "myteam" &&
+						reflect.DeepEqual(r.Form["tag"], []string{"tag1", "tag2"})
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := ServiceInstanceAdd{}
+	command.Flags().Parse(true, []string{"--plan", "big", "-t", "myteam", "--tag", "tag1", "-g", "tag2"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Service successfully added.\n")
+}
+
+func (s *S) TestServiceAddRunTeamFlag(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"mysql", "my_app_db"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusCreated},
+		CondFunc: func(r *http.Request) bool {
+			return r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/services/mysql/instances") && r.FormValue("owner") == "myteam"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := ServiceInstanceAdd{}
+	command.Flags().Parse(true, []string{"--team", "myteam"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestServiceAddRunInvalidPlan(c *check.C) {
+	context := cmd.Context{
+		Args:   []string{"mysql", "my_app_db"},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	}
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `[{"Name":"small"},{"Name":"big"}]`, Status: http.StatusOK},
+		CondFunc: func(r *http.Request) bool {
+			return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/services/mysql/plans")
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := ServiceInstanceAdd{}
+	command.Flags().Parse(true, []string{"-p", "huge"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Plan "huge" does not exist for service "mysql". Available plans: small, big.`)
+}
+
+func (s *S) TestServiceAddRunPlansNotExposed(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"mysql", "my_app_db"},
+		Stdout: &stdout,
+		Stderr: &bytes.Buffer{},
+	}
+	trans := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: "not found", Status: http.StatusNotFound},
+				CondFunc: func(r *http.Request) bool {
+					return strings.HasSuffix(r.URL.Path, "/services/mysql/plans")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `[{"name":"admin","permissions":["service-instance"]}]`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/teams")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: "", Status: http.StatusCreated},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "POST" && r.FormValue("plan") == "huge"
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := ServiceInstanceAdd{}
+	command.Flags().Parse(true, []string{"-p", "huge"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Service successfully added.\n")
+}
+
 func (s *S) TestServiceAddFlags(c *check.C) {
 	flagDesc := "the team that owns the service (mandatory if the user is member of more than one team)"
 	command := ServiceInstanceAdd{}
@@ -469,6 +939,114 @@ func (s *S) TestServiceUpdateFlags(c *check.C) {
 	c.Check(command.description, check.Equals, "description")
 }
 
+func bindStatusTransport(instance, envs string) transportFunc {
+	return transportFunc(func(req *http.Request) (*http.Response, error) {
+		var body string
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/services/mysql/instances/mydb"):
+			body = instance
+		case strings.HasSuffix(req.URL.Path, "/apps/myapp/env"):
+			body = envs
+		}
+		return &http.Response{
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+}
+
+func (s *S) TestServiceInstanceBindStatusInfo(c *check.C) {
+	c.Assert((&ServiceInstanceBindStatus{}).Info(), check.NotNil)
+}
+
+func (s *S) TestServiceInstanceBindStatusRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"mysql", "mydb"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	envs := `[{"name":"DATABASE_HOST","value":"10.0.0.1","public":false},` +
+		`{"name":"TSURU_SERVICES","value":"{\"mysql\":[{\"instance_name\":\"mydb\",\"envs\":{\"DATABASE_HOST\":\"10.0.0.1\",\"DATABASE_PASSWORD\":\"s3cr3t\"}}]}","public":false}]`
+	trans := bindStatusTransport(`{"Apps":["otherapp","myapp"]}`, envs)
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceBindStatus{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `App "myapp" is bound to service instance "mydb" of service "mysql".
+Environment variables injected by the bind:
+  DATABASE_HOST=*** (private variable)
+  DATABASE_PASSWORD (missing in the app)
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+	c.Assert(strings.Contains(stdout.String(), "s3cr3t"), check.Equals, false)
+}
+
+func (s *S) TestServiceInstanceBindStatusRunWithoutEnvs(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"mysql", "mydb"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := bindStatusTransport(`{"Apps":["myapp"]}`, `[]`)
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceBindStatus{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `App "myapp" is bound to service instance "mydb" of service "mysql".
+No environment variables were injected by the bind.
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestServiceInstanceBindStatusRunNotBound(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"mysql", "mydb"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := bindStatusTransport(`{"Apps":["otherapp"]}`, `[{"name":"PORT","value":"8888","public":true}]`)
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceBindStatus{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "App \"myapp\" is not bound to service instance \"mydb\" of service \"mysql\".\n")
+}
+
+func (s *S) TestServiceInstanceBindStatusRunJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"mysql", "mydb"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	envs := `[{"name":"DATABASE_HOST","value":"10.0.0.1","public":false},` +
+		`{"name":"TSURU_SERVICES","value":"{\"mysql\":[{\"instance_name\":\"mydb\",\"envs\":{\"DATABASE_HOST\":\"10.0.0.1\"}}]}","public":false}]`
+	trans := bindStatusTransport(`{"Apps":["myapp"]}`, envs)
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := ServiceInstanceBindStatus{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var data map[string]interface{}
+	err = json.Unmarshal(stdout.Bytes(), &data)
+	c.Assert(err, check.IsNil)
+	c.Assert(data, check.DeepEquals, map[string]interface{}{
+		"app":      "myapp",
+		"service":  "mysql",
+		"instance": "mydb",
+		"bound":    true,
+		"envs": []interface{}{
+			map[string]interface{}{"name": "DATABASE_HOST", "set": true},
+		},
+	})
+}
+
 func (s *S) TestServiceInstanceStatusInfo(c *check.C) {
 	got := (&ServiceInstanceStatus{}).Info()
 	c.Assert(got, check.NotNil)
@@ -491,6 +1069,80 @@ func (s *S) TestServiceInstanceStatusRun(c *check.C) {
 	c.Assert(obtained, check.Equals, result)
 }
 
+func (s *S) TestServiceInstanceStatusRunWait(c *check.C) {
+	defer func(d time.Duration) { statusPollInterval = d }(statusPollInterval)
+	statusPollInterval = time.Millisecond
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"foo", "fooBar"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	down := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `Service instance "fooBar" is down`, Status: http.StatusInternalServerError},
+		CondFunc: func(r *http.Request) bool {
+			return strings.HasSuffix(r.URL.Path, "/services/foo/instances/fooBar/status")
+		},
+	}
+	up := down
+	up.Transport = cmdtest.Transport{Message: `Service instance "fooBar" is up`, Status: http.StatusOK}
+	trans := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{down, down, up},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := ServiceInstanceStatus{}
+	command.Flags().Parse(true, []string{"--wait"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, `Service instance "fooBar" is down
+Service instance "fooBar" is up
+`)
+}
+
+func (s *S) TestServiceInstanceStatusRunWaitTimeout(c *check.C) {
+	defer func(d time.Duration) { statusPollInterval = d }(statusPollInterval)
+	statusPollInterval = time.Millisecond
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"foo", "fooBar"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	trans := cmdtest.Transport{Message: `Service instance "fooBar" is down`, Status: http.StatusInternalServerError}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := ServiceInstanceStatus{}
+	command.Flags().Parse(true, []string{"--wait", "--timeout", "10ms"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `Timed out after 10ms waiting for service instance "fooBar" to be up.`)
+	c.Assert(stdout.String(), check.Equals, `Service instance "fooBar" is down`+"\n")
+}
+
+func (s *S) TestServiceInstanceStatusRunWaitClientError(c *check.C) {
+	defer func(d time.Duration) { statusPollInterval = d }(statusPollInterval)
+	statusPollInterval = time.Millisecond
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"foo", "fooBar"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	notFound := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "service instance not found", Status: http.StatusNotFound},
+		CondFunc: func(r *http.Request) bool {
+			return strings.HasSuffix(r.URL.Path, "/services/foo/instances/fooBar/status")
+		},
+	}
+	trans := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{notFound},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &trans}, nil, manager)
+	command := ServiceInstanceStatus{}
+	command.Flags().Parse(true, []string{"--wait", "--timeout", "1m"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "service instance not found")
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
 func (s *S) TestServiceInfoInfo(c *check.C) {
 	got := (&ServiceInfo{}).Info()
 	c.Assert(got, check.NotNil)
@@ -594,6 +1246,40 @@ Plans
 	c.Assert(obtained, check.Equals, expected)
 }
 
+func (s *S) TestServiceInfoRunYAMLOutput(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	expected := `documentation: |+
+  This is a test doc for a test service.
+  Service test is foo bar.
+instances:
+- Apps:
+  - myapp
+  Id: 0
+  Info:
+    key: value
+    key2: value2
+  Name: mymongo
+  PlanName: small
+  ServiceName: mongoservice
+  Teams:
+  - mongoteam
+plans:
+- Description: another plan
+  Name: small
+service: mongo
+`
+	context := cmd.Context{
+		Args:         []string{"mongo"},
+		Stdout:       &stdout,
+		Stderr:       &stderr,
+		OutputFormat: cmd.YAMLOutput,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &infoTransport{includePlans: true}}, nil, manager)
+	err := (&ServiceInfo{}).Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
 func (s *S) TestServiceInfoNoPlans(c *check.C) {
 	var stdout, stderr bytes.Buffer
 	expected := `Info for "mongodbnoplan"
@@ -902,18 +1588,29 @@ func (s *S) TestServiceInstanceGrantRun(c *check.C) {
 		Stdout: &stdout,
 		Stderr: &stderr,
 	}
-	transp := cmdtest.ConditionalTransport{
-		Transport: cmdtest.Transport{
-			Message: "",
-			Status:  http.StatusOK},
-		CondFunc: func(r *http.Request) bool {
-			path := "/services/test-service/instances/permission/test-service-instance/team"
-			return strings.HasSuffix(r.URL.Path, path) && "PUT" == r.Method
+	transp := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{
+					Message: "",
+					Status:  http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					path := "/services/test-service/instances/permission/test-service-instance/team"
+					return strings.HasSuffix(r.URL.Path, path) && "PUT" == r.Method
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `{"Teams":["admin","team"]}`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return strings.HasSuffix(r.URL.Path, "/services/test-service/instances/test-service-instance") && r.Method == "GET"
+				},
+			},
 		},
 	}
 	client := cmd.NewClient(&http.Client{Transport: &transp}, nil, manager)
 	err := command.Run(&ctx, client)
 	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Granted access to team team in test-service-instance service instance.\nTeams with access to test-service-instance: admin, team\n")
 }
 
 func (s *S) TestServiceInstanceRevokeInfo(c *check.C) {
@@ -929,16 +1626,60 @@ func (s *S) TestServiceInstanceRevokeRun(c *check.C) {
 		Stdout: &stdout,
 		Stderr: &stderr,
 	}
-	transp := cmdtest.ConditionalTransport{
-		Transport: cmdtest.Transport{
-			Message: "",
-			Status:  http.StatusOK},
-		CondFunc: func(r *http.Request) bool {
-			path := "/services/test-service/instances/permission/test-service-instance/team"
-			return strings.HasSuffix(r.URL.Path, path) && "DELETE" == r.Method
+	transp := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{
+					Message: "",
+					Status:  http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					path := "/services/test-service/instances/permission/test-service-instance/team"
+					return strings.HasSuffix(r.URL.Path, path) && "DELETE" == r.Method
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: `{"Teams":["admin"]}`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return strings.HasSuffix(r.URL.Path, "/services/test-service/instances/test-service-instance") && r.Method == "GET"
+				},
+			},
 		},
 	}
 	client := cmd.NewClient(&http.Client{Transport: &transp}, nil, manager)
 	err := command.Run(&ctx, client)
 	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Revoked access to team team in test-service-instance service instance.\nTeams with access to test-service-instance: admin\n")
+}
+
+func (s *S) TestServiceAddRunWithParams(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"mysql", "my_app_db"},
+		Stdout: &stdout,
+		Stderr: &bytes.Buffer{},
+	}
+	trans := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusCreated},
+		CondFunc: func(r *http.Request) bool {
+			return r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/services/mysql/instances") &&
+				r.FormValue("parameters.region") == "us-east" &&
+				r.FormValue("parameters.storage.size") == "10G=large"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: noTeamsTransport{&trans}}, nil, manager)
+	command := ServiceInstanceAdd{}
+	err := command.Flags().Parse(true, []string{"--param", "region=us-east", "--param", "storage.size=10G=large"})
+	c.Assert(err, check.IsNil)
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Service successfully added.\n")
+}
+
+func (s *S) TestServiceAddFlagsInvalidParam(c *check.C) {
+	command := ServiceInstanceAdd{}
+	flagset := command.Flags()
+	flagset.Init("service-instance-add", gnuflag.ContinueOnError)
+	flagset.SetOutput(ioutil.Discard)
+	err := flagset.Parse(true, []string{"--param", "region"})
+	c.Assert(err, check.ErrorMatches, `.*invalid value "region", expected key=value.*`)
 }
@@ -0,0 +1,154 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"syscall"
+
+	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	"golang.org/x/crypto/ssh/terminal"
+	"golang.org/x/net/websocket"
+)
+
+var httpRegexp = regexp.MustCompile(`^http`)
+
+type descriptable interface {
+	Fd() uintptr
+}
+
+type ShellToContainerCmd struct {
+	cmd.GuessingCommand
+	unit string
+	fs   *gnuflag.FlagSet
+}
+
+func (c *ShellToContainerCmd) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "app-shell",
+		Usage: "app-shell [unit-id] [-a/--app appname] [-u/--unit unit-id]",
+		Desc: `Opens a remote shell inside unit, using the API server as a proxy. You
+can access an app unit just giving app name, or specifying the id of the unit.
+You can get the ID of the unit using the app-info command.
+
+The [[--unit]] parameter selects the unit to connect to. If it's not informed,
+tsuru will open the shell in the first unit of the app.
+
+When running in a terminal, the remote terminal is opened with the size of the
+local one. The tsuru API only reads the size when the connection is opened and
+has no way to change it later, so resizing the local terminal afterwards
+doesn't resize the remote one.`,
+		MinArgs: 0,
+		MaxArgs: 1,
+	}
+}
+
+func (c *ShellToContainerCmd) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		unitMessage := "The ID of the unit to open the shell in"
+		c.fs.StringVar(&c.unit, "unit", "", unitMessage)
+		c.fs.StringVar(&c.unit, "u", "", unitMessage)
+	}
+	return c.fs
+}
+
+func (c *ShellToContainerCmd) Run(context *cmd.Context, client *cmd.Client) error {
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	unitID := c.unit
+	if unitID == "" && len(context.Args) > 0 {
+		unitID = context.Args[0]
+	}
+	if unitID == "" {
+		unitID, err = firstUnit(appName, client)
+		if err != nil {
+			return err
+		}
+	}
+	context.RawOutput()
+	var width, height int
+	if desc, ok := context.Stdin.(descriptable); ok {
+		fd := int(desc.Fd())
+		if terminal.IsTerminal(fd) {
+			width, height, _ = terminal.GetSize(fd)
+			oldState, terminalErr := terminal.MakeRaw(fd)
+			if terminalErr != nil {
+				return terminalErr
+			}
+			defer terminal.Restore(fd, oldState)
+			sigChan := make(chan os.Signal, 2)
+			go func(c <-chan os.Signal) {
+				if _, ok := <-c; ok {
+					terminal.Restore(fd, oldState)
+					os.Exit(1)
+				}
+			}(sigChan)
+			signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+			defer signal.Stop(sigChan)
+		}
+	}
+	// The shell endpoint reads the size only from the query string of the
+	// connection request, and the websocket carries nothing but the streams
+	// of the shell afterwards. Following resizes of the local terminal needs
+	// a resize message in the API first, so SIGWINCH is not handled here.
+	queryString := make(url.Values)
+	queryString.Set("width", strconv.Itoa(width))
+	queryString.Set("height", strconv.Itoa(height))
+	queryString.Set("unit", unitID)
+	queryString.Set("container_id", unitID)
+	if term := os.Getenv("TERM"); term != "" {
+		queryString.Set("term", term)
+	}
+	serverURL, err := cmd.GetURL(fmt.Sprintf("/apps/%s/shell?%s", appName, queryString.Encode()))
+	if err != nil {
+		return err
+	}
+	serverURL = httpRegexp.ReplaceAllString(serverURL, "ws")
+	config, err := websocket.NewConfig(serverURL, "ws://localhost")
+	if err != nil {
+		return err
+	}
+	conn, err := client.DialWebsocket(config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	errs := make(chan error, 2)
+	quit := make(chan bool)
+	go io.Copy(conn, context.Stdin)
+	go func() {
+		defer close(quit)
+		_, err := io.Copy(context.Stdout, conn)
+		if err != nil && err != io.EOF {
+			errs <- err
+		}
+	}()
+	<-quit
+	close(errs)
+	return <-errs
+}
+
+func firstUnit(appName string, client *cmd.Client) (string, error) {
+	a, err := getApp(client, appName)
+	if err != nil {
+		return "", err
+	}
+	for _, unit := range a.Units {
+		if unit.ID != "" {
+			return unit.ID, nil
+		}
+	}
+	return "", fmt.Errorf("app %q has no units.", appName)
+}
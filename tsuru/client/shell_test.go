@@ -0,0 +1,114 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	"golang.org/x/net/websocket"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestShellToContainerCmdInfo(c *check.C) {
+	c.Assert((&ShellToContainerCmd{}).Info(), check.NotNil)
+}
+
+func (s *S) TestShellToContainerCmdFlags(c *check.C) {
+	command := ShellToContainerCmd{}
+	flagset := command.Flags()
+	c.Assert(flagset, check.NotNil)
+	flagset.Parse(true, []string{"-a", "myapp", "-u", "abc123"})
+	unit := flagset.Lookup("unit")
+	c.Assert(unit, check.NotNil)
+	c.Assert(unit.Value.String(), check.Equals, "abc123")
+	c.Assert(command.unit, check.Equals, "abc123")
+}
+
+func shellTestServer(appJSON string, units chan<- string) *httptest.Server {
+	shell := websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+		unit := ws.Request().URL.Query().Get("unit")
+		units <- unit
+		input := make([]byte, 5)
+		io.ReadFull(ws, input)
+		fmt.Fprintf(ws, "%s from %s\n", input, unit)
+	})
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/shell") {
+			shell.ServeHTTP(w, r)
+			return
+		}
+		w.Write([]byte(appJSON))
+	}))
+}
+
+func (s *S) TestShellToContainerCmdRunFirstUnit(c *check.C) {
+	appJSON := `{"name":"myapp","units":[{"ID":"","Status":"building"},{"ID":"unit1","Status":"started"},{"ID":"unit2","Status":"started"}]}`
+	units := make(chan string, 1)
+	server := shellTestServer(appJSON, units)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	defer os.Setenv("TSURU_TARGET", "http://localhost:8080")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("hello"),
+	}
+	client := cmd.NewClient(http.DefaultClient, nil, manager)
+	command := ShellToContainerCmd{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(<-units, check.Equals, "unit1")
+	c.Assert(stdout.String(), check.Equals, "hello from unit1\n")
+}
+
+func (s *S) TestShellToContainerCmdRunWithUnitFlag(c *check.C) {
+	units := make(chan string, 1)
+	server := shellTestServer(`{"name":"myapp"}`, units)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	defer os.Setenv("TSURU_TARGET", "http://localhost:8080")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("hello"),
+	}
+	client := cmd.NewClient(http.DefaultClient, nil, manager)
+	command := ShellToContainerCmd{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--unit", "unit2"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(<-units, check.Equals, "unit2")
+	c.Assert(stdout.String(), check.Equals, "hello from unit2\n")
+}
+
+func (s *S) TestShellToContainerCmdRunNoUnits(c *check.C) {
+	units := make(chan string, 1)
+	server := shellTestServer(`{"name":"myapp","units":[]}`, units)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	defer os.Setenv("TSURU_TARGET", "http://localhost:8080")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader(""),
+	}
+	client := cmd.NewClient(http.DefaultClient, nil, manager)
+	command := ShellToContainerCmd{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `app "myapp" has no units.`)
+}
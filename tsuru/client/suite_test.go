@@ -9,7 +9,7 @@ import (
 	"os"
 	"testing"
 
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"gopkg.in/check.v1"
 )
 
@@ -13,7 +13,7 @@ import (
 	"strings"
 
 	"github.com/tsuru/gnuflag"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/errors"
 )
 
@@ -21,13 +21,14 @@ type AppSwap struct {
 	cmd.Command
 	force     bool
 	cnameOnly bool
+	dryRun    bool
 	fs        *gnuflag.FlagSet
 }
 
 func (s *AppSwap) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "app-swap",
-		Usage: "app-swap <app1-name> <app2-name> [-f/--force] [-c/--cname-only]",
+		Usage: "app-swap <app1-name> <app2-name> [-f/--force] [-c/--cname-only] [--dry-run]",
 		Desc: `Swaps routing between two apps. This allows zero downtime and makes rollback
 as simple as swapping the applications back.
 
@@ -35,7 +36,16 @@ Use [[--force]] if you want to swap applications with a different number of
 units or different platform without confirmation.
 
 Use [[--cname-only]] if you want to swap all cnames except the default
-cname of application`,
+cname of application. In this mode the internal addresses of both apps stay
+where they are and only the custom domains are moved, which is useful for
+blue/green cutovers. If one of the apps has no cname, the cnames of the other
+app are moved to it and the other app is left with only its default address.
+
+By default, the full routing of the apps is swapped.
+
+Use [[--dry-run]] to preview the addresses of both apps before and after the
+swap, without actually swapping them. The preview also warns when the apps
+have a different number of units or different platforms.`,
 		MinArgs: 2,
 	}
 }
@@ -47,11 +57,15 @@ func (s *AppSwap) Flags() *gnuflag.FlagSet {
 		s.fs.BoolVar(&s.force, "f", false, "Force Swap among apps with different number of units or different platform.")
 		s.fs.BoolVar(&s.cnameOnly, "cname-only", false, "Swap all cnames except the default cname.")
 		s.fs.BoolVar(&s.cnameOnly, "c", false, "Swap all cnames except the default cname.")
+		s.fs.BoolVar(&s.dryRun, "dry-run", false, "Preview the swap without performing it.")
 	}
 	return s.fs
 }
 
 func (s *AppSwap) Run(context *cmd.Context, client *cmd.Client) error {
+	if s.dryRun {
+		return s.preview(context, client)
+	}
 	v := url.Values{}
 	v.Set("app1", context.Args[0])
 	v.Set("app2", context.Args[1])
@@ -84,7 +98,7 @@ func (s *AppSwap) Run(context *cmd.Context, client *cmd.Client) error {
 		}
 		return err
 	}
-	fmt.Fprintln(context.Stdout, "Apps successfully swapped!")
+	context.Successf("Apps successfully swapped!\n")
 	return err
 }
 
@@ -97,3 +111,55 @@ func makeSwap(client *cmd.Client, url string, body io.Reader) error {
 	_, err = client.Do(request)
 	return err
 }
+
+func (s *AppSwap) preview(context *cmd.Context, client *cmd.Client) error {
+	app1, err := getApp(client, context.Args[0])
+	if err != nil {
+		return err
+	}
+	app2, err := getApp(client, context.Args[1])
+	if err != nil {
+		return err
+	}
+	after1, after2 := *app2, *app1
+	after1.Name, after2.Name = app1.Name, app2.Name
+	if s.cnameOnly {
+		after1.IP, after2.IP = app1.IP, app2.IP
+	}
+	mode := "full routing"
+	if s.cnameOnly {
+		mode = "cnames only"
+	}
+	fmt.Fprintf(context.Stdout, "Dry run: nothing will be swapped (%s).\n\n", mode)
+	fmt.Fprintln(context.Stdout, "Before:")
+	context.Stdout.Write(swapPreviewTable(app1, app2).Bytes())
+	fmt.Fprintln(context.Stdout, "\nAfter:")
+	context.Stdout.Write(swapPreviewTable(&after1, &after2).Bytes())
+	units1, units2 := countUnits(app1), countUnits(app2)
+	if units1 != units2 {
+		fmt.Fprintf(context.Stdout, "\nWARNING: apps have a different number of units (%s: %d, %s: %d).\n", app1.Name, units1, app2.Name, units2)
+	}
+	if app1.Platform != app2.Platform {
+		fmt.Fprintf(context.Stdout, "\nWARNING: apps have different platforms (%s: %s, %s: %s).\n", app1.Name, app1.Platform, app2.Name, app2.Platform)
+	}
+	return nil
+}
+
+func swapPreviewTable(apps ...*app) *cmd.Table {
+	table := cmd.NewTable()
+	table.Headers = cmd.Row([]string{"App", "Address", "Cnames"})
+	for _, a := range apps {
+		table.AddRow(cmd.Row([]string{a.Name, a.IP, strings.Join(a.CName, "\n")}))
+	}
+	return table
+}
+
+func countUnits(a *app) int {
+	var total int
+	for _, unit := range a.Units {
+		if unit.ID != "" {
+			total++
+		}
+	}
+	return total
+}
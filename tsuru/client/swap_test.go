@@ -9,7 +9,7 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"gopkg.in/check.v1"
 )
@@ -142,6 +142,148 @@ func (s *S) TestSwapWhenAppsAreNotEqual(c *check.C) {
 	c.Assert(called, check.Equals, 2)
 }
 
+func (s *S) TestSwapCnameOnlyWhenAppsAreNotEqual(c *check.C) {
+	var buf bytes.Buffer
+	var called int
+	stdin := bytes.NewBufferString("y")
+	transportError := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Status: http.StatusPreconditionFailed, Message: "Apps are not equal."},
+		CondFunc: func(r *http.Request) bool {
+			called += 1
+			forceSwap := r.FormValue("force") == "false"
+			cnameOnly := r.FormValue("cnameOnly") == "true"
+			return cnameOnly && forceSwap
+		},
+	}
+	transportOk := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Status: http.StatusOK, Message: ""},
+		CondFunc: func(r *http.Request) bool {
+			called += 1
+			forceSwap := r.FormValue("force") == "true"
+			cnameOnly := r.FormValue("cnameOnly") == "true"
+			return cnameOnly && forceSwap
+		},
+	}
+	multiTransport := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{transportError, transportOk},
+	}
+	context := cmd.Context{
+		Args:   []string{"app1", "app2"},
+		Stdout: &buf,
+		Stdin:  stdin,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &multiTransport}, nil, manager)
+	command := AppSwap{}
+	command.Flags().Parse(true, []string{"--cname-only"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, 2)
+}
+
+func (s *S) TestSwapDryRun(c *check.C) {
+	var buf bytes.Buffer
+	app1 := `{"name":"app1","platform":"python","ip":"app1.tsuru.io","cname":["www.example.com"],"units":[{"ID":"u1"},{"ID":"u2"}]}`
+	app2 := `{"name":"app2","platform":"go","ip":"app2.tsuru.io","units":[{"ID":"u3"}]}`
+	transport := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Status: http.StatusOK, Message: app1},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/apps/app1")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Status: http.StatusOK, Message: app2},
+				CondFunc: func(r *http.Request) bool {
+					return r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/apps/app2")
+				},
+			},
+		},
+	}
+	context := cmd.Context{
+		Args:   []string{"app1", "app2"},
+		Stdout: &buf,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &transport}, nil, manager)
+	command := AppSwap{}
+	command.Flags().Parse(true, []string{"--dry-run"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `Dry run: nothing will be swapped (full routing).
+
+Before:
++------+---------------+-----------------+
+| App  | Address       | Cnames          |
++------+---------------+-----------------+
+| app1 | app1.tsuru.io | www.example.com |
+| app2 | app2.tsuru.io |                 |
++------+---------------+-----------------+
+
+After:
++------+---------------+-----------------+
+| App  | Address       | Cnames          |
++------+---------------+-----------------+
+| app1 | app2.tsuru.io |                 |
+| app2 | app1.tsuru.io | www.example.com |
++------+---------------+-----------------+
+
+WARNING: apps have a different number of units (app1: 2, app2: 1).
+
+WARNING: apps have different platforms (app1: python, app2: go).
+`
+	c.Assert(buf.String(), check.Equals, expected)
+}
+
+func (s *S) TestSwapDryRunCnameOnly(c *check.C) {
+	var buf bytes.Buffer
+	app1 := `{"name":"app1","platform":"python","ip":"app1.tsuru.io","cname":["www.example.com"],"units":[{"ID":"u1"}]}`
+	app2 := `{"name":"app2","platform":"python","ip":"app2.tsuru.io","units":[{"ID":"u3"}]}`
+	transport := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Status: http.StatusOK, Message: app1},
+				CondFunc: func(r *http.Request) bool {
+					return strings.HasSuffix(r.URL.Path, "/apps/app1")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Status: http.StatusOK, Message: app2},
+				CondFunc: func(r *http.Request) bool {
+					return strings.HasSuffix(r.URL.Path, "/apps/app2")
+				},
+			},
+		},
+	}
+	context := cmd.Context{
+		Args:   []string{"app1", "app2"},
+		Stdout: &buf,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &transport}, nil, manager)
+	command := AppSwap{}
+	command.Flags().Parse(true, []string{"--dry-run", "-c"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `Dry run: nothing will be swapped (cnames only).
+
+Before:
++------+---------------+-----------------+
+| App  | Address       | Cnames          |
++------+---------------+-----------------+
+| app1 | app1.tsuru.io | www.example.com |
+| app2 | app2.tsuru.io |                 |
++------+---------------+-----------------+
+
+After:
++------+---------------+-----------------+
+| App  | Address       | Cnames          |
++------+---------------+-----------------+
+| app1 | app1.tsuru.io |                 |
+| app2 | app2.tsuru.io | www.example.com |
++------+---------------+-----------------+
+`
+	c.Assert(buf.String(), check.Equals, expected)
+}
+
 func (s *S) TestSwapIsACommand(c *check.C) {
 	var _ cmd.Command = &AppSwap{}
 }
@@ -0,0 +1,110 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+)
+
+// appWaitPollInterval is the interval between checks of the units of an app
+// when waiting for them to be started.
+var appWaitPollInterval = 2 * time.Second
+
+// appWait holds the --wait and --timeout flags of the commands that return
+// before the server finishes changing the units of the app.
+type appWait struct {
+	wait    bool
+	timeout time.Duration
+}
+
+func (w *appWait) addFlags(fs *gnuflag.FlagSet) {
+	fs.BoolVar(&w.wait, "wait", false, "Wait until all units of the app are started")
+	fs.DurationVar(&w.timeout, "timeout", 10*time.Minute, "Maximum time to wait for the units of the app to be started")
+}
+
+// waitUnits polls the app until it has units and all of them are started,
+// when --wait is set. It fails if the timeout elapses or any unit is in the
+// error state, or stopped or asleep, as these units are only started again
+// by app-start or a request to the app, so waiting for them is pointless.
+func (w *appWait) waitUnits(context *cmd.Context, client *cmd.Client, appName string) error {
+	if !w.wait {
+		return nil
+	}
+	fmt.Fprintf(context.Stdout, "Waiting for the units of app %q to be started...\n", appName)
+	var last string
+	deadline := time.Now().Add(w.timeout)
+	for {
+		units, err := appUnits(client, appName)
+		if err != nil {
+			return err
+		}
+		var started int
+		var failed, halted []string
+		for _, u := range units {
+			switch u.Status {
+			case "started":
+				started++
+			case "error":
+				failed = append(failed, u.ID)
+			case "stopped", "asleep":
+				halted = append(halted, fmt.Sprintf("%s (%s)", u.ID, u.Status))
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("Units of app %q in error state: %s.", appName, strings.Join(failed, ", "))
+		}
+		if len(halted) > 0 {
+			return fmt.Errorf("Units of app %q are not running and won't be started: %s.", appName, strings.Join(halted, ", "))
+		}
+		if len(units) > 0 && started == len(units) {
+			context.Successf("All units of app %q are started.\n", appName)
+			return nil
+		}
+		msg := fmt.Sprintf("%d of %d units started", started, len(units))
+		if len(units) == 0 {
+			msg = "The app has no units yet"
+		}
+		if msg != last {
+			fmt.Fprintln(context.Stdout, msg)
+			last = msg
+		}
+		if !time.Now().Add(appWaitPollInterval).Before(deadline) {
+			return fmt.Errorf("Timed out after %s waiting for the units of app %q to be started.", w.timeout, appName)
+		}
+		time.Sleep(appWaitPollInterval)
+	}
+}
+
+func appUnits(client *cmd.Client, appName string) ([]unit, error) {
+	u, err := cmd.GetURL("/apps/" + appName)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	var a app
+	err = json.NewDecoder(response.Body).Decode(&a)
+	if err != nil {
+		return nil, err
+	}
+	return a.Units, nil
+}
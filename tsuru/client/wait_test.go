@@ -0,0 +1,171 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	"github.com/tsuru/tsuru/cmd/cmdtest"
+	"github.com/tsuru/tsuru/io"
+	"gopkg.in/check.v1"
+)
+
+// unitsTransport answers app-info requests with each of the given lists of
+// unit statuses in turn, repeating the last one. Other requests are sent to
+// base.
+type unitsTransport struct {
+	base     http.RoundTripper
+	statuses [][]string
+	calls    int
+}
+
+func (t *unitsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" {
+		return t.base.RoundTrip(req)
+	}
+	statuses := t.statuses[len(t.statuses)-1]
+	if t.calls < len(t.statuses) {
+		statuses = t.statuses[t.calls]
+	}
+	t.calls++
+	var a app
+	for i, status := range statuses {
+		a.Units = append(a.Units, unit{ID: "unit" + string('1'+rune(i)), Status: status})
+	}
+	data, _ := json.Marshal(a)
+	return (&cmdtest.Transport{Message: string(data), Status: http.StatusOK}).RoundTrip(req)
+}
+
+func (s *S) TestAppWaitUnits(c *check.C) {
+	defer func(d time.Duration) { appWaitPollInterval = d }(appWaitPollInterval)
+	appWaitPollInterval = time.Millisecond
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	trans := &unitsTransport{statuses: [][]string{
+		{"started", "starting"},
+		{"started", "starting"},
+		{"started", "started"},
+	}}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	w := appWait{wait: true, timeout: time.Minute}
+	err := w.waitUnits(&context, client, "radio")
+	c.Assert(err, check.IsNil)
+	c.Assert(trans.calls, check.Equals, 3)
+	expected := `Waiting for the units of app "radio" to be started...
+1 of 2 units started
+All units of app "radio" are started.
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppWaitUnitsError(c *check.C) {
+	defer func(d time.Duration) { appWaitPollInterval = d }(appWaitPollInterval)
+	appWaitPollInterval = time.Millisecond
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	trans := &unitsTransport{statuses: [][]string{
+		{"started", "starting"},
+		{"started", "error"},
+	}}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	w := appWait{wait: true, timeout: time.Minute}
+	err := w.waitUnits(&context, client, "radio")
+	c.Assert(err, check.ErrorMatches, `Units of app "radio" in error state: unit2.`)
+}
+
+func (s *S) TestAppWaitUnitsWithoutUnits(c *check.C) {
+	defer func(d time.Duration) { appWaitPollInterval = d }(appWaitPollInterval)
+	appWaitPollInterval = time.Millisecond
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	trans := &unitsTransport{statuses: [][]string{
+		{},
+		{},
+		{"started"},
+	}}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	w := appWait{wait: true, timeout: time.Minute}
+	err := w.waitUnits(&context, client, "radio")
+	c.Assert(err, check.IsNil)
+	c.Assert(trans.calls, check.Equals, 3)
+	expected := `Waiting for the units of app "radio" to be started...
+The app has no units yet
+All units of app "radio" are started.
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppWaitUnitsStopped(c *check.C) {
+	defer func(d time.Duration) { appWaitPollInterval = d }(appWaitPollInterval)
+	appWaitPollInterval = time.Millisecond
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	trans := &unitsTransport{statuses: [][]string{
+		{"started", "stopped", "asleep"},
+	}}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	w := appWait{wait: true, timeout: time.Minute}
+	err := w.waitUnits(&context, client, "radio")
+	c.Assert(err, check.ErrorMatches, `Units of app "radio" are not running and won't be started: unit2 \(stopped\), unit3 \(asleep\).`)
+	c.Assert(trans.calls, check.Equals, 1)
+}
+
+func (s *S) TestAppWaitUnitsTimeout(c *check.C) {
+	defer func(d time.Duration) { appWaitPollInterval = d }(appWaitPollInterval)
+	appWaitPollInterval = time.Millisecond
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	trans := &unitsTransport{statuses: [][]string{{"starting"}}}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	w := appWait{wait: true, timeout: 10 * time.Millisecond}
+	err := w.waitUnits(&context, client, "radio")
+	c.Assert(err, check.ErrorMatches, `Timed out after 10ms waiting for the units of app "radio" to be started.`)
+}
+
+func (s *S) TestAppWaitUnitsWithoutWait(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	trans := &unitsTransport{statuses: [][]string{{"starting"}}}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	var w appWait
+	err := w.waitUnits(&context, client, "radio")
+	c.Assert(err, check.IsNil)
+	c.Assert(trans.calls, check.Equals, 0)
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
+func (s *S) TestUnitAddWait(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"1"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	result, err := json.Marshal(io.SimpleJsonMessage{Message: "-- added unit --\n"})
+	c.Assert(err, check.IsNil)
+	put := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return strings.HasSuffix(req.URL.Path, "/apps/radio/units") && req.Method == "PUT"
+		},
+	}
+	units := &unitsTransport{base: put, statuses: [][]string{{"started"}}}
+	client := cmd.NewClient(&http.Client{Transport: units}, nil, manager)
+	command := UnitAdd{}
+	command.Flags().Parse(true, []string{"-a", "radio", "--wait", "--timeout", "1m"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(units.calls, check.Equals, 1)
+	expected := `-- added unit --
+Waiting for the units of app "radio" to be started...
+All units of app "radio" are started.
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
@@ -0,0 +1,49 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RegisterAlias registers a short name for a command. The command may be
+// followed by arguments, which are placed before the arguments given in the
+// command line, like in "app-list -q".
+func (m *Manager) RegisterAlias(alias, command string) {
+	if m.aliases == nil {
+		m.aliases = make(map[string]string)
+	}
+	if _, found := m.Commands[alias]; found {
+		panic(fmt.Sprintf("command already registered: %s", alias))
+	}
+	if _, found := m.aliases[alias]; found {
+		panic(fmt.Sprintf("alias already registered: %s", alias))
+	}
+	m.aliases[alias] = command
+}
+
+// resolveAlias replaces the alias in the first argument with the command it
+// stands for. Aliases from the configuration file take precedence over the
+// built-in ones, but never over commands: in this case a warning is written
+// to stderr and the command is used.
+func (m *Manager) resolveAlias(args []string, stderr io.Writer) []string {
+	name := args[0]
+	command, isUserAlias := fileDefaults.aliases[name]
+	if isUserAlias {
+		if _, found := m.Commands[name]; found {
+			fmt.Fprintf(stderr, "WARNING: alias %q in the configuration file conflicts with the command with the same name and will be ignored.\n", name)
+			return args
+		}
+	} else if command = m.aliases[name]; command == "" {
+		return args
+	}
+	resolved := strings.Fields(command)
+	if len(resolved) == 0 {
+		return args
+	}
+	return append(resolved, args[1:]...)
+}
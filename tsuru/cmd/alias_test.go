@@ -0,0 +1,78 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/check.v1"
+)
+
+type echoCommand struct {
+	name string
+}
+
+func (c *echoCommand) Info() *Info {
+	return &Info{Name: c.name, Usage: c.name}
+}
+
+func (c *echoCommand) Run(context *Context, client *Client) error {
+	fmt.Fprintf(context.Stdout, "%s %s\n", c.name, strings.Join(context.Args, " "))
+	return nil
+}
+
+func (s *S) TestRunBuiltinAlias(c *check.C) {
+	m := s.newManager()
+	m.Register(&echoCommand{name: "echo"})
+	m.RegisterAlias("e", "echo one")
+	m.Run([]string{"e", "two"})
+	c.Assert(s.exiter.value(), check.Equals, 0)
+	c.Assert(s.stdout.String(), check.Equals, "echo one two\n")
+}
+
+func (s *S) TestRunUserAlias(c *check.C) {
+	s.writeConfigFile(c, "aliases:\n  e: echo one\n")
+	m := s.newManager()
+	m.Register(&echoCommand{name: "echo"})
+	m.Run([]string{"e", "two"})
+	c.Assert(s.stdout.String(), check.Equals, "echo one two\n")
+}
+
+func (s *S) TestRunUserAliasOverridesBuiltinAlias(c *check.C) {
+	s.writeConfigFile(c, "aliases:\n  e: shout\n")
+	m := s.newManager()
+	m.Register(&echoCommand{name: "echo"})
+	m.Register(&echoCommand{name: "shout"})
+	m.RegisterAlias("e", "echo")
+	m.Run([]string{"e", "two"})
+	c.Assert(s.stdout.String(), check.Equals, "shout two\n")
+}
+
+func (s *S) TestRunUserAliasConflictingWithCommand(c *check.C) {
+	s.writeConfigFile(c, "aliases:\n  echo: shout\n")
+	m := s.newManager()
+	m.Register(&echoCommand{name: "echo"})
+	m.Register(&echoCommand{name: "shout"})
+	m.Run([]string{"echo", "two"})
+	c.Assert(s.stdout.String(), check.Equals, "echo two\n")
+	c.Assert(s.stderr.String(), check.Equals, "WARNING: alias \"echo\" in the configuration file conflicts with the command with the same name and will be ignored.\n")
+}
+
+func (s *S) TestRunEmptyUserAlias(c *check.C) {
+	s.writeConfigFile(c, "aliases:\n  echo: \"\"\n")
+	m := s.newManager()
+	m.Register(&echoCommand{name: "shout"})
+	m.Run([]string{"echo"})
+	c.Assert(s.exiter.value(), check.Equals, ExitUsage)
+}
+
+func (s *S) TestRegisterAliasConflicts(c *check.C) {
+	m := s.newManager()
+	m.Register(&echoCommand{name: "echo"})
+	c.Assert(func() { m.RegisterAlias("echo", "shout") }, check.PanicMatches, "command already registered: echo")
+	m.RegisterAlias("e", "echo")
+	c.Assert(func() { m.RegisterAlias("e", "shout") }, check.PanicMatches, "alias already registered: e")
+}
@@ -0,0 +1,289 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	tsuruNet "github.com/tsuru/tsuru/net"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+type loginScheme struct {
+	Name string
+	Data map[string]string
+}
+
+type login struct {
+	scheme *loginScheme
+}
+
+func nativeLogin(context *Context, client *Client) error {
+	var email string
+	if len(context.Args) > 0 {
+		email = context.Args[0]
+	} else {
+		fmt.Fprint(context.Stdout, "Email: ")
+		fmt.Fscanf(context.Stdin, "%s\n", &email)
+	}
+	fmt.Fprint(context.Stdout, "Password: ")
+	password, err := PasswordFromReader(context.Stdin)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(context.Stdout)
+	u, err := GetURL("/users/" + email + "/tokens")
+	if err != nil {
+		return err
+	}
+	v := url.Values{}
+	v.Set("password", password)
+	b := strings.NewReader(v.Encode())
+	request, err := http.NewRequest("POST", u, b)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client.loggingIn = true
+	response, err := client.Do(request)
+	client.loggingIn = false
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	result, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	out := make(map[string]interface{})
+	err = json.Unmarshal(result, &out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(context.Stdout, "Successfully logged in!")
+	err = storeLoginTokens(out)
+	if err != nil {
+		return err
+	}
+	return writeLoginEmail(email)
+}
+
+func (c *login) getScheme() *loginScheme {
+	if c.scheme == nil {
+		info, err := schemeInfo()
+		if err != nil {
+			c.scheme = &loginScheme{Name: "native", Data: make(map[string]string)}
+		} else {
+			c.scheme = info
+		}
+	}
+	return c.scheme
+}
+
+func (c *login) Run(context *Context, client *Client) error {
+	if c.getScheme().Name == "oauth" {
+		return c.oauthLogin(context, client)
+	}
+	if c.getScheme().Name == "saml" {
+		return c.samlLogin(context, client)
+	}
+	return nativeLogin(context, client)
+}
+
+func (c *login) Info() *Info {
+	usage := "login [email]"
+	return &Info{
+		Name:  "login",
+		Usage: usage,
+		Desc: `Initiates a new tsuru session for a user. If using tsuru native authentication
+scheme, it will ask for the email and the password and check if the user is
+successfully authenticated. If using OAuth, it will open a web browser for the
+user to complete the login.
+
+After that, the token generated by the tsuru server will be stored in
+[[${HOME}/.tsuru/token]]. When the server informs when the token expires, the
+expiration is stored too and commands print a warning when the session is
+about to expire (one hour before, by default, configurable with the
+token-expiry-warning key in [[${HOME}/.tsuru/config.yaml]]).
+
+If the server supports refresh tokens, the refresh token is stored as well and
+used to renew the session transparently when it expires. Otherwise, when the
+server refuses the token of a command run in a terminal, the password of the
+email used in the last login is asked and the command continues after logging
+in again. Commands not run in a terminal just fail.
+
+Instead of logging in, a token may be given in the TSURU_TOKEN environment
+variable, which is used directly without reading or writing any file, like in
+CI jobs. The token file may also be changed with the --token-file global flag
+or the TSURU_TOKEN_FILE environment variable.
+
+All tsuru actions require the user to be authenticated (except [[tsuru login]]
+and [[tsuru version]]).`,
+		MinArgs: 0,
+	}
+}
+
+type logout struct{}
+
+func (c *logout) Info() *Info {
+	return &Info{
+		Name:  "logout",
+		Usage: "logout",
+		Desc:  "Logout will terminate the session with the tsuru server.",
+	}
+}
+
+func (c *logout) Run(context *Context, client *Client) error {
+	if tokenFromEnv() {
+		fmt.Fprintln(context.Stdout, "The token is given in the TSURU_TOKEN environment variable, unset it to log out.")
+		return nil
+	}
+	if url, err := GetURL("/users/tokens"); err == nil {
+		request, _ := http.NewRequest("DELETE", url, nil)
+		client.Do(request)
+	}
+	writeTokenExpiry(0)
+	writeRefreshToken("")
+	writeLoginEmail("")
+	err := filesystem().Remove(tokenPath())
+	if err != nil && os.IsNotExist(err) {
+		return errors.New("You're not logged in!")
+	}
+	fmt.Fprintln(context.Stdout, "Successfully logged out!")
+	return nil
+}
+
+type APIRolePermissionData struct {
+	Name         string
+	ContextType  string
+	ContextValue string
+}
+
+// APIUser is a user in the tsuru API.
+type APIUser struct {
+	Email       string
+	Roles       []APIRolePermissionData
+	Permissions []APIRolePermissionData
+}
+
+func (u *APIUser) RoleInstances() []string {
+	roles := make([]string, len(u.Roles))
+	for i, r := range u.Roles {
+		if r.ContextValue != "" {
+			r.ContextValue = " " + r.ContextValue
+		}
+		roles[i] = fmt.Sprintf("%s(%s%s)", r.Name, r.ContextType, r.ContextValue)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+func (u *APIUser) PermissionInstances() []string {
+	permissions := make([]string, len(u.Permissions))
+	for i, r := range u.Permissions {
+		if r.Name == "" {
+			r.Name = "*"
+		}
+		if r.ContextValue != "" {
+			r.ContextValue = " " + r.ContextValue
+		}
+		permissions[i] = fmt.Sprintf("%s(%s%s)", r.Name, r.ContextType, r.ContextValue)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+func GetUser(client *Client) (*APIUser, error) {
+	url, err := GetURL("/users/info")
+	if err != nil {
+		return nil, err
+	}
+	request, _ := http.NewRequest("GET", url, nil)
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var u APIUser
+	err = json.NewDecoder(resp.Body).Decode(&u)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+type userInfo struct{}
+
+func (userInfo) Info() *Info {
+	return &Info{
+		Name:  "user-info",
+		Usage: "user-info",
+		Desc:  "Displays information about the current user.",
+	}
+}
+
+func (userInfo) Run(context *Context, client *Client) error {
+	u, err := GetUser(client)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(context.Stdout, "Email: %s\n", u.Email)
+	roles := u.RoleInstances()
+	if len(roles) > 0 {
+		fmt.Fprintf(context.Stdout, "Roles:\n\t%s\n", strings.Join(roles, "\n\t"))
+	}
+	perms := u.PermissionInstances()
+	if len(perms) > 0 {
+		fmt.Fprintf(context.Stdout, "Permissions:\n\t%s\n", strings.Join(perms, "\n\t"))
+	}
+	return nil
+}
+
+func PasswordFromReader(reader io.Reader) (string, error) {
+	var (
+		password []byte
+		err      error
+	)
+	if desc, ok := reader.(descriptable); ok && terminal.IsTerminal(int(desc.Fd())) {
+		password, err = terminal.ReadPassword(int(desc.Fd()))
+		if err != nil {
+			return "", err
+		}
+	} else {
+		fmt.Fscanf(reader, "%s\n", &password)
+	}
+	if len(password) == 0 {
+		msg := "You must provide the password!"
+		return "", errors.New(msg)
+	}
+	return string(password), err
+}
+
+func schemeInfo() (*loginScheme, error) {
+	url, err := GetURL("/auth/scheme")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := withTransport(tsuruNet.Dial5Full60ClientNoKeepAlive).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	info := loginScheme{}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
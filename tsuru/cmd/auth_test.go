@@ -0,0 +1,71 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestLogout(c *check.C) {
+	var deleted bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deleted = r.Method == "DELETE" && r.URL.Path == "/1.0/users/tokens" && r.Header.Get("Authorization") == "bearer abc123"
+	}))
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	c.Assert(writeToken("abc123"), check.IsNil)
+	var stdout bytes.Buffer
+	context := Context{Stdout: &stdout}
+	client := NewClient(http.DefaultClient, nil, s.newManager())
+	err := (&logout{}).Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Successfully logged out!\n")
+	c.Assert(deleted, check.Equals, true)
+	token, err := ReadToken()
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "")
+}
+
+func (s *S) TestLogoutWithTokenFromTheEnvironment(c *check.C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Errorf("unexpected request to %s", r.URL)
+	}))
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	os.Setenv("TSURU_TOKEN", "env-token")
+	c.Assert(writeToken("abc123"), check.IsNil)
+	var stdout bytes.Buffer
+	context := Context{Stdout: &stdout}
+	client := NewClient(http.DefaultClient, nil, s.newManager())
+	err := (&logout{}).Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "The token is given in the TSURU_TOKEN environment variable, unset it to log out.\n")
+	os.Unsetenv("TSURU_TOKEN")
+	token, err := ReadToken()
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "abc123")
+}
+
+func (s *S) TestClientSendsTheTokenFromTheEnvironment(c *check.C) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	os.Setenv("TSURU_TOKEN", "env-token")
+	c.Assert(writeToken("abc123"), check.IsNil)
+	request, err := http.NewRequest("GET", server.URL+"/1.0/apps", nil)
+	c.Assert(err, check.IsNil)
+	client := NewClient(http.DefaultClient, nil, s.newManager())
+	response, err := client.Do(request)
+	c.Assert(err, check.IsNil)
+	response.Body.Close()
+	c.Assert(authorization, check.Equals, "bearer env-token")
+}
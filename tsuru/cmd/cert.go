@@ -0,0 +1,39 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+)
+
+// clientCertificate is the certificate presented to the tsuru API when it
+// requires mutual TLS, loaded from the --client-cert and --client-key flags
+// or from the client-cert and client-key keys of the configuration file.
+var clientCertificate *tls.Certificate
+
+// loadClientCertificate loads the client certificate from the given files.
+// The flags take precedence over the configuration file, and the certificate
+// and the key must be given together.
+func loadClientCertificate(certFile, keyFile string) (*tls.Certificate, error) {
+	if certFile == "" {
+		certFile = fileDefaults.clientCert
+	}
+	if keyFile == "" {
+		keyFile = fileDefaults.clientKey
+	}
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("the client certificate and its key must be given together, use both --client-cert and --client-key")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the client certificate: %s", err)
+	}
+	return &cert, nil
+}
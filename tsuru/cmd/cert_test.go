@@ -0,0 +1,106 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+// writeClientCertificate writes a self-signed certificate and its key to dir,
+// returning the paths of both files.
+func writeClientCertificate(c *check.C, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, check.IsNil)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "tsuru-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	c.Assert(err, check.IsNil)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, check.IsNil)
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	c.Assert(err, check.IsNil)
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	c.Assert(err, check.IsNil)
+	return certFile, keyFile
+}
+
+func (s *S) TestLoadClientCertificate(c *check.C) {
+	certFile, keyFile := writeClientCertificate(c, c.MkDir())
+	cert, err := loadClientCertificate(certFile, keyFile)
+	c.Assert(err, check.IsNil)
+	c.Assert(cert, check.NotNil)
+	c.Assert(cert.Certificate, check.HasLen, 1)
+}
+
+func (s *S) TestLoadClientCertificateFromTheConfigFile(c *check.C) {
+	certFile, keyFile := writeClientCertificate(c, c.MkDir())
+	fileDefaults.clientCert, fileDefaults.clientKey = certFile, keyFile
+	cert, err := loadClientCertificate("", "")
+	c.Assert(err, check.IsNil)
+	c.Assert(cert, check.NotNil)
+}
+
+func (s *S) TestLoadClientCertificateFlagsTakePrecedence(c *check.C) {
+	certFile, keyFile := writeClientCertificate(c, c.MkDir())
+	fileDefaults.clientCert, fileDefaults.clientKey = "/nonexistent/client.crt", "/nonexistent/client.key"
+	cert, err := loadClientCertificate(certFile, keyFile)
+	c.Assert(err, check.IsNil)
+	c.Assert(cert, check.NotNil)
+}
+
+func (s *S) TestLoadClientCertificateNotGiven(c *check.C) {
+	cert, err := loadClientCertificate("", "")
+	c.Assert(err, check.IsNil)
+	c.Assert(cert, check.IsNil)
+}
+
+func (s *S) TestLoadClientCertificateWithoutKey(c *check.C) {
+	certFile, _ := writeClientCertificate(c, c.MkDir())
+	_, err := loadClientCertificate(certFile, "")
+	c.Assert(err, check.ErrorMatches, "the client certificate and its key must be given together, use both --client-cert and --client-key")
+}
+
+func (s *S) TestLoadClientCertificateInvalidFiles(c *check.C) {
+	dir := c.MkDir()
+	certFile, _ := writeClientCertificate(c, dir)
+	_, err := loadClientCertificate(certFile, certFile)
+	c.Assert(err, check.ErrorMatches, "failed to load the client certificate: .*")
+}
+
+func (s *S) TestRunClientCertWithoutKey(c *check.C) {
+	m := s.newManager()
+	m.Register(&failingCommand{})
+	m.Run([]string{"--client-cert", "/home/me/client.crt", "fail"})
+	c.Assert(s.exiter.value(), check.Equals, ExitGeneric)
+	c.Assert(s.stderr.String(), check.Equals, "the client certificate and its key must be given together, use both --client-cert and --client-key\n")
+}
+
+func (s *S) TestRunSetsTheClientCertificate(c *check.C) {
+	defer func() { clientCertificate = nil }()
+	certFile, keyFile := writeClientCertificate(c, c.MkDir())
+	m := s.newManager()
+	m.Register(&failingCommand{})
+	m.Run([]string{"--client-cert", certFile, "--client-key", keyFile, "fail"})
+	c.Assert(s.exiter.value(), check.Equals, 0)
+	c.Assert(clientCertificate, check.NotNil)
+}
@@ -0,0 +1,240 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strings"
+
+	tsuruerr "github.com/tsuru/tsuru/errors"
+	tsuruio "github.com/tsuru/tsuru/io"
+)
+
+var errUnauthorized = &tsuruerr.HTTP{Code: http.StatusUnauthorized, Message: "unauthorized"}
+
+type Client struct {
+	HTTPClient     *http.Client
+	context        *Context
+	progname       string
+	currentVersion string
+	versionHeader  string
+	Verbosity      int
+	// failure is the error built from the last failing response and
+	// requestID is the value of its X-Request-Id header, used to help
+	// correlating the failure with the server logs.
+	failure   error
+	requestID string
+	// loggingIn disables the login prompt when the server refuses the
+	// token, set while running the login command itself.
+	loggingIn bool
+	// profile enables collecting the timing of the requests in timings,
+	// set with the --profile flag.
+	profile bool
+	timings []*requestTiming
+}
+
+func NewClient(client *http.Client, context *Context, manager *Manager) *Client {
+	return &Client{
+		HTTPClient:     client,
+		context:        context,
+		progname:       manager.name,
+		currentVersion: manager.version,
+		versionHeader:  manager.versionHeader,
+	}
+}
+
+func (c *Client) detectClientError(err error) error {
+	urlErr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	switch urlErr.Err.(type) {
+	case x509.UnknownAuthorityError:
+		target, _ := ReadTarget()
+		return &connectionError{message: fmt.Sprintf("Failed to connect to tsuru server (%s): %s", target, urlErr.Err)}
+	}
+	target, _ := ReadTarget()
+	return &connectionError{message: fmt.Sprintf("Failed to connect to tsuru server (%s), it's probably down.", target)}
+}
+
+func (c *Client) Do(request *http.Request) (*http.Response, error) {
+	return c.do(request, true)
+}
+
+// do sends the request. When the server refuses the token with 401 and
+// canRefresh is true, the access token is refreshed (if the server supports
+// refresh tokens) or, on a terminal, the user is asked to log in again, and
+// the request is sent once again.
+func (c *Client) do(request *http.Request, canRefresh bool) (*http.Response, error) {
+	var sentToken bool
+	if token, err := ReadToken(); err == nil && token != "" {
+		sentToken = true
+		request.Header.Set("Authorization", "bearer "+token)
+		if c.context != nil {
+			warnTokenExpiry(c.context.Stderr, c.progname)
+		}
+	}
+	request.Close = true
+	rewind := func() bool { return false }
+	if canRefresh && sentToken {
+		var err error
+		rewind, err = rewindableBody(request)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.context != nil {
+		c.checkMinimumVersion()
+	}
+	if c.Verbosity >= 1 {
+		fmt.Fprintf(c.context.Stderr, "*************************** <Request uri=%q> **********************************\n", request.URL.RequestURI())
+		requestDump, err := httputil.DumpRequestOut(request, c.Verbosity >= 2)
+		if err != nil {
+			return nil, err
+		}
+		writeDump(c.context.Stderr, redactAuthorization(requestDump))
+		fmt.Fprintf(c.context.Stderr, "*************************** </Request uri=%q> **********************************\n", request.URL.RequestURI())
+	}
+	var response *http.Response
+	var err error
+	if c.profile {
+		timing := newRequestTiming(request)
+		traced := request.WithContext(httptrace.WithClientTrace(request.Context(), timing.trace()))
+		response, err = c.HTTPClient.Do(traced)
+		timing.finish(response)
+		c.timings = append(c.timings, timing)
+	} else {
+		response, err = c.HTTPClient.Do(request)
+	}
+	err = c.detectClientError(err)
+	if err != nil {
+		return nil, err
+	}
+	requestID := response.Header.Get(requestIDHeader)
+	if c.Verbosity >= 1 {
+		fmt.Fprintf(c.context.Stderr, "*************************** <Response uri=%q> **********************************\n", request.URL.RequestURI())
+		responseDump, err := httputil.DumpResponse(response, c.Verbosity >= 2)
+		if err != nil {
+			return nil, err
+		}
+		writeDump(c.context.Stderr, responseDump)
+		if requestID != "" {
+			fmt.Fprintf(c.context.Stderr, "Request ID: %s\n", requestID)
+		}
+		fmt.Fprintf(c.context.Stderr, "*************************** </Response uri=%q> **********************************\n", request.URL.RequestURI())
+	}
+	if c.context != nil {
+		c.warnUnsupportedVersion(c.context.Stderr, response.Header.Get(c.versionHeader))
+	}
+	if response.StatusCode == http.StatusUnauthorized {
+		if canRefresh && sentToken && rewind() && (c.refreshToken() || c.relogin()) {
+			response.Body.Close()
+			return c.do(request, false)
+		}
+		c.failure, c.requestID = errUnauthorized, requestID
+		return response, errUnauthorized
+	}
+	if response.StatusCode > 399 {
+		err := &tsuruerr.HTTP{
+			Code:    response.StatusCode,
+			Message: response.Status,
+		}
+
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(response.Body)
+		if len(body) > 0 {
+			err.Message = string(body)
+		}
+		c.failure, c.requestID = err, requestID
+		return response, err
+	}
+	return response, nil
+}
+
+// failureRequestID returns the request ID of the failing response err was
+// built from, directly or wrapped in another message, or "" when err didn't
+// come from a response of the API, so unrelated errors don't show the ID of
+// an earlier request.
+func (c *Client) failureRequestID(err error) string {
+	if c.failure == nil || err == nil || c.failure.Error() == "" {
+		return ""
+	}
+	if err == c.failure || strings.Contains(err.Error(), c.failure.Error()) {
+		return c.requestID
+	}
+	return ""
+}
+
+// maxRewindableBody is the size of the largest request body kept in memory,
+// so the request can be sent again after the token is refreshed.
+const maxRewindableBody = 1 << 20
+
+// rewindableBody keeps the body of the request in memory, when its size is
+// known and small enough, returning a function that rewinds it and reports
+// whether the request can be sent again.
+func rewindableBody(request *http.Request) (func() bool, error) {
+	if request.Body == nil {
+		return func() bool { return true }, nil
+	}
+	if request.ContentLength <= 0 || request.ContentLength > maxRewindableBody {
+		return func() bool { return false }, nil
+	}
+	data, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	rewind := func() bool {
+		request.Body = ioutil.NopCloser(bytes.NewReader(data))
+		return true
+	}
+	rewind()
+	return rewind, nil
+}
+
+var authorizationRegexp = regexp.MustCompile(`(?mi)^(Authorization: )[^\r\n]*`)
+
+// redactAuthorization hides the value of the Authorization header, so dumps
+// can be shared without leaking the user's token.
+func redactAuthorization(dump []byte) []byte {
+	return authorizationRegexp.ReplaceAll(dump, []byte("${1}<redacted>"))
+}
+
+func writeDump(w io.Writer, dump []byte) {
+	w.Write(dump)
+	if len(dump) > 0 && dump[len(dump)-1] != '\n' {
+		fmt.Fprintln(w)
+	}
+}
+
+// StreamJSONResponse supports the JSON streaming format from the tsuru API.
+func StreamJSONResponse(w io.Writer, response *http.Response) error {
+	if response == nil {
+		return errors.New("response cannot be nil")
+	}
+	defer response.Body.Close()
+	var err error
+	output := tsuruio.NewStreamWriter(w, nil)
+	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(output, response.Body) {
+	}
+	if err != nil {
+		return err
+	}
+	unparsed := output.Remaining()
+	if len(unparsed) > 0 {
+		return fmt.Errorf("unparsed message error: %s", string(unparsed))
+	}
+	return nil
+}
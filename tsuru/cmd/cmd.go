@@ -0,0 +1,690 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	goVersion "github.com/hashicorp/go-version"
+	"github.com/sajari/fuzzy"
+	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/fs"
+	"github.com/tsuru/tsuru/net"
+)
+
+var (
+	ErrAbortCommand = stderrors.New("")
+
+	// ErrLookup is the error that should be returned by lookup functions when it
+	// cannot find a matching command for the given parameters.
+	ErrLookup = stderrors.New("lookup error - command not found")
+)
+
+const (
+	loginCmdName = "login"
+)
+
+type exiter interface {
+	Exit(int)
+}
+
+type osExiter struct{}
+
+func (e osExiter) Exit(code int) {
+	os.Exit(code)
+}
+
+type Lookup func(context *Context) error
+
+type Manager struct {
+	Commands      map[string]Command
+	aliases       map[string]string
+	topics        map[string]string
+	name          string
+	stdout        io.Writer
+	stderr        io.Writer
+	stdin         io.Reader
+	version       string
+	versionHeader string
+	e             exiter
+	outputFormat  string
+	errorFormat   string
+	quiet         bool
+	original      string
+	wrong         bool
+	lookup        Lookup
+	contexts      []*Context
+}
+
+func NewManager(name, ver, verHeader string, stdout, stderr io.Writer, stdin io.Reader, lookup Lookup) *Manager {
+	manager := &Manager{name: name, version: ver, versionHeader: verHeader, stdout: stdout, stderr: stderr, stdin: stdin, lookup: lookup}
+	manager.Register(&help{manager})
+	manager.Register(&version{manager: manager})
+	return manager
+}
+
+func BuildBaseManager(name, version, versionHeader string, lookup Lookup) *Manager {
+	m := NewManager(name, version, versionHeader, os.Stdout, os.Stderr, os.Stdin, lookup)
+	m.Register(&login{})
+	m.Register(&logout{})
+	m.Register(&targetList{})
+	m.Register(&targetAdd{})
+	m.Register(&targetRemove{})
+	m.Register(&targetSet{})
+	m.Register(&targetExport{})
+	m.Register(&targetImport{})
+	m.Register(userInfo{})
+	m.Register(&doctor{manager: m})
+	m.RegisterTopic("target", fmt.Sprintf(targetTopic, name))
+	return m
+}
+
+func (m *Manager) Register(command Command) {
+	if m.Commands == nil {
+		m.Commands = make(map[string]Command)
+	}
+	name := command.Info().Name
+	_, found := m.Commands[name]
+	if found {
+		panic(fmt.Sprintf("command already registered: %s", name))
+	}
+	m.Commands[name] = command
+}
+
+func (m *Manager) RegisterDeprecated(command Command, oldName string) {
+	if m.Commands == nil {
+		m.Commands = make(map[string]Command)
+	}
+	name := command.Info().Name
+	_, found := m.Commands[name]
+	if found {
+		panic(fmt.Sprintf("command already registered: %s", name))
+	}
+	m.Commands[name] = command
+	m.Commands[oldName] = &DeprecatedCommand{Command: command, oldName: oldName}
+}
+
+type RemovedCommand struct {
+	Name string
+	Help string
+}
+
+func (c *RemovedCommand) Info() *Info {
+	return &Info{
+		Name:  c.Name,
+		Usage: c.Name,
+		Desc:  fmt.Sprintf("This command was removed. %s", c.Help),
+		fail:  true,
+	}
+}
+
+func (c *RemovedCommand) Run(context *Context, client *Client) error {
+	return ErrAbortCommand
+}
+
+func (m *Manager) RegisterRemoved(name string, help string) {
+	if m.Commands == nil {
+		m.Commands = make(map[string]Command)
+	}
+	_, found := m.Commands[name]
+	if found {
+		panic(fmt.Sprintf("command already registered: %s", name))
+	}
+	m.Commands[name] = &RemovedCommand{Name: name, Help: help}
+}
+
+func (m *Manager) RegisterTopic(name, content string) {
+	if m.topics == nil {
+		m.topics = make(map[string]string)
+	}
+	_, found := m.topics[name]
+	if found {
+		panic(fmt.Sprintf("topic already registered: %s", name))
+	}
+	m.topics[name] = content
+}
+
+func (m *Manager) Run(args []string) {
+	var (
+		status         int
+		verbosity      CountFlag
+		displayHelp    bool
+		displayVersion bool
+		outputFormat   string
+		errorFormat    string
+		target         string
+		noColor        bool
+		quiet          bool
+		proxy          string
+		clientCert     string
+		clientKey      string
+		tokenFile      string
+		profile        bool
+		confirmApp     bool
+	)
+	if len(args) == 0 {
+		args = append(args, "help")
+	}
+	flagset := gnuflag.NewFlagSet("tsuru flags", gnuflag.ContinueOnError)
+	flagset.SetOutput(m.stderr)
+	verbosityMessage := "Verbosity level: 1 => print HTTP requests and response status; 2 => also print bodies. The level may be given as a value (e.g. -v 2) or by repeating -v (e.g. -vv)"
+	flagset.Var(&verbosity, "verbosity", verbosityMessage)
+	flagset.Var(&verbosity, "verbose", verbosityMessage)
+	flagset.Var(&verbosity, "v", verbosityMessage)
+	flagset.BoolVar(&displayHelp, "help", false, "Display help and exit")
+	flagset.BoolVar(&displayHelp, "h", false, "Display help and exit")
+	flagset.BoolVar(&displayVersion, "version", false, "Print version and exit")
+	outputMessage := "Output format: table (default), json or yaml. Only honored by commands supporting structured output"
+	flagset.StringVar(&outputFormat, "output", "", outputMessage)
+	flagset.StringVar(&outputFormat, "o", "", outputMessage)
+	flagset.StringVar(&errorFormat, "error-format", "", "Error format: text (default) or json. The json format writes an object with the code, message and request_id of the error to stderr")
+	flagset.BoolVar(&noColor, "no-color", false, "Disable colors in the output. Colors are also disabled when the output is not a terminal or the NO_COLOR environment variable is set")
+	quietMessage := "Don't print success messages. Data output and errors are still shown"
+	flagset.BoolVar(&quiet, "quiet", false, quietMessage)
+	flagset.BoolVar(&quiet, "q", false, quietMessage)
+	flagset.StringVar(&target, "target", "", "Target used by this command, as a label from target-list or an address. Takes precedence over TSURU_TARGET and the current target")
+	flagset.StringVar(&proxy, "proxy", "", "Proxy used to reach the tsuru API, as an URL like http://proxy.example.com:3128. Takes precedence over the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables")
+	flagset.StringVar(&clientCert, "client-cert", "", "Client certificate file (PEM) presented to the tsuru API when it requires mutual TLS. Must be used with --client-key")
+	flagset.StringVar(&clientKey, "client-key", "", "Private key file (PEM) of the client certificate given in --client-cert")
+	flagset.StringVar(&tokenFile, "token-file", "", "File holding the session token, read and written instead of ~/.tsuru/token. Takes precedence over TSURU_TOKEN and TSURU_TOKEN_FILE")
+	flagset.BoolVar(&profile, "profile", false, "Print the time spent in each phase (DNS, connect, TLS, first byte) of the HTTP requests to stderr after the command")
+	flagset.BoolVar(&confirmApp, "confirm-app", false, "Print the name of the app when it's guessed from the current directory, and ask for confirmation before destructive commands run on a guessed app. May also be enabled with the TSURU_CONFIRM_APP environment variable")
+	parseErr := flagset.Parse(false, expandCountFlags(flagset, args))
+	if parseErr == nil {
+		parseErr = validateOutputFormat(outputFormat)
+	}
+	if parseErr == nil {
+		parseErr = validateErrorFormat(errorFormat)
+	}
+	proxyOverride = nil
+	if parseErr == nil && proxy != "" {
+		proxyOverride, parseErr = parseProxy(proxy)
+	}
+	if parseErr != nil {
+		fmt.Fprint(m.stderr, parseErr)
+		m.finisher().Exit(ExitUsage)
+		return
+	}
+	m.outputFormat = outputFormat
+	m.errorFormat = errorFormat
+	m.quiet = quiet
+	colorsDisabled = noColor || !isTerminal(m.stdout)
+	targetOverride = target
+	tokenFileOverride = tokenFile
+	confirmGuessedApp = confirmApp
+	guessOutput = m.stderr
+	if err := loadConfigFile(); err != nil {
+		fmt.Fprintln(m.stderr, err)
+		m.finisher().Exit(ExitGeneric)
+		return
+	}
+	cert, certErr := loadClientCertificate(clientCert, clientKey)
+	if certErr != nil {
+		fmt.Fprintln(m.stderr, certErr)
+		m.finisher().Exit(ExitGeneric)
+		return
+	}
+	clientCertificate = cert
+	args = flagset.Args()
+	if displayHelp {
+		args = append([]string{"help"}, args...)
+	} else if displayVersion {
+		args = []string{"version"}
+	}
+	if m.lookup != nil {
+		context := m.newContext(args, m.stdout, m.stderr, m.stdin)
+		err := m.lookup(context)
+		if err != nil && err != ErrLookup {
+			writeError(m.stderr, m.errorFormat, err.Error(), err, "", false)
+			m.finisher().Exit(ExitCode(err))
+			return
+		} else if err == nil {
+			return
+		}
+	}
+	args = m.resolveAlias(args, m.stderr)
+	name := args[0]
+	command, ok := m.Commands[name]
+	if !ok {
+		msg := fmt.Sprintf("%s: %q is not a %s command. See %q.\n", m.name, name, m.name, m.name+" help")
+		var keys []string
+		for key := range m.Commands {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			levenshtein := fuzzy.Levenshtein(&key, &args[0])
+			if levenshtein < 3 || strings.Contains(key, args[0]) {
+				if !strings.Contains(msg, "Did you mean?") {
+					msg += fmt.Sprintf("\nDid you mean?\n")
+				}
+				msg += fmt.Sprintf("\t%s\n", key)
+			}
+		}
+		writeError(m.stderr, m.errorFormat, msg, nil, "", false)
+		m.finisher().Exit(ExitUsage)
+		return
+	}
+	args = args[1:]
+	info := command.Info()
+	command, args, err := m.handleFlags(command, name, args)
+	if err != nil {
+		writeError(m.stderr, m.errorFormat, err.Error(), err, "", false)
+		m.finisher().Exit(ExitUsage)
+		return
+	}
+	if info.fail {
+		command = m.Commands["help"]
+		args = []string{name}
+		status = ExitUsage
+	}
+	if length := len(args); (length < info.MinArgs || (info.MaxArgs > 0 && length > info.MaxArgs)) &&
+		name != "help" {
+		m.wrong = true
+		m.original = info.Name
+		command = m.Commands["help"]
+		args = []string{name}
+		status = ExitUsage
+	}
+	context := m.newContext(args, m.stdout, m.stderr, m.stdin)
+	client := NewClient(withTransport(net.Dial5FullUnlimitedClient), context, m)
+	client.Verbosity = int(verbosity)
+	client.profile = profile
+	start := time.Now()
+	err = command.Run(context, client)
+	if err == errUnauthorized && name != loginCmdName {
+		if cmd, ok := m.Commands[loginCmdName]; ok {
+			fmt.Fprintln(m.stderr, "Error: you're not authenticated or your session has expired.")
+			fmt.Fprintf(m.stderr, "Calling the %q command...\n", loginCmdName)
+			loginContext := m.newContext(nil, m.stdout, m.stderr, m.stdin)
+			if err = cmd.Run(loginContext, client); err == nil {
+				fmt.Fprintln(m.stderr)
+				err = command.Run(context, client)
+			}
+		}
+	}
+	if err != nil {
+		errorMsg := err.Error()
+		httpErr, ok := err.(*errors.HTTP)
+		if ok && httpErr.Code == http.StatusUnauthorized && name != loginCmdName {
+			errorMsg = fmt.Sprintf(`You're not authenticated or your session has expired. Please use %q command for authentication.`, loginCmdName)
+		}
+		if err != ErrAbortCommand {
+			writeError(m.stderr, m.errorFormat, errorMsg, err, client.failureRequestID(err), true)
+		}
+		status = ExitCode(err)
+	}
+	if profile {
+		client.writeProfile(m.stderr, start)
+	}
+	m.finisher().Exit(status)
+}
+
+func (m *Manager) newContext(args []string, stdout io.Writer, stderr io.Writer, stdin io.Reader) *Context {
+	stdout = newPagerWriter(stdout)
+	stdin = newSyncReader(stdin, stdout)
+	ctx := &Context{Args: args, Stdout: stdout, Stderr: stderr, Stdin: stdin, OutputFormat: m.outputFormat, Quiet: m.quiet}
+	m.contexts = append(m.contexts, ctx)
+	return ctx
+}
+
+func (m *Manager) handleFlags(command Command, name string, args []string) (Command, []string, error) {
+	var flagset *gnuflag.FlagSet
+	if flagged, ok := command.(FlaggedCommand); ok {
+		flagset = flagged.Flags()
+	} else {
+		flagset = gnuflag.NewFlagSet(name, gnuflag.ExitOnError)
+	}
+	var helpRequested bool
+	flagset.SetOutput(m.stderr)
+	if flagset.Lookup("help") == nil {
+		flagset.BoolVar(&helpRequested, "help", false, "Display help and exit")
+	}
+	if flagset.Lookup("h") == nil {
+		flagset.BoolVar(&helpRequested, "h", false, "Display help and exit")
+	}
+	err := flagset.Parse(true, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = applyFlagDefaults(command.Info().Name, flagset); err != nil {
+		return nil, nil, err
+	}
+	if helpRequested {
+		command = m.Commands["help"]
+		args = []string{name}
+	} else {
+		args = flagset.Args()
+	}
+	return command, args, nil
+}
+
+func (m *Manager) finisher() exiter {
+	if pagerWriter, ok := m.stdout.(*pagerWriter); ok {
+		pagerWriter.close()
+	}
+	for _, ctx := range m.contexts {
+		if pagerWriter, ok := ctx.Stdout.(*pagerWriter); ok {
+			pagerWriter.close()
+		}
+	}
+	if m.e == nil {
+		m.e = osExiter{}
+	}
+	return m.e
+}
+
+type Command interface {
+	Info() *Info
+	Run(context *Context, client *Client) error
+}
+
+type FlaggedCommand interface {
+	Command
+	Flags() *gnuflag.FlagSet
+}
+
+type DeprecatedCommand struct {
+	Command
+	oldName string
+}
+
+func (c *DeprecatedCommand) Run(context *Context, client *Client) error {
+	fmt.Fprintf(context.Stderr, "WARNING: %q has been deprecated, please use %q instead.\n\n", c.oldName, c.Command.Info().Name)
+	return c.Command.Run(context, client)
+}
+
+func (c *DeprecatedCommand) Flags() *gnuflag.FlagSet {
+	if cmd, ok := c.Command.(FlaggedCommand); ok {
+		return cmd.Flags()
+	}
+	return gnuflag.NewFlagSet("", gnuflag.ContinueOnError)
+}
+
+type Context struct {
+	Args         []string
+	Stdout       io.Writer
+	Stderr       io.Writer
+	Stdin        io.Reader
+	OutputFormat string
+	// Quiet indicates that the user asked for success messages to be
+	// omitted. Use Successf to write them.
+	Quiet bool
+}
+
+// Successf writes a success message, like "Service successfully added.", to
+// the standard output, unless the user asked for quiet output.
+func (c *Context) Successf(format string, a ...interface{}) {
+	if c.Quiet {
+		return
+	}
+	fmt.Fprintf(c.Stdout, format, a...)
+}
+
+// IsTerminal reports whether the standard output of the command is a
+// terminal. The pager wrapping the output is not considered.
+func (c *Context) IsTerminal() bool {
+	if pager, ok := c.Stdout.(*pagerWriter); ok {
+		return isTerminal(pager.baseWriter)
+	}
+	return isTerminal(c.Stdout)
+}
+
+func (c *Context) RawOutput() {
+	if pager, ok := c.Stdout.(*pagerWriter); ok {
+		c.Stdout = pager.baseWriter
+	}
+	if sync, ok := c.Stdin.(*syncReader); ok {
+		c.Stdin = sync.baseReader
+	}
+}
+
+type Info struct {
+	Name    string
+	MinArgs int
+	MaxArgs int
+	Usage   string
+	Desc    string
+	fail    bool
+}
+
+// Implementing the Commandable interface allows extending
+// the tsurud command line interface
+type Commandable interface {
+	Commands() []Command
+}
+
+// Implementing the AdminCommandable interface allows extending
+// the tsuru-admin command line interface
+type AdminCommandable interface {
+	AdminCommands() []Command
+}
+
+type help struct {
+	manager *Manager
+}
+
+func (c *help) Info() *Info {
+	return &Info{Name: "help", Usage: "command [args]"}
+}
+
+func (c *help) Run(context *Context, client *Client) error {
+	const deprecatedMsg = "WARNING: %q is deprecated. Showing help for %q instead.\n\n"
+	output := fmt.Sprintf("%s version %s.\n\n", c.manager.name, c.manager.version)
+	if c.manager.wrong {
+		output += fmt.Sprint("ERROR: wrong number of arguments.\n\n")
+	}
+	if len(context.Args) > 0 {
+		if cmd, ok := c.manager.Commands[context.Args[0]]; ok {
+			if deprecated, ok := cmd.(*DeprecatedCommand); ok {
+				fmt.Fprintf(context.Stderr, deprecatedMsg, deprecated.oldName, cmd.Info().Name)
+			}
+			info := cmd.Info()
+			output += fmt.Sprintf("Usage: %s %s\n", c.manager.name, info.Usage)
+			output += fmt.Sprintf("\n%s\n", info.Desc)
+			flags := c.parseFlags(cmd)
+			if flags != "" {
+				output += fmt.Sprintf("\n%s", flags)
+			}
+			if info.MinArgs > 0 {
+				output += fmt.Sprintf("\nMinimum # of arguments: %d", info.MinArgs)
+			}
+			if info.MaxArgs > 0 {
+				output += fmt.Sprintf("\nMaximum # of arguments: %d", info.MaxArgs)
+			}
+			output += fmt.Sprint("\n")
+		} else if topic, ok := c.manager.topics[context.Args[0]]; ok {
+			output += topic
+		} else {
+			return fmt.Errorf("command %q does not exist.", context.Args[0])
+		}
+	} else {
+		output += fmt.Sprintf("Usage: %s %s\n\nAvailable commands:\n", c.manager.name, c.Info().Usage)
+		var commands []string
+		for name, cmd := range c.manager.Commands {
+			if _, ok := cmd.(*DeprecatedCommand); !ok {
+				commands = append(commands, name)
+			}
+		}
+		sort.Strings(commands)
+		maxCmdSize := 20
+		for _, command := range commands {
+			if len(command) > maxCmdSize {
+				maxCmdSize = len(command)
+			}
+		}
+		for _, command := range commands {
+			description := c.manager.Commands[command].Info().Desc
+			description = strings.Split(description, "\n")[0]
+			description = strings.Split(description, ".")[0]
+			if len(description) > 2 {
+				description = strings.ToUpper(description[:1]) + description[1:]
+			}
+			fmtStr := fmt.Sprintf("  %%-%ds %%s\n", maxCmdSize)
+			output += fmt.Sprintf(fmtStr, command, description)
+		}
+		output += fmt.Sprintf("\nUse %s help <commandname> to get more information about a command.\n", c.manager.name)
+		if len(c.manager.topics) > 0 {
+			output += fmt.Sprintln("\nAvailable topics:")
+			for topic := range c.manager.topics {
+				output += fmt.Sprintf("  %s\n", topic)
+			}
+			output += fmt.Sprintf("\nUse %s help <topicname> to get more information about a topic.\n", c.manager.name)
+		}
+	}
+	io.WriteString(context.Stdout, output)
+	return nil
+}
+
+var flagFormatRegexp = regexp.MustCompile(`(?m)^([^-\s])`)
+
+func (c *help) parseFlags(command Command) string {
+	var output string
+	if cmd, ok := command.(FlaggedCommand); ok {
+		var buf bytes.Buffer
+		flagset := cmd.Flags()
+		flagset.SetOutput(&buf)
+		flagset.PrintDefaults()
+		if buf.String() != "" {
+			output = flagFormatRegexp.ReplaceAllString(buf.String(), `    $1`)
+			output = fmt.Sprintf("Flags:\n\n%s", output)
+		}
+	}
+	return strings.Replace(output, "\n", "\n  ", -1)
+}
+
+type version struct {
+	manager    *Manager
+	fs         *gnuflag.FlagSet
+	json       bool
+	clientOnly bool
+}
+
+func (c *version) Info() *Info {
+	return &Info{
+		Name:    "version",
+		MinArgs: 0,
+		Usage:   "version [--client] [--json]",
+		Desc: `display the current version
+
+The version of the server of the current target is also displayed, unless
+the [[--client]] flag is used. A warning is displayed when the major versions
+of the client and the server differ, as some commands may not work.
+
+The [[--json]] flag displays the versions in JSON format.`,
+	}
+}
+
+func (c *version) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("version", gnuflag.ExitOnError)
+		c.fs.BoolVar(&c.json, "json", false, "Display the versions in JSON format")
+		c.fs.BoolVar(&c.clientOnly, "client", false, "Display only the client version")
+	}
+	return c.fs
+}
+
+type versionInfo struct {
+	Client      string `json:"client"`
+	Server      string `json:"server,omitempty"`
+	ServerError string `json:"serverError,omitempty"`
+}
+
+func (c *version) Run(context *Context, client *Client) error {
+	info := versionInfo{Client: c.manager.version}
+	if !c.clientOnly {
+		serverVersion, err := getServerVersion(client)
+		if err != nil {
+			info.ServerError = err.Error()
+		}
+		info.Server = serverVersion
+	}
+	if c.json {
+		context.OutputFormat = JSONOutput
+	}
+	if context.StructuredOutput() {
+		return context.WriteStructured(info)
+	}
+	fmt.Fprintf(context.Stdout, "%s version %s.\n", c.manager.name, c.manager.version)
+	if info.ServerError != "" {
+		fmt.Fprintf(context.Stderr, "Unable to retrieve the server version: %s\n", info.ServerError)
+	}
+	if info.Server != "" {
+		fmt.Fprintf(context.Stdout, "Server version %s.\n", info.Server)
+		if !sameMajorVersion(info.Client, info.Server) {
+			fmt.Fprintf(context.Stderr, "WARNING: the major versions of the client (%s) and the server (%s) differ, some commands may not work.\n", info.Client, info.Server)
+		}
+	}
+	return nil
+}
+
+// getServerVersion returns the version of the server of the current target,
+// or an empty string when there's no target.
+func getServerVersion(client *Client) (string, error) {
+	if _, err := GetTarget(); err != nil {
+		return "", nil
+	}
+	info, err := client.getServerInfo()
+	if err != nil {
+		return "", err
+	}
+	if info.Version == "" {
+		return "", stderrors.New("the server didn't report its version")
+	}
+	return info.Version, nil
+}
+
+func sameMajorVersion(client, server string) bool {
+	vClient, err := goVersion.NewVersion(client)
+	if err != nil {
+		return true
+	}
+	vServer, err := goVersion.NewVersion(server)
+	if err != nil {
+		return true
+	}
+	return vClient.Segments()[0] == vServer.Segments()[0]
+}
+
+func ExtractProgramName(path string) string {
+	parts := strings.Split(path, "/")
+	return parts[len(parts)-1]
+}
+
+var fsystem fs.Fs
+
+func filesystem() fs.Fs {
+	if fsystem == nil {
+		fsystem = fs.OsFs{}
+	}
+	return fsystem
+}
+
+// validateVersion checks whether current version is greater or equal to
+// supported version.
+func validateVersion(supported, current string) bool {
+	if supported == "" {
+		return true
+	}
+	vSupported, err := goVersion.NewVersion(supported)
+	if err != nil {
+		return false
+	}
+	vCurrent, err := goVersion.NewVersion(current)
+	if err != nil {
+		return false
+	}
+	return vCurrent.Compare(vSupported) >= 0
+}
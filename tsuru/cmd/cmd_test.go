@@ -0,0 +1,161 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"gopkg.in/check.v1"
+)
+
+type verbosityCommand struct {
+	verbosity int
+	args      []string
+}
+
+func (c *verbosityCommand) Info() *Info {
+	return &Info{Name: "verbosity", Usage: "verbosity"}
+}
+
+func (c *verbosityCommand) Run(context *Context, client *Client) error {
+	c.verbosity = client.Verbosity
+	c.args = context.Args
+	return nil
+}
+
+func (s *S) TestRunVerbosity(c *check.C) {
+	tests := []struct {
+		args      []string
+		verbosity int
+	}{
+		{[]string{"verbosity"}, 0},
+		{[]string{"-v", "verbosity"}, 1},
+		{[]string{"-vv", "verbosity"}, 2},
+		{[]string{"-v", "-v", "verbosity"}, 2},
+		{[]string{"-v", "2", "verbosity"}, 2},
+		{[]string{"-v", "1", "verbosity"}, 1},
+		{[]string{"--verbosity", "1", "verbosity"}, 1},
+		{[]string{"--verbosity=2", "verbosity"}, 2},
+		{[]string{"--verbose", "2", "verbosity"}, 2},
+		{[]string{"--target", "http://tsuru.io", "-v", "2", "verbosity"}, 2},
+	}
+	for _, t := range tests {
+		s.exiter = new(recordingExiter)
+		command := &verbosityCommand{}
+		m := s.newManager()
+		m.Register(command)
+		m.Run(t.args)
+		c.Check(s.exiter.value(), check.Equals, 0, check.Commentf("args: %v", t.args))
+		c.Check(command.verbosity, check.Equals, t.verbosity, check.Commentf("args: %v", t.args))
+	}
+}
+
+func (s *S) TestRunVerbosityDoesNotTouchCommandArgs(c *check.C) {
+	command := &verbosityCommand{}
+	m := s.newManager()
+	m.Register(command)
+	m.Run([]string{"-v", "2", "verbosity", "--", "-v", "3"})
+	c.Assert(s.exiter.value(), check.Equals, 0)
+	c.Assert(command.verbosity, check.Equals, 2)
+	c.Assert(command.args, check.DeepEquals, []string{"-v", "3"})
+}
+
+func (s *S) runVersion(args ...string) error {
+	manager := s.newManager()
+	command := version{manager: manager}
+	command.Flags().Parse(true, args)
+	context := Context{Stdout: &s.stdout, Stderr: &s.stderr}
+	return command.Run(&context, NewClient(http.DefaultClient, &context, manager))
+}
+
+func (s *S) TestVersion(c *check.C) {
+	var hits int
+	server := infoServer(http.StatusOK, `{"version":"1.2.0"}`, &hits)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	err := s.runVersion()
+	c.Assert(err, check.IsNil)
+	c.Assert(hits, check.Equals, 1)
+	c.Assert(s.stdout.String(), check.Equals, "glb version 1.0.0.\nServer version 1.2.0.\n")
+	c.Assert(s.stderr.String(), check.Equals, "")
+}
+
+func (s *S) TestVersionClientOnly(c *check.C) {
+	var hits int
+	server := infoServer(http.StatusOK, `{"version":"1.2.0"}`, &hits)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	err := s.runVersion("--client")
+	c.Assert(err, check.IsNil)
+	c.Assert(hits, check.Equals, 0)
+	c.Assert(s.stdout.String(), check.Equals, "glb version 1.0.0.\n")
+}
+
+func (s *S) TestVersionWithoutTarget(c *check.C) {
+	err := s.runVersion()
+	c.Assert(err, check.IsNil)
+	c.Assert(s.stdout.String(), check.Equals, "glb version 1.0.0.\n")
+	c.Assert(s.stderr.String(), check.Equals, "")
+}
+
+func (s *S) TestVersionDifferentMajorVersions(c *check.C) {
+	var hits int
+	server := infoServer(http.StatusOK, `{"version":"2.1.0"}`, &hits)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	err := s.runVersion()
+	c.Assert(err, check.IsNil)
+	c.Assert(s.stdout.String(), check.Equals, "glb version 1.0.0.\nServer version 2.1.0.\n")
+	c.Assert(s.stderr.String(), check.Equals, "WARNING: the major versions of the client (1.0.0) and the server (2.1.0) differ, some commands may not work.\n")
+}
+
+func (s *S) TestVersionServerWithoutVersion(c *check.C) {
+	var hits int
+	server := infoServer(http.StatusNotFound, "not found", &hits)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	err := s.runVersion()
+	c.Assert(err, check.IsNil)
+	c.Assert(s.stdout.String(), check.Equals, "glb version 1.0.0.\n")
+	c.Assert(s.stderr.String(), check.Equals, "Unable to retrieve the server version: the server didn't report its version\n")
+}
+
+func (s *S) TestVersionJSON(c *check.C) {
+	var hits int
+	server := infoServer(http.StatusOK, `{"version":"1.2.0"}`, &hits)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	err := s.runVersion("--json")
+	c.Assert(err, check.IsNil)
+	c.Assert(s.stdout.String(), check.Equals, "{\n  \"client\": \"1.0.0\",\n  \"server\": \"1.2.0\"\n}\n")
+}
+
+func (s *S) TestVersionJSONUnreachableServer(c *check.C) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	err := s.runVersion("--json")
+	c.Assert(err, check.IsNil)
+	c.Assert(s.stdout.String(), check.Matches, `(?s)\{\n  "client": "1.0.0",\n  "serverError": ".+"\n\}\n`)
+	c.Assert(s.stderr.String(), check.Equals, "")
+}
+
+func (s *S) TestSameMajorVersion(c *check.C) {
+	tests := []struct {
+		client, server string
+		expected       bool
+	}{
+		{"1.0.0", "1.2.0", true},
+		{"1.0.0", "2.0.0", false},
+		{"2.0.0-rc1", "2.1.0", true},
+		{"1.0.0", "invalid", true},
+		{"dev", "1.0.0", true},
+	}
+	for _, tt := range tests {
+		c.Check(sameMajorVersion(tt.client, tt.server), check.Equals, tt.expected, check.Commentf("client %s, server %s", tt.client, tt.server))
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// versionCheckInterval is how long the minimum client version reported by a
+// target is cached before the client asks the server again.
+const versionCheckInterval = 24 * time.Hour
+
+const unsupportedVersionWarning = `#####################################################################
+
+WARNING: You're using an unsupported version of %s.
+
+You must have at least version %s, your current
+version is %s.
+
+Please go to http://docs.tsuru.io/en/latest/using/install-client.html
+and download the last version.
+
+#####################################################################
+
+`
+
+var (
+	versionChecked bool
+	versionWarned  bool
+)
+
+// versionCheck is the cached result of the last check of the minimum client
+// version supported by a target.
+type versionCheck struct {
+	Target    string    `json:"target"`
+	Minimum   string    `json:"minimum"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+func versionCheckPath() string {
+	return JoinWithUserDir(".tsuru", "version-check")
+}
+
+func readVersionCheck() (versionCheck, bool) {
+	var check versionCheck
+	file, err := filesystem().Open(versionCheckPath())
+	if err != nil {
+		return check, false
+	}
+	defer file.Close()
+	err = json.NewDecoder(file).Decode(&check)
+	return check, err == nil
+}
+
+func writeVersionCheck(check versionCheck) error {
+	file, err := filesystem().Create(versionCheckPath())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(check)
+}
+
+// checkMinimumVersion warns, once per execution, when the current target
+// requires a newer version of the client. The minimum version is read from
+// the /info endpoint and cached for versionCheckInterval, failures are
+// silently ignored. When the cache can't be written, the server isn't asked
+// at all, and the check relies on the version header of the responses.
+func (c *Client) checkMinimumVersion() {
+	if versionChecked {
+		return
+	}
+	versionChecked = true
+	target, err := GetTarget()
+	if err != nil {
+		return
+	}
+	check, ok := readVersionCheck()
+	if !ok || check.Target != target || now().Sub(check.CheckedAt) > versionCheckInterval {
+		check = versionCheck{Target: target, CheckedAt: now()}
+		err = writeVersionCheck(check)
+		if err != nil {
+			return
+		}
+		info, err := c.getServerInfo()
+		if err != nil {
+			return
+		}
+		check.Minimum = info.MinimumClientVersion
+		writeVersionCheck(check)
+	}
+	c.warnUnsupportedVersion(c.context.Stderr, check.Minimum)
+}
+
+// serverInfo is the information returned by the /info endpoint of the
+// server.
+type serverInfo struct {
+	Version              string `json:"version"`
+	MinimumClientVersion string `json:"minimumClientVersion"`
+}
+
+// getServerInfo gets the information of the server of the current target.
+// The request is sent directly by the HTTP client, so it's not authenticated
+// and doesn't trigger any of the checks made by Do. Servers without the /info
+// endpoint only report the minimum client version, in the version header of
+// the response.
+func (c *Client) getServerInfo() (*serverInfo, error) {
+	u, err := GetURL("/info")
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Close = true
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var info serverInfo
+	if response.StatusCode == http.StatusOK {
+		json.NewDecoder(response.Body).Decode(&info)
+	}
+	if info.MinimumClientVersion == "" {
+		info.MinimumClientVersion = response.Header.Get(c.versionHeader)
+	}
+	return &info, nil
+}
+
+// warnUnsupportedVersion prints a warning, once per execution, when the
+// current version of the client is older than the supported one.
+func (c *Client) warnUnsupportedVersion(w io.Writer, supported string) {
+	if versionWarned || validateVersion(supported, c.currentVersion) {
+		return
+	}
+	versionWarned = true
+	fmt.Fprintf(w, unsupportedVersionWarning, c.progname, supported, c.currentVersion)
+}
+
+// RequireServerVersion returns an error when the server of the current
+// target is older than minimum, or doesn't report its version, so commands
+// can refuse to use a feature the server would silently ignore.
+func (c *Client) RequireServerVersion(minimum, feature string) error {
+	info, err := c.getServerInfo()
+	if err != nil {
+		return err
+	}
+	if info.Version == "" {
+		return fmt.Errorf("%s requires tsuru %s or newer, and the server didn't report its version.", feature, minimum)
+	}
+	if !validateVersion(minimum, info.Version) {
+		return fmt.Errorf("%s requires tsuru %s or newer, and the server runs version %s.", feature, minimum, info.Version)
+	}
+	return nil
+}
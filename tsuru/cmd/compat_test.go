@@ -0,0 +1,149 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/tsuru/tsuru/fs"
+	"github.com/tsuru/tsuru/fs/fstest"
+	"gopkg.in/check.v1"
+)
+
+// infoServer starts a server answering /info with the given status and
+// body, counting the requests in hits.
+func infoServer(status int, body string, hits *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/1.0/info" {
+			*hits++
+		}
+		w.Header().Set("Supported-Tsuru", "0.1.0")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+type unwritableFs struct {
+	fstest.RecordingFs
+}
+
+func (f *unwritableFs) Create(name string) (fs.File, error) {
+	return nil, errors.New("read-only file system")
+}
+
+func (s *S) checkMinimumVersion() {
+	versionChecked = false
+	client := NewClient(http.DefaultClient, &Context{Stdout: &s.stdout, Stderr: &s.stderr}, s.newManager())
+	client.checkMinimumVersion()
+}
+
+func (s *S) TestCheckMinimumVersion(c *check.C) {
+	var hits int
+	server := infoServer(http.StatusOK, `{"version":"1.2.0","minimumClientVersion":"2.0.0"}`, &hits)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	s.checkMinimumVersion()
+	c.Assert(hits, check.Equals, 1)
+	c.Assert(s.stderr.String(), check.Matches, `(?s).*WARNING: You're using an unsupported version of glb.*You must have at least version 2.0.0, your current\nversion is 1.0.0.*`)
+	cached, ok := readVersionCheck()
+	c.Assert(ok, check.Equals, true)
+	c.Assert(cached.Target, check.Equals, server.URL)
+	c.Assert(cached.Minimum, check.Equals, "2.0.0")
+}
+
+func (s *S) TestCheckMinimumVersionSupported(c *check.C) {
+	var hits int
+	server := infoServer(http.StatusOK, `{"version":"1.2.0","minimumClientVersion":"0.9.0"}`, &hits)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	s.checkMinimumVersion()
+	c.Assert(hits, check.Equals, 1)
+	c.Assert(s.stderr.String(), check.Equals, "")
+}
+
+func (s *S) TestCheckMinimumVersionUsesTheCache(c *check.C) {
+	var hits int
+	server := infoServer(http.StatusOK, `{"minimumClientVersion":"2.0.0"}`, &hits)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	s.checkMinimumVersion()
+	versionWarned = false
+	s.stderr.Reset()
+	s.checkMinimumVersion()
+	c.Assert(hits, check.Equals, 1)
+	c.Assert(strings.Contains(s.stderr.String(), "You must have at least version 2.0.0"), check.Equals, true)
+}
+
+func (s *S) TestCheckMinimumVersionIgnoresTheCacheOfOtherTargets(c *check.C) {
+	var hits int
+	server := infoServer(http.StatusOK, `{"minimumClientVersion":"2.0.0"}`, &hits)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	err := writeVersionCheck(versionCheck{Target: "http://other.tsuru.io", Minimum: "0.1.0", CheckedAt: now()})
+	c.Assert(err, check.IsNil)
+	s.checkMinimumVersion()
+	c.Assert(hits, check.Equals, 1)
+	c.Assert(strings.Contains(s.stderr.String(), "You must have at least version 2.0.0"), check.Equals, true)
+}
+
+func (s *S) TestCheckMinimumVersionFromTheHeader(c *check.C) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Supported-Tsuru", "3.0.0")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	s.checkMinimumVersion()
+	c.Assert(hits, check.Equals, 1)
+	c.Assert(strings.Contains(s.stderr.String(), "You must have at least version 3.0.0"), check.Equals, true)
+}
+
+func (s *S) TestCheckMinimumVersionWithoutAWritableCache(c *check.C) {
+	fsystem = &unwritableFs{}
+	var hits int
+	server := infoServer(http.StatusOK, `{"minimumClientVersion":"2.0.0"}`, &hits)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	s.checkMinimumVersion()
+	c.Assert(hits, check.Equals, 0)
+	c.Assert(s.stderr.String(), check.Equals, "")
+}
+
+func (s *S) TestCheckMinimumVersionWithoutTarget(c *check.C) {
+	s.checkMinimumVersion()
+	c.Assert(s.stderr.String(), check.Equals, "")
+}
+
+func (s *S) requireServerVersion(c *check.C, status int, body string) error {
+	var hits int
+	server := infoServer(status, body, &hits)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	client := NewClient(http.DefaultClient, nil, s.newManager())
+	err := client.RequireServerVersion("1.2.0", "The --flag flag")
+	c.Assert(hits, check.Equals, 1)
+	return err
+}
+
+func (s *S) TestRequireServerVersion(c *check.C) {
+	err := s.requireServerVersion(c, http.StatusOK, `{"version":"1.2.1"}`)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestRequireServerVersionOlderServer(c *check.C) {
+	err := s.requireServerVersion(c, http.StatusOK, `{"version":"1.1.0"}`)
+	c.Assert(err, check.ErrorMatches, `The --flag flag requires tsuru 1.2.0 or newer, and the server runs version 1.1.0.`)
+}
+
+func (s *S) TestRequireServerVersionServerWithoutVersion(c *check.C) {
+	err := s.requireServerVersion(c, http.StatusNotFound, "not found")
+	c.Assert(err, check.ErrorMatches, `The --flag flag requires tsuru 1.2.0 or newer, and the server didn't report its version.`)
+}
@@ -0,0 +1,162 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/tsuru/gnuflag"
+	"gopkg.in/yaml.v1"
+)
+
+// fileDefaults holds the values loaded from the optional configuration file
+// (~/.tsuru/config.yaml), which looks like:
+//
+//	target: https://tsuru.example.com
+//	token-expiry-warning: 30m
+//	client-cert: /home/me/.tsuru/client.crt
+//	client-key: /home/me/.tsuru/client.key
+//	team: myteam
+//	flags:
+//	  app-create:
+//	    pool: mypool
+//	aliases:
+//	  ls: app-list
+//	  mine: app-list -u me
+//
+// The target is used when no target is set in the environment or with
+// target-set, token-expiry-warning defines how long before the expiration of
+// the session the user is warned about it, client-cert and client-key are the
+// client certificate used when the API requires mutual TLS, the team owns the
+// resources created without --team (see DefaultTeam), the flags are default
+// values for the flags of the given commands, by command name, and the
+// aliases are short names for commands, optionally followed by arguments.
+var fileDefaults struct {
+	target     string
+	clientCert string
+	clientKey  string
+	team       string
+	flags      map[string]map[string]string
+	aliases    map[string]string
+}
+
+func configFilePath() string {
+	return JoinWithUserDir(".tsuru", "config.yaml")
+}
+
+// configFile is the format of the configuration file. It's parsed on its
+// own, as the global configuration of the config package is used by the
+// installer.
+type configFile struct {
+	Target             string                 `yaml:"target"`
+	TokenExpiryWarning string                 `yaml:"token-expiry-warning"`
+	ClientCert         string                 `yaml:"client-cert"`
+	ClientKey          string                 `yaml:"client-key"`
+	Team               string                 `yaml:"team"`
+	Flags              map[string]interface{} `yaml:"flags"`
+	Aliases            map[string]string      `yaml:"aliases"`
+}
+
+// loadConfigFile reads the configuration file, if it exists.
+func loadConfigFile() error {
+	f, err := filesystem().Open(configFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	var file configFile
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid configuration file %s: %s", configFilePath(), err)
+	}
+	if file.TokenExpiryWarning != "" {
+		expiryWarnings, err = time.ParseDuration(file.TokenExpiryWarning)
+		if err != nil {
+			return fmt.Errorf("invalid configuration file %s: token-expiry-warning: %s", configFilePath(), err)
+		}
+	}
+	fileDefaults.target = file.Target
+	fileDefaults.clientCert = file.ClientCert
+	fileDefaults.clientKey = file.ClientKey
+	fileDefaults.team = file.Team
+	fileDefaults.flags, err = parseFlagDefaults(file.Flags)
+	if err != nil {
+		return fmt.Errorf("invalid configuration file %s: %s", configFilePath(), err)
+	}
+	fileDefaults.aliases = file.Aliases
+	if fileDefaults.aliases == nil {
+		fileDefaults.aliases = map[string]string{}
+	}
+	return nil
+}
+
+// DefaultTeam returns the team set in the configuration file, used as the
+// owner of the apps and service instances created without --team.
+func DefaultTeam() string {
+	return fileDefaults.team
+}
+
+// parseFlagDefaults converts the flags section of the configuration file,
+// which maps each command name to the default values of its flags. It's not
+// decoded directly to the final type because the yaml package would silently
+// drop the entries in other formats, like flags without a command name.
+func parseFlagDefaults(flags map[string]interface{}) (map[string]map[string]string, error) {
+	defaults := make(map[string]map[string]string, len(flags))
+	for command, value := range flags {
+		values, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("flags: %q must map the flags of the command %q to their default values, like \"app-create: {pool: mypool}\"", command, command)
+		}
+		defaults[command] = make(map[string]string, len(values))
+		for name, v := range values {
+			defaults[command][fmt.Sprint(name)] = fmt.Sprint(v)
+		}
+	}
+	return defaults, nil
+}
+
+// applyFlagDefaults sets the flags of the given command not given in the
+// command line to the values from the configuration file. Flags sharing the
+// same value (like --team and -t) are considered given if any of them is in
+// the command line.
+func applyFlagDefaults(command string, flagset *gnuflag.FlagSet) error {
+	defaults := fileDefaults.flags[command]
+	if len(defaults) == 0 {
+		return nil
+	}
+	var given []gnuflag.Value
+	flagset.Visit(func(f *gnuflag.Flag) {
+		given = append(given, f.Value)
+	})
+	for name, value := range defaults {
+		f := flagset.Lookup(name)
+		if f == nil {
+			continue
+		}
+		isGiven := false
+		for _, v := range given {
+			if v == f.Value {
+				isGiven = true
+				break
+			}
+		}
+		if isGiven {
+			continue
+		}
+		if err := flagset.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value %q for flag %q of %s in %s: %s", value, name, command, configFilePath(), err)
+		}
+	}
+	return nil
+}
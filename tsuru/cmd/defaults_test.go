@@ -0,0 +1,142 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/gnuflag"
+	"gopkg.in/check.v1"
+)
+
+type poolCommand struct {
+	name string
+	pool string
+	fs   *gnuflag.FlagSet
+}
+
+func (c *poolCommand) Info() *Info {
+	return &Info{Name: c.name, Usage: c.name}
+}
+
+func (c *poolCommand) Run(context *Context, client *Client) error {
+	return nil
+}
+
+func (c *poolCommand) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet(c.name, gnuflag.ContinueOnError)
+		c.fs.StringVar(&c.pool, "pool", "", "the pool")
+		c.fs.StringVar(&c.pool, "o", "", "the pool")
+	}
+	return c.fs
+}
+
+func (s *S) writeConfigFile(c *check.C, content string) {
+	f, err := filesystem().Create(configFilePath())
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	_, err = f.Write([]byte(content))
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestLoadConfigFile(c *check.C) {
+	defer func() { expiryWarnings = defaultExpiryWarning }()
+	s.writeConfigFile(c, `target: https://tsuru.example.com
+token-expiry-warning: 30m
+client-cert: /home/me/client.crt
+client-key: /home/me/client.key
+team: myteam
+flags:
+  app-create:
+    pool: mypool
+    units: 5
+aliases:
+  mine: app-list -u me
+`)
+	err := loadConfigFile()
+	c.Assert(err, check.IsNil)
+	c.Assert(fileDefaults.target, check.Equals, "https://tsuru.example.com")
+	c.Assert(fileDefaults.clientCert, check.Equals, "/home/me/client.crt")
+	c.Assert(fileDefaults.clientKey, check.Equals, "/home/me/client.key")
+	c.Assert(DefaultTeam(), check.Equals, "myteam")
+	c.Assert(expiryWarnings, check.Equals, 30*time.Minute)
+	c.Assert(fileDefaults.flags, check.DeepEquals, map[string]map[string]string{"app-create": {"pool": "mypool", "units": "5"}})
+	c.Assert(fileDefaults.aliases, check.DeepEquals, map[string]string{"mine": "app-list -u me"})
+}
+
+func (s *S) TestLoadConfigFileKeepsTheGlobalConfig(c *check.C) {
+	config.Set("docker-hosts", []string{"192.168.0.10"})
+	defer config.Unset("docker-hosts")
+	s.writeConfigFile(c, "target: https://tsuru.example.com\n")
+	err := loadConfigFile()
+	c.Assert(err, check.IsNil)
+	value, err := config.Get("docker-hosts")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.DeepEquals, []string{"192.168.0.10"})
+	_, err = config.Get("target")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestLoadConfigFileInvalid(c *check.C) {
+	s.writeConfigFile(c, "flags: [team\n")
+	err := loadConfigFile()
+	c.Assert(err, check.ErrorMatches, "invalid configuration file .*")
+}
+
+func (s *S) TestLoadConfigFileFlagsWithoutCommand(c *check.C) {
+	s.writeConfigFile(c, "flags:\n  pool: mypool\n")
+	err := loadConfigFile()
+	c.Assert(err, check.ErrorMatches, `invalid configuration file .*: flags: "pool" must map the flags of the command "pool" to their default values, .*`)
+}
+
+func (s *S) TestLoadConfigFileInvalidExpiryWarning(c *check.C) {
+	s.writeConfigFile(c, "token-expiry-warning: soon\n")
+	err := loadConfigFile()
+	c.Assert(err, check.ErrorMatches, "invalid configuration file .*: token-expiry-warning: .*")
+}
+
+func (s *S) TestLoadConfigFileNotFound(c *check.C) {
+	err := loadConfigFile()
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) runPoolCommands(c *check.C, config string, args ...string) (*poolCommand, *poolCommand) {
+	s.writeConfigFile(c, config)
+	c.Assert(loadConfigFile(), check.IsNil)
+	create := &poolCommand{name: "app-create"}
+	update := &poolCommand{name: "app-update"}
+	m := s.newManager()
+	m.Register(create)
+	m.Register(update)
+	m.Run(args)
+	return create, update
+}
+
+func (s *S) TestFlagDefaultsApplyToTheirCommand(c *check.C) {
+	create, update := s.runPoolCommands(c, "flags:\n  app-create:\n    pool: mypool\n", "app-create")
+	c.Assert(s.exiter.value(), check.Equals, 0)
+	c.Assert(create.pool, check.Equals, "mypool")
+	c.Assert(update.pool, check.Equals, "")
+}
+
+func (s *S) TestFlagDefaultsDoNotApplyToOtherCommands(c *check.C) {
+	_, update := s.runPoolCommands(c, "flags:\n  app-create:\n    pool: mypool\n", "app-update")
+	c.Assert(s.exiter.value(), check.Equals, 0)
+	c.Assert(update.pool, check.Equals, "")
+}
+
+func (s *S) TestFlagDefaultsAreOverriddenByTheCommandLine(c *check.C) {
+	create, _ := s.runPoolCommands(c, "flags:\n  app-create:\n    pool: mypool\n", "app-create", "-o", "other")
+	c.Assert(s.exiter.value(), check.Equals, 0)
+	c.Assert(create.pool, check.Equals, "other")
+}
+
+func (s *S) TestFlagDefaultsIgnoreUnknownFlags(c *check.C) {
+	create, _ := s.runPoolCommands(c, "flags:\n  app-create:\n    plan: small\n", "app-create")
+	c.Assert(s.exiter.value(), check.Equals, 0)
+	c.Assert(create.pool, check.Equals, "")
+}
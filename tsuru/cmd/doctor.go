@@ -0,0 +1,191 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	checkOK   = "OK"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+	checkSkip = "SKIP"
+)
+
+// doctorCheck is the result of one of the checks made by the doctor
+// command, with a hint to fix it when it doesn't pass.
+type doctorCheck struct {
+	name   string
+	status string
+	detail string
+	hint   string
+}
+
+type doctor struct {
+	manager *Manager
+}
+
+func (c *doctor) Info() *Info {
+	return &Info{
+		Name:  "doctor",
+		Usage: "doctor",
+		Desc: `Checks the setup of the client, displaying how to fix the problems found.
+
+The checks are:
+
+  - a target is set and the tsuru server is reachable;
+  - there's a token, and the server accepts it;
+  - the versions of the client and the server are compatible;
+  - the name of the app can be guessed from the current directory.
+
+The command fails when any check fails. Failing to guess the name of the app
+is only a warning, as the app may always be given with the --app flag.`,
+		MinArgs: 0,
+		MaxArgs: 0,
+	}
+}
+
+func (c *doctor) Run(context *Context, client *Client) error {
+	var checks []doctorCheck
+	target, err := GetTarget()
+	if err != nil {
+		checks = append(checks,
+			doctorCheck{name: "Target", status: checkFail, detail: "no target is set",
+				hint: fmt.Sprintf(`Add the address of your tsuru server with "%[1]s target-add <label> <address> --set".`, c.manager.name)},
+			doctorCheck{name: "Server", status: checkSkip, detail: "no target is set"},
+			doctorCheck{name: "Token", status: checkSkip, detail: "no target is set"},
+			doctorCheck{name: "Versions", status: checkSkip, detail: "no target is set"},
+		)
+	} else {
+		detail := target
+		if labels := currentTargetLabels(); len(labels) > 0 {
+			detail = fmt.Sprintf("%s (%s)", target, strings.Join(labels, ", "))
+		}
+		checks = append(checks, doctorCheck{name: "Target", status: checkOK, detail: detail})
+		info, err := client.getServerInfo()
+		if err != nil {
+			checks = append(checks,
+				doctorCheck{name: "Server", status: checkFail, detail: fmt.Sprintf("unable to reach the server: %s", err),
+					hint: fmt.Sprintf(`Check the address of the target with "%s target-list", your network and your proxy (--proxy).`, c.manager.name)},
+				doctorCheck{name: "Token", status: checkSkip, detail: "the server is not reachable"},
+				doctorCheck{name: "Versions", status: checkSkip, detail: "the server is not reachable"},
+			)
+		} else {
+			checks = append(checks, doctorCheck{name: "Server", status: checkOK, detail: "reachable"})
+			checks = append(checks, c.checkToken(client))
+			checks = append(checks, c.checkVersions(info))
+		}
+	}
+	checks = append(checks, c.checkGuess())
+	var failed int
+	for _, check := range checks {
+		fmt.Fprintf(context.Stdout, "[%-4s] %s: %s\n", check.status, check.name, check.detail)
+		if check.hint != "" {
+			fmt.Fprintf(context.Stdout, "       %s\n", check.hint)
+		}
+		if check.status == checkFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed.", failed)
+	}
+	return nil
+}
+
+// checkToken sends the token to the server, without refreshing it or asking
+// the user to log in again when it's refused.
+func (c *doctor) checkToken(client *Client) doctorCheck {
+	check := doctorCheck{name: "Token"}
+	loginHint := fmt.Sprintf(`Run "%s login" to log in.`, c.manager.name)
+	token, err := ReadToken()
+	if err != nil {
+		check.status, check.detail, check.hint = checkFail, fmt.Sprintf("unable to read the token: %s", err), loginHint
+		return check
+	}
+	if token == "" {
+		check.status, check.detail, check.hint = checkFail, "you're not logged in", loginHint
+		return check
+	}
+	u, err := GetURL("/users/info")
+	if err != nil {
+		check.status, check.detail = checkFail, err.Error()
+		return check
+	}
+	request, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		check.status, check.detail = checkFail, err.Error()
+		return check
+	}
+	request.Header.Set("Authorization", "bearer "+token)
+	request.Close = true
+	response, err := client.HTTPClient.Do(request)
+	if err != nil {
+		check.status, check.detail = checkFail, fmt.Sprintf("unable to validate the token: %s", err)
+		return check
+	}
+	defer response.Body.Close()
+	switch {
+	case response.StatusCode == http.StatusUnauthorized:
+		check.status, check.detail, check.hint = checkFail, "the token is invalid or expired", loginHint
+		if tokenFromEnv() {
+			check.hint = "Update or unset the TSURU_TOKEN environment variable."
+		}
+	case response.StatusCode != http.StatusOK:
+		check.status, check.detail = checkFail, fmt.Sprintf("unable to validate the token: %s", response.Status)
+	default:
+		var user APIUser
+		json.NewDecoder(response.Body).Decode(&user)
+		check.status, check.detail = checkOK, "valid"
+		if user.Email != "" {
+			check.detail = fmt.Sprintf("logged in as %s", user.Email)
+		}
+	}
+	return check
+}
+
+func (c *doctor) checkVersions(info *serverInfo) doctorCheck {
+	check := doctorCheck{name: "Versions", status: checkOK}
+	client := c.manager.version
+	check.detail = fmt.Sprintf("client %s, server %s", client, info.Version)
+	if info.Version == "" {
+		check.detail = fmt.Sprintf("client %s, server version unknown", client)
+	}
+	upgradeHint := "Download the last version from http://docs.tsuru.io/en/latest/using/install-client.html."
+	if !validateVersion(info.MinimumClientVersion, client) {
+		check.status = checkFail
+		check.detail += fmt.Sprintf(", but the server requires at least client %s", info.MinimumClientVersion)
+		check.hint = upgradeHint
+	} else if info.Version != "" && !sameMajorVersion(client, info.Version) {
+		check.status = checkWarn
+		check.detail += ", the major versions differ and some commands may not work"
+		check.hint = upgradeHint
+	}
+	return check
+}
+
+func (c *doctor) checkGuess() doctorCheck {
+	check := doctorCheck{name: "App"}
+	dir, err := os.Getwd()
+	if err != nil {
+		check.status, check.detail = checkWarn, fmt.Sprintf("unable to get the current directory: %s", err)
+		return check
+	}
+	var g GuessingCommand
+	name, err := g.guesser().GuessName(dir)
+	if err != nil {
+		check.status = checkWarn
+		check.detail = "unable to guess the name of the app from the current directory"
+		check.hint = `Use the --app flag, or add the repository of the app as the "tsuru" git remote (see app-create --add-remote).`
+		return check
+	}
+	check.status, check.detail = checkOK, fmt.Sprintf("guessed %q from the current directory", name)
+	return check
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"gopkg.in/check.v1"
+)
+
+// doctorServer starts a server reporting the given version and minimum
+// client version, accepting only the token "abc123".
+func doctorServer(version, minimum string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1.0/info":
+			w.Write([]byte(`{"version":"` + version + `","minimumClientVersion":"` + minimum + `"}`))
+		case "/1.0/users/info":
+			if r.Header.Get("Authorization") != "bearer abc123" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"Email":"me@tsuru.io"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// runDoctor runs the doctor command from a directory that isn't a git
+// repository, so the name of the app is never guessed.
+func (s *S) runDoctor(c *check.C) error {
+	wd, err := os.Getwd()
+	c.Assert(err, check.IsNil)
+	defer os.Chdir(wd)
+	err = os.Chdir(c.MkDir())
+	c.Assert(err, check.IsNil)
+	manager := s.newManager()
+	command := doctor{manager: manager}
+	context := Context{Stdout: &s.stdout, Stderr: &s.stderr}
+	return command.Run(&context, NewClient(http.DefaultClient, &context, manager))
+}
+
+const doctorGuessWarning = `[WARN] App: unable to guess the name of the app from the current directory
+       Use the --app flag, or add the repository of the app as the "tsuru" git remote (see app-create --add-remote).
+`
+
+func (s *S) TestDoctor(c *check.C) {
+	server := doctorServer("1.2.0", "0.9.0")
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	os.Setenv("TSURU_TOKEN", "abc123")
+	err := s.runDoctor(c)
+	c.Assert(err, check.IsNil)
+	expected := `[OK  ] Target: ` + server.URL + `
+[OK  ] Server: reachable
+[OK  ] Token: logged in as me@tsuru.io
+[OK  ] Versions: client 1.0.0, server 1.2.0
+` + doctorGuessWarning
+	c.Assert(s.stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestDoctorWithoutTarget(c *check.C) {
+	err := s.runDoctor(c)
+	c.Assert(err, check.ErrorMatches, `1 check\(s\) failed.`)
+	expected := `[FAIL] Target: no target is set
+       Add the address of your tsuru server with "glb target-add <label> <address> --set".
+[SKIP] Server: no target is set
+[SKIP] Token: no target is set
+[SKIP] Versions: no target is set
+` + doctorGuessWarning
+	c.Assert(s.stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestDoctorUnreachableServer(c *check.C) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	err := s.runDoctor(c)
+	c.Assert(err, check.ErrorMatches, `1 check\(s\) failed.`)
+	c.Assert(s.stdout.String(), check.Matches, `(?s)\[OK  \] Target: .*
+\[FAIL\] Server: unable to reach the server: .*
+       Check the address of the target with "glb target-list", your network and your proxy \(--proxy\).
+\[SKIP\] Token: the server is not reachable
+\[SKIP\] Versions: the server is not reachable
+\[WARN\] App: .*`)
+}
+
+func (s *S) TestDoctorNotLoggedIn(c *check.C) {
+	server := doctorServer("1.2.0", "")
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	err := s.runDoctor(c)
+	c.Assert(err, check.ErrorMatches, `1 check\(s\) failed.`)
+	c.Assert(s.stdout.String(), check.Matches, `(?s).*\[FAIL\] Token: you're not logged in
+       Run "glb login" to log in.
+\[OK  \] Versions: client 1.0.0, server 1.2.0
+.*`)
+}
+
+func (s *S) TestDoctorInvalidTokenFromTheEnvironment(c *check.C) {
+	server := doctorServer("1.2.0", "")
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	os.Setenv("TSURU_TOKEN", "expired")
+	err := s.runDoctor(c)
+	c.Assert(err, check.ErrorMatches, `1 check\(s\) failed.`)
+	c.Assert(s.stdout.String(), check.Matches, `(?s).*\[FAIL\] Token: the token is invalid or expired
+       Update or unset the TSURU_TOKEN environment variable.
+.*`)
+}
+
+func (s *S) TestDoctorUnsupportedClient(c *check.C) {
+	server := doctorServer("2.0.0", "2.0.0")
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	os.Setenv("TSURU_TOKEN", "abc123")
+	err := s.runDoctor(c)
+	c.Assert(err, check.ErrorMatches, `1 check\(s\) failed.`)
+	c.Assert(s.stdout.String(), check.Matches, `(?s).*\[FAIL\] Versions: client 1.0.0, server 2.0.0, but the server requires at least client 2.0.0
+       Download the last version from http://docs.tsuru.io/en/latest/using/install-client.html.
+.*`)
+}
+
+func (s *S) TestDoctorDifferentMajorVersions(c *check.C) {
+	server := doctorServer("2.0.0", "")
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	os.Setenv("TSURU_TOKEN", "abc123")
+	err := s.runDoctor(c)
+	c.Assert(err, check.IsNil)
+	c.Assert(s.stdout.String(), check.Matches, `(?s).*\[WARN\] Versions: client 1.0.0, server 2.0.0, the major versions differ and some commands may not work
+.*`)
+}
@@ -0,0 +1,130 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/tsuru/tsuru/errors"
+)
+
+// Error formats accepted by the global --error-format flag.
+const (
+	TextErrorFormat = "text"
+	JSONErrorFormat = "json"
+)
+
+// requestIDHeader is the header used by the API to identify each request.
+const requestIDHeader = "X-Request-Id"
+
+// Exit codes of the commands, so scripts can tell the class of a failure
+// apart.
+const (
+	// ExitGeneric is the exit code of failures not covered by other codes.
+	ExitGeneric = 1
+	// ExitUsage is the exit code of unknown commands and invalid flags or
+	// arguments.
+	ExitUsage = 2
+	// ExitAuth is the exit code when the user is not authenticated or not
+	// allowed to perform the action.
+	ExitAuth = 3
+	// ExitNotFound is the exit code when the API doesn't find a resource.
+	ExitNotFound = 4
+	// ExitServer is the exit code of server failures, including when the
+	// server can't be reached.
+	ExitServer = 5
+)
+
+// connectionError is returned by the client when it can't connect to the
+// tsuru server.
+type connectionError struct {
+	message string
+}
+
+func (e *connectionError) Error() string {
+	return e.message
+}
+
+// StatusError is returned by commands that must exit with a specific status,
+// like the exit status of a command run remotely. The message is reported
+// like the message of any other error.
+type StatusError struct {
+	Status  int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// ExitCode returns the exit code of a command that failed with err, based on
+// the HTTP status of errors returned by the API.
+func ExitCode(err error) int {
+	switch e := err.(type) {
+	case *StatusError:
+		return e.Status
+	case *errors.HTTP:
+		switch {
+		case e.Code == http.StatusUnauthorized || e.Code == http.StatusForbidden:
+			return ExitAuth
+		case e.Code == http.StatusNotFound:
+			return ExitNotFound
+		case e.Code >= http.StatusInternalServerError:
+			return ExitServer
+		}
+	case *connectionError:
+		return ExitServer
+	}
+	return ExitGeneric
+}
+
+// structuredError is the error written to stderr when the user asks for
+// json errors. Code is the HTTP status code returned by the API, or zero
+// when the error didn't come from the API.
+type structuredError struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func validateErrorFormat(format string) error {
+	switch format {
+	case "", TextErrorFormat, JSONErrorFormat:
+		return nil
+	}
+	return fmt.Errorf("invalid error format %q, valid formats are: %s and %s.\n", format, TextErrorFormat, JSONErrorFormat)
+}
+
+// writeError writes the error message to w, as free text (prefixed with
+// "Error: " when prefix is true) or as a json object, according to the
+// format. The request ID, when known, is included in both formats so users
+// can quote it when reporting failures.
+func writeError(w io.Writer, format, message string, err error, requestID string, prefix bool) {
+	if format != JSONErrorFormat {
+		if prefix {
+			message = "Error: " + message
+		}
+		if !strings.HasSuffix(message, "\n") {
+			message += "\n"
+		}
+		if requestID != "" {
+			message += "Request ID: " + requestID + "\n"
+		}
+		io.WriteString(w, message)
+		return
+	}
+	e := structuredError{
+		Message:   strings.TrimSpace(message),
+		RequestID: requestID,
+	}
+	if httpErr, ok := err.(*errors.HTTP); ok {
+		e.Code = httpErr.Code
+	}
+	json.NewEncoder(w).Encode(e)
+}
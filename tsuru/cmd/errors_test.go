@@ -0,0 +1,332 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/tsuru/gnuflag"
+	tsuruerr "github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+type failingCommand struct {
+	err error
+}
+
+func (c *failingCommand) Info() *Info {
+	return &Info{Name: "fail", Usage: "fail", MaxArgs: 1}
+}
+
+func (c *failingCommand) Run(context *Context, client *Client) error {
+	return c.err
+}
+
+type flaggedFailingCommand struct {
+	failingCommand
+}
+
+func (c *flaggedFailingCommand) Flags() *gnuflag.FlagSet {
+	return gnuflag.NewFlagSet("fail", gnuflag.ContinueOnError)
+}
+
+type outputCommand struct {
+	err error
+}
+
+func (c *outputCommand) Info() *Info {
+	return &Info{Name: "output", Usage: "output"}
+}
+
+func (c *outputCommand) Run(context *Context, client *Client) error {
+	fmt.Fprintln(context.Stdout, "partial output")
+	return c.err
+}
+
+// requestCommand sends a request to the API. When handle is set, the result
+// of the request is passed to it and the command returns what it returns.
+type requestCommand struct {
+	handle func(error) error
+}
+
+func (c *requestCommand) Info() *Info {
+	return &Info{Name: "request", Usage: "request"}
+}
+
+func (c *requestCommand) Run(context *Context, client *Client) error {
+	u, err := GetURL("/apps")
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if c.handle != nil {
+		return c.handle(err)
+	}
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+func (s *S) TestExitCode(c *check.C) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{errors.New("something went wrong"), ExitGeneric},
+		{&tsuruerr.HTTP{Code: http.StatusBadRequest}, ExitGeneric},
+		{&tsuruerr.HTTP{Code: http.StatusConflict}, ExitGeneric},
+		{&tsuruerr.HTTP{Code: http.StatusUnauthorized}, ExitAuth},
+		{&tsuruerr.HTTP{Code: http.StatusForbidden}, ExitAuth},
+		{&tsuruerr.HTTP{Code: http.StatusNotFound}, ExitNotFound},
+		{&tsuruerr.HTTP{Code: http.StatusInternalServerError}, ExitServer},
+		{&tsuruerr.HTTP{Code: http.StatusServiceUnavailable}, ExitServer},
+		{&connectionError{message: "it's probably down"}, ExitServer},
+		{&StatusError{Status: 42, Message: "exit status 42"}, 42},
+	}
+	for _, t := range tests {
+		c.Check(ExitCode(t.err), check.Equals, t.code, check.Commentf("error: %#v", t.err))
+	}
+}
+
+func (s *S) TestRunExitsWithZeroOnSuccess(c *check.C) {
+	m := s.newManager()
+	m.Register(&failingCommand{})
+	m.Run([]string{"fail"})
+	c.Assert(s.exiter.value(), check.Equals, 0)
+}
+
+func (s *S) TestRunExitCodeOfCommandErrors(c *check.C) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{errors.New("something went wrong"), ExitGeneric},
+		{&tsuruerr.HTTP{Code: http.StatusForbidden, Message: "forbidden"}, ExitAuth},
+		{&tsuruerr.HTTP{Code: http.StatusNotFound, Message: "app not found"}, ExitNotFound},
+		{&tsuruerr.HTTP{Code: http.StatusInternalServerError, Message: "boom"}, ExitServer},
+	}
+	for _, t := range tests {
+		s.exiter = new(recordingExiter)
+		m := s.newManager()
+		m.Register(&failingCommand{err: t.err})
+		m.Run([]string{"fail"})
+		c.Check(s.exiter.value(), check.Equals, t.code, check.Commentf("error: %#v", t.err))
+	}
+}
+
+func (s *S) TestRunExitCodeStatusError(c *check.C) {
+	m := s.newManager()
+	m.Register(&failingCommand{err: &StatusError{Status: 3, Message: "exit status 3"}})
+	m.Run([]string{"fail"})
+	c.Assert(s.exiter.value(), check.Equals, 3)
+	c.Assert(s.stderr.String(), check.Equals, "Error: exit status 3\n")
+}
+
+func (s *S) TestRunFlushesTheOutputBeforeExiting(c *check.C) {
+	m := s.newManager()
+	m.Register(&outputCommand{err: &StatusError{Status: 3, Message: "exit status 3"}})
+	m.Run([]string{"output"})
+	c.Assert(s.exiter.value(), check.Equals, 3)
+	c.Assert(s.stdout.String(), check.Equals, "partial output\n")
+}
+
+func (s *S) TestRunExitCodeConnectionError(c *check.C) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	m := s.newManager()
+	m.Register(&requestCommand{})
+	m.Run([]string{"request"})
+	c.Assert(s.exiter.value(), check.Equals, ExitServer)
+	c.Assert(s.stderr.String(), check.Matches, `(?s)Error: Failed to connect to tsuru server .*`)
+}
+
+func (s *S) TestRunExitCodeUnknownCommand(c *check.C) {
+	m := s.newManager()
+	m.Run([]string{"unknown-command"})
+	c.Assert(s.exiter.value(), check.Equals, ExitUsage)
+}
+
+func (s *S) TestRunExitCodeInvalidGlobalFlag(c *check.C) {
+	m := s.newManager()
+	m.Run([]string{"--no-such-flag", "help"})
+	c.Assert(s.exiter.value(), check.Equals, ExitUsage)
+}
+
+func (s *S) TestRunExitCodeInvalidCommandFlag(c *check.C) {
+	m := s.newManager()
+	m.Register(&flaggedFailingCommand{})
+	m.Run([]string{"fail", "--no-such-flag"})
+	c.Assert(s.exiter.value(), check.Equals, ExitUsage)
+}
+
+func (s *S) TestRunExitCodeWrongNumberOfArguments(c *check.C) {
+	m := s.newManager()
+	m.Register(&failingCommand{})
+	m.Run([]string{"fail", "one", "two"})
+	c.Assert(s.exiter.value(), check.Equals, ExitUsage)
+	c.Assert(s.stdout.String(), check.Matches, `(?s).*ERROR: wrong number of arguments.*`)
+}
+
+func (s *S) TestRunExitCodeRemovedCommand(c *check.C) {
+	m := s.newManager()
+	m.RegisterRemoved("old-command", "You should use `glb new-command` instead.")
+	m.Run([]string{"old-command"})
+	c.Assert(s.exiter.value(), check.Equals, ExitUsage)
+	c.Assert(s.stdout.String(), check.Matches, "(?s).*This command was removed.*")
+}
+
+func (s *S) TestRunErrorFormatJSON(c *check.C) {
+	m := s.newManager()
+	m.Register(&failingCommand{err: &tsuruerr.HTTP{Code: http.StatusNotFound, Message: "app not found"}})
+	m.Run([]string{"--error-format", "json", "fail"})
+	c.Assert(s.exiter.value(), check.Equals, ExitNotFound)
+	c.Assert(s.stderr.String(), check.Equals, `{"code":404,"message":"app not found"}`+"\n")
+}
+
+func (s *S) TestRunErrorFormatJSONWithoutHTTPError(c *check.C) {
+	m := s.newManager()
+	m.Register(&failingCommand{err: errors.New("something went wrong")})
+	m.Run([]string{"--error-format", "json", "fail"})
+	c.Assert(s.exiter.value(), check.Equals, ExitGeneric)
+	c.Assert(s.stderr.String(), check.Equals, `{"code":0,"message":"something went wrong"}`+"\n")
+}
+
+func (s *S) TestRunErrorFormatJSONUnknownCommand(c *check.C) {
+	m := s.newManager()
+	m.Run([]string{"--error-format", "json", "unknown-command"})
+	c.Assert(s.exiter.value(), check.Equals, ExitUsage)
+	var e structuredError
+	err := json.Unmarshal(s.stderr.Bytes(), &e)
+	c.Assert(err, check.IsNil)
+	c.Assert(e.Code, check.Equals, 0)
+	c.Assert(e.Message, check.Matches, `glb: "unknown-command" is not a glb command\..*`)
+}
+
+func (s *S) TestRunErrorFormatText(c *check.C) {
+	m := s.newManager()
+	m.Register(&failingCommand{err: &tsuruerr.HTTP{Code: http.StatusNotFound, Message: "app not found"}})
+	m.Run([]string{"--error-format", "text", "fail"})
+	c.Assert(s.exiter.value(), check.Equals, ExitNotFound)
+	c.Assert(s.stderr.String(), check.Equals, "Error: app not found\n")
+}
+
+func (s *S) TestRunInvalidErrorFormat(c *check.C) {
+	m := s.newManager()
+	m.Register(&failingCommand{})
+	m.Run([]string{"--error-format", "xml", "fail"})
+	c.Assert(s.exiter.value(), check.Equals, ExitUsage)
+	c.Assert(s.stderr.String(), check.Equals, "invalid error format \"xml\", valid formats are: text and json.\n")
+}
+
+func (s *S) TestWriteError(c *check.C) {
+	var buf bytes.Buffer
+	writeError(&buf, TextErrorFormat, "app not found", nil, "", true)
+	c.Assert(buf.String(), check.Equals, "Error: app not found\n")
+	buf.Reset()
+	writeError(&buf, "", "app not found\n", nil, "abc123", false)
+	c.Assert(buf.String(), check.Equals, "app not found\nRequest ID: abc123\n")
+	buf.Reset()
+	writeError(&buf, JSONErrorFormat, "app not found\n", &tsuruerr.HTTP{Code: http.StatusNotFound}, "abc123", true)
+	c.Assert(buf.String(), check.Equals, `{"code":404,"message":"app not found","request_id":"abc123"}`+"\n")
+}
+
+func requestIDServer(status int, message string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.WriteHeader(status)
+		w.Write([]byte(message))
+	}))
+}
+
+func (s *S) TestRunReportsTheRequestIDOfFailures(c *check.C) {
+	server := requestIDServer(http.StatusBadRequest, "invalid app")
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	m := s.newManager()
+	m.Register(&requestCommand{})
+	m.Run([]string{"request"})
+	c.Assert(s.exiter.value(), check.Equals, ExitGeneric)
+	c.Assert(s.stderr.String(), check.Equals, "Error: invalid app\nRequest ID: req-123\n")
+}
+
+func (s *S) TestRunErrorFormatJSONIncludesTheRequestID(c *check.C) {
+	server := requestIDServer(http.StatusNotFound, "app not found")
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	m := s.newManager()
+	m.Register(&requestCommand{})
+	m.Run([]string{"--error-format", "json", "request"})
+	c.Assert(s.exiter.value(), check.Equals, ExitNotFound)
+	c.Assert(s.stderr.String(), check.Equals, `{"code":404,"message":"app not found","request_id":"req-123"}`+"\n")
+}
+
+func (s *S) TestRunReportsTheRequestIDOfWrappedFailures(c *check.C) {
+	server := requestIDServer(http.StatusBadRequest, "invalid app")
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	m := s.newManager()
+	m.Register(&requestCommand{handle: func(err error) error {
+		return fmt.Errorf("Failed to create the app: %s", err)
+	}})
+	m.Run([]string{"request"})
+	c.Assert(s.stderr.String(), check.Equals, "Error: Failed to create the app: invalid app\nRequest ID: req-123\n")
+}
+
+func (s *S) TestRunDoesNotReportTheRequestIDOfUnrelatedErrors(c *check.C) {
+	server := requestIDServer(http.StatusNotFound, "app not found")
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	m := s.newManager()
+	m.Register(&requestCommand{handle: func(error) error {
+		return errors.New("the plan is invalid")
+	}})
+	m.Run([]string{"request"})
+	c.Assert(s.exiter.value(), check.Equals, ExitGeneric)
+	c.Assert(s.stderr.String(), check.Equals, "Error: the plan is invalid\n")
+}
+
+func (s *S) TestRunDoesNotReportTheRequestIDOfSuccessfulResponses(c *check.C) {
+	server := requestIDServer(http.StatusOK, "ok")
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	m := s.newManager()
+	m.Register(&requestCommand{handle: func(err error) error {
+		c.Check(err, check.IsNil)
+		return errors.New("the app is locked")
+	}})
+	m.Run([]string{"request"})
+	c.Assert(s.stderr.String(), check.Equals, "Error: the app is locked\n")
+}
+
+func (s *S) TestClientVerboseShowsTheRequestID(c *check.C) {
+	server := requestIDServer(http.StatusOK, "ok")
+	defer server.Close()
+	var stderr bytes.Buffer
+	context := &Context{Stdout: &bytes.Buffer{}, Stderr: &stderr}
+	client := NewClient(http.DefaultClient, context, s.newManager())
+	client.Verbosity = 1
+	request, err := http.NewRequest("GET", server.URL+"/1.0/apps", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer abc123")
+	response, err := client.Do(request)
+	c.Assert(err, check.IsNil)
+	response.Body.Close()
+	c.Assert(stderr.String(), check.Matches, `(?s).*Authorization: <redacted>.*Request ID: req-123\n.*`)
+	c.Assert(strings.Contains(stderr.String(), "abc123"), check.Equals, false)
+}
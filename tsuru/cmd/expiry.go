@@ -0,0 +1,88 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultExpiryWarning is how long before the expiration of the token the
+// client starts warning the user. It may be changed with the
+// token-expiry-warning key in the configuration file.
+const defaultExpiryWarning = time.Hour
+
+var (
+	now            = time.Now
+	expiryWarned   bool
+	expiryWarnings = defaultExpiryWarning
+)
+
+func tokenExpiryPath() string {
+	if p := customTokenPath(); p != "" {
+		return p + ".expiry"
+	}
+	return JoinWithUserDir(".tsuru", "token-expiry")
+}
+
+// writeTokenExpiry stores the expiration time of the token, as informed by
+// the server in seconds, so later commands can check it without making any
+// request. A non positive value removes the stored expiration.
+func writeTokenExpiry(seconds int) error {
+	if seconds <= 0 {
+		err := filesystem().Remove(tokenExpiryPath())
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	file, err := filesystem().Create(tokenExpiryPath())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(now().Add(time.Duration(seconds) * time.Second).Format(time.RFC3339))
+	return err
+}
+
+func readTokenExpiry() (time.Time, bool) {
+	file, err := filesystem().Open(tokenExpiryPath())
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return time.Time{}, false
+	}
+	expiry, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expiry, true
+}
+
+// warnTokenExpiry prints a warning once per execution when the stored token
+// expires within the warning window. Tokens given in the TSURU_TOKEN
+// environment variable are not checked.
+func warnTokenExpiry(w io.Writer, progname string) {
+	if expiryWarned || tokenFromEnv() {
+		return
+	}
+	expiryWarned = true
+	expiry, ok := readTokenExpiry()
+	if !ok {
+		return
+	}
+	left := expiry.Sub(now())
+	if left <= 0 || left > expiryWarnings {
+		return
+	}
+	fmt.Fprintf(w, "Warning: your session expires in %s. Please run %q to renew it.\n", left/time.Second*time.Second, progname+" login")
+}
@@ -0,0 +1,70 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) setNow(t time.Time) {
+	now = func() time.Time { return t }
+}
+
+func (s *S) TestWarnTokenExpiry(c *check.C) {
+	defer func() { now = time.Now }()
+	start := time.Date(2016, 10, 15, 12, 0, 0, 0, time.UTC)
+	s.setNow(start)
+	c.Assert(writeTokenExpiry(1800), check.IsNil)
+	s.setNow(start.Add(90*time.Second + 400*time.Millisecond))
+	var buf bytes.Buffer
+	warnTokenExpiry(&buf, "glb")
+	c.Assert(buf.String(), check.Equals, "Warning: your session expires in 28m29s. Please run \"glb login\" to renew it.\n")
+	buf.Reset()
+	warnTokenExpiry(&buf, "glb")
+	c.Assert(buf.String(), check.Equals, "")
+}
+
+func (s *S) TestWarnTokenExpiryOutsideTheWindow(c *check.C) {
+	defer func() { now = time.Now }()
+	s.setNow(time.Date(2016, 10, 15, 12, 0, 0, 0, time.UTC))
+	c.Assert(writeTokenExpiry(7200), check.IsNil)
+	var buf bytes.Buffer
+	warnTokenExpiry(&buf, "glb")
+	c.Assert(buf.String(), check.Equals, "")
+}
+
+func (s *S) TestWarnTokenExpiryExpired(c *check.C) {
+	defer func() { now = time.Now }()
+	start := time.Date(2016, 10, 15, 12, 0, 0, 0, time.UTC)
+	s.setNow(start)
+	c.Assert(writeTokenExpiry(60), check.IsNil)
+	s.setNow(start.Add(time.Hour))
+	var buf bytes.Buffer
+	warnTokenExpiry(&buf, "glb")
+	c.Assert(buf.String(), check.Equals, "")
+}
+
+func (s *S) TestWarnTokenExpiryTokenFromTheEnvironment(c *check.C) {
+	defer func() { now = time.Now }()
+	s.setNow(time.Date(2016, 10, 15, 12, 0, 0, 0, time.UTC))
+	c.Assert(writeTokenExpiry(60), check.IsNil)
+	os.Setenv("TSURU_TOKEN", "abc123")
+	var buf bytes.Buffer
+	warnTokenExpiry(&buf, "glb")
+	c.Assert(buf.String(), check.Equals, "")
+}
+
+func (s *S) TestWriteTokenExpiryRemovesTheExpiry(c *check.C) {
+	c.Assert(writeTokenExpiry(60), check.IsNil)
+	_, ok := readTokenExpiry()
+	c.Assert(ok, check.Equals, true)
+	c.Assert(writeTokenExpiry(0), check.IsNil)
+	_, ok = readTokenExpiry()
+	c.Assert(ok, check.Equals, false)
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tsuru/gnuflag"
+)
+
+type MapFlag map[string]string
+
+func (f *MapFlag) String() string {
+	repr := *f
+	if repr == nil {
+		repr = MapFlag{}
+	}
+	data, _ := json.Marshal(repr)
+	return string(data)
+}
+
+func (f *MapFlag) Set(val string) error {
+	parts := strings.SplitN(val, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid value %q, expected key=value", val)
+	}
+	if *f == nil {
+		*f = map[string]string{}
+	}
+	(*f)[parts[0]] = parts[1]
+	return nil
+}
+
+type MapFlagWrapper struct {
+	Dst *map[string]string
+}
+
+func (f MapFlagWrapper) String() string {
+	m := MapFlag(*f.Dst)
+	return m.String()
+}
+
+func (f MapFlagWrapper) Set(val string) error {
+	parts := strings.SplitN(val, "=", 2)
+	if *f.Dst == nil {
+		*f.Dst = map[string]string{}
+	}
+	(*f.Dst)[parts[0]] = parts[1]
+	return nil
+}
+
+type StringSliceFlagWrapper struct {
+	Dst *[]string
+}
+
+func (f StringSliceFlagWrapper) String() string {
+	s := StringSliceFlag(*f.Dst)
+	return s.String()
+}
+
+func (f StringSliceFlagWrapper) Set(val string) error {
+	*f.Dst = append(*f.Dst, val)
+	return nil
+}
+
+type StringSliceFlag []string
+
+func (f *StringSliceFlag) String() string {
+	repr := *f
+	if repr == nil {
+		repr = StringSliceFlag{}
+	}
+	data, _ := json.Marshal(repr)
+	return string(data)
+}
+
+func (f *StringSliceFlag) Set(val string) error {
+	*f = append(*f, val)
+	return nil
+}
+
+// CountFlag is an integer flag that may be used without a value, in which
+// case each occurrence increments it (e.g. -vv is 2). An explicit value sets
+// it directly, in the --flag=N form, or in the --flag N form when the
+// arguments go through expandCountFlags.
+type CountFlag int
+
+func (f *CountFlag) String() string {
+	return strconv.Itoa(int(*f))
+}
+
+func (f *CountFlag) Set(val string) error {
+	if val == "true" {
+		*f++
+		return nil
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return err
+	}
+	*f = CountFlag(n)
+	return nil
+}
+
+func (f *CountFlag) IsBoolFlag() bool {
+	return true
+}
+
+// expandCountFlags rewrites the "--flag N" and "-f N" forms of the count flags
+// in args to "--flag=N", as count flags used without an inline value are
+// otherwise counted and N taken as an argument. Only the flags before the
+// first argument are rewritten.
+func expandCountFlags(flagset *gnuflag.FlagSet, args []string) []string {
+	expanded := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		expanded = append(expanded, arg)
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			expanded = append(expanded, args[i+1:]...)
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") || (!strings.HasPrefix(arg, "--") && len(name) > 1) {
+			continue
+		}
+		f := flagset.Lookup(name)
+		if f == nil || i+1 == len(args) {
+			continue
+		}
+		if _, ok := f.Value.(*CountFlag); ok {
+			if _, err := strconv.Atoi(args[i+1]); err == nil {
+				expanded[len(expanded)-1] = "--" + name + "=" + args[i+1]
+				i++
+			}
+			continue
+		}
+		if b, ok := f.Value.(interface {
+			IsBoolFlag() bool
+		}); ok && b.IsBoolFlag() {
+			continue
+		}
+		expanded = append(expanded, args[i+1])
+		i++
+	}
+	return expanded
+}
@@ -0,0 +1,332 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru/git"
+)
+
+// AppGuesser is used to guess the name of an app based in a file path.
+type AppGuesser interface {
+	GuessName(path string) (string, error)
+}
+
+// GitGuesser uses git to guess the name of the app.
+//
+// It reads the "tsuru" remote from git config file. Repositories deploying to
+// multiple targets may declare remotes scoped by the label of the target, like
+// "tsuru-staging" and "tsuru-prod", in which case the remote matching the
+// current target is used. If no remote matches the current target, or the
+// remote does not match the tsuru pattern (<user>@<somehost>:<app-name>.git),
+// GuessName will return an error.
+type GitGuesser struct{}
+
+func (g GitGuesser) GuessName(path string) (string, error) {
+	repoPath, err := git.DiscoverRepositoryPath(path)
+	if err != nil {
+		return "", fmt.Errorf("Git repository not found: %s.", err)
+	}
+	if _, err = git.OpenRepository(repoPath); err != nil {
+		return "", err
+	}
+	remotes, err := gitRemotes(repoPath)
+	if err != nil {
+		return "", err
+	}
+	remoteName, err := tsuruRemote(remotes)
+	if err != nil {
+		return "", err
+	}
+	remoteURL := remotes[remoteName]
+	re := regexp.MustCompile(`^.*@.*:(.*)\.git$`)
+	matches := re.FindStringSubmatch(remoteURL)
+	if len(matches) < 2 {
+		return "", fmt.Errorf(`%q remote did not match the pattern. Want something like <user>@<host>:<app-name>.git, got %s`, remoteName, remoteURL)
+	}
+	return matches[1], nil
+}
+
+// gitRemotes returns the URLs of all the remotes declared in the git
+// repository at repoPath, indexed by name.
+func gitRemotes(repoPath string) (map[string]string, error) {
+	config, err := os.Open(filepath.Join(repoPath, "config"))
+	if err != nil {
+		return nil, err
+	}
+	defer config.Close()
+	remotes := map[string]string{}
+	var current string
+	scanner := bufio.NewScanner(config)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			current = ""
+			if strings.HasPrefix(line, `[remote "`) && strings.HasSuffix(line, `"]`) {
+				current = line[len(`[remote "`) : len(line)-2]
+			}
+			continue
+		}
+		if current == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "url" {
+			remotes[current] = strings.TrimSpace(parts[1])
+		}
+	}
+	return remotes, scanner.Err()
+}
+
+// tsuruRemote chooses the remote to guess the app name from: the one scoped
+// to the current target (tsuru-<label>), or the "tsuru" remote when there are
+// no scoped remotes. When scoped remotes exist but the current target doesn't
+// select exactly one of them, the candidates are listed in the error.
+func tsuruRemote(remotes map[string]string) (string, error) {
+	var candidates []string
+	for name := range remotes {
+		if name == "tsuru" || strings.HasPrefix(name, "tsuru-") {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	switch {
+	case len(candidates) == 0:
+		return "", errors.New("tsuru remote not declared.")
+	case len(candidates) == 1 && candidates[0] == "tsuru":
+		return "tsuru", nil
+	}
+	var matching []string
+	for _, label := range currentTargetLabels() {
+		if _, ok := remotes["tsuru-"+label]; ok {
+			matching = append(matching, "tsuru-"+label)
+		}
+	}
+	switch len(matching) {
+	case 1:
+		return matching[0], nil
+	case 0:
+		return "", fmt.Errorf("none of the tsuru remotes matches the current target. Candidates: %s.", strings.Join(candidates, ", "))
+	}
+	return "", fmt.Errorf("more than one tsuru remote matches the current target. Candidates: %s.", strings.Join(matching, ", "))
+}
+
+// FileGuesser reads the name of the app from a project file.
+//
+// It looks for a ".tsuru" file in the given path and in its parent
+// directories, using the nearest one. The file must have a line in the format
+// "app: <app-name>". Lines starting with # are ignored.
+type FileGuesser struct{}
+
+func (g FileGuesser) GuessName(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		filename := filepath.Join(path, ".tsuru")
+		if info, err := os.Stat(filename); err == nil && !info.IsDir() {
+			return appFromFile(filename)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", errors.New(".tsuru file not found.")
+		}
+		path = parent
+	}
+}
+
+func appFromFile(filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "app" {
+			if name := strings.TrimSpace(parts[1]); name != "" {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf(`%s does not declare the app. Want a line like "app: <app-name>".`, filename)
+}
+
+// MultiGuesser can use multiple guessers
+type MultiGuesser struct {
+	Guessers []AppGuesser
+}
+
+func (g MultiGuesser) GuessName(pathname string) (string, error) {
+	cumulativeErr := errors.New("")
+
+	for _, guesser := range g.Guessers {
+		app, err := guesser.GuessName(pathname)
+		if err == nil {
+			return app, nil
+		}
+		cumulativeErr = fmt.Errorf("%s%s\n", cumulativeErr, err)
+	}
+
+	return "", cumulativeErr
+}
+
+// Embed this struct if you want your command to guess the name of the app.
+type GuessingCommand struct {
+	G       AppGuesser
+	fs      *gnuflag.FlagSet
+	appName appNames
+	appDir  string
+}
+
+func (cmd *GuessingCommand) guesser() AppGuesser {
+	if cmd.G == nil {
+		cmd.G = MultiGuesser{Guessers: []AppGuesser{FileGuesser{}, GitGuesser{}}}
+	}
+	return cmd.G
+}
+
+// confirmGuessedApp is set by the manager when the --confirm-app flag is
+// used.
+var confirmGuessedApp bool
+
+// guessOutput is where the name of guessed apps is written when guessed apps
+// must be confirmed. The manager sets it to its stderr.
+var guessOutput io.Writer = os.Stderr
+
+// ErrGuessNotConfirmed is returned by GuessDestructive when the user doesn't
+// confirm the guessed app.
+var ErrGuessNotConfirmed = errors.New("Aborted: the name of the app was guessed and not confirmed. Use the --app flag to specify it.")
+
+// guessedAppsConfirmed reports whether guessed apps are shown and must be
+// confirmed, with the --confirm-app flag or the TSURU_CONFIRM_APP
+// environment variable.
+func guessedAppsConfirmed() bool {
+	return confirmGuessedApp || os.Getenv("TSURU_CONFIRM_APP") != ""
+}
+
+// Guess returns the name of the app given in the --app flag, or guesses it
+// from the directory. When guessed apps must be confirmed, the name of a
+// guessed app is written to stderr.
+func (cmd *GuessingCommand) Guess() (string, error) {
+	name, guessed, err := cmd.guess()
+	if err == nil && guessed && guessedAppsConfirmed() {
+		fmt.Fprintf(guessOutput, "using guessed app %s\n", name)
+	}
+	return name, err
+}
+
+// GuessDestructive works like Guess, for commands destroying resources of
+// the app. When the name of the app was guessed and guessed apps must be
+// confirmed, the user is asked to confirm it, even if the command was asked
+// not to ask for confirmation, and ErrGuessNotConfirmed is returned when the
+// answer is not "y".
+func (cmd *GuessingCommand) GuessDestructive(context *Context) (string, error) {
+	name, guessed, err := cmd.guess()
+	if err != nil || !guessed || !guessedAppsConfirmed() {
+		return name, err
+	}
+	fmt.Fprintf(context.Stderr, "using guessed app %s\n", name)
+	fmt.Fprintf(context.Stdout, "Are you sure you want to run this command on app %q? (y/n) ", name)
+	var answer string
+	fmt.Fscanf(context.Stdin, "%s", &answer)
+	if answer != "y" {
+		return "", ErrGuessNotConfirmed
+	}
+	return name, nil
+}
+
+// GuessApps returns the names of the apps given in the --app flag, which may
+// be repeated, or the guessed app when the flag is not given.
+func (cmd *GuessingCommand) GuessApps() ([]string, error) {
+	if len(cmd.appName) > 1 {
+		return append([]string(nil), cmd.appName...), nil
+	}
+	name, err := cmd.Guess()
+	if err != nil {
+		return nil, err
+	}
+	return []string{name}, nil
+}
+
+// errMultipleApps is returned by the commands handling a single app when the
+// --app flag is given more than once.
+var errMultipleApps = errors.New("This command accepts a single app, but the --app flag was given more than once.")
+
+// AppFlag returns the name of the app given in the --app flag, without
+// guessing it. It fails when the flag is given more than once.
+func (cmd *GuessingCommand) AppFlag() (string, error) {
+	if len(cmd.appName) > 1 {
+		return "", errMultipleApps
+	}
+	return cmd.appName.String(), nil
+}
+
+// appNames holds the values of the --app flag, which may only be repeated in
+// commands handling many apps, through GuessApps.
+type appNames []string
+
+func (a *appNames) String() string {
+	if len(*a) == 0 {
+		return ""
+	}
+	return (*a)[len(*a)-1]
+}
+
+func (a *appNames) Set(value string) error {
+	*a = append(*a, value)
+	return nil
+}
+
+func (cmd *GuessingCommand) guess() (string, bool, error) {
+	if len(cmd.appName) > 1 {
+		return "", false, errMultipleApps
+	}
+	if len(cmd.appName) > 0 {
+		return cmd.appName.String(), false, nil
+	}
+	path := cmd.appDir
+	if path == "" {
+		var err error
+		path, err = os.Getwd()
+		if err != nil {
+			return "", false, fmt.Errorf("Unable to guess app name: %s.", err)
+		}
+	}
+	name, err := cmd.guesser().GuessName(path)
+	if err != nil {
+		return "", false, fmt.Errorf(`tsuru wasn't able to guess the name of the app.
+
+Use the --app flag to specify it.
+
+%s`, err)
+	}
+	return name, true, nil
+}
+
+func (cmd *GuessingCommand) Flags() *gnuflag.FlagSet {
+	if cmd.fs == nil {
+		cmd.fs = gnuflag.NewFlagSet("", gnuflag.ExitOnError)
+		cmd.fs.Var(&cmd.appName, "app", "The name of the app.")
+		cmd.fs.Var(&cmd.appName, "a", "The name of the app.")
+		cmd.fs.StringVar(&cmd.appDir, "app-from-dir", "", "Guess the name of the app from the given directory, instead of the current one.")
+	}
+	return cmd.fs
+}
@@ -0,0 +1,91 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"os"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) writeTargets(c *check.C, content string) {
+	f, err := filesystem().Create(JoinWithUserDir(".tsuru", "targets"))
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	_, err = f.Write([]byte(content))
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestTsuruRemote(c *check.C) {
+	name, err := tsuruRemote(map[string]string{"origin": "git@github.com:me/app.git", "tsuru": "git@tsuru.io:app.git"})
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "tsuru")
+}
+
+func (s *S) TestTsuruRemoteNotDeclared(c *check.C) {
+	_, err := tsuruRemote(map[string]string{"origin": "git@github.com:me/app.git"})
+	c.Assert(err, check.ErrorMatches, "tsuru remote not declared.")
+}
+
+func (s *S) TestTsuruRemoteScopedToTheCurrentTarget(c *check.C) {
+	s.writeTargets(c, "staging\thttp://staging.tsuru.io\nprod\thttp://tsuru.io\n")
+	os.Setenv("TSURU_TARGET", "http://tsuru.io")
+	remotes := map[string]string{
+		"tsuru":         "git@tsuru.io:app.git",
+		"tsuru-staging": "git@staging.tsuru.io:app.git",
+		"tsuru-prod":    "git@tsuru.io:app.git",
+	}
+	name, err := tsuruRemote(remotes)
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "tsuru-prod")
+}
+
+func (s *S) TestTsuruRemoteScopedWithoutMatch(c *check.C) {
+	s.writeTargets(c, "staging\thttp://staging.tsuru.io\ndev\thttp://dev.tsuru.io\n")
+	os.Setenv("TSURU_TARGET", "http://dev.tsuru.io")
+	remotes := map[string]string{
+		"tsuru":         "git@tsuru.io:app.git",
+		"tsuru-staging": "git@staging.tsuru.io:app.git",
+		"tsuru-prod":    "git@tsuru.io:app.git",
+	}
+	_, err := tsuruRemote(remotes)
+	c.Assert(err, check.ErrorMatches, "none of the tsuru remotes matches the current target. Candidates: tsuru, tsuru-prod, tsuru-staging.")
+}
+
+func (s *S) TestTsuruRemoteScopedWithManyMatches(c *check.C) {
+	s.writeTargets(c, "prod\thttp://tsuru.io\nproduction\thttp://tsuru.io\n")
+	os.Setenv("TSURU_TARGET", "http://tsuru.io")
+	remotes := map[string]string{
+		"tsuru-prod":       "git@tsuru.io:app.git",
+		"tsuru-production": "git@tsuru.io:other.git",
+	}
+	_, err := tsuruRemote(remotes)
+	c.Assert(err, check.ErrorMatches, "more than one tsuru remote matches the current target. Candidates: tsuru-prod, tsuru-production.")
+}
+
+func (s *S) TestGuessWithManyApps(c *check.C) {
+	command := GuessingCommand{}
+	err := command.Flags().Parse(true, []string{"-a", "staging", "--app", "prod"})
+	c.Assert(err, check.IsNil)
+	_, err = command.Guess()
+	c.Assert(err, check.Equals, errMultipleApps)
+	_, err = command.AppFlag()
+	c.Assert(err, check.Equals, errMultipleApps)
+	names, err := command.GuessApps()
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"staging", "prod"})
+}
+
+func (s *S) TestAppFlag(c *check.C) {
+	command := GuessingCommand{}
+	name, err := command.AppFlag()
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "")
+	err = command.Flags().Parse(true, []string{"-a", "prod"})
+	c.Assert(err, check.IsNil)
+	name, err = command.AppFlag()
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "prod")
+}
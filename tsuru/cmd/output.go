@@ -0,0 +1,54 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ghodss/yaml"
+)
+
+// Output formats accepted by the global --output flag.
+const (
+	TableOutput = "table"
+	JSONOutput  = "json"
+	YAMLOutput  = "yaml"
+)
+
+func validateOutputFormat(format string) error {
+	switch format {
+	case "", TableOutput, JSONOutput, YAMLOutput:
+		return nil
+	}
+	return fmt.Errorf("invalid output format %q, valid formats are: %s, %s and %s.\n", format, TableOutput, JSONOutput, YAMLOutput)
+}
+
+// StructuredOutput returns whether the user asked for a machine readable
+// output format (json or yaml) using the global --output flag.
+func (c *Context) StructuredOutput() bool {
+	return c.OutputFormat == JSONOutput || c.OutputFormat == YAMLOutput
+}
+
+// WriteStructured writes data to the context's stdout using the format
+// selected with the global --output flag. Commands should only call it when
+// StructuredOutput returns true.
+func (c *Context) WriteStructured(data interface{}) error {
+	var (
+		out []byte
+		err error
+	)
+	if c.OutputFormat == YAMLOutput {
+		out, err = yaml.Marshal(data)
+	} else {
+		out, err = json.MarshalIndent(data, "", "  ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	_, err = c.Stdout.Write(out)
+	return err
+}
@@ -0,0 +1,144 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// requestTiming holds the instants of the phases of an HTTP request made by
+// the client, collected when the --profile flag is used.
+type requestTiming struct {
+	mu           sync.Mutex
+	method       string
+	url          string
+	status       int
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	done         time.Time
+}
+
+func newRequestTiming(request *http.Request) *requestTiming {
+	return &requestTiming{
+		method: request.Method,
+		url:    request.URL.RequestURI(),
+		start:  time.Now(),
+	}
+}
+
+// trace returns the hooks recording the phases of the request. They may be
+// called from other goroutines, like the ones dialing the server.
+func (t *requestTiming) trace() *httptrace.ClientTrace {
+	record := func(instant *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if instant.IsZero() {
+			*instant = time.Now()
+		}
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { record(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { record(&t.dnsDone) },
+		ConnectStart:         func(string, string) { record(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { record(&t.connectDone) },
+		TLSHandshakeStart:    func() { record(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { record(&t.tlsDone) },
+		GotFirstResponseByte: func() { record(&t.firstByte) },
+	}
+}
+
+// finish records the end of the request. When the request succeeds, the
+// body of the response is wrapped so the end is recorded again when it's
+// closed, including the time spent reading it.
+func (t *requestTiming) finish(response *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = time.Now()
+	if response != nil {
+		t.status = response.StatusCode
+		response.Body = &timedBody{ReadCloser: response.Body, timing: t}
+	}
+}
+
+type timedBody struct {
+	io.ReadCloser
+	timing *requestTiming
+	once   sync.Once
+}
+
+func (b *timedBody) Close() error {
+	b.once.Do(func() {
+		b.timing.mu.Lock()
+		defer b.timing.mu.Unlock()
+		b.timing.done = time.Now()
+	})
+	return b.ReadCloser.Close()
+}
+
+func (t *requestTiming) row() Row {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	phase := func(start, end time.Time) string {
+		if start.IsZero() || end.IsZero() {
+			return "-"
+		}
+		return formatDuration(end.Sub(start))
+	}
+	status := "-"
+	if t.status != 0 {
+		status = strconv.Itoa(t.status)
+	}
+	return Row{
+		t.method,
+		t.url,
+		status,
+		phase(t.dnsStart, t.dnsDone),
+		phase(t.connectStart, t.connectDone),
+		phase(t.tlsStart, t.tlsDone),
+		phase(t.start, t.firstByte),
+		phase(t.start, t.done),
+	}
+}
+
+// formatDuration rounds d to milliseconds. Durations are never negative
+// here, so adding half a millisecond before truncating is enough.
+func formatDuration(d time.Duration) string {
+	return ((d + time.Millisecond/2) / time.Millisecond * time.Millisecond).String()
+}
+
+// writeProfile writes the timing of each request made by the client and the
+// total time of the command, started at start.
+func (c *Client) writeProfile(w io.Writer, start time.Time) {
+	total := time.Since(start)
+	if len(c.timings) == 0 {
+		fmt.Fprintf(w, "Profile: no HTTP requests, total time %s.\n", formatDuration(total))
+		return
+	}
+	var inRequests time.Duration
+	table := NewTable()
+	table.Headers = Row{"Method", "URL", "Status", "DNS", "Connect", "TLS", "First byte", "Total"}
+	for _, t := range c.timings {
+		table.AddRow(t.row())
+		t.mu.Lock()
+		inRequests += t.done.Sub(t.start)
+		t.mu.Unlock()
+	}
+	fmt.Fprintf(w, "Profile of %d HTTP requests:\n", len(c.timings))
+	w.Write(table.Bytes())
+	fmt.Fprintf(w, "Total time: %s, %s in HTTP requests.\n", formatDuration(total), formatDuration(inRequests))
+}
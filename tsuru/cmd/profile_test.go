@@ -0,0 +1,60 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestFormatDuration(c *check.C) {
+	c.Assert(formatDuration(0), check.Equals, "0s")
+	c.Assert(formatDuration(1499*time.Microsecond), check.Equals, "1ms")
+	c.Assert(formatDuration(1500*time.Microsecond), check.Equals, "2ms")
+	c.Assert(formatDuration(2*time.Second+300*time.Microsecond), check.Equals, "2s")
+}
+
+func (s *S) TestWriteProfileWithoutRequests(c *check.C) {
+	var buf bytes.Buffer
+	client := NewClient(http.DefaultClient, nil, s.newManager())
+	client.writeProfile(&buf, time.Now())
+	c.Assert(buf.String(), check.Matches, `Profile: no HTTP requests, total time \d+(\.\d+)?m?s\.\n`)
+}
+
+func (s *S) TestClientProfilesRequests(c *check.C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	request, err := http.NewRequest("GET", server.URL+"/1.0/apps?name=myapp", nil)
+	c.Assert(err, check.IsNil)
+	client := NewClient(http.DefaultClient, nil, s.newManager())
+	client.profile = true
+	response, err := client.Do(request)
+	c.Assert(err, check.IsNil)
+	response.Body.Close()
+	c.Assert(client.timings, check.HasLen, 1)
+	var buf bytes.Buffer
+	client.writeProfile(&buf, time.Now())
+	lines := strings.Split(buf.String(), "\n")
+	c.Assert(lines[0], check.Equals, "Profile of 1 HTTP requests:")
+	c.Assert(buf.String(), check.Matches, `(?s).*\| GET +\| /1\.0/apps\?name=myapp +\| 200 +\|.*`)
+	c.Assert(lines[len(lines)-2], check.Matches, `Total time: .*, .* in HTTP requests\.`)
+}
+
+func (s *S) TestRequestTimingRowWithoutResponse(c *check.C) {
+	request, err := http.NewRequest("DELETE", "http://localhost/1.0/apps/myapp", nil)
+	c.Assert(err, check.IsNil)
+	timing := newRequestTiming(request)
+	timing.finish(nil)
+	row := timing.row()
+	c.Assert(row[:6], check.DeepEquals, Row{"DELETE", "/1.0/apps/myapp", "-", "-", "-", "-"})
+	c.Assert(row[6], check.Equals, "-")
+}
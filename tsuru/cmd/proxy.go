@@ -0,0 +1,27 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// proxyOverride is the proxy given in the --proxy global flag. It takes
+// precedence over the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables, which are used otherwise.
+var proxyOverride *url.URL
+
+func parseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q, it should be an URL like http://proxy.example.com:3128.\n", proxy)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return u, nil
+	}
+	return nil, fmt.Errorf("invalid proxy %q, supported schemes are http, https and socks5.\n", proxy)
+}
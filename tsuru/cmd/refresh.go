@@ -0,0 +1,119 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+func refreshTokenPath() string {
+	if p := customTokenPath(); p != "" {
+		return p + ".refresh"
+	}
+	return JoinWithUserDir(".tsuru", "refresh-token")
+}
+
+// writeRefreshToken stores the refresh token sent by servers supporting
+// them. An empty token removes the stored one.
+func writeRefreshToken(token string) error {
+	if token == "" {
+		err := filesystem().Remove(refreshTokenPath())
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	file, err := filesystem().OpenFile(refreshTokenPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(token)
+	return err
+}
+
+func readRefreshToken() string {
+	file, err := filesystem().Open(refreshTokenPath())
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// storeLoginTokens stores the tokens returned by the server on login: the
+// access token and, when supported by the server, its expiration and the
+// refresh token.
+func storeLoginTokens(data map[string]interface{}) error {
+	token, _ := data["token"].(string)
+	err := writeToken(token)
+	if err != nil {
+		return err
+	}
+	if expires, ok := data["expires"].(float64); ok {
+		err = writeTokenExpiry(int(expires))
+		if err != nil {
+			return err
+		}
+	}
+	if refresh, ok := data["refresh_token"].(string); ok {
+		return writeRefreshToken(refresh)
+	}
+	return nil
+}
+
+// refreshToken exchanges the stored refresh token for a new access token. It
+// returns false when there's no refresh token, the token comes from the
+// TSURU_TOKEN environment variable or the server refuses the refresh.
+func (c *Client) refreshToken() bool {
+	if tokenFromEnv() {
+		return false
+	}
+	refresh := readRefreshToken()
+	if refresh == "" {
+		return false
+	}
+	u, err := GetURL("/users/tokens/refresh")
+	if err != nil {
+		return false
+	}
+	v := url.Values{}
+	v.Set("refresh_token", refresh)
+	request, err := http.NewRequest("POST", u, strings.NewReader(v.Encode()))
+	if err != nil {
+		return false
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Close = true
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return false
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return false
+	}
+	var data map[string]interface{}
+	if err = json.NewDecoder(response.Body).Decode(&data); err != nil {
+		return false
+	}
+	if token, _ := data["token"].(string); token == "" {
+		return false
+	}
+	if _, ok := data["refresh_token"]; !ok {
+		// servers that don't rotate refresh tokens keep the current one
+		data["refresh_token"] = refresh
+	}
+	return storeLoginTokens(data) == nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"gopkg.in/check.v1"
+)
+
+// refreshServer starts a server refusing the token "old" in /apps and
+// refreshing it to "new", recording the bodies received in /apps.
+func refreshServer(bodies *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1.0/users/tokens/refresh":
+			if r.FormValue("refresh_token") != "refresh123" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token":"new","refresh_token":"refresh456"}`))
+		case "/1.0/apps":
+			body, _ := ioutil.ReadAll(r.Body)
+			*bodies = append(*bodies, string(body))
+			if r.Header.Get("Authorization") != "bearer new" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("ok"))
+		}
+	}))
+}
+
+func (s *S) sendWithRefresh(c *check.C, server *httptest.Server, body string) (*http.Response, error) {
+	os.Setenv("TSURU_TARGET", server.URL)
+	c.Assert(writeToken("old"), check.IsNil)
+	c.Assert(writeRefreshToken("refresh123"), check.IsNil)
+	request, err := http.NewRequest("POST", server.URL+"/1.0/apps", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	client := NewClient(http.DefaultClient, nil, s.newManager())
+	return client.Do(request)
+}
+
+func (s *S) TestDoRefreshesTheToken(c *check.C) {
+	var bodies []string
+	server := refreshServer(&bodies)
+	defer server.Close()
+	response, err := s.sendWithRefresh(c, server, "name=myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(response.StatusCode, check.Equals, http.StatusOK)
+	c.Assert(bodies, check.DeepEquals, []string{"name=myapp", "name=myapp"})
+	token, err := ReadToken()
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "new")
+	c.Assert(readRefreshToken(), check.Equals, "refresh456")
+}
+
+func (s *S) TestDoDoesNotResendLargeBodies(c *check.C) {
+	var bodies []string
+	server := refreshServer(&bodies)
+	defer server.Close()
+	body := strings.Repeat("a", maxRewindableBody+1)
+	_, err := s.sendWithRefresh(c, server, body)
+	c.Assert(err, check.Equals, errUnauthorized)
+	c.Assert(bodies, check.HasLen, 1)
+	token, err := ReadToken()
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "old")
+}
@@ -0,0 +1,124 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func loginEmailPath() string {
+	if p := customTokenPath(); p != "" {
+		return p + ".email"
+	}
+	return JoinWithUserDir(".tsuru", "login-email")
+}
+
+// writeLoginEmail stores the email used in the last native login, so the
+// user can log in again without typing it when the token becomes invalid. An
+// empty email removes the stored one.
+func writeLoginEmail(email string) error {
+	if email == "" {
+		err := filesystem().Remove(loginEmailPath())
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	file, err := filesystem().OpenFile(loginEmailPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(email)
+	return err
+}
+
+func readLoginEmail() string {
+	file, err := filesystem().Open(loginEmailPath())
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// isTerminalReader reports whether r is a terminal, so the user can be
+// prompted for the password.
+var isTerminalReader = func(r io.Reader) bool {
+	desc, ok := r.(descriptable)
+	return ok && terminal.IsTerminal(int(desc.Fd()))
+}
+
+// relogin asks the user for the password of the email used in the last
+// login, when the server refuses the token and the command is attached to a
+// terminal, and stores the new token. It returns false when the user can't
+// be prompted, the server doesn't use native authentication or the login
+// fails.
+func (c *Client) relogin() bool {
+	if c.context == nil || c.loggingIn || tokenFromEnv() || !isTerminalReader(c.context.Stdin) {
+		return false
+	}
+	email := readLoginEmail()
+	if email == "" {
+		return false
+	}
+	if scheme, err := schemeInfo(); err != nil || (scheme.Name != "" && scheme.Name != "native") {
+		return false
+	}
+	fmt.Fprintf(c.context.Stderr, "Your session has expired or is invalid. Log in again as %s to continue, or press enter to cancel.\n", email)
+	fmt.Fprint(c.context.Stderr, "Password: ")
+	password, err := PasswordFromReader(c.context.Stdin)
+	fmt.Fprintln(c.context.Stderr)
+	if err != nil {
+		return false
+	}
+	u, err := GetURL("/users/" + email + "/tokens")
+	if err != nil {
+		return false
+	}
+	v := url.Values{}
+	v.Set("password", password)
+	request, err := http.NewRequest("POST", u, strings.NewReader(v.Encode()))
+	if err != nil {
+		return false
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Close = true
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		fmt.Fprintf(c.context.Stderr, "Failed to log in: %s\n", c.detectClientError(err))
+		return false
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		fmt.Fprintln(c.context.Stderr, "Failed to log in: invalid email or password.")
+		return false
+	}
+	var data map[string]interface{}
+	if err = json.NewDecoder(response.Body).Decode(&data); err != nil {
+		return false
+	}
+	if token, _ := data["token"].(string); token == "" {
+		return false
+	}
+	if storeLoginTokens(data) != nil {
+		return false
+	}
+	fmt.Fprintln(c.context.Stderr, "Successfully logged in!")
+	return true
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"gopkg.in/check.v1"
+)
+
+// reloginServer starts a native auth server refusing any token other than
+// "new" in /apps and issuing "new" for the password "secret".
+func reloginServer(logins *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1.0/auth/scheme":
+			w.Write([]byte(`{"name":"native"}`))
+		case "/1.0/users/user@example.com/tokens":
+			*logins++
+			if r.FormValue("password") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token":"new"}`))
+		case "/1.0/apps":
+			if r.Header.Get("Authorization") != "bearer new" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("ok"))
+		}
+	}))
+}
+
+func (s *S) sendWithRelogin(c *check.C, server *httptest.Server, terminal bool, stdin io.Reader, stderr io.Writer) (*http.Response, error) {
+	original := isTerminalReader
+	isTerminalReader = func(io.Reader) bool { return terminal }
+	defer func() { isTerminalReader = original }()
+	os.Setenv("TSURU_TARGET", server.URL)
+	c.Assert(writeToken("old"), check.IsNil)
+	c.Assert(writeLoginEmail("user@example.com"), check.IsNil)
+	request, err := http.NewRequest("GET", server.URL+"/1.0/apps", nil)
+	c.Assert(err, check.IsNil)
+	context := Context{Stdin: stdin, Stdout: &bytes.Buffer{}, Stderr: stderr}
+	client := NewClient(http.DefaultClient, &context, s.newManager())
+	return client.Do(request)
+}
+
+func (s *S) TestDoAsksThePasswordInATerminal(c *check.C) {
+	var logins int
+	server := reloginServer(&logins)
+	defer server.Close()
+	var stderr bytes.Buffer
+	response, err := s.sendWithRelogin(c, server, true, strings.NewReader("secret\n"), &stderr)
+	c.Assert(err, check.IsNil)
+	c.Assert(response.StatusCode, check.Equals, http.StatusOK)
+	c.Assert(logins, check.Equals, 1)
+	c.Assert(stderr.String(), check.Matches, "(?s)Your session has expired or is invalid. Log in again as user@example.com.*Password: \nSuccessfully logged in!\n")
+	token, err := ReadToken()
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "new")
+}
+
+func (s *S) TestDoWithWrongPasswordInATerminal(c *check.C) {
+	var logins int
+	server := reloginServer(&logins)
+	defer server.Close()
+	var stderr bytes.Buffer
+	_, err := s.sendWithRelogin(c, server, true, strings.NewReader("wrong\n"), &stderr)
+	c.Assert(err, check.Equals, errUnauthorized)
+	c.Assert(logins, check.Equals, 1)
+	c.Assert(stderr.String(), check.Matches, "(?s).*Failed to log in: invalid email or password.\n")
+	token, err := ReadToken()
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "old")
+}
+
+func (s *S) TestDoDoesNotAskThePasswordOutOfATerminal(c *check.C) {
+	var logins int
+	server := reloginServer(&logins)
+	defer server.Close()
+	var stderr bytes.Buffer
+	_, err := s.sendWithRelogin(c, server, false, strings.NewReader("secret\n"), &stderr)
+	c.Assert(err, check.Equals, errUnauthorized)
+	c.Assert(logins, check.Equals, 0)
+	c.Assert(stderr.String(), check.Not(check.Matches), "(?s).*Password.*")
+}
+
+func (s *S) TestDoDoesNotAskThePasswordWithTokenFromTheEnvironment(c *check.C) {
+	os.Setenv("TSURU_TOKEN", "old")
+	var logins int
+	server := reloginServer(&logins)
+	defer server.Close()
+	var stderr bytes.Buffer
+	_, err := s.sendWithRelogin(c, server, true, strings.NewReader("secret\n"), &stderr)
+	c.Assert(err, check.Equals, errUnauthorized)
+	c.Assert(logins, check.Equals, 0)
+}
+
+func (s *S) TestWriteEmptyLoginEmailRemovesIt(c *check.C) {
+	c.Assert(writeLoginEmail("user@example.com"), check.IsNil)
+	c.Assert(readLoginEmail(), check.Equals, "user@example.com")
+	c.Assert(writeLoginEmail(""), check.IsNil)
+	c.Assert(readLoginEmail(), check.Equals, "")
+}
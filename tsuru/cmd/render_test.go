@@ -0,0 +1,55 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/check.v1"
+)
+
+type colorCommand struct{}
+
+func (c *colorCommand) Info() *Info {
+	return &Info{Name: "color", Usage: "color"}
+}
+
+func (c *colorCommand) Run(context *Context, client *Client) error {
+	fmt.Fprintln(context.Stdout, Colorfy("hello", "red", "", "bold"))
+	return nil
+}
+
+func (s *S) TestColorfy(c *check.C) {
+	c.Assert(Colorfy("hello", "red", "", "bold"), check.Equals, "\033[1;31;10mhello\033[0m")
+}
+
+func (s *S) TestColorfyDisabled(c *check.C) {
+	colorsDisabled = true
+	c.Assert(Colorfy("hello", "red", "", "bold"), check.Equals, "hello")
+}
+
+func (s *S) TestColorfyDisabledByTheEnvironment(c *check.C) {
+	for _, name := range []string{"NO_COLOR", "TSURU_DISABLE_COLORS"} {
+		os.Setenv(name, "1")
+		c.Check(Colorfy("hello", "red", "", "bold"), check.Equals, "hello", check.Commentf("variable: %s", name))
+		os.Unsetenv(name)
+	}
+}
+
+func (s *S) TestRunNoColor(c *check.C) {
+	m := s.newManager()
+	m.Register(&colorCommand{})
+	m.Run([]string{"--no-color", "color"})
+	c.Assert(s.stdout.String(), check.Equals, "hello\n")
+}
+
+func (s *S) TestRunWithoutColorsWhenStdoutIsNotATerminal(c *check.C) {
+	m := s.newManager()
+	m.Register(&colorCommand{})
+	m.Run([]string{"color"})
+	c.Assert(s.stdout.String(), check.Equals, "hello\n")
+	c.Assert(colorsDisabled, check.Equals, true)
+}
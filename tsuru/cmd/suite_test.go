@@ -0,0 +1,67 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/tsuru/tsuru/fs/fstest"
+	"gopkg.in/check.v1"
+)
+
+type S struct {
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	exiter *recordingExiter
+}
+
+var _ = check.Suite(&S{})
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+func (s *S) SetUpTest(c *check.C) {
+	s.stdout.Reset()
+	s.stderr.Reset()
+	s.exiter = new(recordingExiter)
+	fsystem = &fstest.RecordingFs{}
+	versionChecked = true
+	versionWarned = false
+	expiryWarned = false
+	expiryWarnings = defaultExpiryWarning
+	colorsDisabled = false
+	tokenFileOverride = ""
+	fileDefaults.target, fileDefaults.clientCert, fileDefaults.clientKey, fileDefaults.team = "", "", "", ""
+	fileDefaults.flags, fileDefaults.aliases = nil, nil
+	os.Unsetenv("TSURU_TARGET")
+	os.Unsetenv("TSURU_TOKEN")
+	os.Unsetenv("TSURU_TOKEN_FILE")
+}
+
+func (s *S) TearDownTest(c *check.C) {
+	fsystem = nil
+	versionChecked = false
+	os.Unsetenv("TSURU_TARGET")
+	os.Unsetenv("TSURU_TOKEN")
+}
+
+// newManager returns a manager writing to the buffers of the suite, which
+// records the exit code instead of exiting.
+func (s *S) newManager() *Manager {
+	m := NewManager("glb", "1.0.0", "Supported-Tsuru", &s.stdout, &s.stderr, os.Stdin, nil)
+	m.e = s.exiter
+	return m
+}
+
+type recordingExiter int
+
+func (e *recordingExiter) Exit(code int) {
+	*e = recordingExiter(code)
+}
+
+func (e recordingExiter) value() int {
+	return int(e)
+}
@@ -0,0 +1,197 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/tsuru/gnuflag"
+)
+
+const targetExportHeader = "# tsuru targets, import them with: tsuru target-import <file>\n"
+
+type targetExport struct{}
+
+func (t *targetExport) Info() *Info {
+	desc := `Writes the list of targets to the given file, which can be shared and then
+imported with target-import. Use "-" as the file to write the list to the
+standard output.
+
+Only the labels and the addresses of the targets are exported, the session
+token is never included.`
+	return &Info{
+		Name:    "target-export",
+		Usage:   "target-export <file>",
+		Desc:    desc,
+		MinArgs: 1,
+		MaxArgs: 1,
+	}
+}
+
+func (t *targetExport) Run(ctx *Context, client *Client) error {
+	targets, err := getTargets()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(targetExportHeader)
+	for _, label := range sortedTargetLabels(targets) {
+		fmt.Fprintf(&buf, "%s\t%s\n", label, targets[label])
+	}
+	if ctx.Args[0] == "-" {
+		_, err = ctx.Stdout.Write(buf.Bytes())
+		return err
+	}
+	f, err := filesystem().OpenFile(ctx.Args[0], syscall.O_WRONLY|syscall.O_CREAT|syscall.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.Stdout, "%d targets exported to %s.\n", len(targets), ctx.Args[0])
+	return nil
+}
+
+type targetImport struct {
+	fs        *gnuflag.FlagSet
+	overwrite bool
+}
+
+func (t *targetImport) Info() *Info {
+	desc := `Adds the targets in the given file, written by target-export, to the list of
+targets. Use "-" as the file to read the targets from the standard input.
+
+Targets whose label already exists with a different address are skipped,
+unless the [[--overwrite]] flag is used.`
+	return &Info{
+		Name:    "target-import",
+		Usage:   "target-import <file> [--overwrite|-o]",
+		Desc:    desc,
+		MinArgs: 1,
+		MaxArgs: 1,
+	}
+}
+
+func (t *targetImport) Flags() *gnuflag.FlagSet {
+	if t.fs == nil {
+		t.fs = gnuflag.NewFlagSet("target-import", gnuflag.ExitOnError)
+		overwrite := "Overwrite the address of targets whose label already exists"
+		t.fs.BoolVar(&t.overwrite, "overwrite", false, overwrite)
+		t.fs.BoolVar(&t.overwrite, "o", false, overwrite)
+	}
+	return t.fs
+}
+
+func (t *targetImport) Run(ctx *Context, client *Client) error {
+	r := ctx.Stdin
+	if ctx.Args[0] != "-" {
+		f, err := filesystem().Open(ctx.Args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	imported, err := parseTargetExport(r)
+	if err != nil {
+		return fmt.Errorf("invalid targets file %s: %s", ctx.Args[0], err)
+	}
+	targets, err := getTargets()
+	if err != nil {
+		return err
+	}
+	current, _ := readTarget(JoinWithUserDir(".tsuru", "target"))
+	var added, updated, skipped int
+	var updateCurrent bool
+	for _, target := range imported {
+		existing, ok := targets[target.label]
+		switch {
+		case !ok:
+			added++
+		case existing == target.url:
+			continue
+		case t.overwrite:
+			updated++
+			if existing == current {
+				current = target.url
+				updateCurrent = true
+			}
+		default:
+			skipped++
+			fmt.Fprintf(ctx.Stderr, "Skipping target %q: it already exists pointing to %s.\n", target.label, existing)
+			continue
+		}
+		targets[target.label] = target.url
+	}
+	err = writeTargetList(targets)
+	if err != nil {
+		return err
+	}
+	if updateCurrent {
+		err = WriteTarget(current)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(ctx.Stdout, "%d targets added, %d updated, %d skipped.\n", added, updated, skipped)
+	return nil
+}
+
+// parseTargetExport reads the targets in the format written by
+// target-export: one target per line, with the label and the address
+// separated by blanks. Empty lines and lines starting with # are ignored.
+func parseTargetExport(r io.Reader) ([]tsuruTarget, error) {
+	var targets []tsuruTarget
+	scanner := bufio.NewScanner(r)
+	var n int
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d should have a label and an address", n)
+		}
+		targets = append(targets, tsuruTarget{label: fields[0], url: fields[1]})
+	}
+	return targets, scanner.Err()
+}
+
+func sortedTargetLabels(targets map[string]string) []string {
+	labels := make([]string, 0, len(targets))
+	for label := range targets {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// writeTargetList replaces the target list file with the given targets,
+// sorted by label, in a single write.
+func writeTargetList(targets map[string]string) error {
+	var buf bytes.Buffer
+	for _, label := range sortedTargetLabels(targets) {
+		fmt.Fprintf(&buf, "%s\t%s\n", label, targets[label])
+	}
+	targetsPath := JoinWithUserDir(".tsuru", "targets")
+	targetsFile, err := filesystem().OpenFile(targetsPath, syscall.O_WRONLY|syscall.O_CREAT|syscall.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer targetsFile.Close()
+	_, err = targetsFile.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/check.v1"
+)
+
+func writeFakeFile(c *check.C, path, content string) {
+	f, err := filesystem().Create(path)
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	_, err = f.WriteString(content)
+	c.Assert(err, check.IsNil)
+}
+
+func readFakeFile(c *check.C, path string) string {
+	f, err := filesystem().Open(path)
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	c.Assert(err, check.IsNil)
+	return string(data)
+}
+
+func (s *S) TestTargetExport(c *check.C) {
+	writeFakeFile(c, JoinWithUserDir(".tsuru", "targets"), "prod\thttps://tsuru.example.com\ndev\thttp://localhost:8080\n")
+	c.Assert(writeToken("abc123"), check.IsNil)
+	var stdout bytes.Buffer
+	ctx := Context{Args: []string{"/tmp/targets"}, Stdout: &stdout}
+	err := (&targetExport{}).Run(&ctx, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "2 targets exported to /tmp/targets.\n")
+	expected := targetExportHeader + "dev\thttp://localhost:8080\nprod\thttps://tsuru.example.com\n"
+	c.Assert(readFakeFile(c, "/tmp/targets"), check.Equals, expected)
+}
+
+func (s *S) TestTargetExportNeverIncludesTheToken(c *check.C) {
+	writeFakeFile(c, JoinWithUserDir(".tsuru", "targets"), "prod\thttps://tsuru.example.com\n")
+	writeFakeFile(c, JoinWithUserDir(".tsuru", "target"), "https://tsuru.example.com")
+	c.Assert(writeToken("abc123"), check.IsNil)
+	c.Assert(writeRefreshToken("refresh123"), check.IsNil)
+	var stdout bytes.Buffer
+	ctx := Context{Args: []string{"-"}, Stdout: &stdout}
+	err := (&targetExport{}).Run(&ctx, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, targetExportHeader+"prod\thttps://tsuru.example.com\n")
+	c.Assert(strings.Contains(stdout.String(), "abc123"), check.Equals, false)
+	c.Assert(strings.Contains(stdout.String(), "refresh123"), check.Equals, false)
+}
+
+func (s *S) TestTargetImport(c *check.C) {
+	targetsPath := JoinWithUserDir(".tsuru", "targets")
+	writeFakeFile(c, targetsPath, "prod\thttps://tsuru.example.com\ndev\thttp://localhost:8080\n")
+	writeFakeFile(c, "/tmp/targets", targetExportHeader+"\nprod\thttps://other.example.com\nstaging  https://staging.example.com\ndev\thttp://localhost:8080\n")
+	var stdout, stderr bytes.Buffer
+	ctx := Context{Args: []string{"/tmp/targets"}, Stdout: &stdout, Stderr: &stderr}
+	err := (&targetImport{}).Run(&ctx, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "1 targets added, 0 updated, 1 skipped.\n")
+	c.Assert(stderr.String(), check.Equals, "Skipping target \"prod\": it already exists pointing to https://tsuru.example.com.\n")
+	c.Assert(readFakeFile(c, targetsPath), check.Equals, "dev\thttp://localhost:8080\nprod\thttps://tsuru.example.com\nstaging\thttps://staging.example.com\n")
+}
+
+func (s *S) TestTargetImportOverwrite(c *check.C) {
+	targetsPath := JoinWithUserDir(".tsuru", "targets")
+	writeFakeFile(c, targetsPath, "prod\thttps://tsuru.example.com\ndev\thttp://localhost:8080\n")
+	writeFakeFile(c, JoinWithUserDir(".tsuru", "target"), "https://tsuru.example.com")
+	var stdout, stderr bytes.Buffer
+	ctx := Context{
+		Args:   []string{"-"},
+		Stdin:  strings.NewReader("prod\thttps://other.example.com\n"),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	cmd := targetImport{}
+	err := cmd.Flags().Parse(true, []string{"--overwrite"})
+	c.Assert(err, check.IsNil)
+	err = cmd.Run(&ctx, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "0 targets added, 1 updated, 0 skipped.\n")
+	c.Assert(stderr.String(), check.Equals, "")
+	c.Assert(readFakeFile(c, targetsPath), check.Equals, "dev\thttp://localhost:8080\nprod\thttps://other.example.com\n")
+	c.Assert(readFakeFile(c, JoinWithUserDir(".tsuru", "target")), check.Equals, "https://other.example.com")
+}
+
+func (s *S) TestTargetImportInvalidFile(c *check.C) {
+	targetsPath := JoinWithUserDir(".tsuru", "targets")
+	writeFakeFile(c, targetsPath, "prod\thttps://tsuru.example.com\n")
+	ctx := Context{Args: []string{"-"}, Stdin: strings.NewReader("# targets\nprod\n")}
+	err := (&targetImport{}).Run(&ctx, nil)
+	c.Assert(err, check.ErrorMatches, "invalid targets file -: line 2 should have a label and an address")
+	c.Assert(readFakeFile(c, targetsPath), check.Equals, "prod\thttps://tsuru.example.com\n")
+}
@@ -0,0 +1,33 @@
+// Copyright 2014 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+const targetTopic = `In tsuru, a target is the address of the remote tsuru server.
+
+Each target is identified by a label and a HTTP/HTTPS address. The client
+requires at least one target to connect to, there's no default target. A user
+may have multiple targets, but he/she will be able to use only per session.
+
+The following commands are used to manage targets in the client:
+
+  * target-add: adds a new target to the list os available targets
+  * target-list: list available targets, marking the current
+  * target-remove: removes a target by its label
+  * target-set: defines the current target, to which the CLI will send next
+    commands
+
+The target used by a command is chosen in the following order:
+
+  1. the --target global flag (e.g. %[1]s --target prod app-list), which
+     accepts either a label or an address
+  2. the TSURU_TARGET environment variable
+  3. the current target, defined by target-set
+  4. the target in the ~/.tsuru/config.yaml file
+
+Both the flag and the environment variable only affect the running command,
+without changing the current target.
+
+See each command usage by running %[1]s help <commandname>
+`
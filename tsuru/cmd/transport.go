@@ -0,0 +1,49 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// withTransport returns a copy of client using the proxy given in the --proxy
+// flag, or the one from the environment, and the client certificate given in
+// the --client-cert and --client-key flags. The transport of client is copied
+// field by field, so the shared clients of the net package are not changed.
+func withTransport(client *http.Client) *http.Client {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		return client
+	}
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		Dial:                  transport.Dial,
+		DialTLS:               transport.DialTLS,
+		TLSClientConfig:       transport.TLSClientConfig,
+		TLSHandshakeTimeout:   transport.TLSHandshakeTimeout,
+		DisableKeepAlives:     transport.DisableKeepAlives,
+		DisableCompression:    transport.DisableCompression,
+		MaxIdleConnsPerHost:   transport.MaxIdleConnsPerHost,
+		ResponseHeaderTimeout: transport.ResponseHeaderTimeout,
+		ExpectContinueTimeout: transport.ExpectContinueTimeout,
+	}
+	if proxyOverride != nil {
+		t.Proxy = http.ProxyURL(proxyOverride)
+	}
+	if clientCertificate != nil {
+		config := &tls.Config{}
+		if t.TLSClientConfig != nil {
+			config.RootCAs = t.TLSClientConfig.RootCAs
+			config.ServerName = t.TLSClientConfig.ServerName
+			config.InsecureSkipVerify = t.TLSClientConfig.InsecureSkipVerify
+		}
+		config.Certificates = []tls.Certificate{*clientCertificate}
+		t.TLSClientConfig = config
+	}
+	c := *client
+	c.Transport = t
+	return &c
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func newTestClient() *http.Client {
+	dialer := &net.Dialer{Timeout: time.Second}
+	return &http.Client{
+		Transport: &http.Transport{
+			Dial:                dialer.Dial,
+			TLSHandshakeTimeout: time.Second,
+			MaxIdleConnsPerHost: 5,
+		},
+		Timeout: time.Minute,
+	}
+}
+
+func (s *S) TestWithTransport(c *check.C) {
+	original := newTestClient()
+	client := withTransport(original)
+	c.Assert(client, check.Not(check.Equals), original)
+	c.Assert(client.Timeout, check.Equals, time.Minute)
+	transport := client.Transport.(*http.Transport)
+	c.Assert(transport, check.Not(check.Equals), original.Transport)
+	c.Assert(transport.Dial, check.NotNil)
+	c.Assert(transport.TLSHandshakeTimeout, check.Equals, time.Second)
+	c.Assert(transport.MaxIdleConnsPerHost, check.Equals, 5)
+	c.Assert(transport.TLSClientConfig, check.IsNil)
+	c.Assert(transport.Proxy, check.NotNil)
+	c.Assert(original.Transport.(*http.Transport).Proxy, check.IsNil)
+}
+
+func (s *S) TestWithTransportProxyOverride(c *check.C) {
+	defer func() { proxyOverride = nil }()
+	proxyOverride = &url.URL{Scheme: "http", Host: "other.example.com:8080"}
+	client := withTransport(newTestClient())
+	req, err := http.NewRequest("GET", "https://tsuru.example.com/apps", nil)
+	c.Assert(err, check.IsNil)
+	proxy, err := client.Transport.(*http.Transport).Proxy(req)
+	c.Assert(err, check.IsNil)
+	c.Assert(proxy.String(), check.Equals, "http://other.example.com:8080")
+}
+
+func (s *S) TestWithTransportClientCertificate(c *check.C) {
+	defer func() { clientCertificate = nil }()
+	clientCertificate = &tls.Certificate{Certificate: [][]byte{[]byte("cert")}}
+	original := newTestClient()
+	pool := x509.NewCertPool()
+	originalConfig := &tls.Config{RootCAs: pool, ServerName: "tsuru.example.com"}
+	original.Transport.(*http.Transport).TLSClientConfig = originalConfig
+	client := withTransport(original)
+	config := client.Transport.(*http.Transport).TLSClientConfig
+	c.Assert(config, check.Not(check.Equals), originalConfig)
+	c.Assert(config.Certificates, check.DeepEquals, []tls.Certificate{*clientCertificate})
+	c.Assert(config.RootCAs, check.Equals, pool)
+	c.Assert(config.ServerName, check.Equals, "tsuru.example.com")
+	c.Assert(originalConfig.Certificates, check.HasLen, 0)
+}
+
+func (s *S) TestWithTransportOtherTransports(c *check.C) {
+	original := &http.Client{Transport: http.NewFileTransport(http.Dir("/"))}
+	c.Assert(withTransport(original), check.Equals, original)
+}
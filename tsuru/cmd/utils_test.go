@@ -0,0 +1,85 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tsuru/tsuru/fs/fstest"
+	"gopkg.in/check.v1"
+)
+
+type tokenCommand struct{}
+
+func (c *tokenCommand) Info() *Info {
+	return &Info{Name: "token", Usage: "token"}
+}
+
+func (c *tokenCommand) Run(context *Context, client *Client) error {
+	token, err := ReadToken()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(context.Stdout, token)
+	return nil
+}
+
+func (s *S) TestTokenPaths(c *check.C) {
+	c.Assert(tokenPath(), check.Equals, JoinWithUserDir(".tsuru", "token"))
+	c.Assert(refreshTokenPath(), check.Equals, JoinWithUserDir(".tsuru", "refresh-token"))
+	c.Assert(tokenExpiryPath(), check.Equals, JoinWithUserDir(".tsuru", "token-expiry"))
+}
+
+func (s *S) TestTokenPathsFromTheEnvironment(c *check.C) {
+	os.Setenv("TSURU_TOKEN_FILE", "/tmp/ci-token")
+	c.Assert(tokenPath(), check.Equals, "/tmp/ci-token")
+	c.Assert(refreshTokenPath(), check.Equals, "/tmp/ci-token.refresh")
+	c.Assert(tokenExpiryPath(), check.Equals, "/tmp/ci-token.expiry")
+}
+
+func (s *S) TestTokenPathsFlagTakesPrecedence(c *check.C) {
+	os.Setenv("TSURU_TOKEN_FILE", "/tmp/ci-token")
+	tokenFileOverride = "/tmp/other-token"
+	c.Assert(tokenPath(), check.Equals, "/tmp/other-token")
+	c.Assert(refreshTokenPath(), check.Equals, "/tmp/other-token.refresh")
+}
+
+func (s *S) TestWriteAndReadTokenFile(c *check.C) {
+	tokenFileOverride = "/tmp/other-token"
+	c.Assert(writeToken("abc123"), check.IsNil)
+	token, err := ReadToken()
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "abc123")
+	rfs := fsystem.(*fstest.RecordingFs)
+	c.Assert(rfs.HasAction("create /tmp/other-token"), check.Equals, true)
+	c.Assert(rfs.HasAction("create "+JoinWithUserDir(".tsuru", "token")), check.Equals, false)
+}
+
+func (s *S) TestTokenFileFlagIgnoresTheTokenFromTheEnvironment(c *check.C) {
+	os.Setenv("TSURU_TOKEN", "env-token")
+	c.Assert(tokenFromEnv(), check.Equals, true)
+	tokenFileOverride = "/tmp/other-token"
+	c.Assert(tokenFromEnv(), check.Equals, false)
+}
+
+func (s *S) TestRunTokenFile(c *check.C) {
+	writeFakeFile(c, "/tmp/other-token", "file-token")
+	writeFakeFile(c, JoinWithUserDir(".tsuru", "token"), "default-token")
+	os.Setenv("TSURU_TOKEN", "env-token")
+	m := s.newManager()
+	m.Register(&tokenCommand{})
+	m.Run([]string{"--token-file", "/tmp/other-token", "token"})
+	c.Assert(s.stdout.String(), check.Equals, "file-token\n")
+}
+
+func (s *S) TestRunTokenFileFromTheEnvironment(c *check.C) {
+	writeFakeFile(c, "/tmp/ci-token", "file-token")
+	os.Setenv("TSURU_TOKEN_FILE", "/tmp/ci-token")
+	m := s.newManager()
+	m.Register(&tokenCommand{})
+	m.Run([]string{"token"})
+	c.Assert(s.stdout.String(), check.Equals, "file-token\n")
+}
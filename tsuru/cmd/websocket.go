@@ -0,0 +1,160 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/websocket"
+)
+
+// DialWebsocket opens the websocket connection described by config with the
+// token of the user, through the transport of the client, so the proxy and
+// the client certificate used by the other requests also apply to it.
+// websocket.DialConfig would connect directly to the server.
+func (c *Client) DialWebsocket(config *websocket.Config) (*websocket.Conn, error) {
+	if token, err := ReadToken(); err == nil && token != "" {
+		config.Header.Set("Authorization", "bearer "+token)
+	}
+	transport, ok := c.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	conn, err := dialTransport(transport, config.Location)
+	if _, ok := err.(net.Error); ok {
+		return nil, c.detectClientError(&url.Error{Op: "Get", URL: config.Location.String(), Err: err})
+	}
+	if err != nil {
+		return nil, err
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// dialTransport opens a connection to the server of the websocket location,
+// like transport would for an HTTP request: through its proxy, when there's
+// one, and with its TLS configuration for wss locations.
+func dialTransport(transport *http.Transport, location *url.URL) (net.Conn, error) {
+	target := &url.URL{Scheme: "http", Host: location.Host}
+	if location.Scheme == "wss" {
+		target.Scheme = "https"
+	}
+	dial := transport.Dial
+	if dial == nil {
+		dial = (&net.Dialer{}).Dial
+	}
+	var proxy *url.URL
+	if transport.Proxy != nil {
+		var err error
+		proxy, err = transport.Proxy(&http.Request{Method: "GET", URL: target, Header: http.Header{}})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var conn net.Conn
+	var err error
+	if proxy == nil {
+		conn, err = dial("tcp", hostPort(target))
+	} else {
+		conn, err = dialProxy(dial, proxy, hostPort(target))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme == "https" {
+		host, _, _ := net.SplitHostPort(hostPort(target))
+		tlsConn := tls.Client(conn, tlsConfigFor(transport.TLSClientConfig, host))
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	return conn, nil
+}
+
+// dialProxy opens a tunnel to addr with the CONNECT method of the given HTTP
+// proxy.
+func dialProxy(dial func(network, addr string) (net.Conn, error), proxy *url.URL, addr string) (net.Conn, error) {
+	if proxy.Scheme != "http" && proxy.Scheme != "https" {
+		return nil, fmt.Errorf("the %s proxy %s can't be used to open a websocket connection, only http and https proxies are supported", proxy.Scheme, proxy.Host)
+	}
+	conn, err := dial("tcp", hostPort(proxy))
+	if err != nil {
+		return nil, err
+	}
+	if proxy.Scheme == "https" {
+		host, _, _ := net.SplitHostPort(hostPort(proxy))
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	request := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + password))
+		request.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err = request.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	response, err := http.ReadResponse(bufio.NewReader(conn), request)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("the proxy %s refused the connection to %s: %s", proxy.Host, addr, response.Status)
+	}
+	return conn, nil
+}
+
+// tlsConfigFor returns the TLS configuration of the transport for the given
+// server. The fields used by the client are copied one by one, so the
+// configuration of the transport is not changed.
+func tlsConfigFor(config *tls.Config, serverName string) *tls.Config {
+	c := &tls.Config{ServerName: serverName}
+	if config != nil {
+		c.RootCAs = config.RootCAs
+		c.Certificates = config.Certificates
+		c.InsecureSkipVerify = config.InsecureSkipVerify
+		if config.ServerName != "" {
+			c.ServerName = config.ServerName
+		}
+	}
+	return c
+}
+
+// hostPort returns the host of u with its port, adding the default port of
+// the scheme when it's missing.
+func hostPort(u *url.URL) string {
+	if _, _, err := net.SplitHostPort(u.Host); err == nil {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Host, "443")
+	}
+	return net.JoinHostPort(u.Host, "80")
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/websocket"
+	"gopkg.in/check.v1"
+)
+
+// echoServer returns a websocket handler echoing 5 bytes, recording the
+// Authorization header and the number of client certificates received.
+func echoServer(authorization *string, certificates *int) http.Handler {
+	return websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+		r := ws.Request()
+		*authorization = r.Header.Get("Authorization")
+		if r.TLS != nil {
+			*certificates = len(r.TLS.PeerCertificates)
+		}
+		input := make([]byte, 5)
+		io.ReadFull(ws, input)
+		ws.Write(input)
+	})
+}
+
+// connectProxy starts an HTTP proxy accepting CONNECT requests, recording
+// the addresses of the tunnels.
+func connectProxy(tunnels *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "CONNECT" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		*tunnels = append(*tunnels, r.Host)
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+}
+
+func (s *S) echo(c *check.C, client *Client, location string) {
+	config, err := websocket.NewConfig(location, "ws://localhost")
+	c.Assert(err, check.IsNil)
+	ws, err := client.DialWebsocket(config)
+	c.Assert(err, check.IsNil)
+	defer ws.Close()
+	_, err = ws.Write([]byte("hello"))
+	c.Assert(err, check.IsNil)
+	output := make([]byte, 5)
+	_, err = io.ReadFull(ws, output)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(output), check.Equals, "hello")
+}
+
+func (s *S) TestDialWebsocketThroughTheProxy(c *check.C) {
+	var authorization string
+	var certificates int
+	server := httptest.NewServer(echoServer(&authorization, &certificates))
+	defer server.Close()
+	var tunnels []string
+	proxy := connectProxy(&tunnels)
+	defer proxy.Close()
+	defer func() { proxyOverride = nil }()
+	proxyOverride, _ = url.Parse(proxy.URL)
+	c.Assert(writeToken("mytoken"), check.IsNil)
+	client := NewClient(withTransport(newTestClient()), nil, s.newManager())
+	s.echo(c, client, strings.Replace(server.URL, "http", "ws", 1)+"/shell")
+	c.Assert(tunnels, check.DeepEquals, []string{server.Listener.Addr().String()})
+	c.Assert(authorization, check.Equals, "bearer mytoken")
+}
+
+func (s *S) TestDialWebsocketWithTheClientCertificate(c *check.C) {
+	var authorization string
+	var certificates int
+	server := httptest.NewUnstartedServer(echoServer(&authorization, &certificates))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+	certFile, keyFile := writeClientCertificate(c, c.MkDir())
+	cert, err := loadClientCertificate(certFile, keyFile)
+	c.Assert(err, check.IsNil)
+	defer func() { clientCertificate = nil }()
+	clientCertificate = cert
+	original := newTestClient()
+	original.Transport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	client := NewClient(withTransport(original), nil, s.newManager())
+	s.echo(c, client, strings.Replace(server.URL, "https", "wss", 1)+"/shell")
+	c.Assert(certificates, check.Equals, 1)
+}
+
+func (s *S) TestDialWebsocketUnsupportedProxy(c *check.C) {
+	defer func() { proxyOverride = nil }()
+	proxyOverride = &url.URL{Scheme: "socks5", Host: "127.0.0.1:1080"}
+	client := NewClient(withTransport(newTestClient()), nil, s.newManager())
+	config, err := websocket.NewConfig("ws://tsuru.example.com/shell", "ws://localhost")
+	c.Assert(err, check.IsNil)
+	_, err = client.DialWebsocket(config)
+	c.Assert(err, check.ErrorMatches, "the socks5 proxy 127.0.0.1:1080 can't be used to open a websocket connection, only http and https proxies are supported")
+}
+
+func (s *S) TestDialWebsocketProxyRefusesTheConnection(c *check.C) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer proxy.Close()
+	defer func() { proxyOverride = nil }()
+	proxyOverride, _ = url.Parse(proxy.URL)
+	client := NewClient(withTransport(newTestClient()), nil, s.newManager())
+	config, err := websocket.NewConfig("ws://tsuru.example.com/shell", "ws://localhost")
+	c.Assert(err, check.IsNil)
+	_, err = client.DialWebsocket(config)
+	c.Assert(err, check.ErrorMatches, `the proxy .* refused the connection to tsuru.example.com:80: 403 Forbidden`)
+}
+
+func (s *S) TestDialWebsocketServerDown(c *check.C) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	client := NewClient(withTransport(newTestClient()), nil, s.newManager())
+	config, err := websocket.NewConfig(strings.Replace(server.URL, "http", "ws", 1)+"/shell", "ws://localhost")
+	c.Assert(err, check.IsNil)
+	_, err = client.DialWebsocket(config)
+	c.Assert(err, check.FitsTypeOf, &connectionError{})
+}
@@ -7,9 +7,12 @@ package installer
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/mgo.v2"
@@ -22,8 +25,8 @@ import (
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru-client/tsuru/admin"
 	tclient "github.com/tsuru/tsuru-client/tsuru/client"
-	"github.com/tsuru/tsuru/cmd"
-	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -40,7 +43,132 @@ var (
 
 type ComponentsConfig struct {
 	ComponentAddress map[string]string
+	ImageRegistry    ImageRegistryConfig
+	// Replicas is the number of replicas of the services of the components
+	// set in the configuration file, by component key. Components without
+	// a number of replicas keep the default of the swarm cluster.
+	Replicas map[string]int
+	// InstallTimeout is how long the installation of each component may
+	// take, set in components:install-timeout, and InstallTimeouts are the
+	// overrides of specific components, by component key. The installation
+	// of a component fails after defaultComponentInstallTimeout when none is
+	// set.
+	InstallTimeout  time.Duration
+	InstallTimeouts map[string]time.Duration
+	// HealthTimeout is how long the installer waits for the replicas of the
+	// components to be running after they're installed, set in
+	// components:health-timeout, defaultComponentsHealthTimeout when not set.
+	HealthTimeout time.Duration
 	TsuruAPIConfig
+	mu sync.RWMutex
+}
+
+// replicatedComponents are the keys of the components whose number of
+// replicas can be set in the components:<key>:replicas configuration key.
+// MongoDB and Redis are not replicated, as their data would not be shared
+// between the replicas.
+var replicatedComponents = []string{"planb", "registry", "api"}
+
+// installTimeoutComponents are the keys of the components whose install
+// timeout can be set in the components:<key>:install-timeout configuration
+// key.
+var installTimeoutComponents = []string{"mongo", "redis", "planb", "registry", "api"}
+
+// managerComponents are the keys of the components that run only on the
+// core hosts, the managers of the swarm cluster, as they use the docker
+// certificates of the hosts.
+var managerComponents = map[string]bool{"registry": true, "api": true}
+
+// serviceMode returns the mode of the service of the component with the
+// given key, with the number of replicas set in the configuration file, if
+// any.
+func (i *ComponentsConfig) serviceMode(key string) swarm.ServiceMode {
+	n, ok := i.Replicas[key]
+	if !ok {
+		return swarm.ServiceMode{}
+	}
+	replicas := uint64(n)
+	return swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}}
+}
+
+// defaultComponentInstallTimeout is how long the installation of a component
+// may take when no timeout is set in the configuration file.
+var defaultComponentInstallTimeout = 10 * time.Minute
+
+// installTimeout returns how long the installation of the component with the
+// given key may take.
+func (i *ComponentsConfig) installTimeout(key string) time.Duration {
+	if timeout, ok := i.InstallTimeouts[key]; ok {
+		return timeout
+	}
+	if i.InstallTimeout > 0 {
+		return i.InstallTimeout
+	}
+	return defaultComponentInstallTimeout
+}
+
+// defaultComponentsHealthTimeout is how long the installer waits for the
+// components to be healthy when no timeout is set in the configuration file.
+var defaultComponentsHealthTimeout = 5 * time.Minute
+
+// healthTimeout returns how long the installer waits for the component with
+// the given key to be healthy after it's installed. The install timeout set
+// for the component in components:<key>:install-timeout also bounds the wait.
+func (i *ComponentsConfig) healthTimeout(key string) time.Duration {
+	if timeout, ok := i.InstallTimeouts[key]; ok {
+		return timeout
+	}
+	if i.HealthTimeout > 0 {
+		return i.HealthTimeout
+	}
+	return defaultComponentsHealthTimeout
+}
+
+// ImageRegistryConfig is an external registry hosting the images of the
+// components. When credentials are set, the images are pulled on every host
+// before creating the services, sending the credentials only in the pull
+// requests to the docker engine.
+type ImageRegistryConfig struct {
+	URL      string
+	Username string
+	Password string
+}
+
+// image returns the reference of the given image in the configured registry.
+func (i *ComponentsConfig) image(name string) string {
+	if i.ImageRegistry.URL == "" {
+		return name
+	}
+	return strings.TrimSuffix(i.ImageRegistry.URL, "/") + "/" + name
+}
+
+// createService creates the service of a component, pulling its image with
+// the credentials of the registry first when they're set.
+func (i *ComponentsConfig) createService(cluster ServiceCluster, opts docker.CreateServiceOptions) error {
+	if i.ImageRegistry.Username != "" {
+		auth := docker.AuthConfiguration{
+			Username:      i.ImageRegistry.Username,
+			Password:      i.ImageRegistry.Password,
+			ServerAddress: strings.TrimSuffix(i.ImageRegistry.URL, "/"),
+		}
+		err := cluster.PullImage(opts.TaskTemplate.ContainerSpec.Image, auth)
+		if err != nil {
+			return fmt.Errorf("failed to pull image %s: %s", opts.TaskTemplate.ContainerSpec.Image, err)
+		}
+	}
+	return cluster.CreateService(opts)
+}
+
+func (i *ComponentsConfig) address(component string) string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.ComponentAddress[component]
+}
+
+func (i *ComponentsConfig) setAddress(component, addr string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.ComponentAddress[component] = addr
 }
 
 func NewInstallConfig(targetName string) *ComponentsConfig {
@@ -48,7 +176,43 @@ func NewInstallConfig(targetName string) *ComponentsConfig {
 	redis, _ := config.GetString("components:redis")
 	registry, _ := config.GetString("components:registry")
 	planb, _ := config.GetString("components:planb")
+	registryURL, _ := config.GetString("registry:url")
+	registryUsername, _ := config.GetString("registry:username")
+	registryPassword, _ := config.GetString("registry:password")
+	var replicas map[string]int
+	for _, key := range replicatedComponents {
+		n, err := config.GetInt("components:" + key + ":replicas")
+		if err != nil {
+			continue
+		}
+		if replicas == nil {
+			replicas = make(map[string]int)
+		}
+		replicas[key] = n
+	}
+	installTimeout, _ := config.GetDuration("components:install-timeout")
+	healthTimeout, _ := config.GetDuration("components:health-timeout")
+	var installTimeouts map[string]time.Duration
+	for _, key := range installTimeoutComponents {
+		timeout, err := config.GetDuration("components:" + key + ":install-timeout")
+		if err != nil {
+			continue
+		}
+		if installTimeouts == nil {
+			installTimeouts = make(map[string]time.Duration)
+		}
+		installTimeouts[key] = timeout
+	}
 	return &ComponentsConfig{
+		Replicas:        replicas,
+		InstallTimeout:  installTimeout,
+		InstallTimeouts: installTimeouts,
+		HealthTimeout:   healthTimeout,
+		ImageRegistry: ImageRegistryConfig{
+			URL:      registryURL,
+			Username: registryUsername,
+			Password: registryPassword,
+		},
 		TsuruAPIConfig: TsuruAPIConfig{
 			TargetName:       targetName,
 			RootUserEmail:    "admin@example.com",
@@ -70,6 +234,328 @@ type TsuruComponent interface {
 	Healthcheck(string) error
 }
 
+// DependentComponent is implemented by components that can only be installed
+// after other components, referenced by their names.
+type DependentComponent interface {
+	Dependencies() []string
+}
+
+// ServiceComponent is implemented by components running as a service of the
+// swarm cluster, returning the name of the service.
+type ServiceComponent interface {
+	ServiceName() string
+}
+
+// componentLogLines is the number of lines of the logs of a component shown
+// when it fails to install.
+var componentLogLines = 50
+
+// componentInstallWorkers is the maximum number of components installed
+// concurrently.
+var componentInstallWorkers = 3
+
+// installComponents installs the given components concurrently, respecting
+// the dependencies they declare. Components whose dependencies failed are not
+// installed. The returned error aggregates all failures. When logs is not
+// nil, the last lines of the logs of each component that fails to install are
+// written to it.
+func installComponents(w, logs io.Writer, cluster ServiceCluster, config *ComponentsConfig, components []TsuruComponent) error {
+	done := make(map[string]chan struct{}, len(components))
+	for _, component := range components {
+		done[component.Name()] = make(chan struct{})
+	}
+	for _, component := range components {
+		for _, dep := range componentDependencies(component) {
+			if _, ok := done[dep]; !ok {
+				return fmt.Errorf("%s depends on unknown component %q", component.Name(), dep)
+			}
+		}
+	}
+	if err := checkDependencyCycles(components); err != nil {
+		return err
+	}
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed = make(map[string]bool)
+		errs   []string
+	)
+	printf := func(format string, a ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, format, a...)
+	}
+	sem := make(chan struct{}, componentInstallWorkers)
+	for _, component := range components {
+		wg.Add(1)
+		go func(component TsuruComponent) {
+			defer wg.Done()
+			defer close(done[component.Name()])
+			for _, dep := range componentDependencies(component) {
+				<-done[dep]
+				mu.Lock()
+				depFailed := failed[dep]
+				if depFailed {
+					failed[component.Name()] = true
+					errs = append(errs, fmt.Sprintf("%s not installed: dependency %s failed", component.Name(), dep))
+				}
+				mu.Unlock()
+				if depFailed {
+					return
+				}
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			printf("Installing %s\n", component.Name())
+			if err := installComponent(cluster, config, component); err != nil {
+				mu.Lock()
+				failed[component.Name()] = true
+				errs = append(errs, fmt.Sprintf("error installing %s: %s", component.Name(), err))
+				mu.Unlock()
+				if logs != nil {
+					writeComponentLogs(logs, &mu, cluster, component)
+				}
+				return
+			}
+			printf("%s successfully installed!\n", component.Name())
+		}(component)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// installComponent installs the given component, failing when the
+// installation takes longer than its install timeout. The error includes the
+// last known status of the service of the component, as a slow image pull
+// would otherwise look like a hung installation. When the installation times
+// out, the operation in progress in the cluster is canceled, when supported,
+// and the following ones fail.
+func installComponent(cluster ServiceCluster, config *ComponentsConfig, component TsuruComponent) error {
+	timeout := config.installTimeout(componentKey(component))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		result <- component.Install(&cancelableCluster{ServiceCluster: cluster, ctx: ctx}, config)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		cancel()
+	}
+	var status string
+	info, err := component.Status(cluster)
+	switch {
+	case err != nil:
+		status = fmt.Sprintf("unable to get the status: %s", err)
+	case info == nil:
+		status = "unknown"
+	default:
+		status = fmt.Sprintf("%d/%d replicas running", info.RunningReplicas, info.Replicas)
+	}
+	return fmt.Errorf("timed out after %s installing %s (last known status: %s), the install timeout may be increased in components:install-timeout", timeout, component.Name(), status)
+}
+
+var errInstallCanceled = errors.New("installation canceled")
+
+// cancelableCluster is the cluster given to the installation of a component,
+// refusing new operations once ctx is done. The creation of services, which
+// may wait for the image to be pulled, is canceled along with ctx.
+type cancelableCluster struct {
+	ServiceCluster
+	ctx context.Context
+}
+
+func (c *cancelableCluster) canceled() bool {
+	select {
+	case <-c.ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *cancelableCluster) ServiceExec(service string, cmd []string, opts docker.StartExecOptions) error {
+	if c.canceled() {
+		return errInstallCanceled
+	}
+	return c.ServiceCluster.ServiceExec(service, cmd, opts)
+}
+
+func (c *cancelableCluster) CreateService(opts docker.CreateServiceOptions) error {
+	if c.canceled() {
+		return errInstallCanceled
+	}
+	if opts.Context == nil {
+		opts.Context = c.ctx
+	}
+	return c.ServiceCluster.CreateService(opts)
+}
+
+func (c *cancelableCluster) ServiceInfo(name string) (*ServiceInfo, error) {
+	if c.canceled() {
+		return nil, errInstallCanceled
+	}
+	return c.ServiceCluster.ServiceInfo(name)
+}
+
+func (c *cancelableCluster) ClusterInfo() ([]NodeInfo, error) {
+	if c.canceled() {
+		return nil, errInstallCanceled
+	}
+	return c.ServiceCluster.ClusterInfo()
+}
+
+func (c *cancelableCluster) PullImage(image string, auth docker.AuthConfiguration) error {
+	if c.canceled() {
+		return errInstallCanceled
+	}
+	return c.ServiceCluster.PullImage(image, auth)
+}
+
+func (c *cancelableCluster) ServiceLogs(service string, lines int) (string, error) {
+	if c.canceled() {
+		return "", errInstallCanceled
+	}
+	return c.ServiceCluster.ServiceLogs(service, lines)
+}
+
+// writeComponentLogs writes the last lines of the logs of the service of a
+// component to w, holding mu while writing.
+func writeComponentLogs(w io.Writer, mu *sync.Mutex, cluster ServiceCluster, component TsuruComponent) {
+	var (
+		logs string
+		err  error
+	)
+	if c, ok := component.(ServiceComponent); ok {
+		logs, err = cluster.ServiceLogs(c.ServiceName(), componentLogLines)
+	} else {
+		err = errors.New("not running as a service")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	switch {
+	case err != nil:
+		fmt.Fprintf(w, "Failed to retrieve the logs of %s: %s\n", component.Name(), err)
+	case strings.TrimSpace(logs) == "":
+		fmt.Fprintf(w, "No logs found for %s.\n", component.Name())
+	default:
+		fmt.Fprintf(w, "Last %d lines of the logs of %s:\n%s", componentLogLines, component.Name(), logs)
+		if !strings.HasSuffix(logs, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// componentsHealthInterval is how often the status of the components is
+// checked while waiting for them to be healthy.
+var componentsHealthInterval = 5 * time.Second
+
+// managedComponents returns the components that are run by the installer in
+// the swarm cluster, leaving out the external ones, whose address is set in
+// the configuration file. It must be called before the components are
+// installed, as installing them sets their addresses.
+func managedComponents(config *ComponentsConfig, components []TsuruComponent) []TsuruComponent {
+	var managed []TsuruComponent
+	for _, component := range components {
+		if config.address(componentKey(component)) == "" {
+			managed = append(managed, component)
+		}
+	}
+	return managed
+}
+
+// waitComponentsHealthy polls the status of the given components until all
+// their replicas are running, failing when some component is still unhealthy
+// after its health timeout in config. External components must not be given,
+// as they have no service in the cluster.
+func waitComponentsHealthy(w io.Writer, cluster ServiceCluster, config *ComponentsConfig, components []TsuruComponent) error {
+	if len(components) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "Waiting for the components to be healthy...")
+	start := time.Now()
+	pending := components
+	var unhealthy []string
+	for {
+		var waiting []TsuruComponent
+		for _, component := range pending {
+			var status string
+			info, err := component.Status(cluster)
+			switch {
+			case err != nil:
+				status = err.Error()
+			case info.Replicas == 0 || info.RunningReplicas < info.Replicas:
+				status = fmt.Sprintf("%d/%d replicas running", info.RunningReplicas, info.Replicas)
+			default:
+				continue
+			}
+			timeout := config.healthTimeout(componentKey(component))
+			if time.Since(start) >= timeout {
+				unhealthy = append(unhealthy, fmt.Sprintf("%s (%s after %s)", component.Name(), status, timeout))
+				continue
+			}
+			waiting = append(waiting, component)
+		}
+		if len(waiting) == 0 {
+			break
+		}
+		pending = waiting
+		time.Sleep(componentsHealthInterval)
+	}
+	if len(unhealthy) > 0 {
+		return fmt.Errorf("components not healthy: %s, the timeout may be increased in components:health-timeout", strings.Join(unhealthy, ", "))
+	}
+	return nil
+}
+
+func componentDependencies(component TsuruComponent) []string {
+	if c, ok := component.(DependentComponent); ok {
+		return c.Dependencies()
+	}
+	return nil
+}
+
+func checkDependencyCycles(components []TsuruComponent) error {
+	deps := make(map[string][]string, len(components))
+	for _, component := range components {
+		deps[component.Name()] = componentDependencies(component)
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(components))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle detected on component %s", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, component := range components {
+		if err := visit(component.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type MongoDB struct{}
 
 func (c *MongoDB) Name() string {
@@ -77,17 +563,17 @@ func (c *MongoDB) Name() string {
 }
 
 func (c *MongoDB) Install(cluster ServiceCluster, i *ComponentsConfig) error {
-	if i.ComponentAddress["mongo"] != "" {
-		return c.Healthcheck(i.ComponentAddress["mongo"])
+	if i.address("mongo") != "" {
+		return c.Healthcheck(i.address("mongo"))
 	}
-	err := cluster.CreateService(docker.CreateServiceOptions{
+	err := i.createService(cluster, docker.CreateServiceOptions{
 		ServiceSpec: swarm.ServiceSpec{
 			Annotations: swarm.Annotations{
 				Name: "mongo",
 			},
 			TaskTemplate: swarm.TaskSpec{
 				ContainerSpec: swarm.ContainerSpec{
-					Image: "mongo:latest",
+					Image: i.image("mongo:latest"),
 				},
 			},
 		},
@@ -95,12 +581,16 @@ func (c *MongoDB) Install(cluster ServiceCluster, i *ComponentsConfig) error {
 	if err != nil {
 		return err
 	}
-	i.ComponentAddress["mongo"] = "mongo"
+	i.setAddress("mongo", "mongo")
 	return nil
 }
 
+func (c *MongoDB) ServiceName() string {
+	return "mongo"
+}
+
 func (c *MongoDB) Status(cluster ServiceCluster) (*ServiceInfo, error) {
-	return cluster.ServiceInfo("mongo")
+	return cluster.ServiceInfo(c.ServiceName())
 }
 
 func (c *MongoDB) Healthcheck(addr string) error {
@@ -117,18 +607,23 @@ func (c *PlanB) Name() string {
 	return "PlanB"
 }
 
+func (c *PlanB) Dependencies() []string {
+	return []string{"Redis"}
+}
+
 func (c *PlanB) Install(cluster ServiceCluster, i *ComponentsConfig) error {
-	if i.ComponentAddress["planb"] != "" {
-		return c.Healthcheck(i.ComponentAddress["planb"])
+	if i.address("planb") != "" {
+		return c.Healthcheck(i.address("planb"))
 	}
-	err := cluster.CreateService(docker.CreateServiceOptions{
+	err := i.createService(cluster, docker.CreateServiceOptions{
 		ServiceSpec: swarm.ServiceSpec{
 			Annotations: swarm.Annotations{
 				Name: "planb",
 			},
+			Mode: i.serviceMode("planb"),
 			TaskTemplate: swarm.TaskSpec{
 				ContainerSpec: swarm.ContainerSpec{
-					Image: "tsuru/planb:latest",
+					Image: i.image("tsuru/planb:latest"),
 					Args:  []string{"--listen", ":8080", "--read-redis-host", "redis", "--write-redis-host", "redis"},
 				},
 			},
@@ -146,12 +641,16 @@ func (c *PlanB) Install(cluster ServiceCluster, i *ComponentsConfig) error {
 	if err != nil {
 		return err
 	}
-	i.ComponentAddress["planb"] = cluster.GetManager().IP
+	i.setAddress("planb", cluster.GetManager().IP)
 	return nil
 }
 
+func (c *PlanB) ServiceName() string {
+	return "planb"
+}
+
 func (c *PlanB) Status(cluster ServiceCluster) (*ServiceInfo, error) {
-	return cluster.ServiceInfo("planb")
+	return cluster.ServiceInfo(c.ServiceName())
 }
 
 func (c *PlanB) Healthcheck(addr string) error {
@@ -178,17 +677,17 @@ func (c *Redis) Name() string {
 }
 
 func (c *Redis) Install(cluster ServiceCluster, i *ComponentsConfig) error {
-	if i.ComponentAddress["redis"] != "" {
-		return c.Healthcheck(i.ComponentAddress["redis"])
+	if i.address("redis") != "" {
+		return c.Healthcheck(i.address("redis"))
 	}
-	err := cluster.CreateService(docker.CreateServiceOptions{
+	err := i.createService(cluster, docker.CreateServiceOptions{
 		ServiceSpec: swarm.ServiceSpec{
 			Annotations: swarm.Annotations{
 				Name: "redis",
 			},
 			TaskTemplate: swarm.TaskSpec{
 				ContainerSpec: swarm.ContainerSpec{
-					Image: "redis:latest",
+					Image: i.image("redis:latest"),
 				},
 			},
 		},
@@ -196,12 +695,16 @@ func (c *Redis) Install(cluster ServiceCluster, i *ComponentsConfig) error {
 	if err != nil {
 		return err
 	}
-	i.ComponentAddress["redis"] = "redis"
+	i.setAddress("redis", "redis")
 	return nil
 }
 
+func (c *Redis) ServiceName() string {
+	return "redis"
+}
+
 func (c *Redis) Status(cluster ServiceCluster) (*ServiceInfo, error) {
-	return cluster.ServiceInfo("redis")
+	return cluster.ServiceInfo(c.ServiceName())
 }
 
 func (c *Redis) Healthcheck(addr string) error {
@@ -219,17 +722,18 @@ func (c *Registry) Name() string {
 }
 
 func (c *Registry) Install(cluster ServiceCluster, i *ComponentsConfig) error {
-	if i.ComponentAddress["registry"] != "" {
-		return c.Healthcheck(i.ComponentAddress["registry"])
+	if i.address("registry") != "" {
+		return c.Healthcheck(i.address("registry"))
 	}
-	err := cluster.CreateService(docker.CreateServiceOptions{
+	err := i.createService(cluster, docker.CreateServiceOptions{
 		ServiceSpec: swarm.ServiceSpec{
 			Annotations: swarm.Annotations{
 				Name: "registry",
 			},
+			Mode: i.serviceMode("registry"),
 			TaskTemplate: swarm.TaskSpec{
 				ContainerSpec: swarm.ContainerSpec{
-					Image: "registry:2",
+					Image: i.image("registry:2"),
 					Env: []string{
 						"REGISTRY_STORAGE_FILESYSTEM_ROOTDIRECTORY=/var/lib/registry",
 						fmt.Sprintf("REGISTRY_HTTP_TLS_CERTIFICATE=/certs/%s:5000/registry-cert.pem", cluster.GetManager().IP),
@@ -265,12 +769,16 @@ func (c *Registry) Install(cluster ServiceCluster, i *ComponentsConfig) error {
 	if err != nil {
 		return err
 	}
-	i.ComponentAddress["registry"] = cluster.GetManager().IP
+	i.setAddress("registry", cluster.GetManager().IP)
 	return nil
 }
 
+func (c *Registry) ServiceName() string {
+	return "registry"
+}
+
 func (c *Registry) Status(cluster ServiceCluster) (*ServiceInfo, error) {
-	return cluster.ServiceInfo("registry")
+	return cluster.ServiceInfo(c.ServiceName())
 }
 
 func (c *Registry) Healthcheck(addr string) error {
@@ -296,6 +804,10 @@ func (c *TsuruAPI) Name() string {
 	return "Tsuru API"
 }
 
+func (c *TsuruAPI) Dependencies() []string {
+	return []string{"MongoDB", "Redis", "PlanB", "Docker Registry"}
+}
+
 func parseAddress(address, defaultPort string) (addr, port string) {
 	parts := strings.Split(address, ":")
 	if len(parts) == 1 {
@@ -308,18 +820,19 @@ func parseAddress(address, defaultPort string) (addr, port string) {
 }
 
 func (c *TsuruAPI) Install(cluster ServiceCluster, i *ComponentsConfig) error {
-	mongo, mongoPort := parseAddress(i.ComponentAddress["mongo"], "27017")
-	redis, redisPort := parseAddress(i.ComponentAddress["redis"], "6379")
-	registry, registryPort := parseAddress(i.ComponentAddress["registry"], "5000")
-	planb, _ := parseAddress(i.ComponentAddress["planb"], "80")
-	err := cluster.CreateService(docker.CreateServiceOptions{
+	mongo, mongoPort := parseAddress(i.address("mongo"), "27017")
+	redis, redisPort := parseAddress(i.address("redis"), "6379")
+	registry, registryPort := parseAddress(i.address("registry"), "5000")
+	planb, _ := parseAddress(i.address("planb"), "80")
+	err := i.createService(cluster, docker.CreateServiceOptions{
 		ServiceSpec: swarm.ServiceSpec{
 			Annotations: swarm.Annotations{
 				Name: "tsuru",
 			},
+			Mode: i.serviceMode("api"),
 			TaskTemplate: swarm.TaskSpec{
 				ContainerSpec: swarm.ContainerSpec{
-					Image: "tsuru/api:v1",
+					Image: i.image("tsuru/api:v1"),
 					Env: []string{fmt.Sprintf("MONGODB_ADDR=%s", mongo),
 						fmt.Sprintf("MONGODB_PORT=%s", mongoPort),
 						fmt.Sprintf("REDIS_ADDR=%s", redis),
@@ -366,8 +879,12 @@ func (c *TsuruAPI) Install(cluster ServiceCluster, i *ComponentsConfig) error {
 	return c.setupRootUser(cluster, i.RootUserEmail, i.RootUserPassword)
 }
 
+func (c *TsuruAPI) ServiceName() string {
+	return "tsuru"
+}
+
 func (c *TsuruAPI) Status(cluster ServiceCluster) (*ServiceInfo, error) {
-	return cluster.ServiceInfo("tsuru")
+	return cluster.ServiceInfo(c.ServiceName())
 }
 
 func (c *TsuruAPI) Healthcheck(addr string) error {
@@ -395,20 +912,26 @@ type TsuruSetupOptions struct {
 	NodesAddr  []string
 }
 
-func SetupTsuru(opts TsuruSetupOptions) error {
-	manager := cmd.BuildBaseManager("setup-client", "0.0.0", "", nil)
-	provisioners, err := provision.Registry()
+// registerNodes adds the given docker nodes to the pool in the tsuru API.
+func registerNodes(context *cmd.Context, client *cmd.Client, nodesAddr []string, pool string) error {
+	nodeAdd := admin.AddNodeCmd{}
+	err := nodeAdd.Flags().Parse(true, []string{"--register"})
 	if err != nil {
 		return err
 	}
-	for _, p := range provisioners {
-		if c, ok := p.(cmd.AdminCommandable); ok {
-			commands := c.AdminCommands()
-			for _, comm := range commands {
-				manager.Register(comm)
-			}
+	for _, n := range nodesAddr {
+		fmt.Fprintf(context.Stdout, "adding node %s\n", n)
+		context.Args = []string{"docker", fmt.Sprintf("address=%s", n), fmt.Sprintf("pool=%s", pool)}
+		err = nodeAdd.Run(context, client)
+		if err != nil {
+			return fmt.Errorf("failed to register node: %s", err)
 		}
 	}
+	return nil
+}
+
+func SetupTsuru(opts TsuruSetupOptions) error {
+	manager := cmd.BuildBaseManager("setup-client", "0.0.0", "", nil)
 	fmt.Fprintln(os.Stdout, "adding target")
 	client := cmd.NewClient(&http.Client{}, nil, manager)
 	context := cmd.Context{
@@ -419,7 +942,7 @@ func SetupTsuru(opts TsuruSetupOptions) error {
 	context.RawOutput()
 	targetadd := manager.Commands["target-add"]
 	t, _ := targetadd.(cmd.FlaggedCommand)
-	err = t.Flags().Parse(true, []string{"-s"})
+	err := t.Flags().Parse(true, []string{"-s"})
 	if err != nil {
 		return err
 	}
@@ -447,19 +970,10 @@ func SetupTsuru(opts TsuruSetupOptions) error {
 	if err != nil {
 		return fmt.Errorf("failed to add pool: %s", err)
 	}
-	nodeAdd := admin.AddNodeCmd{}
-	err = nodeAdd.Flags().Parse(true, []string{"--register"})
+	err = registerNodes(&context, client, opts.NodesAddr, "theonepool")
 	if err != nil {
 		return err
 	}
-	for _, n := range opts.NodesAddr {
-		fmt.Printf("adding node %s\n", n)
-		context.Args = []string{"docker", fmt.Sprintf("address=%s", n), "pool=theonepool"}
-		err = nodeAdd.Run(&context, client)
-		if err != nil {
-			return fmt.Errorf("failed to register node: %s", err)
-		}
-	}
 	fmt.Fprintln(os.Stdout, "adding platform")
 	platformAdd := admin.PlatformAdd{}
 	context.Args = []string{"python"}
@@ -517,18 +1031,6 @@ func installDashboard(client *cmd.Client) error {
 
 func (c *TsuruAPI) Uninstall(installation string) error {
 	manager := cmd.BuildBaseManager("uninstall-client", "0.0.0", "", nil)
-	provisioners, err := provision.Registry()
-	if err != nil {
-		return err
-	}
-	for _, p := range provisioners {
-		if c, ok := p.(cmd.AdminCommandable); ok {
-			commands := c.AdminCommands()
-			for _, cmd := range commands {
-				manager.Register(cmd)
-			}
-		}
-	}
 	fmt.Fprint(os.Stdout, "removing target\n")
 	client := cmd.NewClient(&http.Client{}, nil, manager)
 	context := cmd.Context{
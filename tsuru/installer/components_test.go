@@ -5,7 +5,9 @@
 package installer
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -14,18 +16,23 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/docker/engine-api/types/swarm"
 	"github.com/fsouza/go-dockerclient"
 	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru-client/tsuru/installer/dm"
-	"github.com/tsuru/tsuru/cmd"
 	_ "github.com/tsuru/tsuru/provision/docker"
 	"gopkg.in/check.v1"
 )
 
 type FakeServiceCluster struct {
 	Services chan<- docker.CreateServiceOptions
+	Pulls    []string
+	Auth     docker.AuthConfiguration
+	Logs     map[string]string
 }
 
 func (c *FakeServiceCluster) GetManager() *dm.Machine {
@@ -47,6 +54,20 @@ func (c *FakeServiceCluster) ServiceInfo(service string) (*ServiceInfo, error) {
 	return &ServiceInfo{Name: service, Replicas: 1, Ports: []string{"8080"}}, nil
 }
 
+func (c *FakeServiceCluster) PullImage(image string, auth docker.AuthConfiguration) error {
+	c.Pulls = append(c.Pulls, image)
+	c.Auth = auth
+	return nil
+}
+
+func (c *FakeServiceCluster) ServiceLogs(service string, lines int) (string, error) {
+	logs, ok := c.Logs[service]
+	if !ok {
+		return "", fmt.Errorf("no task container found for service %s", service)
+	}
+	return logs, nil
+}
+
 func (c *FakeServiceCluster) ClusterInfo() ([]NodeInfo, error) {
 	return []NodeInfo{{IP: "127.0.0.1", State: "running", Manager: true}}, nil
 }
@@ -103,6 +124,22 @@ func (s *S) TestInstallComponentsDefaultConfig(c *check.C) {
 	c.Assert(installConfig.ComponentAddress["planb"], check.Equals, "127.0.0.1")
 }
 
+func (s *S) TestInstallComponentsReplicas(c *check.C) {
+	services := make(chan docker.CreateServiceOptions, 1)
+	fakeCluster := &FakeServiceCluster{Services: services}
+	installConfig := NewInstallConfig("test")
+	installConfig.Replicas = map[string]int{"planb": 3}
+	err := (&PlanB{}).Install(fakeCluster, installConfig)
+	c.Assert(err, check.IsNil)
+	opts := <-services
+	c.Assert(opts.Mode.Replicated, check.NotNil)
+	c.Assert(*opts.Mode.Replicated.Replicas, check.Equals, uint64(3))
+	err = (&Redis{}).Install(fakeCluster, installConfig)
+	c.Assert(err, check.IsNil)
+	opts = <-services
+	c.Assert(opts.Mode.Replicated, check.IsNil)
+}
+
 func (s *S) TestInstallPlanbHostPortBindings(c *check.C) {
 	services := make(chan docker.CreateServiceOptions, 1)
 	fakeCluster := &FakeServiceCluster{Services: services}
@@ -276,3 +313,340 @@ func (s *S) TestInstallTsuruApiWithCustomComponentsAddress(c *check.C) {
 	}
 	c.Assert(apiConf.TaskTemplate.ContainerSpec.Env, check.DeepEquals, expected)
 }
+
+type fakeComponent struct {
+	name    string
+	deps    []string
+	err     error
+	mu      *sync.Mutex
+	events  *[]string
+	running *int
+	maxRun  *int
+}
+
+func (f *fakeComponent) Name() string {
+	return f.name
+}
+
+func (f *fakeComponent) Dependencies() []string {
+	return f.deps
+}
+
+func (f *fakeComponent) Install(cluster ServiceCluster, i *ComponentsConfig) error {
+	f.mu.Lock()
+	*f.events = append(*f.events, "start "+f.name)
+	*f.running++
+	if *f.running > *f.maxRun {
+		*f.maxRun = *f.running
+	}
+	f.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	f.mu.Lock()
+	*f.running--
+	*f.events = append(*f.events, "end "+f.name)
+	f.mu.Unlock()
+	return f.err
+}
+
+func (f *fakeComponent) Status(cluster ServiceCluster) (*ServiceInfo, error) {
+	return nil, nil
+}
+
+func (f *fakeComponent) Healthcheck(string) error {
+	return nil
+}
+
+type fakeComponents struct {
+	mu      sync.Mutex
+	events  []string
+	running int
+	maxRun  int
+}
+
+func (f *fakeComponents) new(name string, err error, deps ...string) TsuruComponent {
+	return &fakeComponent{name: name, deps: deps, err: err, mu: &f.mu, events: &f.events, running: &f.running, maxRun: &f.maxRun}
+}
+
+func (f *fakeComponents) index(event string) int {
+	for i, e := range f.events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *S) TestInstallComponentsRespectsDependencies(c *check.C) {
+	f := &fakeComponents{}
+	components := []TsuruComponent{
+		f.new("api", nil, "db", "cache", "router"),
+		f.new("router", nil, "cache"),
+		f.new("db", nil),
+		f.new("cache", nil),
+	}
+	var buf bytes.Buffer
+	err := installComponents(&buf, nil, &FakeServiceCluster{}, NewInstallConfig("test"), components)
+	c.Assert(err, check.IsNil)
+	c.Assert(f.events, check.HasLen, 8)
+	c.Assert(f.index("end cache") < f.index("start router"), check.Equals, true)
+	c.Assert(f.index("end router") < f.index("start api"), check.Equals, true)
+	c.Assert(f.index("end db") < f.index("start api"), check.Equals, true)
+	c.Assert(f.maxRun > 1, check.Equals, true)
+	c.Assert(strings.Contains(buf.String(), "api successfully installed!\n"), check.Equals, true)
+}
+
+func (s *S) TestInstallComponentsBoundedWorkers(c *check.C) {
+	defer func(n int) { componentInstallWorkers = n }(componentInstallWorkers)
+	componentInstallWorkers = 2
+	f := &fakeComponents{}
+	var components []TsuruComponent
+	for i := 0; i < 6; i++ {
+		components = append(components, f.new(fmt.Sprintf("c%d", i), nil))
+	}
+	err := installComponents(ioutil.Discard, nil, &FakeServiceCluster{}, NewInstallConfig("test"), components)
+	c.Assert(err, check.IsNil)
+	c.Assert(f.maxRun, check.Equals, 2)
+}
+
+func (s *S) TestInstallComponentsAggregatesErrors(c *check.C) {
+	f := &fakeComponents{}
+	components := []TsuruComponent{
+		f.new("db", errors.New("db is down")),
+		f.new("cache", errors.New("cache is down")),
+		f.new("api", nil, "db"),
+		f.new("router", nil),
+	}
+	err := installComponents(ioutil.Discard, nil, &FakeServiceCluster{}, NewInstallConfig("test"), components)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "api not installed: dependency db failed\nerror installing cache: cache is down\nerror installing db: db is down")
+	c.Assert(f.index("start api"), check.Equals, -1)
+	c.Assert(f.index("end router"), check.Not(check.Equals), -1)
+}
+
+type fakeServiceComponent struct {
+	TsuruComponent
+	service string
+}
+
+func (f *fakeServiceComponent) ServiceName() string {
+	return f.service
+}
+
+func (s *S) TestInstallComponentsShowsLogsOfFailures(c *check.C) {
+	f := &fakeComponents{}
+	components := []TsuruComponent{
+		&fakeServiceComponent{TsuruComponent: f.new("db", errors.New("db is down")), service: "mongo"},
+		&fakeServiceComponent{TsuruComponent: f.new("cache", errors.New("cache is down")), service: "redis"},
+		f.new("router", errors.New("router is down")),
+		&fakeServiceComponent{TsuruComponent: f.new("api", nil), service: "tsuru"},
+	}
+	cluster := &FakeServiceCluster{Logs: map[string]string{
+		"mongo": "starting\nout of disk space",
+		"tsuru": "listening\n",
+	}}
+	var logs bytes.Buffer
+	err := installComponents(ioutil.Discard, &logs, cluster, NewInstallConfig("test"), components)
+	c.Assert(err, check.NotNil)
+	c.Assert(strings.Contains(logs.String(), "Last 50 lines of the logs of db:\nstarting\nout of disk space\n"), check.Equals, true)
+	c.Assert(strings.Contains(logs.String(), "Failed to retrieve the logs of cache: no task container found for service redis\n"), check.Equals, true)
+	c.Assert(strings.Contains(logs.String(), "Failed to retrieve the logs of router: not running as a service\n"), check.Equals, true)
+	c.Assert(strings.Contains(logs.String(), "listening"), check.Equals, false)
+}
+
+func (s *S) TestInstallComponentsInvalidDependencies(c *check.C) {
+	f := &fakeComponents{}
+	err := installComponents(ioutil.Discard, nil, &FakeServiceCluster{}, NewInstallConfig("test"), []TsuruComponent{
+		f.new("api", nil, "db"),
+	})
+	c.Assert(err, check.ErrorMatches, `api depends on unknown component "db"`)
+	err = installComponents(ioutil.Discard, nil, &FakeServiceCluster{}, NewInstallConfig("test"), []TsuruComponent{
+		f.new("api", nil, "db"),
+		f.new("db", nil, "api"),
+	})
+	c.Assert(err, check.ErrorMatches, "dependency cycle detected on component api")
+	c.Assert(f.events, check.HasLen, 0)
+}
+
+type hangingComponent struct {
+	release chan struct{}
+	result  chan error
+}
+
+func (h *hangingComponent) Name() string {
+	return "PlanB"
+}
+
+func (h *hangingComponent) Install(cluster ServiceCluster, i *ComponentsConfig) error {
+	<-h.release
+	err := cluster.CreateService(docker.CreateServiceOptions{})
+	if h.result != nil {
+		h.result <- err
+	}
+	return err
+}
+
+func (h *hangingComponent) Status(cluster ServiceCluster) (*ServiceInfo, error) {
+	return &ServiceInfo{Name: "planb", Replicas: 2}, nil
+}
+
+func (h *hangingComponent) Healthcheck(string) error {
+	return nil
+}
+
+func (s *S) TestInstallComponentsTimeout(c *check.C) {
+	h := &hangingComponent{release: make(chan struct{})}
+	defer close(h.release)
+	f := &fakeComponents{}
+	config := NewInstallConfig("test")
+	config.InstallTimeout = time.Minute
+	config.InstallTimeouts = map[string]time.Duration{"planb": 10 * time.Millisecond}
+	err := installComponents(ioutil.Discard, nil, &FakeServiceCluster{}, config, []TsuruComponent{h, f.new("api", nil, "PlanB")})
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "api not installed: dependency PlanB failed\nerror installing PlanB: timed out after 10ms installing PlanB (last known status: 0/2 replicas running), the install timeout may be increased in components:install-timeout")
+	c.Assert(f.index("start api"), check.Equals, -1)
+}
+
+func (s *S) TestInstallComponentTimeoutCancelsTheInstallation(c *check.C) {
+	h := &hangingComponent{release: make(chan struct{}), result: make(chan error, 1)}
+	config := NewInstallConfig("test")
+	config.InstallTimeouts = map[string]time.Duration{"planb": 10 * time.Millisecond}
+	err := installComponent(&FakeServiceCluster{}, config, h)
+	c.Assert(err, check.ErrorMatches, "timed out after 10ms installing PlanB .*")
+	close(h.release)
+	c.Assert(<-h.result, check.Equals, errInstallCanceled)
+}
+
+func (s *S) TestInstallComponentCreatesServicesWithTheContext(c *check.C) {
+	services := make(chan docker.CreateServiceOptions, 1)
+	err := installComponent(&FakeServiceCluster{Services: services}, NewInstallConfig("test"), &Redis{})
+	c.Assert(err, check.IsNil)
+	opts := <-services
+	c.Assert(opts.Context, check.NotNil)
+}
+
+func (s *S) TestComponentsConfigInstallTimeout(c *check.C) {
+	config := &ComponentsConfig{}
+	c.Assert(config.installTimeout("api"), check.Equals, defaultComponentInstallTimeout)
+	config.InstallTimeout = time.Minute
+	config.InstallTimeouts = map[string]time.Duration{"api": time.Hour}
+	c.Assert(config.installTimeout("api"), check.Equals, time.Hour)
+	c.Assert(config.installTimeout("mongo"), check.Equals, time.Minute)
+}
+
+func (s *S) TestTsuruComponentsDependencies(c *check.C) {
+	err := checkDependencyCycles(TsuruComponents)
+	c.Assert(err, check.IsNil)
+	names := make(map[string]bool)
+	for _, component := range TsuruComponents {
+		names[component.Name()] = true
+	}
+	for _, component := range TsuruComponents {
+		for _, dep := range componentDependencies(component) {
+			c.Assert(names[dep], check.Equals, true)
+		}
+	}
+}
+
+type healthComponent struct {
+	fakeComponent
+	running []int
+	calls   int
+}
+
+func (h *healthComponent) Status(cluster ServiceCluster) (*ServiceInfo, error) {
+	running := h.running[len(h.running)-1]
+	if h.calls < len(h.running) {
+		running = h.running[h.calls]
+	}
+	h.calls++
+	return &ServiceInfo{Name: h.name, Replicas: 1, RunningReplicas: running}, nil
+}
+
+func (s *S) TestWaitComponentsHealthy(c *check.C) {
+	defer func(i time.Duration) { componentsHealthInterval = i }(componentsHealthInterval)
+	componentsHealthInterval = time.Millisecond
+	component := &healthComponent{fakeComponent: fakeComponent{name: "api"}, running: []int{0, 0, 1}}
+	err := waitComponentsHealthy(ioutil.Discard, &FakeServiceCluster{}, &ComponentsConfig{}, []TsuruComponent{component})
+	c.Assert(err, check.IsNil)
+	c.Assert(component.calls, check.Equals, 3)
+}
+
+func (s *S) TestWaitComponentsHealthyTimeout(c *check.C) {
+	defer func(i time.Duration) { componentsHealthInterval = i }(componentsHealthInterval)
+	componentsHealthInterval = time.Millisecond
+	components := []TsuruComponent{
+		&healthComponent{fakeComponent: fakeComponent{name: "api"}, running: []int{1}},
+		&healthComponent{fakeComponent: fakeComponent{name: "db"}, running: []int{0}},
+	}
+	config := &ComponentsConfig{HealthTimeout: 10 * time.Millisecond}
+	err := waitComponentsHealthy(ioutil.Discard, &FakeServiceCluster{}, config, components)
+	c.Assert(err, check.ErrorMatches, `components not healthy: db \(0/1 replicas running after 10ms\), the timeout may be increased in components:health-timeout`)
+}
+
+func (s *S) TestWaitComponentsHealthyComponentTimeout(c *check.C) {
+	defer func(i time.Duration) { componentsHealthInterval = i }(componentsHealthInterval)
+	componentsHealthInterval = time.Millisecond
+	api := &healthComponent{fakeComponent: fakeComponent{name: "api"}, running: []int{0, 0, 1}}
+	db := &healthComponent{fakeComponent: fakeComponent{name: "db"}, running: []int{0}}
+	config := &ComponentsConfig{
+		HealthTimeout:   time.Hour,
+		InstallTimeouts: map[string]time.Duration{"db": 10 * time.Millisecond},
+	}
+	err := waitComponentsHealthy(ioutil.Discard, &FakeServiceCluster{}, config, []TsuruComponent{api, db})
+	c.Assert(err, check.ErrorMatches, `components not healthy: db \(0/1 replicas running after 10ms\), .*`)
+	c.Assert(api.calls, check.Equals, 3)
+}
+
+func (s *S) TestWaitComponentsHealthyWithoutComponents(c *check.C) {
+	var buf bytes.Buffer
+	err := waitComponentsHealthy(&buf, &FakeServiceCluster{}, &ComponentsConfig{}, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, "")
+}
+
+func (s *S) TestComponentsConfigHealthTimeout(c *check.C) {
+	config := &ComponentsConfig{}
+	c.Assert(config.healthTimeout("api"), check.Equals, defaultComponentsHealthTimeout)
+	config.HealthTimeout = time.Minute
+	config.InstallTimeouts = map[string]time.Duration{"api": time.Hour}
+	c.Assert(config.healthTimeout("api"), check.Equals, time.Hour)
+	c.Assert(config.healthTimeout("mongo"), check.Equals, time.Minute)
+}
+
+func (s *S) TestManagedComponents(c *check.C) {
+	config := &ComponentsConfig{ComponentAddress: map[string]string{
+		"mongo": "mongodb.example.com:27017",
+		"planb": "10.0.0.5",
+	}}
+	managed := managedComponents(config, TsuruComponents)
+	var names []string
+	for _, component := range managed {
+		names = append(names, component.Name())
+	}
+	c.Assert(names, check.DeepEquals, []string{"Redis", "Docker Registry", "Tsuru API"})
+}
+
+func (s *S) TestInstallComponentWithImageRegistry(c *check.C) {
+	services := make(chan docker.CreateServiceOptions, 1)
+	cluster := &FakeServiceCluster{Services: services}
+	conf := NewInstallConfig("test")
+	conf.ImageRegistry = ImageRegistryConfig{URL: "registry.example.com/", Username: "user", Password: "secret"}
+	err := (&Redis{}).Install(cluster, conf)
+	c.Assert(err, check.IsNil)
+	opts := <-services
+	c.Assert(opts.TaskTemplate.ContainerSpec.Image, check.Equals, "registry.example.com/redis:latest")
+	c.Assert(cluster.Pulls, check.DeepEquals, []string{"registry.example.com/redis:latest"})
+	c.Assert(cluster.Auth, check.DeepEquals, docker.AuthConfiguration{Username: "user", Password: "secret", ServerAddress: "registry.example.com"})
+}
+
+func (s *S) TestInstallComponentWithImageRegistryWithoutCredentials(c *check.C) {
+	services := make(chan docker.CreateServiceOptions, 1)
+	cluster := &FakeServiceCluster{Services: services}
+	conf := NewInstallConfig("test")
+	conf.ImageRegistry = ImageRegistryConfig{URL: "registry.example.com"}
+	err := (&Redis{}).Install(cluster, conf)
+	c.Assert(err, check.IsNil)
+	opts := <-services
+	c.Assert(opts.TaskTemplate.ContainerSpec.Image, check.Equals, "registry.example.com/redis:latest")
+	c.Assert(cluster.Pulls, check.IsNil)
+}
@@ -13,6 +13,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 
 	"github.com/docker/machine/libmachine"
@@ -21,7 +22,7 @@ import (
 	"github.com/docker/machine/libmachine/drivers/rpc"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/mcnutils"
-	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 )
 
 var (
@@ -44,6 +45,10 @@ type DockerMachine struct {
 	machinesCount    uint64
 	globalDriverOpts DriverOpts
 	dockerHubMirror  string
+	engineEnv        []string
+	sshUser          string
+	sshPort          int
+	sshBastion       *SSHBastion
 }
 
 type DockerMachineConfig struct {
@@ -52,6 +57,28 @@ type DockerMachineConfig struct {
 	Name            string
 	DriverOpts      DriverOpts
 	DockerHubMirror string
+	SSHUser         string
+	SSHPort         int
+	SSHBastion      *SSHBastion
+	HTTPProxy       string
+	HTTPSProxy      string
+	NoProxy         string
+}
+
+// proxyEnv returns the environment variables used by the docker engine to
+// reach the network through the configured proxies.
+func (c *DockerMachineConfig) proxyEnv() []string {
+	var env []string
+	if c.HTTPProxy != "" {
+		env = append(env, "HTTP_PROXY="+c.HTTPProxy)
+	}
+	if c.HTTPSProxy != "" {
+		env = append(env, "HTTPS_PROXY="+c.HTTPSProxy)
+	}
+	if c.NoProxy != "" {
+		env = append(env, "NO_PROXY="+c.NoProxy)
+	}
+	return env
 }
 
 type MachineProvisioner interface {
@@ -85,6 +112,10 @@ func NewDockerMachine(config *DockerMachineConfig) (*DockerMachine, error) {
 		client:           libmachine.NewClient(storePath, certsPath),
 		globalDriverOpts: config.DriverOpts,
 		dockerHubMirror:  config.DockerHubMirror,
+		engineEnv:        config.proxyEnv(),
+		sshUser:          config.SSHUser,
+		sshPort:          config.SSHPort,
+		sshBastion:       config.SSHBastion,
 	}, nil
 }
 
@@ -116,6 +147,8 @@ func (d *DockerMachine) CreateMachine(driverOpts map[string]interface{}) (*Machi
 	rawDriver, err := json.Marshal(&drivers.BaseDriver{
 		MachineName: d.generateMachineName(),
 		StorePath:   d.storePath,
+		SSHUser:     d.sshUser,
+		SSHPort:     d.sshPort,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("Error creating docker-machine driver: %s", err)
@@ -140,6 +173,7 @@ func (d *DockerMachine) CreateMachine(driverOpts map[string]interface{}) (*Machi
 		Host:       host,
 		Address:    fmt.Sprintf("https://%s:%d", ip, dockerHTTPSPort),
 		DriverOpts: DriverOpts(driverOpts),
+		Bastion:    d.sshBastion,
 	}
 	if host.AuthOptions() != nil {
 		host.AuthOptions().ServerCertSANs = append(host.AuthOptions().ServerCertSANs, m.GetPrivateIP())
@@ -159,11 +193,21 @@ func (d *DockerMachine) configureHost(h *host.Host) {
 	if d.dockerHubMirror != "" {
 		h.HostOptions.EngineOptions.RegistryMirror = []string{d.dockerHubMirror}
 	}
+	if len(d.engineEnv) > 0 {
+		h.HostOptions.EngineOptions.Env = append(h.HostOptions.EngineOptions.Env, d.engineEnv...)
+	}
 }
 
+// generateMachineName returns the next machine name of the installation,
+// skipping the names of machines already created, so hosts can be added to
+// an existing installation.
 func (d *DockerMachine) generateMachineName() string {
-	atomic.AddUint64(&d.machinesCount, 1)
-	return fmt.Sprintf("%s-%d", d.Name, atomic.LoadUint64(&d.machinesCount))
+	for {
+		name := fmt.Sprintf("%s-%d", d.Name, atomic.AddUint64(&d.machinesCount, 1))
+		if exists, err := d.client.Exists(name); err != nil || !exists {
+			return name
+		}
+	}
 }
 
 func (d *DockerMachine) uploadRegistryCertificate(host SSHTarget) error {
@@ -249,6 +293,7 @@ func (d *DockerMachine) configureDriver(driver drivers.Driver, driverOpts Driver
 		mergedOpts[k] = v
 	}
 	opts := &rpcdriver.RPCFlags{Values: mergedOpts}
+	d.configureSSH(driver, opts)
 	for _, c := range driver.GetCreateFlags() {
 		_, ok := opts.Values[c.String()]
 		if !ok {
@@ -268,6 +313,48 @@ func (d *DockerMachine) configureDriver(driver drivers.Driver, driverOpts Driver
 	return nil
 }
 
+// configureSSH sets the SSH user and port flags of the driver (like
+// amazonec2-ssh-user and amazonec2-ssh-port) from the installer config,
+// unless they were explicitly set in the driver options.
+func (d *DockerMachine) configureSSH(driver drivers.Driver, opts *rpcdriver.RPCFlags) {
+	for _, c := range driver.GetCreateFlags() {
+		name := c.String()
+		if _, ok := opts.Values[name]; ok {
+			continue
+		}
+		if d.sshUser != "" && strings.HasSuffix(name, "-ssh-user") {
+			opts.Values[name] = d.sshUser
+		}
+		if d.sshPort != 0 && strings.HasSuffix(name, "-ssh-port") {
+			opts.Values[name] = d.sshPort
+		}
+	}
+}
+
+// LoadMachine loads a machine of the installation by its name. The IP and
+// address of the machine are left empty when it's not running.
+func (d *DockerMachine) LoadMachine(name string) (*Machine, error) {
+	h, err := d.client.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	m := &Machine{
+		CAPath:  d.certsPath,
+		Host:    h,
+		Bastion: d.sshBastion,
+	}
+	if ip, err := h.Driver.GetIP(); err == nil {
+		m.IP = ip
+		m.Address = fmt.Sprintf("https://%s:%d", ip, dockerHTTPSPort)
+	}
+	return m, nil
+}
+
+// ListMachines returns the names of the machines of the installation.
+func (d *DockerMachine) ListMachines() ([]string, error) {
+	return d.client.List()
+}
+
 func (d *DockerMachine) DeleteAll() error {
 	hosts, err := d.client.List()
 	if err != nil {
@@ -15,6 +15,7 @@ import (
 
 	"github.com/docker/machine/drivers/amazonec2"
 	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/drivers/generic"
 	"github.com/docker/machine/libmachine/engine"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/persist/persisttest"
@@ -182,7 +183,7 @@ func (s *S) TestCreateMachine(c *check.C) {
 	defer server.Stop()
 	dm, err := NewDockerMachine(DefaultDockerMachineConfig)
 	c.Assert(err, check.IsNil)
-	fakeAPI := &fakeMachineAPI{}
+	fakeAPI := &fakeMachineAPI{FakeStore: &persisttest.FakeStore{}}
 	dm.client = fakeAPI
 	dm.certsPath = s.TLSCertsPath.RootDir
 	machine, err := dm.CreateMachine(map[string]interface{}{})
@@ -286,3 +287,121 @@ func (s *S) TestTempDockerMachineNewHost(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(string(b), check.Equals, "my-ssh-key")
 }
+
+func (s *S) TestConfigureDriverSSH(c *check.C) {
+	dm := &DockerMachine{
+		globalDriverOpts: DriverOpts{"generic-ip-address": "10.0.0.1"},
+		sshUser:          "admin",
+		sshPort:          2222,
+	}
+	driver := generic.NewDriver("", "")
+	err := dm.configureDriver(driver, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(driver.GetSSHUsername(), check.Equals, "admin")
+	port, err := driver.GetSSHPort()
+	c.Assert(err, check.IsNil)
+	c.Assert(port, check.Equals, 2222)
+	driver = generic.NewDriver("", "")
+	err = dm.configureDriver(driver, map[string]interface{}{"generic-ssh-user": "centos"})
+	c.Assert(err, check.IsNil)
+	c.Assert(driver.GetSSHUsername(), check.Equals, "centos")
+	port, err = driver.GetSSHPort()
+	c.Assert(err, check.IsNil)
+	c.Assert(port, check.Equals, 2222)
+}
+
+func (s *S) TestConfigureDriverSSHDefaults(c *check.C) {
+	dm := &DockerMachine{globalDriverOpts: DriverOpts{"generic-ip-address": "10.0.0.1"}}
+	driver := generic.NewDriver("", "")
+	err := dm.configureDriver(driver, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(driver.GetSSHUsername(), check.Equals, "root")
+	port, err := driver.GetSSHPort()
+	c.Assert(err, check.IsNil)
+	c.Assert(port, check.Equals, 22)
+}
+
+func (s *S) TestConfigureHostProxy(c *check.C) {
+	config := &DockerMachineConfig{
+		HTTPProxy:  "http://proxy:3128",
+		HTTPSProxy: "http://proxy:3129",
+		NoProxy:    "localhost,10.0.0.0/8",
+	}
+	defer os.Remove(s.StoreBasePath)
+	dm, err := NewDockerMachine(config)
+	c.Assert(err, check.IsNil)
+	h := &host.Host{HostOptions: &host.Options{EngineOptions: &engine.Options{Env: []string{"FOO=bar"}}}}
+	dm.configureHost(h)
+	c.Assert(h.HostOptions.EngineOptions.Env, check.DeepEquals, []string{
+		"FOO=bar",
+		"HTTP_PROXY=http://proxy:3128",
+		"HTTPS_PROXY=http://proxy:3129",
+		"NO_PROXY=localhost,10.0.0.0/8",
+	})
+}
+
+func (s *S) TestConfigureHostWithoutProxy(c *check.C) {
+	defer os.Remove(s.StoreBasePath)
+	dm, err := NewDockerMachine(&DockerMachineConfig{})
+	c.Assert(err, check.IsNil)
+	h := &host.Host{HostOptions: &host.Options{EngineOptions: &engine.Options{}}}
+	dm.configureHost(h)
+	c.Assert(h.HostOptions.EngineOptions.Env, check.IsNil)
+}
+
+func (s *S) TestListMachines(c *check.C) {
+	dm, err := NewDockerMachine(DefaultDockerMachineConfig)
+	c.Assert(err, check.IsNil)
+	dm.client = &fakeMachineAPI{
+		FakeStore: &persisttest.FakeStore{
+			Hosts: []*host.Host{{Name: "tsuru-1"}, {Name: "tsuru-2"}},
+		},
+	}
+	machines, err := dm.ListMachines()
+	c.Assert(err, check.IsNil)
+	c.Assert(machines, check.DeepEquals, []string{"tsuru-1", "tsuru-2"})
+}
+
+func (s *S) TestLoadMachine(c *check.C) {
+	dm, err := NewDockerMachine(DefaultDockerMachineConfig)
+	c.Assert(err, check.IsNil)
+	dm.client = &fakeMachineAPI{
+		FakeStore: &persisttest.FakeStore{
+			Hosts: []*host.Host{{
+				Name:   "tsuru-1",
+				Driver: &fakedriver.Driver{MockState: state.Running, MockIP: "1.2.3.4"},
+			}, {
+				Name:   "tsuru-2",
+				Driver: &fakedriver.Driver{MockState: state.Stopped},
+			}},
+		},
+	}
+	m, err := dm.LoadMachine("tsuru-1")
+	c.Assert(err, check.IsNil)
+	c.Assert(m.Name, check.Equals, "tsuru-1")
+	c.Assert(m.IP, check.Equals, "1.2.3.4")
+	c.Assert(m.Address, check.Equals, "https://1.2.3.4:2376")
+	c.Assert(m.CAPath, check.Equals, dm.certsPath)
+	m, err = dm.LoadMachine("tsuru-2")
+	c.Assert(err, check.IsNil)
+	c.Assert(m.Name, check.Equals, "tsuru-2")
+	c.Assert(m.IP, check.Equals, "")
+	c.Assert(m.Address, check.Equals, "")
+	dm.client = &fakeMachineAPI{
+		FakeStore: &persisttest.FakeStore{LoadErr: fmt.Errorf("not found")},
+	}
+	_, err = dm.LoadMachine("tsuru-3")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestGenerateMachineNameSkipsExistingMachines(c *check.C) {
+	dm, err := NewDockerMachine(DefaultDockerMachineConfig)
+	c.Assert(err, check.IsNil)
+	dm.client = &fakeMachineAPI{
+		FakeStore: &persisttest.FakeStore{
+			Hosts: []*host.Host{{Name: "tsuru-1"}, {Name: "tsuru-2"}, {Name: "tsuru-4"}},
+		},
+	}
+	c.Assert(dm.generateMachineName(), check.Equals, "tsuru-3")
+	c.Assert(dm.generateMachineName(), check.Equals, "tsuru-5")
+}
@@ -5,13 +5,16 @@
 package dm
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/ssh"
 	docker "github.com/fsouza/go-dockerclient"
 )
 
@@ -21,6 +24,81 @@ type Machine struct {
 	Address    string
 	CAPath     string
 	DriverOpts DriverOpts
+	Bastion    *SSHBastion
+}
+
+// SSHBastion is a jump host used to reach the machines with SSH when they're
+// not directly reachable, like in private networks.
+type SSHBastion struct {
+	// Host is the address of the bastion, optionally with the SSH port
+	// (like bastion.example.com:2222).
+	Host    string
+	User    string
+	KeyPath string
+}
+
+var errBastionNativeClient = errors.New("the ssh binary is required to connect to the hosts through a bastion")
+
+// proxyCommand returns the ssh ProxyCommand option forwarding the connection
+// to the target host through the bastion.
+func (b *SSHBastion) proxyCommand() string {
+	args := []string{"ssh", "-F", "/dev/null",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "LogLevel=quiet",
+	}
+	host := b.Host
+	if h, port, err := net.SplitHostPort(b.Host); err == nil {
+		host = h
+		args = append(args, "-p", port)
+	}
+	if b.KeyPath != "" {
+		args = append(args, "-i", b.KeyPath)
+	}
+	if b.User != "" {
+		host = b.User + "@" + host
+	}
+	args = append(args, "-W", "%h:%p", host)
+	return "ProxyCommand=" + strings.Join(args, " ")
+}
+
+// wrap routes the connections of the client through the bastion. Only the
+// external ssh client is supported.
+func (b *SSHBastion) wrap(client ssh.Client) (ssh.Client, error) {
+	external, ok := client.(*ssh.ExternalClient)
+	if !ok {
+		return nil, errBastionNativeClient
+	}
+	args := append([]string{}, external.BaseArgs...)
+	external.BaseArgs = append(args, "-o", b.proxyCommand())
+	return external, nil
+}
+
+// CreateSSHClient returns a client connecting to the machine with SSH,
+// through the bastion when there's one.
+func (m *Machine) CreateSSHClient() (ssh.Client, error) {
+	client, err := m.Host.CreateSSHClient()
+	if err != nil || m.Bastion == nil {
+		return client, err
+	}
+	return m.Bastion.wrap(client)
+}
+
+// RunSSHCommand runs the command in the machine with SSH, through the bastion
+// when there's one.
+func (m *Machine) RunSSHCommand(command string) (string, error) {
+	if m.Bastion == nil {
+		return m.Host.RunSSHCommand(command)
+	}
+	client, err := m.CreateSSHClient()
+	if err != nil {
+		return "", err
+	}
+	output, err := client.Output(command)
+	if err != nil {
+		return "", fmt.Errorf("failed to run %q through the bastion %s: %s\n%s", command, m.Bastion.Host, err, output)
+	}
+	return output, nil
 }
 
 type SSHTarget interface {
@@ -7,6 +7,7 @@ package dm
 import (
 	"errors"
 
+	"github.com/docker/machine/libmachine/ssh"
 	check "gopkg.in/check.v1"
 )
 
@@ -62,3 +63,22 @@ valid_lft forever preferred_lft forever`},
 	ip = getIp("eth1", target)
 	c.Assert(ip, check.Equals, "127.0.0.1")
 }
+
+func (s *S) TestSSHBastionProxyCommand(c *check.C) {
+	b := &SSHBastion{Host: "bastion.example.com"}
+	c.Assert(b.proxyCommand(), check.Equals, "ProxyCommand=ssh -F /dev/null -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o LogLevel=quiet -W %h:%p bastion.example.com")
+	b = &SSHBastion{Host: "bastion.example.com:2222", User: "jump", KeyPath: "/keys/bastion"}
+	c.Assert(b.proxyCommand(), check.Equals, "ProxyCommand=ssh -F /dev/null -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o LogLevel=quiet -p 2222 -i /keys/bastion -W %h:%p jump@bastion.example.com")
+}
+
+func (s *S) TestSSHBastionWrap(c *check.C) {
+	b := &SSHBastion{Host: "bastion.example.com", User: "jump"}
+	base := []string{"ubuntu@10.0.0.1", "-p", "22"}
+	client, err := b.wrap(&ssh.ExternalClient{BaseArgs: base, BinaryPath: "/usr/bin/ssh"})
+	c.Assert(err, check.IsNil)
+	external := client.(*ssh.ExternalClient)
+	c.Assert(external.BaseArgs, check.DeepEquals, []string{"ubuntu@10.0.0.1", "-p", "22", "-o", b.proxyCommand()})
+	c.Assert(base, check.DeepEquals, []string{"ubuntu@10.0.0.1", "-p", "22"})
+	_, err = b.wrap(&ssh.NativeClient{})
+	c.Assert(err, check.Equals, errBastionNativeClient)
+}
@@ -0,0 +1,44 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+
+	"github.com/tsuru/tsuru-client/tsuru/installer/dm"
+)
+
+// dockerHost returns the address of the docker engine of the machine in the
+// format of the DOCKER_HOST environment variable.
+func dockerHost(m *dm.Machine) string {
+	if u, err := url.Parse(m.Address); err == nil && u.Host != "" {
+		return "tcp://" + u.Host
+	}
+	return "tcp://" + m.Address
+}
+
+// writeDockerContext writes a shell snippet that configures the docker CLI
+// to manage the swarm cluster through the given manager, using the TLS
+// certificates created by docker machine during the installation. The
+// snippet also shows how to create an equivalent docker context.
+func writeDockerContext(path, name string, manager *dm.Machine) error {
+	host := dockerHost(manager)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Docker connection to the swarm manager of the tsuru installation %q.\n", name)
+	fmt.Fprintf(&buf, "# Load it in the shell with: source %s\n", path)
+	fmt.Fprintf(&buf, "export DOCKER_HOST=%s\n", host)
+	fmt.Fprintf(&buf, "export DOCKER_TLS_VERIFY=1\n")
+	fmt.Fprintf(&buf, "export DOCKER_CERT_PATH=%s\n", manager.CAPath)
+	fmt.Fprintf(&buf, "\n# Or create a docker context with:\n")
+	fmt.Fprintf(&buf, "# docker context create %s --docker \"host=%s,ca=%s,cert=%s,key=%s\"\n", name, host,
+		filepath.Join(manager.CAPath, "ca.pem"),
+		filepath.Join(manager.CAPath, "cert.pem"),
+		filepath.Join(manager.CAPath, "key.pem"))
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,41 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/tsuru/tsuru-client/tsuru/installer/dm"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestDockerHost(c *check.C) {
+	c.Assert(dockerHost(&dm.Machine{Address: "https://10.0.0.1:2376"}), check.Equals, "tcp://10.0.0.1:2376")
+	c.Assert(dockerHost(&dm.Machine{Address: "10.0.0.1:2376"}), check.Equals, "tcp://10.0.0.1:2376")
+}
+
+func (s *S) TestWriteDockerContext(c *check.C) {
+	dir, err := ioutil.TempDir("", "docker-context")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tsuru.env")
+	manager := &dm.Machine{Address: "https://10.0.0.1:2376", CAPath: "/certs"}
+	err = writeDockerContext(path, "tsuru-ec2", manager)
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	expected := `# Docker connection to the swarm manager of the tsuru installation "tsuru-ec2".
+# Load it in the shell with: source ` + path + `
+export DOCKER_HOST=tcp://10.0.0.1:2376
+export DOCKER_TLS_VERIFY=1
+export DOCKER_CERT_PATH=/certs
+
+# Or create a docker context with:
+# docker context create tsuru-ec2 --docker "host=tcp://10.0.0.1:2376,ca=/certs/ca.pem,cert=/certs/cert.pem,key=/certs/key.pem"
+`
+	c.Assert(string(data), check.Equals, expected)
+}
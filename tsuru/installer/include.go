@@ -0,0 +1,109 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v1"
+)
+
+const includeKey = "include"
+
+// loadInstallConfig reads the installation configuration file, resolving the
+// files listed in its include directive. Included files are merged in order
+// and the including file overrides them: maps are merged recursively, any
+// other value is replaced. Relative paths are resolved from the directory of
+// the including file.
+func loadInstallConfig(file string) (map[interface{}]interface{}, error) {
+	return loadConfigWithIncludes(file, nil)
+}
+
+func loadConfigWithIncludes(file string, chain []string) (map[interface{}]interface{}, error) {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range chain {
+		if p == path {
+			return nil, fmt.Errorf("include cycle detected: %s", strings.Join(append(chain, path), " -> "))
+		}
+	}
+	chain = append(chain, path)
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var conf map[interface{}]interface{}
+	err = yaml.Unmarshal(data, &conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", file, err)
+	}
+	if conf == nil {
+		conf = make(map[interface{}]interface{})
+	}
+	includes, err := configIncludes(conf[includeKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid include in %s: %s", file, err)
+	}
+	delete(conf, includeKey)
+	merged := make(map[interface{}]interface{})
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(file), include)
+		}
+		included, err := loadConfigWithIncludes(include, chain)
+		if err != nil {
+			if _, ok := err.(includeError); ok {
+				return nil, err
+			}
+			return nil, includeError(fmt.Sprintf("failed to include %s from %s: %s", include, file, err))
+		}
+		merged = mergeConfig(merged, included)
+	}
+	return mergeConfig(merged, conf), nil
+}
+
+type includeError string
+
+func (e includeError) Error() string {
+	return string(e)
+}
+
+func configIncludes(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		includes := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a file path, got %v", item)
+			}
+			includes[i] = s
+		}
+		return includes, nil
+	}
+	return nil, fmt.Errorf("expected a file path or a list of file paths, got %v", value)
+}
+
+func mergeConfig(base, override map[interface{}]interface{}) map[interface{}]interface{} {
+	for k, v := range override {
+		baseMap, baseIsMap := base[k].(map[interface{}]interface{})
+		overrideMap, overrideIsMap := v.(map[interface{}]interface{})
+		if baseIsMap && overrideIsMap {
+			base[k] = mergeConfig(baseMap, overrideMap)
+			continue
+		}
+		base[k] = v
+	}
+	return base
+}
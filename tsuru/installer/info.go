@@ -0,0 +1,165 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	"github.com/tsuru/tsuru-client/tsuru/installer/dm"
+)
+
+type InstallInfo struct {
+	fs     *gnuflag.FlagSet
+	config string
+	json   bool
+}
+
+type installInfo struct {
+	Name       string          `json:"name"`
+	Hosts      []NodeInfo      `json:"hosts"`
+	Components []componentInfo `json:"components"`
+	Nodes      json.RawMessage `json:"nodes"`
+	Apps       json.RawMessage `json:"apps"`
+}
+
+type componentInfo struct {
+	Name            string   `json:"name"`
+	Ports           []string `json:"ports,omitempty"`
+	Replicas        int      `json:"replicas"`
+	RunningReplicas int      `json:"runningReplicas"`
+	Error           string   `json:"error,omitempty"`
+}
+
+func (c *InstallInfo) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "install-info",
+		Usage: "install-info [--config/-c config_file] [--json]",
+		Desc: `Shows the overview of an existing installation: the core hosts, the
+components and their replicas, the applications hosts and the apps.
+
+The [[--config]] parameter is the path to the .yml file used in the
+installation. The [[--json]] flag shows the overview in JSON, including the
+nodes and apps as returned by the tsuru API.`,
+		MinArgs: 0,
+		MaxArgs: 0,
+	}
+}
+
+func (c *InstallInfo) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("install-info", gnuflag.ExitOnError)
+		c.fs.StringVar(&c.config, "c", "", "Configuration file")
+		c.fs.StringVar(&c.config, "config", "", "Configuration file")
+		c.fs.BoolVar(&c.json, "json", false, "Show the overview in JSON")
+	}
+	return c.fs
+}
+
+func (c *InstallInfo) Run(context *cmd.Context, cli *cmd.Client) error {
+	config, err := parseConfigFile(c.config)
+	if err != nil {
+		return err
+	}
+	dockerMachine, err := dm.NewDockerMachine(config.DockerMachineConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create docker machine: %s", err)
+	}
+	defer dockerMachine.Close()
+	cluster, err := loadInstallation(dockerMachine)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		context.OutputFormat = cmd.JSONOutput
+	}
+	if !context.StructuredOutput() {
+		printInstallOverview(context, cli, cluster)
+		return nil
+	}
+	info, err := buildInstallInfo(config.Name, cluster, TsuruComponents, cli)
+	if err != nil {
+		return err
+	}
+	return context.WriteStructured(info)
+}
+
+// loadInstallation loads the hosts of an existing installation and
+// reconnects to its swarm cluster.
+func loadInstallation(dockerMachine *dm.DockerMachine) (*SwarmCluster, error) {
+	names, err := dockerMachine.ListMachines()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hosts: %s", err)
+	}
+	var machines []*dm.Machine
+	for _, name := range names {
+		m, err := dockerMachine.LoadMachine(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load host %s: %s", name, err)
+		}
+		machines = append(machines, m)
+	}
+	return loadSwarmCluster(machines)
+}
+
+func buildInstallInfo(name string, cluster ServiceCluster, components []TsuruComponent, cli *cmd.Client) (*installInfo, error) {
+	hosts, err := cluster.ClusterInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve cluster info: %s", err)
+	}
+	info := &installInfo{Name: name, Hosts: hosts}
+	for _, component := range components {
+		status, err := component.Status(cluster)
+		if err != nil {
+			info.Components = append(info.Components, componentInfo{Name: component.Name(), Error: err.Error()})
+			continue
+		}
+		info.Components = append(info.Components, componentInfo{
+			Name:            component.Name(),
+			Ports:           status.Ports,
+			Replicas:        status.Replicas,
+			RunningReplicas: status.RunningReplicas,
+		})
+	}
+	info.Nodes, err = getRawJSON(cli, "1.2", "/node")
+	if err != nil {
+		return nil, err
+	}
+	info.Apps, err = getRawJSON(cli, "1.0", "/apps")
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// getRawJSON returns the body of the response of the tsuru API, or null
+// when there's no content.
+func getRawJSON(cli *cmd.Client, version, path string) (json.RawMessage, error) {
+	u, err := cmd.GetURLVersion(version, path)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := cli.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNoContent || len(data) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	return json.RawMessage(data), nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
+	"github.com/tsuru/tsuru/cmd/cmdtest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestBuildInstallInfo(c *check.C) {
+	os.Setenv("TSURU_TARGET", "http://localhost")
+	defer os.Unsetenv("TSURU_TARGET")
+	transport := cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: `{"nodes":[]}`, Status: http.StatusOK},
+				CondFunc: func(r *http.Request) bool {
+					return strings.HasSuffix(r.URL.Path, "/1.2/node")
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Status: http.StatusNoContent},
+				CondFunc: func(r *http.Request) bool {
+					return strings.HasSuffix(r.URL.Path, "/1.0/apps")
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: &transport}, nil, manager)
+	components := []TsuruComponent{&MongoDB{}, &Redis{}}
+	info, err := buildInstallInfo("tsuru", &FakeServiceCluster{}, components, client)
+	c.Assert(err, check.IsNil)
+	data, err := json.Marshal(info)
+	c.Assert(err, check.IsNil)
+	expected := `{"name":"tsuru","hosts":[{"ip":"127.0.0.1","state":"running","manager":true}],` +
+		`"components":[{"name":"MongoDB","ports":["8080"],"replicas":1,"runningReplicas":0},` +
+		`{"name":"Redis","ports":["8080"],"replicas":1,"runningReplicas":0}],"nodes":{"nodes":[]},"apps":null}`
+	c.Assert(string(data), check.Equals, expected)
+}
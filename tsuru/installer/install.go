@@ -5,8 +5,11 @@
 package installer
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -14,12 +17,15 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/docker/engine-api/types/swarm"
+	"github.com/fsouza/go-dockerclient"
 	"github.com/tsuru/config"
 	"github.com/tsuru/gnuflag"
 	"github.com/tsuru/tsuru-client/tsuru/admin"
 	"github.com/tsuru/tsuru-client/tsuru/client"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru-client/tsuru/installer/dm"
-	"github.com/tsuru/tsuru/cmd"
+	"gopkg.in/yaml.v1"
 )
 
 var (
@@ -41,17 +47,25 @@ type TsuruInstallConfig struct {
 	AppsHosts          int
 	DedicatedAppsHosts bool
 	AppsDriversOpts    map[string][]interface{}
+	// Warnings about the configuration file that don't prevent the
+	// installation, shown to the user before provisioning any host.
+	Warnings []string
 }
 
 type Install struct {
-	fs     *gnuflag.FlagSet
-	config string
+	fs           *gnuflag.FlagSet
+	config       string
+	inventoryOut string
+	strict       bool
+	noWait       bool
+	components   string
+	contextOut   string
 }
 
 func (c *Install) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "install",
-		Usage: "install [--config/-c config_file]",
+		Usage: "install [--config/-c config_file] [--inventory-out file] [--strict] [--no-wait] [--components name,...] [--docker-context-out file]",
 		Desc: `Installs Tsuru and It's components as containers on hosts provisioned
 with docker machine drivers.
 
@@ -59,6 +73,50 @@ The [[--config]] parameter is the path to a .yml file containing the installatio
 configuration. If not provided, Tsuru will be installed into a VirtualBox VM for
 experimentation.
 
+Unknown keys in the configuration file, usually typos, are reported as
+warnings before provisioning any host. With the [[--strict]] flag they are
+reported as errors and the installation is aborted.
+
+After installing the components, the installer waits up to 5 minutes for all
+their replicas to be running, failing the installation otherwise. Use the
+[[--no-wait]] flag to skip this check.
+
+The [[--components]] flag installs only the given comma-separated components
+(mongo, redis, planb, registry and api) on the hosts of an existing
+installation, without provisioning hosts or bootstrapping the API. It's
+useful to add or reinstall a component: the existing service of a selected
+component is removed and created again. The images of the registry are kept
+in its hosts, but the data of mongo and redis is lost. The dependencies of
+the selected components that are not selected must already be installed.
+
+When a component fails to install, its error is often too terse to diagnose.
+With the global [[--verbose]] flag, like in [[tsuru -v install]], the
+installer also shows the last lines of the logs of the container of the
+failing component.
+
+The [[--inventory-out]] parameter is the path of a file where the installer
+writes the inventory of the machines created, with the name, IP, driver and
+role (core or apps) of each one. The file is written in JSON if its name ends
+with .json and in YAML otherwise. It references the paths of the SSH keys and
+CA files, but never includes the content of private keys.
+
+The [[--docker-context-out]] parameter is the path of a file where the
+installer writes the connection to the docker engine of the swarm manager,
+as DOCKER_HOST, DOCKER_TLS_VERIFY and DOCKER_CERT_PATH variables to be
+sourced by the shell, along with the equivalent [[docker context create]]
+command. It references the TLS certificates created during the installation,
+so the cluster can be managed directly with the docker CLI.
+
+The configuration file may include other files with the include directive,
+that accepts a path or a list of paths, relative to the including file. The
+included files are merged in order and the including file overrides them,
+allowing teams to share a base configuration:
+
+==========
+include: base.yml
+name: tsuru-staging
+==========
+
 The following is an example of installation configuration to install Tsuru on
 Amazon EC2:
 
@@ -81,6 +139,36 @@ Name of the installation.
 - docker-hub-mirror
 Url of a docker hub mirror used to fetch the components docker images.
 
+- http-proxy, https-proxy and no-proxy
+Proxy settings set in the environment of the docker engine of every host provisioned, as HTTP_PROXY, HTTPS_PROXY and NO_PROXY. Use them when the hosts can only reach the internet through a proxy.
+
+- registry:url, registry:username and registry:password
+Registry hosting the images of the components, like registry.example.com, used
+instead of the Docker Hub. The images must be pushed to it with the same names,
+like registry.example.com/tsuru/api:v1. When the credentials are set, the
+images are pulled on every host before creating the components, sending the
+credentials only to the docker engine of the hosts. They're never included in
+the image names nor stored in the hosts.
+
+- components:planb:replicas, components:registry:replicas and components:api:replicas
+Number of replicas of the PlanB router, the docker registry and the tsuru API,
+1 by default. The registry and the API run only on the core hosts, so their
+replicas can't exceed hosts:core:size. MongoDB and Redis always run a single
+replica.
+
+- components:install-timeout
+How long the installation of each component may take, like 15m, failing with
+the last known status of the component afterwards, when the operation in
+progress is canceled. Defaults to 10m. The timeout of a single component may be
+set in components:<component>:install-timeout, where component is mongo, redis,
+planb, registry or api, and it also bounds the wait for the component to be
+healthy.
+
+- components:health-timeout
+How long the installer waits for the replicas of the components to be running
+after they're installed, like 10m. Defaults to 5m. External components, whose
+address is set in the configuration file, are not waited for.
+
 - ca-path
 A path to a directory containing a ca.pem and ca-key.pem files that are going to be used to sign certificates used by docker and docker registry.
 If not set, a CA will be created, copied to every host provisioned and used to sign the certificates.
@@ -103,6 +191,20 @@ the core components.
 Driver parameters specific to the applications hosts can be set on this namespace. The format is: <driver-param>>: ["value1", "value2"]. Each
 host will use one value from the list. Refer to the driver configuration for more information on what parameter are available.
 
+- hosts:core:tags and hosts:apps:tags
+Tags, in the format <key>: <value>, added to the core and applications hosts
+respectively, for example to track costs. They're set using the
+amazonec2-tags and digitalocean-tags options, other drivers don't support
+tags and they're ignored with a warning. Machines shared by core components and
+applications, when hosts:apps:dedicated is false, only get the core tags.
+
+- hosts:apps:spot
+Boolean to indicate if the applications hosts should be spot instances. It sets
+the required driver options for the amazonec2 (amazonec2-request-spot-instance)
+and google (google-preemptible) drivers, other drivers must set them in
+hosts:apps:driver:options. Machines shared with the core components, when
+hosts:apps:dedicated is false, are not affected.
+
 - driver
 Under this namespace lies all the docker machine driver configuration.
 
@@ -111,6 +213,21 @@ Name of the driver to be used by the installer. This can be any core or 3rd part
 
 - driver:options
 Under this namespace every driver parameters can be set. Refer to the driver configuration for more information on what parameter are available.
+
+- driver:ssh-user
+User used to connect to the hosts with SSH, for drivers supporting it (like amazonec2-ssh-user). If not set, the default user of the driver is used.
+
+- driver:ssh-port
+Port used to connect to the hosts with SSH, for drivers supporting it (like generic-ssh-port). If not set, the default port of the driver is used.
+
+- driver:ssh-bastion-host
+Address of a bastion (jump host), optionally with its port, used to reach the hosts with SSH when they're not directly reachable. The SSH commands run by the installer after creating the hosts and install-ssh are routed through it. If not set, the hosts are reached directly. Requires the ssh binary.
+
+- driver:ssh-bastion-user
+User used to connect to the bastion. If not set, the local user is used.
+
+- driver:ssh-bastion-key
+Path of the private key used to connect to the bastion. If not set, the default keys of the local user are used.
 `,
 		MinArgs: 0,
 	}
@@ -121,6 +238,11 @@ func (c *Install) Flags() *gnuflag.FlagSet {
 		c.fs = gnuflag.NewFlagSet("install", gnuflag.ExitOnError)
 		c.fs.StringVar(&c.config, "c", "", "Configuration file")
 		c.fs.StringVar(&c.config, "config", "", "Configuration file")
+		c.fs.StringVar(&c.inventoryOut, "inventory-out", "", "File to write the inventory of the machines to")
+		c.fs.BoolVar(&c.strict, "strict", false, "Fail on unknown keys in the configuration file")
+		c.fs.BoolVar(&c.noWait, "no-wait", false, "Don't wait for the components to be healthy")
+		c.fs.StringVar(&c.components, "components", "", "Comma-separated components to install on an existing installation")
+		c.fs.StringVar(&c.contextOut, "docker-context-out", "", "File to write the docker connection to the swarm manager to")
 	}
 	return c.fs
 }
@@ -131,6 +253,14 @@ func (c *Install) Run(context *cmd.Context, cli *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	err = c.validateConfig(context)
+	if err != nil {
+		return err
+	}
+	printConfigWarnings(context, config)
+	if c.components != "" {
+		return c.installSelectedComponents(context, cli, config)
+	}
 	fmt.Fprintf(context.Stdout, "Running pre-install checks...\n")
 	err = c.PreInstallChecks(config)
 	if err != nil {
@@ -150,13 +280,16 @@ func (c *Install) Run(context *cmd.Context, cli *cmd.Client) error {
 	if err != nil {
 		return fmt.Errorf("failed to setup swarm cluster: %s", err)
 	}
-	for _, component := range TsuruComponents {
-		fmt.Fprintf(context.Stdout, "Installing %s\n", component.Name())
-		errInstall := component.Install(cluster, config.ComponentsConfig)
-		if errInstall != nil {
-			return fmt.Errorf("error installing %s: %s", component.Name(), errInstall)
+	managed := managedComponents(config.ComponentsConfig, TsuruComponents)
+	err = installComponents(context.Stdout, logsWriter(context, cli), cluster, config.ComponentsConfig, TsuruComponents)
+	if err != nil {
+		return err
+	}
+	if !c.noWait {
+		err = waitComponentsHealthy(context.Stdout, cluster, config.ComponentsConfig, managed)
+		if err != nil {
+			return err
 		}
-		fmt.Fprintf(context.Stdout, "%s successfully installed!\n", component.Name())
 	}
 	appsMachines, err := ProvisionPool(dockerMachine, config, coreMachines)
 	if err != nil {
@@ -167,10 +300,11 @@ func (c *Install) Run(context *cmd.Context, cli *cmd.Client) error {
 		nodesAddr = append(nodesAddr, m.GetPrivateAddress())
 	}
 	fmt.Fprintf(context.Stdout, "Bootstrapping Tsuru API...")
+	target := fmt.Sprintf("http://%s:%d", cluster.GetManager().IP, defaultTsuruAPIPort)
 	opts := TsuruSetupOptions{
 		Login:      config.ComponentsConfig.RootUserEmail,
 		Password:   config.ComponentsConfig.RootUserPassword,
-		Target:     fmt.Sprintf("http://%s:%d", cluster.GetManager().IP, defaultTsuruAPIPort),
+		Target:     target,
 		TargetName: config.ComponentsConfig.TargetName,
 		NodesAddr:  nodesAddr,
 	}
@@ -189,15 +323,7 @@ func (c *Install) Run(context *cmd.Context, cli *cmd.Client) error {
 			fmt.Fprintf(context.Stderr, "Failed to apply iptables rule: %s. Maybe it is not needed anymore?\n", err)
 		}
 	}
-	fmt.Fprint(context.Stdout, "--- Installation Overview ---\n")
-	fmt.Fprint(context.Stdout, "Core Hosts: \n"+buildClusterTable(cluster).String())
-	fmt.Fprint(context.Stdout, "Core Components: \n"+buildComponentsTable(TsuruComponents, cluster).String())
-	fmt.Fprintln(context.Stdout, "Apps Hosts:")
-	nodeList := &admin.ListNodesCmd{}
-	nodeList.Run(context, cli)
-	fmt.Fprintln(context.Stdout, "Apps:")
-	appList := &client.AppList{}
-	appList.Run(context, cli)
+	printInstallOverview(context, cli, cluster)
 	machineIndex := make(map[string]*dm.Machine)
 	allMachines := append(coreMachines, appsMachines...)
 	for _, m := range allMachines {
@@ -211,9 +337,94 @@ func (c *Install) Run(context *cmd.Context, cli *cmd.Client) error {
 	if err != nil {
 		return fmt.Errorf("failed to register hosts: %s", err)
 	}
+	if c.inventoryOut != "" {
+		err = newInventory(config.Name, target, coreMachines, appsMachines).Write(c.inventoryOut)
+		if err != nil {
+			return fmt.Errorf("failed to write inventory: %s", err)
+		}
+		fmt.Fprintf(context.Stdout, "Inventory written to %s\n", c.inventoryOut)
+	}
+	if c.contextOut != "" {
+		err = writeDockerContext(c.contextOut, config.Name, cluster.GetManager())
+		if err != nil {
+			return fmt.Errorf("failed to write docker context: %s", err)
+		}
+		fmt.Fprintf(context.Stdout, "Docker connection to the swarm manager written to %s\n", c.contextOut)
+	}
 	return nil
 }
 
+// installSelectedComponents installs the components given in the
+// --components flag on the hosts of an existing installation.
+func (c *Install) installSelectedComponents(context *cmd.Context, cli *cmd.Client, config *TsuruInstallConfig) error {
+	selected, err := selectComponents(TsuruComponents, strings.Split(c.components, ","))
+	if err != nil {
+		return err
+	}
+	dockerMachine, err := dm.NewDockerMachine(config.DockerMachineConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create docker machine: %s", err)
+	}
+	defer dockerMachine.Close()
+	cluster, err := loadInstallation(dockerMachine)
+	if err != nil {
+		return err
+	}
+	components, err := prepareSelectedComponents(cluster, config.ComponentsConfig, TsuruComponents, selected)
+	if err != nil {
+		return err
+	}
+	managed := managedComponents(config.ComponentsConfig, components)
+	err = installComponents(context.Stdout, logsWriter(context, cli), cluster, config.ComponentsConfig, components)
+	if err != nil {
+		return err
+	}
+	if !c.noWait {
+		err = waitComponentsHealthy(context.Stdout, cluster, config.ComponentsConfig, managed)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprint(context.Stdout, buildComponentsTable(components, cluster).String())
+	return nil
+}
+
+// logsWriter returns where the logs of the components that fail to install
+// are written, or nil when they shouldn't be shown, which is when the global
+// --verbose flag is not set.
+func logsWriter(context *cmd.Context, cli *cmd.Client) io.Writer {
+	if cli == nil || cli.Verbosity < 1 {
+		return nil
+	}
+	return context.Stderr
+}
+
+func (c *Install) validateConfig(context *cmd.Context) error {
+	if c.config == "" {
+		return nil
+	}
+	unknown, err := unknownConfigKeys(c.config)
+	if err != nil {
+		return err
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	if c.strict {
+		return fmt.Errorf("unknown keys in the configuration file: %s", strings.Join(unknown, ", "))
+	}
+	for _, key := range unknown {
+		fmt.Fprintf(context.Stderr, "WARNING: unknown key %q in the configuration file will be ignored.\n", key)
+	}
+	return nil
+}
+
+func printConfigWarnings(context *cmd.Context, config *TsuruInstallConfig) {
+	for _, w := range config.Warnings {
+		fmt.Fprintf(context.Stderr, "WARNING: %s\n", w)
+	}
+}
+
 func addInstallHosts(machines []*dm.Machine, client *cmd.Client) error {
 	path, err := cmd.GetURLVersion("1.3", "/install/hosts")
 	if err != nil {
@@ -299,6 +510,18 @@ func (c *Install) PreInstallChecks(config *TsuruInstallConfig) error {
 	return nil
 }
 
+func printInstallOverview(context *cmd.Context, cli *cmd.Client, cluster ServiceCluster) {
+	fmt.Fprint(context.Stdout, "--- Installation Overview ---\n")
+	fmt.Fprint(context.Stdout, "Core Hosts: \n"+buildClusterTable(cluster).String())
+	fmt.Fprint(context.Stdout, "Core Components: \n"+buildComponentsTable(TsuruComponents, cluster).String())
+	fmt.Fprintln(context.Stdout, "Apps Hosts:")
+	nodeList := &admin.ListNodesCmd{}
+	nodeList.Run(context, cli)
+	fmt.Fprintln(context.Stdout, "Apps:")
+	appList := &client.AppList{}
+	appList.Run(context, cli)
+}
+
 func buildClusterTable(cluster ServiceCluster) *cmd.Table {
 	t := cmd.NewTable()
 	t.Headers = cmd.Row{"IP", "State", "Manager"}
@@ -335,13 +558,18 @@ func buildComponentsTable(components []TsuruComponent, cluster ServiceCluster) *
 type Uninstall struct {
 	fs     *gnuflag.FlagSet
 	config string
+	yes    bool
 }
 
 func (c *Uninstall) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "uninstall",
-		Usage:   "uninstall [--config/-c config_file]",
-		Desc:    "Uninstalls Tsuru and It's components.",
+		Name:  "uninstall",
+		Usage: "uninstall [--config/-c config_file] [--yes/-y]",
+		Desc: `Uninstalls Tsuru and It's components.
+
+Before deleting anything, the command lists the machines of the installation
+and asks the user to type the name of the installation to confirm. Use the
+[[--yes]] flag to skip the confirmation.`,
 		MinArgs: 0,
 	}
 }
@@ -351,10 +579,34 @@ func (c *Uninstall) Flags() *gnuflag.FlagSet {
 		c.fs = gnuflag.NewFlagSet("uninstall", gnuflag.ExitOnError)
 		c.fs.StringVar(&c.config, "c", "", "Configuration file")
 		c.fs.StringVar(&c.config, "config", "", "Configuration file")
+		yes := "Don't ask for confirmation."
+		c.fs.BoolVar(&c.yes, "y", false, yes)
+		c.fs.BoolVar(&c.yes, "yes", false, yes)
 	}
 	return c.fs
 }
 
+func (c *Uninstall) confirm(context *cmd.Context, name string, machines []string) bool {
+	if len(machines) > 0 {
+		fmt.Fprintf(context.Stdout, "The following machines of the installation %q will be deleted:\n", name)
+		for _, m := range machines {
+			fmt.Fprintf(context.Stdout, "  - %s\n", m)
+		}
+	} else {
+		fmt.Fprintf(context.Stdout, "No machines found for the installation %q.\n", name)
+	}
+	if c.yes {
+		return true
+	}
+	fmt.Fprintf(context.Stdout, "Type the name of the installation (%s) to confirm: ", name)
+	answer, _ := bufio.NewReader(context.Stdin).ReadString('\n')
+	if strings.TrimSpace(answer) != name {
+		fmt.Fprintln(context.Stdout, "Abort.")
+		return false
+	}
+	return true
+}
+
 func (c *Uninstall) Run(context *cmd.Context, client *cmd.Client) error {
 	context.RawOutput()
 	config, err := parseConfigFile(c.config)
@@ -368,6 +620,14 @@ func (c *Uninstall) Run(context *cmd.Context, client *cmd.Client) error {
 		return err
 	}
 	defer d.Close()
+	machines, err := d.ListMachines()
+	if err != nil {
+		fmt.Fprintf(context.Stderr, "Failed to list machines: %s\n", err)
+		return err
+	}
+	if !c.confirm(context, config.Name, machines) {
+		return nil
+	}
 	err = d.DeleteAll()
 	if err != nil {
 		fmt.Fprintf(context.Stderr, "Failed to delete machines: %s\n", err)
@@ -385,11 +645,21 @@ func (c *Uninstall) Run(context *cmd.Context, client *cmd.Client) error {
 }
 
 func parseConfigFile(file string) (*TsuruInstallConfig, error) {
-	installConfig := defaultTsuruInstallConfig
 	if file == "" {
-		return installConfig, nil
+		return defaultTsuruInstallConfig, nil
+	}
+	installConfig := *defaultTsuruInstallConfig
+	dmConfig := *installConfig.DockerMachineConfig
+	installConfig.DockerMachineConfig = &dmConfig
+	conf, err := loadInstallConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := yaml.Marshal(conf)
+	if err != nil {
+		return nil, err
 	}
-	err := config.ReadConfigFile(file)
+	err = config.ReadConfigBytes(data)
 	if err != nil {
 		return nil, err
 	}
@@ -401,10 +671,37 @@ func parseConfigFile(file string) (*TsuruInstallConfig, error) {
 	if err == nil {
 		installConfig.Name = name
 	}
+	sshUser, err := config.GetString("driver:ssh-user")
+	if err == nil {
+		installConfig.SSHUser = sshUser
+	}
+	sshPort, err := config.GetInt("driver:ssh-port")
+	if err == nil {
+		installConfig.SSHPort = sshPort
+	}
+	bastionHost, err := config.GetString("driver:ssh-bastion-host")
+	if err == nil {
+		bastion := &dm.SSHBastion{Host: bastionHost}
+		bastion.User, _ = config.GetString("driver:ssh-bastion-user")
+		bastion.KeyPath, _ = config.GetString("driver:ssh-bastion-key")
+		installConfig.SSHBastion = bastion
+	}
 	hub, err := config.GetString("docker-hub-mirror")
 	if err == nil {
 		installConfig.DockerHubMirror = hub
 	}
+	httpProxy, err := config.GetString("http-proxy")
+	if err == nil {
+		installConfig.HTTPProxy = httpProxy
+	}
+	httpsProxy, err := config.GetString("https-proxy")
+	if err == nil {
+		installConfig.HTTPSProxy = httpsProxy
+	}
+	noProxy, err := config.GetString("no-proxy")
+	if err == nil {
+		installConfig.NoProxy = noProxy
+	}
 	driverOpts := make(dm.DriverOpts)
 	opts, _ := config.Get("driver:options")
 	if opts != nil {
@@ -446,8 +743,42 @@ func parseConfigFile(file string) (*TsuruInstallConfig, error) {
 			return nil, err
 		}
 	}
+	for _, hosts := range []string{"core", "apps"} {
+		tags, _ := config.Get("hosts:" + hosts + ":tags")
+		if tags == nil {
+			continue
+		}
+		parsedTags, err := parseTags(tags)
+		if err != nil {
+			return nil, err
+		}
+		opts := &installConfig.CoreDriversOpts
+		if hosts == "apps" {
+			opts = &installConfig.AppsDriversOpts
+		}
+		var ok bool
+		*opts, ok = addTagDriverOpts(installConfig.DriverName, parsedTags, *opts)
+		if !ok {
+			installConfig.Warnings = append(installConfig.Warnings, fmt.Sprintf("the %q driver doesn't support tags, hosts:%s:tags will be ignored.", installConfig.DriverName, hosts))
+		}
+	}
+	spot, err := config.GetBool("hosts:apps:spot")
+	if err == nil && spot {
+		installConfig.AppsDriversOpts, err = addSpotDriverOpts(installConfig.DriverName, installConfig.AppsDriversOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = validateInstallTimeouts()
+	if err != nil {
+		return nil, err
+	}
 	installConfig.ComponentsConfig = NewInstallConfig(installConfig.Name)
-	return installConfig, nil
+	err = validateReplicas(installConfig.ComponentsConfig, installConfig.CoreHosts)
+	if err != nil {
+		return nil, err
+	}
+	return &installConfig, nil
 }
 
 func parseDriverOptsSlice(opts interface{}) (map[string][]interface{}, error) {
@@ -552,19 +883,32 @@ func (c *InstallHostList) Show(result []byte, context *cmd.Context) error {
 	return nil
 }
 
-type InstallSSH struct{}
+type InstallSSH struct {
+	fs     *gnuflag.FlagSet
+	config string
+}
 
 func (c *InstallSSH) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "install-ssh",
-		Usage:   "install-ssh <hostname> [arg...]",
-		Desc:    "Log into or run a command on a host with SSH.",
+		Name:  "install-ssh",
+		Usage: "install-ssh <hostname> [arg...] [--config/-c config_file]",
+		Desc: `Log into or run a command on a host with SSH.
+
+The [[--config]] parameter is the path to the .yml file used in the
+installation. When provided, the SSH user and port set in driver:ssh-user and
+driver:ssh-port are used to connect to the host, through the bastion set in
+driver:ssh-bastion-host if any.`,
 		MinArgs: 1,
 	}
 }
 
 func (c *InstallSSH) Flags() *gnuflag.FlagSet {
-	return gnuflag.NewFlagSet("install-ssh", gnuflag.ExitOnError)
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("install-ssh", gnuflag.ExitOnError)
+		c.fs.StringVar(&c.config, "c", "", "Configuration file")
+		c.fs.StringVar(&c.config, "config", "", "Configuration file")
+	}
+	return c.fs
 }
 
 func (c *InstallSSH) Run(context *cmd.Context, cli *cmd.Client) error {
@@ -587,6 +931,15 @@ func (c *InstallSSH) Run(context *cmd.Context, cli *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	var bastion *dm.SSHBastion
+	if c.config != "" {
+		config, err := parseConfigFile(c.config)
+		if err != nil {
+			return err
+		}
+		setSSHOptions(ih.Driver, config.DockerMachineConfig)
+		bastion = config.SSHBastion
+	}
 	dockerMachine, err := dm.NewTempDockerMachine()
 	if err != nil {
 		return err
@@ -596,7 +949,8 @@ func (c *InstallSSH) Run(context *cmd.Context, cli *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	sshClient, err := h.CreateSSHClient()
+	m := &dm.Machine{Host: h, Bastion: bastion}
+	sshClient, err := m.CreateSSHClient()
 	if err != nil {
 		return fmt.Errorf("failed to create ssh client: %s", err)
 	}
@@ -606,3 +960,212 @@ func (c *InstallSSH) Run(context *cmd.Context, cli *cmd.Client) error {
 	}
 	return sshClient.Shell(sshArgs...)
 }
+
+func setSSHOptions(driver map[string]interface{}, config *dm.DockerMachineConfig) {
+	if config.SSHUser != "" {
+		driver["SSHUser"] = config.SSHUser
+	}
+	if config.SSHPort != 0 {
+		driver["SSHPort"] = config.SSHPort
+	}
+}
+
+type InstallHostRemove struct {
+	fs     *gnuflag.FlagSet
+	config string
+	force  bool
+}
+
+func (c *InstallHostRemove) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "install-host-remove",
+		Usage: "install-host-remove <hostname> [--config/-c config_file] [--force/-f]",
+		Desc: `Removes a host created by the installer, deleting it with its docker
+machine driver and unregistering it from the tsuru API.
+
+The [[--config]] parameter is the path to the .yml file used in the
+installation.
+
+The command refuses to remove the last swarm manager of the cluster, as it
+would leave the core components unmanageable. Use the [[--force]] flag to
+remove it anyway.`,
+		MinArgs: 1,
+		MaxArgs: 1,
+	}
+}
+
+func (c *InstallHostRemove) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("install-host-remove", gnuflag.ExitOnError)
+		c.fs.StringVar(&c.config, "c", "", "Configuration file")
+		c.fs.StringVar(&c.config, "config", "", "Configuration file")
+		force := "Remove the host even if it's the last swarm manager"
+		c.fs.BoolVar(&c.force, "f", false, force)
+		c.fs.BoolVar(&c.force, "force", false, force)
+	}
+	return c.fs
+}
+
+func (c *InstallHostRemove) Run(context *cmd.Context, cli *cmd.Client) error {
+	hostName := context.Args[0]
+	config, err := parseConfigFile(c.config)
+	if err != nil {
+		return err
+	}
+	d, err := dm.NewDockerMachine(config.DockerMachineConfig)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	m, err := d.LoadMachine(hostName)
+	if err != nil {
+		return fmt.Errorf("failed to load host %s: %s", hostName, err)
+	}
+	err = c.leaveSwarm(context, m)
+	if err != nil {
+		return err
+	}
+	err = d.DeleteMachine(hostName)
+	if err != nil {
+		return fmt.Errorf("failed to delete host %s: %s", hostName, err)
+	}
+	url, err := cmd.GetURLVersion("1.3", "/install/hosts/"+hostName)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	response, err := cli.Do(request)
+	if err != nil {
+		return fmt.Errorf("host %s deleted, but failed to unregister it: %s", hostName, err)
+	}
+	response.Body.Close()
+	fmt.Fprintf(context.Stdout, "Host %s successfully removed.\n", hostName)
+	return nil
+}
+
+// leaveSwarm removes the host from the swarm cluster before deleting it,
+// refusing to remove the last manager unless forced.
+func (c *InstallHostRemove) leaveSwarm(context *cmd.Context, m *dm.Machine) error {
+	info, err := swarmInfo(m)
+	if err != nil {
+		if c.force {
+			return nil
+		}
+		return fmt.Errorf("failed to check the swarm state of %s: %s. Use --force to remove it anyway", m.Name, err)
+	}
+	err = checkSwarmManager(m.Name, info, c.force)
+	if err != nil {
+		return err
+	}
+	if info.LocalNodeState != swarm.LocalNodeStateActive {
+		return nil
+	}
+	dockerClient, err := m.DockerClient()
+	if err == nil {
+		err = dockerClient.LeaveSwarm(docker.LeaveSwarmOptions{Force: c.force})
+	}
+	if err != nil {
+		fmt.Fprintf(context.Stderr, "Failed to leave the swarm cluster: %s\n", err)
+	}
+	return nil
+}
+
+var swarmInfo = func(m *dm.Machine) (swarm.Info, error) {
+	dockerClient, err := m.DockerClient()
+	if err != nil {
+		return swarm.Info{}, err
+	}
+	info, err := dockerClient.Info()
+	if err != nil {
+		return swarm.Info{}, err
+	}
+	return info.Swarm, nil
+}
+
+func checkSwarmManager(hostName string, info swarm.Info, force bool) error {
+	if force || !info.ControlAvailable || info.Managers > 1 {
+		return nil
+	}
+	return fmt.Errorf("%s is the last swarm manager of the cluster. Use --force to remove it anyway", hostName)
+}
+
+type InstallHostAdd struct {
+	fs     *gnuflag.FlagSet
+	config string
+	number int
+	pool   string
+}
+
+func (c *InstallHostAdd) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "install-host-add",
+		Usage: "install-host-add [--config/-c config_file] [--number/-n number] [--pool/-p pool]",
+		Desc: `Provisions new hosts for applications in an existing installation, using
+the driver configuration of the installation, and adds them as nodes in tsuru.
+
+The [[--config]] parameter is the path to the .yml file used in the
+installation. The driver options set in hosts:apps:driver:options are used for
+the new hosts.
+
+The [[--number]] parameter is the number of hosts to add, 1 by default. The
+[[--pool]] parameter is the pool of the new nodes, the pool created by the
+installer by default.`,
+		MinArgs: 0,
+		MaxArgs: 0,
+	}
+}
+
+func (c *InstallHostAdd) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("install-host-add", gnuflag.ExitOnError)
+		c.fs.StringVar(&c.config, "c", "", "Configuration file")
+		c.fs.StringVar(&c.config, "config", "", "Configuration file")
+		number := "Number of hosts to add"
+		c.fs.IntVar(&c.number, "n", 1, number)
+		c.fs.IntVar(&c.number, "number", 1, number)
+		pool := "Pool of the new nodes"
+		c.fs.StringVar(&c.pool, "p", "theonepool", pool)
+		c.fs.StringVar(&c.pool, "pool", "theonepool", pool)
+	}
+	return c.fs
+}
+
+func (c *InstallHostAdd) Run(context *cmd.Context, cli *cmd.Client) error {
+	context.RawOutput()
+	if c.number < 1 {
+		return errors.New("the number of hosts must be greater than zero")
+	}
+	config, err := parseConfigFile(c.config)
+	if err != nil {
+		return err
+	}
+	printConfigWarnings(context, config)
+	dockerMachine, err := dm.NewDockerMachine(config.DockerMachineConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create docker machine: %s", err)
+	}
+	defer dockerMachine.Close()
+	machines, err := ProvisionMachines(dockerMachine, c.number, config.AppsDriversOpts)
+	if err != nil {
+		return err
+	}
+	err = addInstallHosts(machines, cli)
+	if err != nil {
+		return fmt.Errorf("failed to register hosts: %s", err)
+	}
+	var nodesAddr []string
+	for _, m := range machines {
+		nodesAddr = append(nodesAddr, m.GetPrivateAddress())
+	}
+	err = registerNodes(context, cli, nodesAddr, c.pool)
+	if err != nil {
+		return err
+	}
+	for _, m := range machines {
+		fmt.Fprintf(context.Stdout, "Host %s (%s) successfully added.\n", m.Name, m.IP)
+	}
+	return nil
+}
@@ -7,16 +7,20 @@ package installer
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"reflect"
 	"strings"
+	"time"
 
+	"github.com/docker/engine-api/types/swarm"
 	"github.com/docker/machine/drivers/fakedriver"
 	"github.com/docker/machine/libmachine/host"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru-client/tsuru/installer/dm"
-	"github.com/tsuru/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"gopkg.in/check.v1"
 )
@@ -288,3 +292,311 @@ func (s *S) TestInstallHostList(c *check.C) {
 `
 	c.Assert(buf.String(), check.Equals, expected)
 }
+
+func (s *S) TestParseConfigFileSSH(c *check.C) {
+	dmConfig, err := parseConfigFile("./testdata/ssh.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(dmConfig.SSHUser, check.Equals, "admin")
+	c.Assert(dmConfig.SSHPort, check.Equals, 2222)
+}
+
+func (s *S) TestParseConfigFileSSHBastion(c *check.C) {
+	dmConfig, err := parseConfigFile("./testdata/ssh-bastion.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(dmConfig.SSHBastion, check.DeepEquals, &dm.SSHBastion{Host: "bastion.example.com:2222", User: "jump", KeyPath: "/keys/bastion"})
+	dmConfig, err = parseConfigFile("./testdata/ssh.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(dmConfig.SSHBastion, check.IsNil)
+}
+
+func (s *S) TestSetSSHOptions(c *check.C) {
+	driver := map[string]interface{}{"SSHUser": "ubuntu", "SSHPort": 22.0, "IPAddress": "10.0.0.1"}
+	setSSHOptions(driver, &dm.DockerMachineConfig{})
+	c.Assert(driver, check.DeepEquals, map[string]interface{}{"SSHUser": "ubuntu", "SSHPort": 22.0, "IPAddress": "10.0.0.1"})
+	setSSHOptions(driver, &dm.DockerMachineConfig{SSHUser: "admin", SSHPort: 2222})
+	c.Assert(driver, check.DeepEquals, map[string]interface{}{"SSHUser": "admin", "SSHPort": 2222, "IPAddress": "10.0.0.1"})
+}
+
+func (s *S) TestParseConfigFileProxy(c *check.C) {
+	dmConfig, err := parseConfigFile("./testdata/proxy.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(dmConfig.HTTPProxy, check.Equals, "http://proxy:3128")
+	c.Assert(dmConfig.HTTPSProxy, check.Equals, "http://proxy:3129")
+	c.Assert(dmConfig.NoProxy, check.Equals, "localhost,10.0.0.0/8")
+}
+
+func (s *S) TestParseConfigFileImageRegistry(c *check.C) {
+	defer config.Unset("registry")
+	dmConfig, err := parseConfigFile("./testdata/registry.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(dmConfig.ComponentsConfig.ImageRegistry, check.DeepEquals, ImageRegistryConfig{
+		URL:      "registry.example.com",
+		Username: "user",
+		Password: "secret",
+	})
+}
+
+func (s *S) TestParseConfigFileReplicas(c *check.C) {
+	defer config.Unset("components")
+	dmConfig, err := parseConfigFile("./testdata/replicas.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(dmConfig.ComponentsConfig.Replicas, check.DeepEquals, map[string]int{
+		"planb":    3,
+		"registry": 2,
+		"api":      2,
+	})
+	c.Assert(dmConfig.ComponentsConfig.ComponentAddress["registry"], check.Equals, "")
+	unknown, err := unknownConfigKeys("./testdata/replicas.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(unknown, check.HasLen, 0)
+}
+
+func (s *S) TestParseConfigFileReplicasExceedCoreHosts(c *check.C) {
+	defer config.Unset("components")
+	_, err := parseConfigFile("./testdata/replicas-exceed.yml")
+	c.Assert(err, check.ErrorMatches, "invalid components:api:replicas 3, api runs only on the core hosts and hosts:core:size is 2")
+}
+
+func (s *S) TestParseConfigFileInstallTimeout(c *check.C) {
+	defer config.Unset("components")
+	dmConfig, err := parseConfigFile("./testdata/install-timeout.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(dmConfig.ComponentsConfig.InstallTimeout, check.Equals, 15*time.Minute)
+	c.Assert(dmConfig.ComponentsConfig.InstallTimeouts, check.DeepEquals, map[string]time.Duration{
+		"mongo": 20 * time.Minute,
+		"api":   5 * time.Minute,
+	})
+	c.Assert(dmConfig.ComponentsConfig.installTimeout("mongo"), check.Equals, 20*time.Minute)
+	c.Assert(dmConfig.ComponentsConfig.installTimeout("redis"), check.Equals, 15*time.Minute)
+	c.Assert(dmConfig.ComponentsConfig.HealthTimeout, check.Equals, 10*time.Minute)
+	c.Assert(dmConfig.ComponentsConfig.ComponentAddress["mongo"], check.Equals, "")
+	unknown, err := unknownConfigKeys("./testdata/install-timeout.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(unknown, check.HasLen, 0)
+}
+
+func (s *S) TestParseConfigFileInvalidInstallTimeout(c *check.C) {
+	defer config.Unset("components")
+	_, err := parseConfigFile("./testdata/install-timeout-invalid.yml")
+	c.Assert(err, check.ErrorMatches, "invalid components:planb:install-timeout 600, it must be a positive duration, like 10m")
+}
+
+func (s *S) TestValidateReplicas(c *check.C) {
+	err := validateReplicas(&ComponentsConfig{Replicas: map[string]int{"planb": 5}}, 1)
+	c.Assert(err, check.IsNil)
+	err = validateReplicas(&ComponentsConfig{Replicas: map[string]int{"planb": 0}}, 1)
+	c.Assert(err, check.ErrorMatches, "invalid components:planb:replicas 0, it must be at least 1")
+	err = validateReplicas(&ComponentsConfig{}, 1)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestParseConfigFileSpot(c *check.C) {
+	dmConfig, err := parseConfigFile("./testdata/spot.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(dmConfig.AppsDriversOpts, check.DeepEquals, map[string][]interface{}{
+		"amazonec2-spot-price":            {"0.10"},
+		"amazonec2-request-spot-instance": {true},
+	})
+}
+
+func (s *S) TestParseConfigFileSpotUnknownDriver(c *check.C) {
+	_, err := parseConfigFile("./testdata/spot-unknown-driver.yml")
+	c.Assert(err, check.ErrorMatches, `hosts:apps:spot is not supported by the "virtualbox" driver, .*`)
+}
+
+func (s *S) TestAddSpotDriverOptsKeepsUserOptions(c *check.C) {
+	opts, err := addSpotDriverOpts("google", map[string][]interface{}{"google-preemptible": {false}})
+	c.Assert(err, check.IsNil)
+	c.Assert(opts, check.DeepEquals, map[string][]interface{}{"google-preemptible": {false}})
+}
+
+func (s *S) TestParseConfigFileTags(c *check.C) {
+	dmConfig, err := parseConfigFile("./testdata/tags.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(dmConfig.CoreDriversOpts, check.DeepEquals, map[string][]interface{}{
+		"amazonec2-tags": {"cost-center,42,team,infra"},
+	})
+	c.Assert(dmConfig.AppsDriversOpts, check.DeepEquals, map[string][]interface{}{
+		"amazonec2-tags": {"env,prod,team,apps"},
+	})
+	c.Assert(dmConfig.Warnings, check.IsNil)
+	c.Assert(defaultTsuruInstallConfig.CoreDriversOpts, check.HasLen, 0)
+}
+
+func (s *S) TestParseConfigFileTagsUnsupportedDriver(c *check.C) {
+	dmConfig, err := parseConfigFile("./testdata/tags-unsupported-driver.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(dmConfig.AppsDriversOpts, check.IsNil)
+	c.Assert(dmConfig.Warnings, check.DeepEquals, []string{`the "virtualbox" driver doesn't support tags, hosts:apps:tags will be ignored.`})
+}
+
+func (s *S) TestAddTagDriverOpts(c *check.C) {
+	opts, ok := addTagDriverOpts("digitalocean", map[string]string{"team": "infra", "env": "prod"}, nil)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(opts, check.DeepEquals, map[string][]interface{}{"digitalocean-tags": {"env:prod,team:infra"}})
+}
+
+func (s *S) TestUninstallConfirm(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stdin: strings.NewReader("tsuru\n")}
+	command := Uninstall{}
+	c.Assert(command.confirm(&context, "tsuru", []string{"tsuru-1", "tsuru-2"}), check.Equals, true)
+	expected := `The following machines of the installation "tsuru" will be deleted:
+  - tsuru-1
+  - tsuru-2
+Type the name of the installation (tsuru) to confirm: `
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestUninstallConfirmWrongName(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stdin: strings.NewReader("y\n")}
+	command := Uninstall{}
+	c.Assert(command.confirm(&context, "tsuru", []string{"tsuru-1"}), check.Equals, false)
+	c.Assert(strings.HasSuffix(stdout.String(), "to confirm: Abort.\n"), check.Equals, true)
+}
+
+func (s *S) TestUninstallConfirmYes(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stdin: strings.NewReader("")}
+	command := Uninstall{}
+	err := command.Flags().Parse(true, []string{"--yes"})
+	c.Assert(err, check.IsNil)
+	c.Assert(command.confirm(&context, "tsuru", nil), check.Equals, true)
+	c.Assert(stdout.String(), check.Equals, "No machines found for the installation \"tsuru\".\n")
+}
+
+func (s *S) TestCheckSwarmManager(c *check.C) {
+	err := checkSwarmManager("m1", swarm.Info{ControlAvailable: true, Managers: 1}, false)
+	c.Assert(err, check.ErrorMatches, "m1 is the last swarm manager of the cluster. Use --force to remove it anyway")
+	err = checkSwarmManager("m1", swarm.Info{ControlAvailable: true, Managers: 1}, true)
+	c.Assert(err, check.IsNil)
+	err = checkSwarmManager("m1", swarm.Info{ControlAvailable: true, Managers: 3}, false)
+	c.Assert(err, check.IsNil)
+	err = checkSwarmManager("m1", swarm.Info{ControlAvailable: false, Managers: 0}, false)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestInstallHostRemoveLeaveSwarmUnreachable(c *check.C) {
+	defer func(f func(*dm.Machine) (swarm.Info, error)) { swarmInfo = f }(swarmInfo)
+	swarmInfo = func(*dm.Machine) (swarm.Info, error) {
+		return swarm.Info{}, errors.New("connection refused")
+	}
+	m := &dm.Machine{Host: &host.Host{Name: "m1"}}
+	var stderr bytes.Buffer
+	context := cmd.Context{Stderr: &stderr}
+	command := InstallHostRemove{}
+	err := command.leaveSwarm(&context, m)
+	c.Assert(err, check.ErrorMatches, "failed to check the swarm state of m1: connection refused. Use --force to remove it anyway")
+	err = command.Flags().Parse(true, []string{"--force"})
+	c.Assert(err, check.IsNil)
+	err = command.leaveSwarm(&context, m)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestInstallHostRemoveLeaveSwarmLastManager(c *check.C) {
+	defer func(f func(*dm.Machine) (swarm.Info, error)) { swarmInfo = f }(swarmInfo)
+	swarmInfo = func(*dm.Machine) (swarm.Info, error) {
+		return swarm.Info{ControlAvailable: true, Managers: 1}, nil
+	}
+	m := &dm.Machine{Host: &host.Host{Name: "m1"}}
+	command := InstallHostRemove{}
+	err := command.leaveSwarm(&cmd.Context{}, m)
+	c.Assert(err, check.ErrorMatches, "m1 is the last swarm manager of the cluster. Use --force to remove it anyway")
+}
+
+func (s *S) TestInstallHostAddFlags(c *check.C) {
+	command := InstallHostAdd{}
+	flags := command.Flags()
+	c.Assert(command.number, check.Equals, 1)
+	c.Assert(command.pool, check.Equals, "theonepool")
+	err := flags.Parse(true, []string{"-c", "my-conf.yml", "-n", "3", "--pool", "apps"})
+	c.Assert(err, check.IsNil)
+	c.Assert(command.config, check.Equals, "my-conf.yml")
+	c.Assert(command.number, check.Equals, 3)
+	c.Assert(command.pool, check.Equals, "apps")
+}
+
+func (s *S) TestInstallHostAddInvalidNumber(c *check.C) {
+	command := InstallHostAdd{}
+	err := command.Flags().Parse(true, []string{"-n", "0"})
+	c.Assert(err, check.IsNil)
+	err = command.Run(&cmd.Context{}, nil)
+	c.Assert(err, check.ErrorMatches, "the number of hosts must be greater than zero")
+}
+
+func (s *S) TestUnknownConfigKeys(c *check.C) {
+	unknown, err := unknownConfigKeys("./testdata/unknown-keys.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(unknown, check.DeepEquals, []string{
+		"driver:ssh-usr",
+		"hosts:apps:dedicate",
+		"hosts:core:sizee",
+		"nmae",
+	})
+	unknown, err = unknownConfigKeys("./testdata/hosts.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(unknown, check.HasLen, 0)
+}
+
+func (s *S) TestInstallValidateConfig(c *check.C) {
+	var stderr bytes.Buffer
+	context := cmd.Context{Stderr: &stderr}
+	command := Install{}
+	err := command.Flags().Parse(true, []string{"-c", "./testdata/unknown-keys.yml"})
+	c.Assert(err, check.IsNil)
+	err = command.validateConfig(&context)
+	c.Assert(err, check.IsNil)
+	c.Assert(stderr.String(), check.Equals, `WARNING: unknown key "driver:ssh-usr" in the configuration file will be ignored.
+WARNING: unknown key "hosts:apps:dedicate" in the configuration file will be ignored.
+WARNING: unknown key "hosts:core:sizee" in the configuration file will be ignored.
+WARNING: unknown key "nmae" in the configuration file will be ignored.
+`)
+}
+
+func (s *S) TestInstallValidateConfigStrict(c *check.C) {
+	command := Install{}
+	err := command.Flags().Parse(true, []string{"-c", "./testdata/unknown-keys.yml", "--strict"})
+	c.Assert(err, check.IsNil)
+	err = command.validateConfig(&cmd.Context{})
+	c.Assert(err, check.ErrorMatches, "unknown keys in the configuration file: driver:ssh-usr, hosts:apps:dedicate, hosts:core:sizee, nmae")
+}
+
+func (s *S) TestParseConfigFileInclude(c *check.C) {
+	dmConfig, err := parseConfigFile("./testdata/include/staging.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(dmConfig.Name, check.Equals, "tsuru-staging")
+	c.Assert(dmConfig.DriverName, check.Equals, "amazonec2")
+	c.Assert(dmConfig.DriverOpts, check.DeepEquals, dm.DriverOpts{"opt1": "base-value", "opt2": "staging-value"})
+	c.Assert(dmConfig.CoreHosts, check.Equals, 2)
+	c.Assert(dmConfig.CoreDriversOpts, check.DeepEquals, map[string][]interface{}{
+		"amazonec2-region": {"us-east", "us-west"},
+	})
+	c.Assert(dmConfig.AppsHosts, check.Equals, 3)
+}
+
+func (s *S) TestParseConfigFileIncludeCycle(c *check.C) {
+	_, err := parseConfigFile("./testdata/include/cycle-a.yml")
+	c.Assert(err, check.ErrorMatches, `failed to include .*/cycle-a.yml from .*/cycle-b.yml: include cycle detected: .*/cycle-a.yml -> .*/cycle-b.yml -> .*/cycle-a.yml`)
+}
+
+func (s *S) TestParseConfigFileIncludeMissing(c *check.C) {
+	_, err := parseConfigFile("./testdata/include/missing.yml")
+	c.Assert(err, check.ErrorMatches, `failed to include testdata/include/not-found.yml from ./testdata/include/missing.yml: .*no such file or directory`)
+}
+
+func (s *S) TestUnknownConfigKeysWithInclude(c *check.C) {
+	unknown, err := unknownConfigKeys("./testdata/include/staging.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(unknown, check.HasLen, 0)
+}
+
+func (s *S) TestInstallLogsWriterFollowsTheVerbosity(c *check.C) {
+	var stderr bytes.Buffer
+	context := cmd.Context{Stderr: &stderr}
+	client := cmd.NewClient(http.DefaultClient, &context, manager)
+	c.Assert(logsWriter(&context, client), check.IsNil)
+	c.Assert(logsWriter(&context, nil), check.IsNil)
+	client.Verbosity = 1
+	c.Assert(logsWriter(&context, client), check.Equals, &stderr)
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/tsuru/tsuru-client/tsuru/installer/dm"
+)
+
+const (
+	coreRole = "core"
+	appsRole = "apps"
+)
+
+// Inventory describes the machines created by the installer, so they can be
+// used by other tools after the installation. It never includes the content
+// of private keys, only the path of the files.
+type Inventory struct {
+	Name     string             `json:"name"`
+	Target   string             `json:"target"`
+	Machines []InventoryMachine `json:"machines"`
+}
+
+type InventoryMachine struct {
+	Name       string   `json:"name"`
+	IP         string   `json:"ip"`
+	Driver     string   `json:"driver"`
+	Roles      []string `json:"roles"`
+	SSHUser    string   `json:"ssh-user,omitempty"`
+	SSHKeyPath string   `json:"ssh-key-path,omitempty"`
+	CAPath     string   `json:"ca-path,omitempty"`
+}
+
+func newInventory(name, target string, coreMachines, appsMachines []*dm.Machine) *Inventory {
+	inv := &Inventory{Name: name, Target: target}
+	index := make(map[*dm.Machine]int)
+	add := func(m *dm.Machine, role string) {
+		if i, ok := index[m]; ok {
+			inv.Machines[i].Roles = append(inv.Machines[i].Roles, role)
+			return
+		}
+		im := InventoryMachine{IP: m.IP, Roles: []string{role}, CAPath: m.CAPath}
+		if m.Host != nil {
+			im.Name = m.Name
+			im.Driver = m.DriverName
+			if m.Driver != nil {
+				im.SSHUser = m.GetSSHUsername()
+				im.SSHKeyPath = m.GetSSHKeyPath()
+			}
+		}
+		index[m] = len(inv.Machines)
+		inv.Machines = append(inv.Machines, im)
+	}
+	for _, m := range coreMachines {
+		add(m, coreRole)
+	}
+	for _, m := range appsMachines {
+		add(m, appsRole)
+	}
+	return inv
+}
+
+// Write saves the inventory in the given path, in JSON format if the file has
+// the .json extension and in YAML otherwise.
+func (inv *Inventory) Write(path string) error {
+	var (
+		data []byte
+		err  error
+	)
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		data, err = json.MarshalIndent(inv, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(inv)
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,83 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/tsuru/tsuru-client/tsuru/installer/dm"
+	"gopkg.in/check.v1"
+)
+
+// sshDriver is a fake driver reporting the SSH settings of its base driver,
+// which the fake driver of docker machine ignores.
+type sshDriver struct {
+	fakedriver.Driver
+}
+
+func (d *sshDriver) GetSSHUsername() string {
+	return d.BaseDriver.GetSSHUsername()
+}
+
+func (d *sshDriver) GetSSHKeyPath() string {
+	return d.BaseDriver.GetSSHKeyPath()
+}
+
+func newFakeMachine(name, ip string) *dm.Machine {
+	driver := &sshDriver{fakedriver.Driver{
+		BaseDriver: &drivers.BaseDriver{SSHUser: "ubuntu", SSHKeyPath: "/keys/" + name},
+		MockIP:     ip,
+	}}
+	return &dm.Machine{
+		Host:   &host.Host{Name: name, DriverName: "amazonec2", Driver: driver},
+		IP:     ip,
+		CAPath: "/certs",
+	}
+}
+
+func (s *S) TestNewInventory(c *check.C) {
+	core := []*dm.Machine{newFakeMachine("m1", "10.0.0.1"), newFakeMachine("m2", "10.0.0.2")}
+	apps := []*dm.Machine{newFakeMachine("m3", "10.0.0.3"), core[0]}
+	inv := newInventory("tsuru", "http://10.0.0.1:8080", core, apps)
+	c.Assert(inv, check.DeepEquals, &Inventory{
+		Name:   "tsuru",
+		Target: "http://10.0.0.1:8080",
+		Machines: []InventoryMachine{
+			{Name: "m1", IP: "10.0.0.1", Driver: "amazonec2", Roles: []string{"core", "apps"}, SSHUser: "ubuntu", SSHKeyPath: "/keys/m1", CAPath: "/certs"},
+			{Name: "m2", IP: "10.0.0.2", Driver: "amazonec2", Roles: []string{"core"}, SSHUser: "ubuntu", SSHKeyPath: "/keys/m2", CAPath: "/certs"},
+			{Name: "m3", IP: "10.0.0.3", Driver: "amazonec2", Roles: []string{"apps"}, SSHUser: "ubuntu", SSHKeyPath: "/keys/m3", CAPath: "/certs"},
+		},
+	})
+}
+
+func (s *S) TestInventoryWrite(c *check.C) {
+	dir, err := ioutil.TempDir("", "inventory")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	inv := newInventory("tsuru", "http://10.0.0.1:8080", []*dm.Machine{newFakeMachine("m1", "10.0.0.1")}, nil)
+	jsonPath := filepath.Join(dir, "inventory.json")
+	err = inv.Write(jsonPath)
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadFile(jsonPath)
+	c.Assert(err, check.IsNil)
+	var written Inventory
+	err = json.Unmarshal(data, &written)
+	c.Assert(err, check.IsNil)
+	c.Assert(&written, check.DeepEquals, inv)
+	yamlPath := filepath.Join(dir, "inventory.yml")
+	err = inv.Write(yamlPath)
+	c.Assert(err, check.IsNil)
+	data, err = ioutil.ReadFile(yamlPath)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Contains(string(data), "  name: m1\n"), check.Equals, true)
+	c.Assert(strings.Contains(string(data), "ssh-key-path: /keys/m1\n"), check.Equals, true)
+}
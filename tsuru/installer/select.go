@@ -0,0 +1,123 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// componentKeys are the short names of the components, used in the
+// --components flag of install and in the components section of the
+// configuration file.
+var componentKeys = map[string]string{
+	"MongoDB":         "mongo",
+	"Redis":           "redis",
+	"PlanB":           "planb",
+	"Docker Registry": "registry",
+	"Tsuru API":       "api",
+}
+
+func componentKey(component TsuruComponent) string {
+	if key, ok := componentKeys[component.Name()]; ok {
+		return key
+	}
+	return component.Name()
+}
+
+// selectComponents returns the components with the given keys, keeping the
+// order of all.
+func selectComponents(all []TsuruComponent, keys []string) ([]TsuruComponent, error) {
+	valid := make(map[string]bool, len(all))
+	validKeys := make([]string, 0, len(all))
+	for _, component := range all {
+		valid[componentKey(component)] = true
+		validKeys = append(validKeys, componentKey(component))
+	}
+	sort.Strings(validKeys)
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if !valid[key] {
+			return nil, fmt.Errorf("unknown component %q, valid components are: %s", key, strings.Join(validKeys, ", "))
+		}
+		wanted[key] = true
+	}
+	if len(wanted) == 0 {
+		return nil, fmt.Errorf("no components selected, valid components are: %s", strings.Join(validKeys, ", "))
+	}
+	var selected []TsuruComponent
+	for _, component := range all {
+		if wanted[componentKey(component)] {
+			selected = append(selected, component)
+		}
+	}
+	return selected, nil
+}
+
+// selectedComponent restricts the dependencies of a component to the other
+// selected components, the remaining ones being already installed.
+type selectedComponent struct {
+	TsuruComponent
+	deps []string
+}
+
+func (c *selectedComponent) Dependencies() []string {
+	return c.deps
+}
+
+// installedComponentAddress returns the address of a component installed in
+// the cluster, as set by its Install method.
+func installedComponentAddress(key string, cluster ServiceCluster) string {
+	switch key {
+	case "mongo", "redis":
+		return key
+	case "planb", "registry":
+		return cluster.GetManager().IP
+	}
+	return ""
+}
+
+// prepareSelectedComponents checks that the dependencies of the selected
+// components that were not selected are already installed in the cluster,
+// setting their addresses in config, and returns the selected components
+// depending only on each other.
+func prepareSelectedComponents(cluster ServiceCluster, config *ComponentsConfig, all, selected []TsuruComponent) ([]TsuruComponent, error) {
+	byName := make(map[string]TsuruComponent, len(all))
+	for _, component := range all {
+		byName[component.Name()] = component
+	}
+	isSelected := make(map[string]bool, len(selected))
+	for _, component := range selected {
+		isSelected[component.Name()] = true
+	}
+	prepared := make([]TsuruComponent, len(selected))
+	for i, component := range selected {
+		var deps []string
+		for _, dep := range componentDependencies(component) {
+			if isSelected[dep] {
+				deps = append(deps, dep)
+				continue
+			}
+			depComponent, ok := byName[dep]
+			if !ok {
+				return nil, fmt.Errorf("%s depends on unknown component %q", component.Name(), dep)
+			}
+			key := componentKey(depComponent)
+			if _, err := depComponent.Status(cluster); err != nil {
+				return nil, fmt.Errorf("%s depends on %s, which is not installed: add %s to the selected components", component.Name(), dep, key)
+			}
+			if config.address(key) == "" {
+				config.setAddress(key, installedComponentAddress(key, cluster))
+			}
+		}
+		prepared[i] = &selectedComponent{TsuruComponent: component, deps: deps}
+	}
+	return prepared, nil
+}
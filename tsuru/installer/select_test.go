@@ -0,0 +1,67 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import (
+	"errors"
+
+	"gopkg.in/check.v1"
+)
+
+type missingServiceCluster struct {
+	FakeServiceCluster
+	missing string
+}
+
+func (c *missingServiceCluster) ServiceInfo(service string) (*ServiceInfo, error) {
+	if service == c.missing {
+		return nil, errors.New("service not found")
+	}
+	return c.FakeServiceCluster.ServiceInfo(service)
+}
+
+func componentNames(components []TsuruComponent) []string {
+	names := make([]string, len(components))
+	for i, component := range components {
+		names[i] = component.Name()
+	}
+	return names
+}
+
+func (s *S) TestSelectComponents(c *check.C) {
+	selected, err := selectComponents(TsuruComponents, []string{"registry", " mongo", ""})
+	c.Assert(err, check.IsNil)
+	c.Assert(componentNames(selected), check.DeepEquals, []string{"MongoDB", "Docker Registry"})
+}
+
+func (s *S) TestSelectComponentsUnknown(c *check.C) {
+	_, err := selectComponents(TsuruComponents, []string{"mongo", "mysql"})
+	c.Assert(err, check.ErrorMatches, `unknown component "mysql", valid components are: api, mongo, planb, redis, registry`)
+	_, err = selectComponents(TsuruComponents, []string{""})
+	c.Assert(err, check.ErrorMatches, `no components selected, valid components are: api, mongo, planb, redis, registry`)
+}
+
+func (s *S) TestPrepareSelectedComponents(c *check.C) {
+	config := &ComponentsConfig{ComponentAddress: map[string]string{"redis": "redis.example.com"}}
+	selected, err := selectComponents(TsuruComponents, []string{"planb", "api"})
+	c.Assert(err, check.IsNil)
+	components, err := prepareSelectedComponents(&FakeServiceCluster{}, config, TsuruComponents, selected)
+	c.Assert(err, check.IsNil)
+	c.Assert(componentNames(components), check.DeepEquals, []string{"PlanB", "Tsuru API"})
+	c.Assert(componentDependencies(components[0]), check.HasLen, 0)
+	c.Assert(componentDependencies(components[1]), check.DeepEquals, []string{"PlanB"})
+	c.Assert(config.address("mongo"), check.Equals, "mongo")
+	c.Assert(config.address("redis"), check.Equals, "redis.example.com")
+	c.Assert(config.address("registry"), check.Equals, "127.0.0.1")
+	c.Assert(config.address("planb"), check.Equals, "")
+}
+
+func (s *S) TestPrepareSelectedComponentsDependencyNotInstalled(c *check.C) {
+	selected, err := selectComponents(TsuruComponents, []string{"api"})
+	c.Assert(err, check.IsNil)
+	cluster := &missingServiceCluster{missing: "mongo"}
+	_, err = prepareSelectedComponents(cluster, NewInstallConfig("test"), TsuruComponents, selected)
+	c.Assert(err, check.ErrorMatches, "Tsuru API depends on MongoDB, which is not installed: add mongo to the selected components")
+}
@@ -0,0 +1,36 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import "fmt"
+
+// spotDriverOpts are the driver options that make each known driver
+// provision spot (or preemptible) instances.
+var spotDriverOpts = map[string]map[string]interface{}{
+	"amazonec2": {
+		"amazonec2-request-spot-instance": true,
+	},
+	"google": {
+		"google-preemptible": true,
+	},
+}
+
+// addSpotDriverOpts adds the options required by the given driver to
+// provision spot instances to opts. Options already set by the user are kept.
+func addSpotDriverOpts(driverName string, opts map[string][]interface{}) (map[string][]interface{}, error) {
+	spotOpts, ok := spotDriverOpts[driverName]
+	if !ok {
+		return nil, fmt.Errorf("hosts:apps:spot is not supported by the %q driver, set the driver options in hosts:apps:driver:options instead", driverName)
+	}
+	if opts == nil {
+		opts = make(map[string][]interface{})
+	}
+	for k, v := range spotOpts {
+		if _, ok := opts[k]; !ok {
+			opts[k] = []interface{}{v}
+		}
+	}
+	return opts, nil
+}
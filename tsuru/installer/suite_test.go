@@ -11,9 +11,9 @@ import (
 	"testing"
 
 	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru-client/tsuru/installer/dm"
 	"github.com/tsuru/tsuru-client/tsuru/installer/testing"
-	"github.com/tsuru/tsuru/cmd"
 	check "gopkg.in/check.v1"
 )
 
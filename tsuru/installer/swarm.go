@@ -5,6 +5,8 @@
 package installer
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -21,8 +23,14 @@ type ServiceCluster interface {
 	CreateService(docker.CreateServiceOptions) error
 	ServiceInfo(string) (*ServiceInfo, error)
 	ClusterInfo() ([]NodeInfo, error)
+	PullImage(string, docker.AuthConfiguration) error
+	ServiceLogs(string, int) (string, error)
 }
 
+// swarmNetworkName is the name of the overlay network connecting the services
+// of the components.
+const swarmNetworkName = "tsuru"
+
 type SwarmCluster struct {
 	Managers []*dm.Machine
 	Workers  []*dm.Machine
@@ -59,7 +67,7 @@ func NewSwarmCluster(machines []*dm.Machine, numManagers int) (*SwarmCluster, er
 		return nil, fmt.Errorf("failed to inspect swarm: %s", err)
 	}
 	createNetworkOpts := docker.CreateNetworkOptions{
-		Name:           "tsuru",
+		Name:           swarmNetworkName,
 		Driver:         "overlay",
 		CheckDuplicate: true,
 		IPAM: docker.IPAMOptions{
@@ -110,6 +118,30 @@ func NewSwarmCluster(machines []*dm.Machine, numManagers int) (*SwarmCluster, er
 	}, nil
 }
 
+// loadSwarmCluster reconnects to the swarm cluster of an existing
+// installation, given all its machines. The managers are found by asking the
+// docker engine of each machine.
+func loadSwarmCluster(machines []*dm.Machine) (*SwarmCluster, error) {
+	var managers []*dm.Machine
+	for _, m := range machines {
+		info, err := swarmInfo(m)
+		if err != nil {
+			continue
+		}
+		if info.ControlAvailable {
+			managers = append(managers, m)
+		}
+	}
+	if len(managers) == 0 {
+		return nil, errors.New("no swarm manager found in the hosts of the installation")
+	}
+	return &SwarmCluster{
+		Managers: managers,
+		Workers:  machines,
+		network:  &docker.Network{Name: swarmNetworkName},
+	}, nil
+}
+
 // ServiceExec finds a container running a service task and runs exec on it
 func (c *SwarmCluster) ServiceExec(service string, cmd []string, startOpts docker.StartExecOptions) error {
 	mClient, err := c.dockerClient()
@@ -128,24 +160,9 @@ func (c *SwarmCluster) ServiceExec(service string, cmd []string, startOpts docke
 	if len(tasks) == 0 {
 		return fmt.Errorf("no running task found for service %s", service)
 	}
-	node, err := mClient.InspectNode(tasks[0].NodeID)
+	client, err := c.taskDockerClient(mClient, tasks[0])
 	if err != nil {
-		return fmt.Errorf("failed to inspect node %s: %s", tasks[0].NodeID, err)
-	}
-	nodeName := node.Description.Hostname
-	var machine *dm.Machine
-	for _, m := range c.Workers {
-		if m.Name == nodeName {
-			machine = m
-			break
-		}
-	}
-	if machine == nil {
-		return fmt.Errorf("machine %s not found", nodeName)
-	}
-	client, err := machine.DockerClient()
-	if err != nil {
-		return fmt.Errorf("failed to retrieve task node %s docker client: %s", machine.Name, err)
+		return err
 	}
 	container := tasks[0].Status.ContainerStatus.ContainerID
 	exec, err := client.CreateExec(docker.CreateExecOptions{
@@ -161,12 +178,88 @@ func (c *SwarmCluster) ServiceExec(service string, cmd []string, startOpts docke
 	return client.StartExec(exec.ID, startOpts)
 }
 
-// CreateService creates a service on the swarm cluster
+// ServiceLogs returns the last lines of the logs of the container of the most
+// recent task of a service, whatever its state, so the logs of a task that
+// failed to start can also be retrieved.
+func (c *SwarmCluster) ServiceLogs(service string, lines int) (string, error) {
+	mClient, err := c.dockerClient()
+	if err != nil {
+		return "", fmt.Errorf("failed to retrive swarm docker client: %s", err)
+	}
+	tasks, err := mClient.ListTasks(docker.ListTasksOptions{
+		Filters: map[string][]string{"service": {service}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list tasks for service %s: %s", service, err)
+	}
+	var task *swarm.Task
+	for i, t := range tasks {
+		if t.Status.ContainerStatus.ContainerID == "" {
+			continue
+		}
+		if task == nil || t.UpdatedAt.After(task.UpdatedAt) {
+			task = &tasks[i]
+		}
+	}
+	if task == nil {
+		return "", fmt.Errorf("no task container found for service %s", service)
+	}
+	client, err := c.taskDockerClient(mClient, *task)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = client.Logs(docker.LogsOptions{
+		Container:    task.Status.ContainerStatus.ContainerID,
+		OutputStream: &buf,
+		ErrorStream:  &buf,
+		Stdout:       true,
+		Stderr:       true,
+		Tail:         strconv.Itoa(lines),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve logs of task container %s: %s", task.Status.ContainerStatus.ContainerID, err)
+	}
+	return buf.String(), nil
+}
+
+// taskDockerClient returns the docker client of the machine running a task.
+func (c *SwarmCluster) taskDockerClient(mClient *docker.Client, task swarm.Task) (*docker.Client, error) {
+	node, err := mClient.InspectNode(task.NodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect node %s: %s", task.NodeID, err)
+	}
+	machine, err := c.GetMachine(node.Description.Hostname)
+	if err != nil {
+		return nil, err
+	}
+	client, err := machine.DockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve task node %s docker client: %s", machine.Name, err)
+	}
+	return client, nil
+}
+
+// CreateService creates a service on the swarm cluster. A service with the
+// same name, left by a previous installation of the component, is removed
+// first, so installing a component again on an existing cluster replaces its
+// service.
 func (c *SwarmCluster) CreateService(opts docker.CreateServiceOptions) error {
 	client, err := c.dockerClient()
 	if err != nil {
 		return err
 	}
+	if opts.Name != "" {
+		service, err := client.InspectService(opts.Name)
+		if err == nil {
+			err = client.RemoveService(docker.RemoveServiceOptions{ID: service.ID, Context: opts.Context})
+			if err != nil {
+				return fmt.Errorf("failed to remove existing service %s: %s", opts.Name, err)
+			}
+		} else if _, ok := err.(*docker.NoSuchService); !ok {
+			return fmt.Errorf("failed to inspect service %s: %s", opts.Name, err)
+		}
+	}
 	opts.Networks = []swarm.NetworkAttachmentConfig{
 		{Target: c.network.Name},
 	}
@@ -174,10 +267,26 @@ func (c *SwarmCluster) CreateService(opts docker.CreateServiceOptions) error {
 	return err
 }
 
+// PullImage pulls the image on every machine of the cluster.
+func (c *SwarmCluster) PullImage(image string, auth docker.AuthConfiguration) error {
+	repository, tag := docker.ParseRepositoryTag(image)
+	for _, m := range c.Workers {
+		client, err := m.DockerClient()
+		if err != nil {
+			return err
+		}
+		err = client.PullImage(docker.PullImageOptions{Repository: repository, Tag: tag}, auth)
+		if err != nil {
+			return fmt.Errorf("failed to pull image on %s: %s", m.Name, err)
+		}
+	}
+	return nil
+}
+
 type NodeInfo struct {
-	IP      string
-	State   string
-	Manager bool
+	IP      string `json:"ip"`
+	State   string `json:"state"`
+	Manager bool   `json:"manager"`
 }
 
 func (c *SwarmCluster) ClusterInfo() ([]NodeInfo, error) {
@@ -218,9 +327,10 @@ func (c *SwarmCluster) GetMachine(name string) (*dm.Machine, error) {
 }
 
 type ServiceInfo struct {
-	Name     string
-	Replicas int
-	Ports    []string
+	Name            string
+	Replicas        int
+	RunningReplicas int
+	Ports           []string
 }
 
 func (c *SwarmCluster) ServiceInfo(name string) (*ServiceInfo, error) {
@@ -236,9 +346,22 @@ func (c *SwarmCluster) ServiceInfo(name string) (*ServiceInfo, error) {
 	for _, p := range service.Endpoint.Ports {
 		ports = append(ports, strconv.Itoa(int(p.PublishedPort)))
 	}
+	tasks, err := client.ListTasks(docker.ListTasksOptions{
+		Filters: map[string][]string{"service": {name}, "desired-state": {"running"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var running int
+	for _, t := range tasks {
+		if t.Status.State == swarm.TaskStateRunning {
+			running++
+		}
+	}
 	return &ServiceInfo{
-		Name:     name,
-		Replicas: int(*service.Spec.Mode.Replicated.Replicas),
-		Ports:    ports,
+		Name:            name,
+		Replicas:        int(*service.Spec.Mode.Replicated.Replicas),
+		RunningReplicas: running,
+		Ports:           ports,
 	}, nil
 }
@@ -193,6 +193,48 @@ func (s *S) TestCreateService(c *check.C) {
 	c.Assert(created, check.Equals, true)
 }
 
+func (s *S) TestCreateServiceReplacesExistingService(c *check.C) {
+	testCluster, err := s.createCluster()
+	c.Assert(err, check.IsNil)
+	defer testCluster.Stop()
+	testCluster.ManagerServer.CustomHandler("^/services/registry$", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		service := swarm.Service{
+			ID:   "registry-id",
+			Spec: swarm.ServiceSpec{Annotations: swarm.Annotations{Name: "registry"}},
+		}
+		json.NewEncoder(w).Encode(service)
+	}))
+	var calls []string
+	testCluster.ManagerServer.CustomHandler("^/services/registry-id$", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+	}))
+	testCluster.ManagerServer.CustomHandler("/services/create", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{"ID":"new-registry-id"}`))
+	}))
+	err = testCluster.SwarmCluster.CreateService(docker.CreateServiceOptions{
+		ServiceSpec: swarm.ServiceSpec{Annotations: swarm.Annotations{Name: "registry"}},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(calls, check.DeepEquals, []string{"DELETE /services/registry-id", "POST /services/create"})
+}
+
+func (s *S) TestCreateServiceFailsToRemoveExistingService(c *check.C) {
+	testCluster, err := s.createCluster()
+	c.Assert(err, check.IsNil)
+	defer testCluster.Stop()
+	testCluster.ManagerServer.CustomHandler("^/services/registry$", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(swarm.Service{ID: "registry-id"})
+	}))
+	testCluster.ManagerServer.CustomHandler("^/services/registry-id$", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "service is busy", http.StatusInternalServerError)
+	}))
+	err = testCluster.SwarmCluster.CreateService(docker.CreateServiceOptions{
+		ServiceSpec: swarm.ServiceSpec{Annotations: swarm.Annotations{Name: "registry"}},
+	})
+	c.Assert(err, check.ErrorMatches, "(?s)failed to remove existing service registry: .*service is busy.*")
+}
+
 func (s *S) TestServiceExec(c *check.C) {
 	testCluster, err := s.createCluster()
 	c.Assert(err, check.IsNil)
@@ -272,9 +314,16 @@ func (s *S) TestServiceInfo(c *check.C) {
 		}
 		json.NewEncoder(w).Encode(service)
 	}))
+	testCluster.ManagerServer.CustomHandler("/tasks", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tasks := []swarm.Task{
+			{Status: swarm.TaskStatus{State: swarm.TaskStateRunning}},
+			{Status: swarm.TaskStatus{State: swarm.TaskStateStarting}},
+		}
+		json.NewEncoder(w).Encode(tasks)
+	}))
 	info, err := testCluster.SwarmCluster.ServiceInfo("tsuru")
 	c.Assert(err, check.IsNil)
-	c.Assert(info, check.DeepEquals, &ServiceInfo{Name: "tsuru", Replicas: 2, Ports: []string{"80"}})
+	c.Assert(info, check.DeepEquals, &ServiceInfo{Name: "tsuru", Replicas: 2, RunningReplicas: 1, Ports: []string{"80"}})
 }
 
 func (s *S) TestClusterInfo(c *check.C) {
@@ -0,0 +1,68 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// tagDriverOpts maps the drivers supporting tags to the option holding them
+// and the separator between the key and the value of each tag.
+var tagDriverOpts = map[string]struct {
+	option    string
+	separator string
+}{
+	"amazonec2":    {option: "amazonec2-tags", separator: ","},
+	"digitalocean": {option: "digitalocean-tags", separator: ":"},
+}
+
+func parseTags(tags interface{}) (map[string]string, error) {
+	unparsed, ok := tags.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to parse tags: %+v", tags)
+	}
+	parsed := make(map[string]string, len(unparsed))
+	for k, v := range unparsed {
+		parsed[fmt.Sprintf("%v", k)] = fmt.Sprintf("%v", v)
+	}
+	return parsed, nil
+}
+
+// addTagDriverOpts returns a copy of opts with the option tagging the hosts
+// with the given tags, appended to the tags already set by the user. It
+// returns false when the driver doesn't support tags.
+func addTagDriverOpts(driverName string, tags map[string]string, opts map[string][]interface{}) (map[string][]interface{}, bool) {
+	driverOpt, ok := tagDriverOpts[driverName]
+	if !ok {
+		return opts, false
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + driverOpt.separator + tags[k]
+	}
+	value := strings.Join(pairs, ",")
+	result := make(map[string][]interface{}, len(opts)+1)
+	for k, v := range opts {
+		result[k] = v
+	}
+	current, ok := opts[driverOpt.option]
+	if !ok {
+		result[driverOpt.option] = []interface{}{value}
+		return result, true
+	}
+	values := make([]interface{}, len(current))
+	for i, v := range current {
+		values[i] = fmt.Sprintf("%v,%s", v, value)
+	}
+	result[driverOpt.option] = values
+	return result, true
+}
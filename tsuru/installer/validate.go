@@ -0,0 +1,156 @@
+// Copyright 2016 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package installer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tsuru/config"
+)
+
+// anyKey marks namespaces accepting arbitrary keys, like driver options.
+const anyKey = "*"
+
+// configSchema describes the keys known by parseConfigFile. Leaves are nil.
+var configSchema = map[string]interface{}{
+	"name":              nil,
+	"docker-hub-mirror": nil,
+	"ca-path":           nil,
+	"http-proxy":        nil,
+	"https-proxy":       nil,
+	"no-proxy":          nil,
+	"driver": map[string]interface{}{
+		"name":             nil,
+		"options":          anyKey,
+		"ssh-user":         nil,
+		"ssh-port":         nil,
+		"ssh-bastion-host": nil,
+		"ssh-bastion-user": nil,
+		"ssh-bastion-key":  nil,
+	},
+	"hosts": map[string]interface{}{
+		"core": map[string]interface{}{
+			"size": nil,
+			"tags": anyKey,
+			"driver": map[string]interface{}{
+				"options": anyKey,
+			},
+		},
+		"apps": map[string]interface{}{
+			"size":      nil,
+			"dedicated": nil,
+			"spot":      nil,
+			"tags":      anyKey,
+			"driver": map[string]interface{}{
+				"options": anyKey,
+			},
+		},
+	},
+	"registry": map[string]interface{}{
+		"url":      nil,
+		"username": nil,
+		"password": nil,
+	},
+	"components": map[string]interface{}{
+		"install-timeout": nil,
+		"health-timeout":  nil,
+		"mongo": map[string]interface{}{
+			"install-timeout": nil,
+		},
+		"redis": map[string]interface{}{
+			"install-timeout": nil,
+		},
+		"registry": map[string]interface{}{
+			"replicas":        nil,
+			"install-timeout": nil,
+		},
+		"planb": map[string]interface{}{
+			"replicas":        nil,
+			"install-timeout": nil,
+		},
+		"api": map[string]interface{}{
+			"replicas":        nil,
+			"install-timeout": nil,
+		},
+	},
+}
+
+// unknownConfigKeys returns the full path of the keys in the given
+// configuration file that are not known by the installer, sorted.
+func unknownConfigKeys(file string) ([]string, error) {
+	conf, err := loadInstallConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	unknown := findUnknownKeys("", conf, configSchema)
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+func findUnknownKeys(prefix string, conf map[interface{}]interface{}, schema map[string]interface{}) []string {
+	var unknown []string
+	for k, v := range conf {
+		key := fmt.Sprintf("%v", k)
+		path := key
+		if prefix != "" {
+			path = prefix + ":" + key
+		}
+		expected, ok := schema[key]
+		if !ok {
+			unknown = append(unknown, path)
+			continue
+		}
+		namespace, isNamespace := expected.(map[string]interface{})
+		if !isNamespace {
+			continue
+		}
+		if sub, ok := v.(map[interface{}]interface{}); ok {
+			unknown = append(unknown, findUnknownKeys(path, sub, namespace)...)
+		}
+	}
+	return unknown
+}
+
+// validateReplicas checks the number of replicas of the components set in
+// the configuration file. The components running only on the core hosts
+// can't have more replicas than hosts.
+func validateReplicas(config *ComponentsConfig, coreHosts int) error {
+	for _, key := range replicatedComponents {
+		n, ok := config.Replicas[key]
+		if !ok {
+			continue
+		}
+		if n < 1 {
+			return fmt.Errorf("invalid components:%s:replicas %d, it must be at least 1", key, n)
+		}
+		if managerComponents[key] && n > coreHosts {
+			return fmt.Errorf("invalid components:%s:replicas %d, %s runs only on the core hosts and hosts:core:size is %d", key, n, key, coreHosts)
+		}
+	}
+	return nil
+}
+
+// validateInstallTimeouts checks the install timeouts of the components and
+// the health timeout set in the configuration file, which must be positive
+// durations. Numbers are refused, as they would be taken as nanoseconds.
+func validateInstallTimeouts() error {
+	keys := []string{"components:install-timeout", "components:health-timeout"}
+	for _, key := range installTimeoutComponents {
+		keys = append(keys, "components:"+key+":install-timeout")
+	}
+	for _, key := range keys {
+		value, err := config.Get(key)
+		if err != nil {
+			continue
+		}
+		_, isString := value.(string)
+		timeout, err := config.GetDuration(key)
+		if !isString || err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid %s %v, it must be a positive duration, like 10m", key, value)
+		}
+	}
+	return nil
+}
@@ -5,15 +5,21 @@
 package main
 
 import (
+	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"strings"
 
 	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/tsuru/gnuflag"
 	"github.com/tsuru/tsuru-client/tsuru/admin"
 	"github.com/tsuru/tsuru-client/tsuru/client"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru-client/tsuru/installer"
 	"github.com/tsuru/tsuru-client/tsuru/installer/dm"
-	"github.com/tsuru/tsuru/cmd"
+	tsurucmd "github.com/tsuru/tsuru/cmd"
+	tsuruerr "github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/provision"
 	_ "github.com/tsuru/tsuru/provision/docker"
 )
@@ -33,10 +39,16 @@ func buildManager(name string) *cmd.Manager {
 	m.Register(&client.AppCreate{})
 	m.Register(&client.AppRemove{})
 	m.Register(&client.AppUpdate{})
+	m.Register(&client.AppMove{})
+	m.Register(&client.AppRoutersList{})
+	m.Register(&client.AppRoutersAdd{})
+	m.Register(&client.AppRoutersRemove{})
 	m.Register(&client.UnitAdd{})
 	m.Register(&client.UnitRemove{})
+	m.Register(&client.UnitKill{})
 	m.Register(&client.AppList{})
 	m.Register(&client.AppLog{})
+	m.Register(&client.AppLogMulti{})
 	m.Register(&client.AppGrant{})
 	m.Register(&client.AppRevoke{})
 	m.Register(&client.AppRestart{})
@@ -49,6 +61,8 @@ func buildManager(name string) *cmd.Manager {
 	m.Register(&client.EnvGet{})
 	m.Register(&client.EnvSet{})
 	m.Register(&client.EnvUnset{})
+	m.Register(&client.EnvDiff{})
+	m.Register(&client.EnvCopy{})
 	m.Register(&client.KeyAdd{})
 	m.Register(&client.KeyRemove{})
 	m.Register(&client.KeyList{})
@@ -62,13 +76,14 @@ func buildManager(name string) *cmd.Manager {
 	m.Register(client.ServiceInfo{})
 	m.Register(client.ServiceInstanceInfo{})
 	m.RegisterRemoved("service-status", "You should use `tsuru service-instance-status` instead.")
-	m.Register(client.ServiceInstanceStatus{})
+	m.Register(&client.ServiceInstanceStatus{})
 	m.Register(&client.ServiceInstanceGrant{})
 	m.Register(&client.ServiceInstanceRevoke{})
 	m.Register(&client.ServiceInstanceBind{})
 	m.RegisterRemoved("service-bind", "You should use `tsuru service-instance-bind` instead.")
 	m.Register(&client.ServiceInstanceUnbind{})
 	m.RegisterRemoved("service-unbind", "You should use `tsuru service-instance-unbind` instead.")
+	m.Register(&client.ServiceInstanceBindStatus{})
 	m.Register(&admin.PlatformList{})
 	m.Register(&admin.PlatformAdd{})
 	m.Register(&client.PluginInstall{})
@@ -76,6 +91,7 @@ func buildManager(name string) *cmd.Manager {
 	m.Register(&client.PluginList{})
 	m.Register(&client.AppSwap{})
 	m.Register(&client.AppDeploy{})
+	m.Register(&client.AppBuild{})
 	m.Register(&client.PlanList{})
 	m.RegisterRemoved("app-team-owner-set", "You should use `tsuru service-info` instead.")
 	m.Register(&client.UserCreate{})
@@ -94,7 +110,7 @@ func buildManager(name string) *cmd.Manager {
 	m.Register(&client.RegenerateAPIToken{})
 	m.Register(&client.AppDeployList{})
 	m.Register(&client.AppDeployRollback{})
-	m.Register(&cmd.ShellToContainerCmd{})
+	m.Register(&client.ShellToContainerCmd{})
 	m.Register(&client.PoolList{})
 	m.Register(&client.PermissionList{})
 	m.Register(&client.RoleAdd{})
@@ -110,12 +126,16 @@ func buildManager(name string) *cmd.Manager {
 	m.Register(&client.RoleDefaultRemove{})
 	m.Register(&installer.Install{})
 	m.Register(&installer.Uninstall{})
+	m.Register(&installer.InstallInfo{})
 	m.Register(&installer.InstallHostList{})
 	m.Register(&installer.InstallSSH{})
+	m.Register(&installer.InstallHostAdd{})
+	m.Register(&installer.InstallHostRemove{})
 	m.Register(&admin.AddPoolToSchedulerCmd{})
 	m.Register(&client.EventList{})
 	m.Register(&client.EventInfo{})
 	m.Register(&client.EventCancel{})
+	m.Register(&client.APIRequest{})
 	m.RegisterDeprecated(&admin.AddNodeCmd{}, "docker-node-add")
 	m.RegisterDeprecated(&admin.RemoveNodeCmd{}, "docker-node-remove")
 	m.RegisterDeprecated(&admin.UpdateNodeCmd{}, "docker-node-update")
@@ -123,25 +143,120 @@ func buildManager(name string) *cmd.Manager {
 	m.RegisterDeprecated(&admin.GetNodeHealingConfigCmd{}, "docker-healing-info")
 	m.RegisterDeprecated(&admin.SetNodeHealingConfigCmd{}, "docker-healing-update")
 	m.RegisterDeprecated(&admin.DeleteNodeHealingConfigCmd{}, "docker-healing-delete")
-	registerProvisionersCommands(m)
+	registerProvisionersCommands(m, name)
+	m.RegisterAlias("ls", "app-list")
+	m.RegisterAlias("info", "app-info")
+	m.RegisterAlias("log", "app-log")
+	m.RegisterAlias("run", "app-run")
 	return m
 }
 
-func registerProvisionersCommands(m *cmd.Manager) {
+func registerProvisionersCommands(m *cmd.Manager, name string) {
 	provisioners, err := provision.Registry()
 	if err != nil {
 		log.Fatalf("Unable to list provisioners: %s", err)
 	}
+	// The version header is left empty because the client given to the
+	// commands already warns about unsupported versions.
+	manager := tsurucmd.NewManager(name, version, "", os.Stdout, os.Stderr, os.Stdin, nil)
 	for _, p := range provisioners {
-		if c, ok := p.(cmd.AdminCommandable); ok {
+		if c, ok := p.(tsurucmd.AdminCommandable); ok {
 			commands := c.AdminCommands()
-			for _, cmd := range commands {
-				m.Register(cmd)
+			for _, command := range commands {
+				if removed, ok := command.(*tsurucmd.RemovedCommand); ok {
+					m.RegisterRemoved(removed.Name, removed.Help)
+					continue
+				}
+				m.Register(&provisionerCommand{Command: command, manager: manager})
 			}
 		}
 	}
 }
 
+// provisionerCommand adapts the admin commands of the provisioners, which
+// are written against the cmd package of tsuru, to the client's manager.
+type provisionerCommand struct {
+	tsurucmd.Command
+	manager *tsurucmd.Manager
+}
+
+func (c *provisionerCommand) Info() *cmd.Info {
+	info := c.Command.Info()
+	return &cmd.Info{
+		Name:    info.Name,
+		MinArgs: info.MinArgs,
+		MaxArgs: info.MaxArgs,
+		Usage:   info.Usage,
+		Desc:    info.Desc,
+	}
+}
+
+func (c *provisionerCommand) Flags() *gnuflag.FlagSet {
+	if flagged, ok := c.Command.(tsurucmd.FlaggedCommand); ok {
+		return flagged.Flags()
+	}
+	return gnuflag.NewFlagSet(c.Command.Info().Name, gnuflag.ExitOnError)
+}
+
+// Run runs the command with a client of the tsuru cmd package that sends the
+// requests through the client given by the manager, so the target, token,
+// proxy, client certificate and verbosity of the client apply to them.
+func (c *provisionerCommand) Run(context *cmd.Context, client *cmd.Client) error {
+	target, err := cmd.GetTarget()
+	if err != nil {
+		return err
+	}
+	// The commands build the URLs with the tsuru cmd package, which only
+	// knows about the TSURU_TARGET environment variable and the target file.
+	if previous, ok := os.LookupEnv("TSURU_TARGET"); ok {
+		defer os.Setenv("TSURU_TARGET", previous)
+	} else {
+		defer os.Unsetenv("TSURU_TARGET")
+	}
+	os.Setenv("TSURU_TARGET", target)
+	ctx := &tsurucmd.Context{
+		Args:   context.Args,
+		Stdout: context.Stdout,
+		Stderr: context.Stderr,
+		Stdin:  context.Stdin,
+	}
+	transport := &clientTransport{client: client}
+	err = c.Command.Run(ctx, tsurucmd.NewClient(&http.Client{Transport: transport}, ctx, c.manager))
+	if transport.err != nil {
+		return transport.err
+	}
+	return err
+}
+
+// clientTransport sends the requests of the provisioner commands with the
+// client of the manager. HTTP errors are given back as responses, so the
+// tsuru cmd package builds the same error, and other errors are kept in err,
+// to be returned instead of the connection error reported by that package.
+type clientTransport struct {
+	client *cmd.Client
+	err    error
+}
+
+func (t *clientTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	r := *request
+	r.Header = make(http.Header, len(request.Header))
+	for k, v := range request.Header {
+		r.Header[k] = v
+	}
+	r.Header.Del("Authorization")
+	response, err := t.client.Do(&r)
+	if httpErr, ok := err.(*tsuruerr.HTTP); ok && response != nil {
+		response.Body.Close()
+		response.Body = ioutil.NopCloser(strings.NewReader(httpErr.Message))
+		return response, nil
+	}
+	if err != nil {
+		t.err = err
+		return nil, err
+	}
+	return response, nil
+}
+
 func inDockerMachineDriverMode() bool {
 	return os.Getenv(localbinary.PluginEnvKey) == localbinary.PluginEnvVal
 }
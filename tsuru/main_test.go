@@ -6,6 +6,8 @@ package main
 
 import (
 	"bytes"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -14,8 +16,9 @@ import (
 
 	"github.com/tsuru/tsuru-client/tsuru/admin"
 	"github.com/tsuru/tsuru-client/tsuru/client"
+	"github.com/tsuru/tsuru-client/tsuru/cmd"
 	"github.com/tsuru/tsuru-client/tsuru/installer"
-	"github.com/tsuru/tsuru/cmd"
+	tsuruerr "github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/exec/exectest"
 )
 
@@ -93,6 +96,13 @@ func (s *S) TestAppLogIsRegistered(c *check.C) {
 	c.Assert(log, check.FitsTypeOf, &client.AppLog{})
 }
 
+func (s *S) TestAppLogMultiIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	log, ok := manager.Commands["app-log-multi"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(log, check.FitsTypeOf, &client.AppLogMulti{})
+}
+
 func (s *S) TestAppRunIsRegistered(c *check.C) {
 	manager = buildManager("tsuru")
 	run, ok := manager.Commands["app-run"]
@@ -128,6 +138,20 @@ func (s *S) TestEnvUnsetIsRegistered(c *check.C) {
 	c.Assert(unset, check.FitsTypeOf, &client.EnvUnset{})
 }
 
+func (s *S) TestEnvDiffIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	diff, ok := manager.Commands["env-diff"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(diff, check.FitsTypeOf, &client.EnvDiff{})
+}
+
+func (s *S) TestEnvCopyIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	copy, ok := manager.Commands["env-copy"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(copy, check.FitsTypeOf, &client.EnvCopy{})
+}
+
 func (s *S) TestKeyAddIsRegistered(c *check.C) {
 	manager = buildManager("tsuru")
 	add, ok := manager.Commands["key-add"]
@@ -226,6 +250,13 @@ func (s *S) TestServiceInstanceUnbindIsRegistered(c *check.C) {
 	c.Assert(unbind, check.FitsTypeOf, &client.ServiceInstanceUnbind{})
 }
 
+func (s *S) TestServiceInstanceBindStatusIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	status, ok := manager.Commands["service-instance-bind-status"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(status, check.FitsTypeOf, &client.ServiceInstanceBindStatus{})
+}
+
 func (s *S) TestServiceInstanceInfoIsRegistered(c *check.C) {
 	manager = buildManager("tsuru")
 	info, ok := manager.Commands["service-instance-info"]
@@ -251,7 +282,7 @@ func (s *S) TestServiceInstanceStatusIsRegistered(c *check.C) {
 	manager = buildManager("tsuru")
 	status, ok := manager.Commands["service-instance-status"]
 	c.Assert(ok, check.Equals, true)
-	c.Assert(status, check.FitsTypeOf, client.ServiceInstanceStatus{})
+	c.Assert(status, check.FitsTypeOf, &client.ServiceInstanceStatus{})
 }
 
 func (s *S) TestAppInfoIsRegistered(c *check.C) {
@@ -275,6 +306,20 @@ func (s *S) TestUnitRemoveIsRegistered(c *check.C) {
 	c.Assert(rmunit, check.FitsTypeOf, &client.UnitRemove{})
 }
 
+func (s *S) TestUnitKillIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	kill, ok := manager.Commands["unit-kill"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(kill, check.FitsTypeOf, &client.UnitKill{})
+}
+
+func (s *S) TestAPIRequestIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	api, ok := manager.Commands["api"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(api, check.FitsTypeOf, &client.APIRequest{})
+}
+
 func (s *S) TestCNameAddIsRegistered(c *check.C) {
 	manager = buildManager("tsuru")
 	cname, ok := manager.Commands["cname-add"]
@@ -368,6 +413,13 @@ func (s *S) TestAppDeployIsRegistered(c *check.C) {
 	c.Assert(deployCmd, check.FitsTypeOf, &client.AppDeploy{})
 }
 
+func (s *S) TestAppBuildIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	buildCmd, ok := manager.Commands["app-build"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(buildCmd, check.FitsTypeOf, &client.AppBuild{})
+}
+
 func (s *S) TestPlanListRegistered(c *check.C) {
 	manager = buildManager("tsuru")
 	list, ok := manager.Commands["plan-list"]
@@ -459,6 +511,13 @@ func (s *S) TestPoolListIsRegistered(c *check.C) {
 	c.Assert(list, check.FitsTypeOf, &client.PoolList{})
 }
 
+func (s *S) TestAppShellIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	shell, ok := manager.Commands["app-shell"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(shell, check.FitsTypeOf, &client.ShellToContainerCmd{})
+}
+
 func (s *S) TestAppUpdateIsRegistered(c *check.C) {
 	manager = buildManager("tsuru")
 	change, ok := manager.Commands["app-update"]
@@ -466,6 +525,34 @@ func (s *S) TestAppUpdateIsRegistered(c *check.C) {
 	c.Assert(change, check.FitsTypeOf, &client.AppUpdate{})
 }
 
+func (s *S) TestAppMoveIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	move, ok := manager.Commands["app-move"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(move, check.FitsTypeOf, &client.AppMove{})
+}
+
+func (s *S) TestAppRoutersListIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	command, ok := manager.Commands["app-router-list"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(command, check.FitsTypeOf, &client.AppRoutersList{})
+}
+
+func (s *S) TestAppRoutersAddIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	command, ok := manager.Commands["app-router-add"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(command, check.FitsTypeOf, &client.AppRoutersAdd{})
+}
+
+func (s *S) TestAppRoutersRemoveIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	command, ok := manager.Commands["app-router-remove"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(command, check.FitsTypeOf, &client.AppRoutersRemove{})
+}
+
 func (s *S) TestInstallIsRegistered(c *check.C) {
 	manager = buildManager("tsuru")
 	change, ok := manager.Commands["install"]
@@ -480,6 +567,27 @@ func (s *S) TestUninstallIsRegistered(c *check.C) {
 	c.Assert(change, check.FitsTypeOf, &installer.Uninstall{})
 }
 
+func (s *S) TestInstallInfoIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	change, ok := manager.Commands["install-info"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(change, check.FitsTypeOf, &installer.InstallInfo{})
+}
+
+func (s *S) TestInstallHostAddIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	change, ok := manager.Commands["install-host-add"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(change, check.FitsTypeOf, &installer.InstallHostAdd{})
+}
+
+func (s *S) TestInstallHostRemoveIsRegistered(c *check.C) {
+	manager = buildManager("tsuru")
+	change, ok := manager.Commands["install-host-remove"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(change, check.FitsTypeOf, &installer.InstallHostRemove{})
+}
+
 func (s *S) TestNodeAddIsRegistered(c *check.C) {
 	manager = buildManager("tsuru")
 	change, ok := manager.Commands["node-add"]
@@ -549,3 +657,43 @@ func (s *S) TestNodeHealingDeleteIsRegistered(c *check.C) {
 	c.Assert(ok, check.Equals, true)
 	c.Assert(change, check.FitsTypeOf, &cmd.DeprecatedCommand{})
 }
+
+func (s *S) runProvisionerCommand(c *check.C, handler http.HandlerFunc, stdout, stderr *bytes.Buffer) error {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", server.URL)
+	defer os.Setenv("TSURU_TARGET", "http://localhost:8080")
+	m := cmd.NewManager("tsuru", version, header, stdout, stderr, os.Stdin, nil)
+	registerProvisionersCommands(m, "tsuru")
+	command, ok := m.Commands["docker-log-info"]
+	c.Assert(ok, check.Equals, true)
+	context := cmd.Context{Stdout: stdout, Stderr: stderr}
+	client := cmd.NewClient(http.DefaultClient, &context, m)
+	client.Verbosity = 1
+	return command.Run(&context, client)
+}
+
+func (s *S) TestProvisionerCommandUsesTheClientOfTheManager(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	err := s.runProvisionerCommand(c, func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/1.0/docker/logs")
+		c.Check(r.Header.Get("Authorization"), check.Equals, "bearer sometoken")
+		w.Write([]byte(`{"":{"Driver":"syslog"}}`))
+	}, &stdout, &stderr)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Matches, "(?s)Log driver \\[default\\]: syslog\n.*")
+	c.Assert(stderr.String(), check.Matches, `(?s)\*+ <Request uri="/1.0/docker/logs"> \*+.*Authorization: <redacted>.*`)
+	c.Assert(os.Getenv("TSURU_TARGET"), check.Equals, "http://localhost:8080")
+}
+
+func (s *S) TestProvisionerCommandHTTPError(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	err := s.runProvisionerCommand(c, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "pool not found", http.StatusNotFound)
+	}, &stdout, &stderr)
+	httpErr, ok := err.(*tsuruerr.HTTP)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(httpErr.Code, check.Equals, http.StatusNotFound)
+	c.Assert(httpErr.Message, check.Equals, "pool not found\n")
+	c.Assert(cmd.ExitCode(err), check.Equals, cmd.ExitNotFound)
+}